@@ -17,10 +17,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"os/signal"
 	"syscall"
 
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
@@ -69,19 +72,27 @@ func realMain(ctx context.Context) error {
 	}
 	defer env.Close(ctx)
 
-	federationServer := federationout.NewServer(env, &config)
+	federationServer, err := federationout.NewServer(env, &config)
+	if err != nil {
+		return fmt.Errorf("federationout.NewServer: %w", err)
+	}
+
+	fedServer := federationServer.(*federationout.Server)
 
 	var sopts []grpc.ServerOption
+	var tlsConfig *tls.Config
 	if config.TLSCertFile != "" && config.TLSKeyFile != "" {
-		creds, err := credentials.NewServerTLSFromFile(config.TLSCertFile, config.TLSKeyFile)
+		tlsConfig, err = federationout.ServerTLSConfig(&config)
 		if err != nil {
 			return fmt.Errorf("failed to create credentials: %w", err)
 		}
-		sopts = append(sopts, grpc.Creds(creds))
+		sopts = append(sopts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 	}
 
 	if !config.AllowAnyClient {
-		sopts = append(sopts, grpc.UnaryInterceptor(federationServer.(*federationout.Server).AuthInterceptor))
+		sopts = append(sopts,
+			grpc.UnaryInterceptor(fedServer.AuthInterceptor),
+			grpc.StreamInterceptor(fedServer.StreamAuthInterceptor))
 	}
 
 	sopts = append(sopts, grpc.StatsHandler(&ocgrpc.ServerHandler{}))
@@ -94,5 +105,30 @@ func realMain(ctx context.Context) error {
 	}
 	logger.Infof("listening on :%s", config.Port)
 
-	return srv.ServeGRPC(ctx, grpcServer)
+	if config.HTTPPort == "" {
+		return srv.ServeGRPC(ctx, grpcServer)
+	}
+
+	listener, err := net.Listen("tcp", ":"+config.HTTPPort)
+	if err != nil {
+		return fmt.Errorf("failed to create listener on %s: %w", config.HTTPPort, err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	httpSrv, err := server.NewFromListener(listener)
+	if err != nil {
+		return fmt.Errorf("server.NewFromListener: %w", err)
+	}
+	logger.Infof("HTTP gateway listening on :%s", config.HTTPPort)
+
+	// Stop both servers if either fails.
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		return srv.ServeGRPC(ctx, grpcServer)
+	})
+	g.Go(func() error {
+		return httpSrv.ServeHTTPHandler(ctx, fedServer.Routes(ctx))
+	})
+	return g.Wait()
 }
@@ -248,3 +248,10 @@ func timePtr(t time.Time) *time.Time {
 	}
 	return &t
 }
+
+func durationPtr(d time.Duration) *time.Duration {
+	if d == 0 {
+		return nil
+	}
+	return &d
+}
@@ -82,24 +82,71 @@ func (s *Server) HandleExportsShow() func(c *gin.Context) {
 			return
 		}
 
-		usedSigInfos := make(map[int64]bool)
-		for _, id := range record.SignatureInfoIDs {
-			usedSigInfos[id] = true
+		s.renderExport(c, db, record, m)
+	}
+}
+
+// HandleExportsBackfill regenerates the files of an export config for a time
+// range.
+func (s *Server) HandleExportsBackfill() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form backfillFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		db := database.New(s.env.Database())
+		record, err := s.getExportConfig(ctx, db, c.Param("id"))
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Failed to load export config: %s", err))
+			return
+		}
+		if record.ConfigID == 0 {
+			ErrorPage(c, "Export config must be saved before it can be backfilled")
+			return
+		}
+
+		from, thru, err := form.Range()
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Invalid backfill range: %v", err))
+			return
 		}
 
-		sigInfos, err := db.ListAllSignatureInfos(ctx)
+		n, err := db.BackfillBatches(ctx, record, from, thru)
 		if err != nil {
-			ErrorPage(c, fmt.Sprintf("Error reading the database: %v", err))
+			ErrorPage(c, fmt.Sprintf("Error creating backfill batches: %v", err))
 			return
 		}
 
-		m["export"] = record
-		m["usedSigInfos"] = usedSigInfos
-		m["siginfos"] = sigInfos
-		c.HTML(http.StatusOK, "export", m)
+		m.AddSuccess(fmt.Sprintf("Created %d backfill batches for export config #%v", n, record.ConfigID))
+		s.renderExport(c, db, record, m)
 	}
 }
 
+func (s *Server) renderExport(c *gin.Context, db *database.ExportDB, record *model.ExportConfig, m TemplateMap) {
+	ctx := c.Request.Context()
+
+	usedSigInfos := make(map[int64]bool)
+	for _, id := range record.SignatureInfoIDs {
+		usedSigInfos[id] = true
+	}
+
+	sigInfos, err := db.ListAllSignatureInfos(ctx)
+	if err != nil {
+		ErrorPage(c, fmt.Sprintf("Error reading the database: %v", err))
+		return
+	}
+
+	m["export"] = record
+	m["usedSigInfos"] = usedSigInfos
+	m["siginfos"] = sigInfos
+	c.HTML(http.StatusOK, "export", m)
+}
+
 // getExportConfig gets an export config with the given id. If the id is "" or
 // "0", an empty record is returned. Otherwise, it attempts to find a record
 // with the id.
@@ -134,6 +181,35 @@ type exportFormData struct {
 	ThruTime           string        `form:"thru-time"`
 	SigInfoIDs         []int64       `form:"sig-info"`
 	MaxRecordsOverride int           `form:"max-records-override"`
+	IndexFormats       []string      `form:"index-formats"`
+	RollingHours       int           `form:"rolling-hours"`
+	DailyRollup        bool          `form:"daily-rollup"`
+}
+
+type backfillFormData struct {
+	FromDate string `form:"backfill-from-date"`
+	FromTime string `form:"backfill-from-time"`
+	ThruDate string `form:"backfill-thru-date"`
+	ThruTime string `form:"backfill-thru-time"`
+}
+
+// Range returns the time range to backfill.
+func (f *backfillFormData) Range() (time.Time, time.Time, error) {
+	from, err := CombineDateAndTime(f.FromDate, f.FromTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from time: %w", err)
+	}
+	thru, err := CombineDateAndTime(f.ThruDate, f.ThruTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid thru time: %w", err)
+	}
+	if from.IsZero() || thru.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("from and thru are required")
+	}
+	if !thru.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("thru must be after from")
+	}
+	return from, thru, nil
 }
 
 // splitRegions turns a string of regions (generally separated by newlines), and
@@ -174,6 +250,9 @@ func (f *exportFormData) PopulateExportConfig(ec *model.ExportConfig) error {
 	ec.From = from
 	ec.Thru = thru
 	ec.SignatureInfoIDs = f.SigInfoIDs
+	ec.IndexFormats = f.IndexFormats
+	ec.RollingHours = f.RollingHours
+	ec.DailyRollup = f.DailyRollup
 	if f.MaxRecordsOverride > 0 {
 		ec.MaxRecordsOverride = &f.MaxRecordsOverride
 	} else {
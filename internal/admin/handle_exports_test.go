@@ -408,3 +408,100 @@ func TestHandleExportsSave(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleExportsBackfill(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := env.Database()
+	exportDB := database.New(db)
+
+	exportConfig := &model.ExportConfig{
+		BucketName:   "bucket",
+		FilenameRoot: "root",
+		Period:       4 * time.Hour,
+		OutputRegion: "TEST",
+	}
+	if err := exportDB.AddExportConfig(ctx, exportConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		id     string
+		form   *backfillFormData
+		status int
+		want   []string
+	}{
+		{
+			name: "backfill",
+			id:   fmt.Sprintf("%d", exportConfig.ConfigID),
+			form: &backfillFormData{
+				FromDate: "2021-01-02",
+				FromTime: "00:00",
+				ThruDate: "2021-01-03",
+				ThruTime: "00:00",
+			},
+			status: 200,
+			want:   []string{"Created 0 backfill batches"},
+		},
+		{
+			name:   "missing_range",
+			id:     fmt.Sprintf("%d", exportConfig.ConfigID),
+			form:   &backfillFormData{},
+			status: 500,
+			want:   []string{"Invalid backfill range"},
+		},
+		{
+			name: "new_config",
+			id:   "0",
+			form: &backfillFormData{
+				FromDate: "2021-01-02",
+				ThruDate: "2021-01-03",
+			},
+			status: 500,
+			want:   []string{"must be saved before it can be backfilled"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodPost, "/:id", s.HandleExportsBackfill())
+
+			form, err := serializeForm(tc.form)
+			if err != nil {
+				t.Fatalf("unable to serialize form: %v", err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s", server.URL, tc.id), strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("error making http call: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if got, want := resp.StatusCode, tc.status; got != want {
+				b, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Errorf("expected status %d to be %d; body: %s", got, want, b)
+			}
+
+			if len(tc.want) > 0 {
+				mustFindStrings(t, resp, tc.want...)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/federationin/database"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// federationInSyncsShown is the number of recent syncs shown for a query.
+const federationInSyncsShown = 10
+
+// federationInResetLockTTL is how long the sync lock of a query is held for
+// while its cursor is reset, in case it is not released.
+const federationInResetLockTTL = time.Minute
+
+// HandleFederationInSave handles the create/update, disable/enable and reset
+// cursor actions for federation in queries.
+func (s *Server) HandleFederationInSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form federationInFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		db := database.New(s.env.Database())
+		query := &model.FederationInQuery{}
+		isNew := form.FormKey == ""
+		if !isNew {
+			var err error
+			query, err = db.GetFederationInQuery(ctx, form.FormKey)
+			if err != nil {
+				if errors.Is(err, coredb.ErrNotFound) {
+					ErrorPage(c, "Unknown federation in query")
+					return
+				}
+				ErrorPage(c, fmt.Sprintf("Error loading federation in query: %v", err))
+				return
+			}
+		}
+
+		switch form.Action {
+		case "save":
+			form.PopulateFederationInQuery(query)
+
+			errs := query.Validate()
+			if isNew && len(errs) == 0 {
+				if _, err := db.GetFederationInQuery(ctx, query.QueryID); err == nil {
+					errs = append(errs, fmt.Sprintf("Query %v already exists", query.QueryID))
+				} else if !errors.Is(err, coredb.ErrNotFound) {
+					ErrorPage(c, fmt.Sprintf("Error loading federation in query: %v", err))
+					return
+				}
+			}
+			if len(errs) > 0 {
+				m.AddErrors(errs...)
+				s.renderFederationIn(c, m, db, query, isNew)
+				return
+			}
+
+			if err := db.AddFederationInQuery(ctx, query); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing federation in query: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Saved federation in query: %v", query.QueryID))
+		case "disable", "enable":
+			if isNew {
+				ErrorPage(c, "Invalid request, query to edit not found.")
+				return
+			}
+
+			query.Disabled = form.Action == "disable"
+			if err := db.AddFederationInQuery(ctx, query); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing federation in query: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Successfully %sd federation in query: %v", form.Action, query.QueryID))
+		case "reset":
+			if isNew {
+				ErrorPage(c, "Invalid request, query to edit not found.")
+				return
+			}
+
+			since, err := CombineDateAndTime(form.ResetDate, form.ResetTime)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("Invalid reset time: %v", err))
+				return
+			}
+
+			// Take the sync lock, so that a running sync can't overwrite the cursor
+			// when it finishes.
+			unlock, err := db.Lock(ctx, database.QueryLockID(query.QueryID), federationInResetLockTTL)
+			if err != nil {
+				if errors.Is(err, coredb.ErrAlreadyLocked) {
+					ErrorPage(c, "Unable to reset federation in query cursor: sync in progress, try again later.")
+					return
+				}
+				ErrorPage(c, fmt.Sprintf("Error locking federation in query: %v", err))
+				return
+			}
+			resetErr := db.ResetFederationInQueryCursor(ctx, query.QueryID, since)
+			if err := unlock(); err != nil {
+				m.AddErrors(fmt.Sprintf("Error unlocking federation in query, syncs resume in %v: %v", federationInResetLockTTL, err))
+			}
+			if resetErr != nil {
+				ErrorPage(c, fmt.Sprintf("Error resetting federation in query cursor: %v", resetErr))
+				return
+			}
+			query.ResetFetchState(since)
+			m.AddSuccess(fmt.Sprintf("Reset cursor of federation in query: %v", query.QueryID))
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		s.renderFederationIn(c, m, db, query, false)
+	}
+}
+
+// HandleFederationInShow handles the show page for federation in queries.
+func (s *Server) HandleFederationInShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		db := database.New(s.env.Database())
+		query := &model.FederationInQuery{}
+
+		queryID, _ := c.GetQuery("id")
+		isNew := queryID == ""
+		if !isNew {
+			var err error
+			query, err = db.GetFederationInQuery(ctx, queryID)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("error loading federation in query: %v", err))
+				return
+			}
+		}
+
+		s.renderFederationIn(c, m, db, query, isNew)
+	}
+}
+
+// renderFederationIn renders the federation in query page, including the most
+// recent syncs of existing queries.
+func (s *Server) renderFederationIn(c *gin.Context, m TemplateMap, db *database.FederationInDB, query *model.FederationInQuery, isNew bool) {
+	var syncs []*model.FederationInSync
+	if !isNew {
+		var err error
+		syncs, err = db.ListFederationInSyncs(c.Request.Context(), query.QueryID, federationInSyncsShown)
+		if err != nil {
+			ErrorPage(c, fmt.Sprintf("Error loading federation in syncs: %v", err))
+			return
+		}
+	}
+
+	if isNew {
+		m.AddTitle("New federation in query")
+	} else {
+		m.AddTitle(fmt.Sprintf("federation in query %q", query.QueryID))
+	}
+	m["new"] = isNew
+	m["query"] = query
+	m["syncs"] = syncs
+	m["credentialTypes"] = model.CredentialTypes
+	m["transports"] = model.Transports
+	c.HTML(http.StatusOK, "federation-in", m)
+}
+
+type federationInFormData struct {
+	// Top Level
+	FormKey string `form:"key"`
+	Action  string `form:"action"`
+
+	// Query Data
+	QueryID             string `form:"query-id"`
+	ServerAddr          string `form:"server-addr"`
+	Audience            string `form:"audience"`
+	CredentialType      string `form:"credential-type"`
+	Transport           string `form:"transport"`
+	IncludeRegions      string `form:"include-regions"`
+	ExcludeRegions      string `form:"exclude-regions"`
+	OnlyLocalProvenance bool   `form:"only-local-provenance"`
+	OnlyTravelers       bool   `form:"only-travelers"`
+
+	// ResetDate and ResetTime are combined into the time the next pull
+	// starts from after a reset.
+	ResetDate string `form:"reset-date"`
+	ResetTime string `form:"reset-time"`
+}
+
+// PopulateFederationInQuery populates the query with the form data. The query
+// ID is the primary key of the query, so it is only set on new queries.
+func (f *federationInFormData) PopulateFederationInQuery(q *model.FederationInQuery) {
+	if q.QueryID == "" {
+		q.QueryID = project.TrimSpaceAndNonPrintable(f.QueryID)
+	}
+	q.ServerAddr = project.TrimSpaceAndNonPrintable(f.ServerAddr)
+	q.Audience = project.TrimSpaceAndNonPrintable(f.Audience)
+	q.CredentialType = project.TrimSpaceAndNonPrintable(f.CredentialType)
+	q.Transport = project.TrimSpaceAndNonPrintable(f.Transport)
+	q.IncludeRegions = regionsFromLines(f.IncludeRegions)
+	q.ExcludeRegions = regionsFromLines(f.ExcludeRegions)
+	q.OnlyLocalProvenance = f.OnlyLocalProvenance
+	q.OnlyTravelers = f.OnlyTravelers
+}
+
+// regionsFromLines parses a list of regions, one per line, into unique upper
+// case regions.
+func regionsFromLines(s string) []string {
+	var regions []string
+	seen := make(map[string]struct{})
+	for _, region := range strings.Split(s, "\n") {
+		region = strings.ToUpper(project.TrimSpaceAndNonPrintable(region))
+		if region == "" {
+			continue
+		}
+		if _, ok := seen[region]; ok {
+			continue
+		}
+		seen[region] = struct{}{}
+		regions = append(regions, region)
+	}
+	return regions
+}
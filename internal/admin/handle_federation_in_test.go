@@ -0,0 +1,400 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/database"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderFederationIn(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["new"] = false
+	m["query"] = &model.FederationInQuery{QueryID: "query"}
+	m["syncs"] = []*model.FederationInSync{{QueryID: "query", Started: time.Now()}}
+	m["credentialTypes"] = model.CredentialTypes
+	m["transports"] = model.Transports
+
+	testRenderTemplate(t, "federation-in", m)
+}
+
+func TestPopulateFederationInQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		query *model.FederationInQuery
+		form  *federationInFormData
+		exp   *model.FederationInQuery
+	}{
+		{
+			name:  "new",
+			query: &model.FederationInQuery{},
+			form: &federationInFormData{
+				QueryID:             " query ",
+				ServerAddr:          "example.com:443",
+				Audience:            "aud",
+				CredentialType:      model.CredentialTypeOIDC,
+				Transport:           model.TransportHTTP,
+				IncludeRegions:      "us\nCA\n\nus",
+				ExcludeRegions:      "MX",
+				OnlyLocalProvenance: true,
+				OnlyTravelers:       true,
+			},
+			exp: &model.FederationInQuery{
+				QueryID:             "query",
+				ServerAddr:          "example.com:443",
+				Audience:            "aud",
+				CredentialType:      model.CredentialTypeOIDC,
+				Transport:           model.TransportHTTP,
+				IncludeRegions:      []string{"US", "CA"},
+				ExcludeRegions:      []string{"MX"},
+				OnlyLocalProvenance: true,
+				OnlyTravelers:       true,
+			},
+		},
+		{
+			name: "existing",
+			query: &model.FederationInQuery{
+				QueryID:        "query",
+				IncludeRegions: []string{"US"},
+				Disabled:       true,
+				LastCursor:     "cursor",
+			},
+			form: &federationInFormData{
+				QueryID:    "other",
+				ServerAddr: "example.com",
+			},
+			exp: &model.FederationInQuery{
+				QueryID:    "query",
+				ServerAddr: "example.com",
+				Disabled:   true,
+				LastCursor: "cursor",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.form.PopulateFederationInQuery(tc.query)
+			if diff := cmp.Diff(tc.exp, tc.query); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandleFederationInShow(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := database.New(env.Database())
+
+	query := &model.FederationInQuery{
+		QueryID:    "query",
+		ServerAddr: "federation.example.com:443",
+	}
+	if err := db.AddFederationInQuery(ctx, query); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, finalize, err := db.StartFederationInSync(ctx, query, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &federation.FetchState{
+		KeyCursor:        &federation.Cursor{Timestamp: started.Unix()},
+		RevisedKeyCursor: &federation.Cursor{Timestamp: started.Unix()},
+	}
+	if err := finalize(state, query, 42, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		id   string
+		want []string
+	}{
+		{
+			name: "lookup_existing",
+			id:   query.QueryID,
+			want: []string{query.ServerAddr, "Recent Syncs", started.Format(time.UnixDate), "<td class=\"text-monospace\">42</td>"},
+		},
+		{
+			name: "show_new",
+			id:   "",
+			want: []string{"New Federation In Query"},
+		},
+		{
+			name: "non_existing",
+			id:   "banana",
+			want: []string{"error loading federation in query"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodGet, "/", s.HandleFederationInShow())
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?id=%s", server.URL, url.QueryEscape(tc.id)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := server.Client()
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("error making http call: %v", err)
+			}
+			defer resp.Body.Close()
+
+			mustFindStrings(t, resp, tc.want...)
+		})
+	}
+}
+
+func TestHandleFederationInSave(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := database.New(env.Database())
+
+	for _, id := range []string{"existing", "to-disable", "to-enable", "to-reset", "syncing"} {
+		query := &model.FederationInQuery{
+			QueryID:    id,
+			ServerAddr: "federation.example.com:443",
+			Disabled:   id == "to-enable",
+		}
+		if err := db.AddFederationInQuery(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hold the sync lock of a query, like a running sync.
+	if _, err := db.Lock(ctx, database.QueryLockID("syncing"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		form  *federationInFormData
+		want  []string
+		check func(t *testing.T, q *model.FederationInQuery)
+	}{
+		// Create
+		{
+			name: "create_new",
+			form: &federationInFormData{
+				Action:     "save",
+				QueryID:    "created",
+				ServerAddr: "federation.example.com:443",
+			},
+			want: []string{"Saved federation in query: created"},
+		},
+		{
+			name: "create_duplicate",
+			form: &federationInFormData{
+				Action:     "save",
+				QueryID:    "existing",
+				ServerAddr: "federation.example.com:443",
+			},
+			want: []string{"Query existing already exists"},
+		},
+		{
+			name: "create_bad_validation",
+			form: &federationInFormData{
+				Action:     "save",
+				QueryID:    "Not Valid",
+				ServerAddr: "https://federation.example.com",
+			},
+			want: []string{"Query ID must match", "Server address must match"},
+		},
+
+		// Update
+		{
+			name: "update_unknown",
+			form: &federationInFormData{
+				FormKey:    "not-a-real-query",
+				Action:     "save",
+				ServerAddr: "federation.example.com:443",
+			},
+			want: []string{"Unknown federation in query"},
+		},
+		{
+			name: "update_existing",
+			form: &federationInFormData{
+				FormKey:        "existing",
+				Action:         "save",
+				QueryID:        "existing",
+				ServerAddr:     "other.example.com:443",
+				IncludeRegions: "US",
+			},
+			want: []string{"Saved federation in query: existing"},
+			check: func(t *testing.T, q *model.FederationInQuery) {
+				if got, want := q.ServerAddr, "other.example.com:443"; got != want {
+					t.Errorf("expected server address %q to be %q", got, want)
+				}
+			},
+		},
+
+		// Disable/enable
+		{
+			name: "disable",
+			form: &federationInFormData{
+				FormKey: "to-disable",
+				Action:  "disable",
+			},
+			want: []string{"Successfully disabled federation in query: to-disable"},
+			check: func(t *testing.T, q *model.FederationInQuery) {
+				if !q.Disabled {
+					t.Errorf("expected query to be disabled")
+				}
+			},
+		},
+		{
+			name: "enable",
+			form: &federationInFormData{
+				FormKey: "to-enable",
+				Action:  "enable",
+			},
+			want: []string{"Successfully enabled federation in query: to-enable"},
+			check: func(t *testing.T, q *model.FederationInQuery) {
+				if q.Disabled {
+					t.Errorf("expected query to be enabled")
+				}
+			},
+		},
+		{
+			name: "disable_new",
+			form: &federationInFormData{
+				Action: "disable",
+			},
+			want: []string{"query to edit not found"},
+		},
+
+		// Reset
+		{
+			name: "reset",
+			form: &federationInFormData{
+				FormKey:   "to-reset",
+				Action:    "reset",
+				ResetDate: "2021-01-02",
+				ResetTime: "03:04",
+			},
+			want: []string{"Reset cursor of federation in query: to-reset"},
+			check: func(t *testing.T, q *model.FederationInQuery) {
+				if got, want := q.LastTimestamp, time.Date(2021, 1, 2, 3, 4, 0, 0, time.UTC); !got.Equal(want) {
+					t.Errorf("expected last timestamp %v to be %v", got, want)
+				}
+			},
+		},
+		{
+			name: "reset_bad_time",
+			form: &federationInFormData{
+				FormKey:   "to-reset",
+				Action:    "reset",
+				ResetDate: "banana",
+			},
+			want: []string{"Invalid reset time"},
+		},
+		{
+			name: "reset_syncing",
+			form: &federationInFormData{
+				FormKey:   "syncing",
+				Action:    "reset",
+				ResetDate: "2021-01-02",
+				ResetTime: "03:04",
+			},
+			want: []string{"sync in progress"},
+			check: func(t *testing.T, q *model.FederationInQuery) {
+				if !q.LastTimestamp.IsZero() {
+					t.Errorf("expected last timestamp %v to be unchanged", q.LastTimestamp)
+				}
+			},
+		},
+
+		// Unknown action
+		{
+			name: "unknown_action",
+			form: &federationInFormData{
+				FormKey: "existing",
+				Action:  "banana",
+			},
+			want: []string{"Invalid form action"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodPost, "/", s.HandleFederationInSave())
+
+			// URL values
+			form, err := serializeForm(tc.form)
+			if err != nil {
+				t.Fatalf("unable to serialize form: %v", err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			client := server.Client()
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("error making http call: %v", err)
+			}
+			defer resp.Body.Close()
+
+			mustFindStrings(t, resp, tc.want...)
+
+			if tc.check != nil {
+				q, err := db.GetFederationInQuery(ctx, tc.form.FormKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tc.check(t, q)
+			}
+		})
+	}
+}
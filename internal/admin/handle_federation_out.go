@@ -0,0 +1,167 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/federationout/database"
+	"github.com/google/exposure-notifications-server/internal/project"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+)
+
+// HandleFederationOutSave handles the create/update and disable/enable actions
+// for federation out authorizations.
+func (s *Server) HandleFederationOutSave() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var form federationOutFormData
+		if err := c.Bind(&form); err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		db := database.New(s.env.Database())
+		auth := &model.FederationOutAuthorization{}
+		isNew := form.KeyIssuer == "" && form.KeySubject == ""
+		if !isNew {
+			var err error
+			auth, err = db.GetFederationOutAuthorization(ctx, form.KeyIssuer, form.KeySubject)
+			if err != nil {
+				if errors.Is(err, coredb.ErrNotFound) {
+					ErrorPage(c, "Unknown federation out authorization")
+					return
+				}
+				ErrorPage(c, fmt.Sprintf("Error loading federation out authorization: %v", err))
+				return
+			}
+		}
+
+		switch form.Action {
+		case "save":
+			form.PopulateFederationOutAuthorization(auth)
+
+			errs := auth.Validate()
+			if isNew && len(errs) == 0 {
+				if _, err := db.GetFederationOutAuthorization(ctx, auth.Issuer, auth.Subject); err == nil {
+					errs = append(errs, "Authorization for this issuer and subject already exists")
+				} else if !errors.Is(err, coredb.ErrNotFound) {
+					ErrorPage(c, fmt.Sprintf("Error loading federation out authorization: %v", err))
+					return
+				}
+			}
+			if len(errs) > 0 {
+				m.AddErrors(errs...)
+				renderFederationOut(c, m, auth, isNew)
+				return
+			}
+
+			if err := db.AddFederationOutAuthorization(ctx, auth); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing federation out authorization: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Saved federation out authorization: %v", auth.Subject))
+		case "disable", "enable":
+			if isNew {
+				ErrorPage(c, "Invalid request, authorization to edit not found.")
+				return
+			}
+
+			auth.Disabled = form.Action == "disable"
+			if err := db.AddFederationOutAuthorization(ctx, auth); err != nil {
+				ErrorPage(c, fmt.Sprintf("Error writing federation out authorization: %v", err))
+				return
+			}
+			m.AddSuccess(fmt.Sprintf("Successfully %sd federation out authorization: %v", form.Action, auth.Subject))
+		default:
+			ErrorPage(c, "Invalid form action")
+			return
+		}
+
+		renderFederationOut(c, m, auth, false)
+	}
+}
+
+// HandleFederationOutShow handles the show page for federation out
+// authorizations.
+func (s *Server) HandleFederationOutShow() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		m := TemplateMap{}
+
+		auth := &model.FederationOutAuthorization{}
+
+		issuer, _ := c.GetQuery("issuer")
+		subject, _ := c.GetQuery("subject")
+		isNew := issuer == "" && subject == ""
+		if !isNew {
+			var err error
+			auth, err = database.New(s.env.Database()).GetFederationOutAuthorization(ctx, issuer, subject)
+			if err != nil {
+				ErrorPage(c, fmt.Sprintf("error loading federation out authorization: %v", err))
+				return
+			}
+		}
+
+		renderFederationOut(c, m, auth, isNew)
+	}
+}
+
+// renderFederationOut renders the federation out authorization page.
+func renderFederationOut(c *gin.Context, m TemplateMap, auth *model.FederationOutAuthorization, isNew bool) {
+	if isNew {
+		m.AddTitle("New federation out authorization")
+	} else {
+		m.AddTitle(fmt.Sprintf("federation out authorization %q", auth.Subject))
+	}
+	m["new"] = isNew
+	m["auth"] = auth
+	c.HTML(http.StatusOK, "federation-out", m)
+}
+
+type federationOutFormData struct {
+	// Top Level
+	KeyIssuer  string `form:"key-issuer"`
+	KeySubject string `form:"key-subject"`
+	Action     string `form:"action"`
+
+	// Authorization Data
+	Issuer         string `form:"issuer"`
+	Subject        string `form:"subject"`
+	Audience       string `form:"audience"`
+	Note           string `form:"note"`
+	IncludeRegions string `form:"include-regions"`
+	ExcludeRegions string `form:"exclude-regions"`
+}
+
+// PopulateFederationOutAuthorization populates the authorization with the
+// form data. The issuer and subject are the primary key of the authorization,
+// so they are only set on new authorizations.
+func (f *federationOutFormData) PopulateFederationOutAuthorization(a *model.FederationOutAuthorization) {
+	if a.Issuer == "" && a.Subject == "" {
+		a.Issuer = project.TrimSpaceAndNonPrintable(f.Issuer)
+		a.Subject = project.TrimSpaceAndNonPrintable(f.Subject)
+	}
+	a.Audience = project.TrimSpaceAndNonPrintable(f.Audience)
+	a.Note = project.TrimSpaceAndNonPrintable(f.Note)
+	a.IncludeRegions = regionsFromLines(f.IncludeRegions)
+	a.ExcludeRegions = regionsFromLines(f.ExcludeRegions)
+}
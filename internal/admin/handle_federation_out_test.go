@@ -0,0 +1,338 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/federationout/database"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderFederationOut(t *testing.T) {
+	t.Parallel()
+
+	m := TemplateMap{}
+	m["new"] = true
+	m["auth"] = &model.FederationOutAuthorization{}
+
+	testRenderTemplate(t, "federation-out", m)
+}
+
+func TestPopulateFederationOutAuthorization(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		auth *model.FederationOutAuthorization
+		form *federationOutFormData
+		exp  *model.FederationOutAuthorization
+	}{
+		{
+			name: "new",
+			auth: &model.FederationOutAuthorization{},
+			form: &federationOutFormData{
+				Issuer:         "https://accounts.google.com ",
+				Subject:        " subject",
+				Audience:       "aud",
+				Note:           "note",
+				IncludeRegions: "us\nca",
+				ExcludeRegions: "MX\n",
+			},
+			exp: &model.FederationOutAuthorization{
+				Issuer:         "https://accounts.google.com",
+				Subject:        "subject",
+				Audience:       "aud",
+				Note:           "note",
+				IncludeRegions: []string{"US", "CA"},
+				ExcludeRegions: []string{"MX"},
+			},
+		},
+		{
+			name: "existing",
+			auth: &model.FederationOutAuthorization{
+				Issuer:   "issuer",
+				Subject:  "subject",
+				Audience: "aud",
+				Disabled: true,
+			},
+			form: &federationOutFormData{
+				Issuer:  "other-issuer",
+				Subject: "other-subject",
+				Note:    "note",
+			},
+			exp: &model.FederationOutAuthorization{
+				Issuer:   "issuer",
+				Subject:  "subject",
+				Note:     "note",
+				Disabled: true,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.form.PopulateFederationOutAuthorization(tc.auth)
+			if diff := cmp.Diff(tc.exp, tc.auth); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandleFederationOutShow(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := database.New(env.Database())
+
+	auth := &model.FederationOutAuthorization{
+		Issuer:  "https://accounts.google.com",
+		Subject: "subject",
+		Note:    "A partner server",
+	}
+	if err := db.AddFederationOutAuthorization(ctx, auth); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		issuer  string
+		subject string
+		want    []string
+	}{
+		{
+			name:    "lookup_existing",
+			issuer:  auth.Issuer,
+			subject: auth.Subject,
+			want:    []string{auth.Note, "Edit Federation Out Authorization"},
+		},
+		{
+			name: "show_new",
+			want: []string{"New Federation Out Authorization"},
+		},
+		{
+			name:    "non_existing",
+			issuer:  auth.Issuer,
+			subject: "banana",
+			want:    []string{"error loading federation out authorization"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodGet, "/", s.HandleFederationOutShow())
+
+			params := url.Values{}
+			params.Set("issuer", tc.issuer)
+			params.Set("subject", tc.subject)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?"+params.Encode(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := server.Client()
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("error making http call: %v", err)
+			}
+			defer resp.Body.Close()
+
+			mustFindStrings(t, resp, tc.want...)
+		})
+	}
+}
+
+func TestHandleFederationOutSave(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	env, s := newTestServer(t)
+	db := database.New(env.Database())
+
+	const issuer = "https://accounts.google.com"
+	for _, subject := range []string{"existing", "to-disable", "to-enable"} {
+		auth := &model.FederationOutAuthorization{
+			Issuer:   issuer,
+			Subject:  subject,
+			Disabled: subject == "to-enable",
+		}
+		if err := db.AddFederationOutAuthorization(ctx, auth); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		form  *federationOutFormData
+		want  []string
+		check func(t *testing.T, a *model.FederationOutAuthorization)
+	}{
+		// Create
+		{
+			name: "create_new",
+			form: &federationOutFormData{
+				Action:  "save",
+				Issuer:  issuer,
+				Subject: "created",
+			},
+			want: []string{"Saved federation out authorization: created"},
+		},
+		{
+			name: "create_duplicate",
+			form: &federationOutFormData{
+				Action:  "save",
+				Issuer:  issuer,
+				Subject: "existing",
+			},
+			want: []string{"Authorization for this issuer and subject already exists"},
+		},
+		{
+			name: "create_bad_validation",
+			form: &federationOutFormData{
+				Action: "save",
+			},
+			want: []string{"Issuer cannot be empty", "Subject cannot be empty"},
+		},
+
+		// Update
+		{
+			name: "update_unknown",
+			form: &federationOutFormData{
+				KeyIssuer:  issuer,
+				KeySubject: "not-a-real-subject",
+				Action:     "save",
+			},
+			want: []string{"Unknown federation out authorization"},
+		},
+		{
+			name: "update_existing",
+			form: &federationOutFormData{
+				KeyIssuer:  issuer,
+				KeySubject: "existing",
+				Action:     "save",
+				Note:       "updated note",
+			},
+			want: []string{"Saved federation out authorization: existing"},
+			check: func(t *testing.T, a *model.FederationOutAuthorization) {
+				if got, want := a.Note, "updated note"; got != want {
+					t.Errorf("expected note %q to be %q", got, want)
+				}
+			},
+		},
+
+		// Disable/enable
+		{
+			name: "disable",
+			form: &federationOutFormData{
+				KeyIssuer:  issuer,
+				KeySubject: "to-disable",
+				Action:     "disable",
+			},
+			want: []string{"Successfully disabled federation out authorization: to-disable"},
+			check: func(t *testing.T, a *model.FederationOutAuthorization) {
+				if !a.Disabled {
+					t.Errorf("expected authorization to be disabled")
+				}
+			},
+		},
+		{
+			name: "enable",
+			form: &federationOutFormData{
+				KeyIssuer:  issuer,
+				KeySubject: "to-enable",
+				Action:     "enable",
+			},
+			want: []string{"Successfully enabled federation out authorization: to-enable"},
+			check: func(t *testing.T, a *model.FederationOutAuthorization) {
+				if a.Disabled {
+					t.Errorf("expected authorization to be enabled")
+				}
+			},
+		},
+		{
+			name: "disable_new",
+			form: &federationOutFormData{
+				Action: "disable",
+			},
+			want: []string{"authorization to edit not found"},
+		},
+
+		// Unknown action
+		{
+			name: "unknown_action",
+			form: &federationOutFormData{
+				KeyIssuer:  issuer,
+				KeySubject: "existing",
+				Action:     "banana",
+			},
+			want: []string{"Invalid form action"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newHTTPServer(t, http.MethodPost, "/", s.HandleFederationOutSave())
+
+			// URL values
+			form, err := serializeForm(tc.form)
+			if err != nil {
+				t.Fatalf("unable to serialize form: %v", err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			client := server.Client()
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("error making http call: %v", err)
+			}
+			defer resp.Body.Close()
+
+			mustFindStrings(t, resp, tc.want...)
+
+			if tc.check != nil {
+				a, err := db.GetFederationOutAuthorization(ctx, tc.form.KeyIssuer, tc.form.KeySubject)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tc.check(t, a)
+			}
+		})
+	}
+}
@@ -27,6 +27,9 @@ import (
 	"github.com/google/exposure-notifications-server/internal/verification/model"
 )
 
+// maxJWKSKeySets is how many of the most recent JWKS key sets are shown.
+const maxJWKSKeySets = 10
+
 // HandleHealthAuthoritySave handles the create/update actions for health
 // authorities.
 func (s *Server) HandleHealthAuthoritySave() func(c *gin.Context) {
@@ -55,7 +58,10 @@ func (s *Server) HandleHealthAuthoritySave() func(c *gin.Context) {
 				return
 			}
 		}
-		form.PopulateHealthAuthority(healthAuthority)
+		if err := form.PopulateHealthAuthority(healthAuthority); err != nil {
+			ErrorPage(c, fmt.Sprintf("Error parsing health authority: %v", err))
+			return
+		}
 
 		// Decide if update or insert.
 		updateFn := haDB.AddHealthAuthority
@@ -97,6 +103,21 @@ func (s *Server) HandleHealthAuthorityShow() func(c *gin.Context) {
 				ErrorPage(c, fmt.Sprintf("Unable to find requested health authority: %v. Error: %v", haID, err))
 				return
 			}
+
+			if healthAuthority.JWKSEnabled() {
+				status, err := haDB.GetJWKSStatus(ctx, haID)
+				if err != nil {
+					ErrorPage(c, fmt.Sprintf("Unable to read jwks status: %v", err))
+					return
+				}
+				keySets, err := haDB.ListJWKSKeySets(ctx, haID, maxJWKSKeySets)
+				if err != nil {
+					ErrorPage(c, fmt.Sprintf("Unable to read jwks key sets: %v", err))
+					return
+				}
+				m["jwksStatus"] = status
+				m["jwksKeySets"] = keySets
+			}
 		}
 		m["ha"] = healthAuthority
 		m["hak"] = &model.HealthAuthorityKey{From: time.Now()} // For create form.
@@ -187,23 +208,88 @@ type healthAuthorityFormData struct {
 	Name           string `form:"name"`
 	EnableStatsAPI bool   `form:"enable-stats-api"`
 	JwksURI        string `form:"jwks-uri"`
+
+	EnableReplayProtection  bool `form:"enable-replay-protection"`
+	TestDateOnsetOffsetDays uint `form:"test-date-onset-offset-days"`
+
+	StatsNoise        string  `form:"stats-noise"`
+	StatsNoiseEpsilon float64 `form:"stats-noise-epsilon"`
+
+	StatsExportBucket       string `form:"stats-export-bucket"`
+	StatsExportFilenameRoot string `form:"stats-export-filename-root"`
+
+	// Transform policy overrides, blank uses the server's configuration.
+	MaxExposureKeys                string `form:"max-exposure-keys"`
+	MaxIntervalStartAge            string `form:"max-interval-start-age"`
+	MaxSymptomOnsetDays            string `form:"max-symptom-onset-days"`
+	MaxValidSymptomOnsetReportDays string `form:"max-valid-symptom-onset-report-days"`
+	DefaultSymptomOnsetDaysAgo     string `form:"default-symptom-onset-days-ago"`
 }
 
-func (f *healthAuthorityFormData) PopulateHealthAuthority(ha *model.HealthAuthority) {
+func (f *healthAuthorityFormData) PopulateHealthAuthority(ha *model.HealthAuthority) error {
 	ha.Issuer = f.Issuer
 	ha.Audience = f.Audience
 	ha.Name = f.Name
 	ha.EnableStatsAPI = f.EnableStatsAPI
+	ha.EnableReplayProtection = f.EnableReplayProtection
+	ha.TestDateOnsetOffsetDays = f.TestDateOnsetOffsetDays
+	ha.StatsNoise = f.StatsNoise
+	ha.StatsNoiseEpsilon = f.StatsNoiseEpsilon
+	ha.StatsExportBucket = project.TrimSpaceAndNonPrintable(f.StatsExportBucket)
+	ha.StatsExportFilenameRoot = project.TrimSpaceAndNonPrintable(f.StatsExportFilenameRoot)
 	ha.SetJWKS(f.JwksURI)
+
+	var policy model.TransformPolicy
+	var err error
+	if policy.MaxExposureKeys, err = parseOptionalUint("max exposure keys", f.MaxExposureKeys); err != nil {
+		return err
+	}
+	if v := project.TrimSpaceAndNonPrintable(f.MaxIntervalStartAge); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid max interval start age %q: %w", v, err)
+		}
+		policy.MaxIntervalStartAge = &d
+	}
+	if policy.MaxSymptomOnsetDays, err = parseOptionalUint("max symptom onset days", f.MaxSymptomOnsetDays); err != nil {
+		return err
+	}
+	if policy.MaxValidSymptomOnsetReportDays, err = parseOptionalUint("max valid symptom onset report days", f.MaxValidSymptomOnsetReportDays); err != nil {
+		return err
+	}
+	if policy.DefaultSymptomOnsetDaysAgo, err = parseOptionalUint("default symptom onset days ago", f.DefaultSymptomOnsetDaysAgo); err != nil {
+		return err
+	}
+
+	ha.TransformPolicy = nil
+	if !policy.IsEmpty() {
+		ha.TransformPolicy = &policy
+	}
+	return nil
+}
+
+// parseOptionalUint parses a form value, returning nil if it is blank.
+func parseOptionalUint(name, value string) (*uint, error) {
+	value = project.TrimSpaceAndNonPrintable(value)
+	if value == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	u := uint(v)
+	return &u, nil
 }
 
 type keyhealthAuthorityFormData struct {
-	Version  string `form:"version"`
-	PEMBlock string `form:"public-key-pem"`
-	FromDate string `form:"from-date"`
-	FromTime string `form:"from-time"`
-	ThruDate string `form:"thru-date"`
-	ThruTime string `form:"thru-time"`
+	Version   string `form:"version"`
+	PEMBlock  string `form:"public-key-pem"`
+	Algorithm string `form:"algorithm"`
+	FromDate  string `form:"from-date"`
+	FromTime  string `form:"from-time"`
+	ThruDate  string `form:"thru-date"`
+	ThruTime  string `form:"thru-time"`
 }
 
 func (f *keyhealthAuthorityFormData) FromTimestamp() (time.Time, error) {
@@ -227,6 +313,7 @@ func (f *keyhealthAuthorityFormData) PopulateHealthAuthorityKey(hak *model.Healt
 	hak.From = fTime
 	hak.Thru = tTime
 	hak.PublicKeyPEM = strings.ReplaceAll(project.TrimSpaceAndNonPrintable(f.PEMBlock), "\r", "")
+	hak.Algorithm = project.TrimSpaceAndNonPrintable(f.Algorithm)
 
 	_, err = hak.PublicKey()
 	return err
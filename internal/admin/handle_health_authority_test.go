@@ -25,6 +25,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/verification/database"
 	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -41,6 +42,28 @@ func TestRenderHealthAuthority(t *testing.T) {
 	testRenderTemplate(t, "healthauthority", m)
 }
 
+func TestRenderHealthAuthority_JWKSStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	m := TemplateMap{}
+	m["ha"] = &model.HealthAuthority{JwksURI: stringPtr("https://example.com/jwks")}
+	m["hak"] = new(model.HealthAuthorityKey)
+	m["jwksStatus"] = &model.JWKSStatus{
+		ETag:                `"v1"`,
+		LastSuccessAt:       now.Add(-time.Hour),
+		LastError:           "resp (500) != 200",
+		LastErrorAt:         now,
+		ConsecutiveFailures: 2,
+		KeyFingerprint:      "abc",
+	}
+	m["jwksKeySets"] = []*model.JWKSKeySet{
+		{Fingerprint: "abc", KeyCount: 1, ObservedAt: now.Add(-time.Hour)},
+	}
+
+	testRenderTemplate(t, "healthauthority", m)
+}
+
 func TestPopulateHealthAuthority(t *testing.T) {
 	t.Parallel()
 
@@ -58,6 +81,15 @@ func TestPopulateHealthAuthority(t *testing.T) {
 				Name:           "test-ha",
 				EnableStatsAPI: true,
 				JwksURI:        "https://foo.bar",
+
+				EnableReplayProtection:  true,
+				TestDateOnsetOffsetDays: 2,
+
+				StatsNoise:        model.StatsNoiseLaplace,
+				StatsNoiseEpsilon: 0.5,
+
+				StatsExportBucket:       " stats-bucket ",
+				StatsExportFilenameRoot: "stats/test",
 			},
 			exp: &model.HealthAuthority{
 				Issuer:         "test-iss",
@@ -65,8 +97,53 @@ func TestPopulateHealthAuthority(t *testing.T) {
 				Name:           "test-ha",
 				EnableStatsAPI: true,
 				JwksURI:        stringPtr("https://foo.bar"),
+
+				EnableReplayProtection:  true,
+				TestDateOnsetOffsetDays: 2,
+
+				StatsNoise:        model.StatsNoiseLaplace,
+				StatsNoiseEpsilon: 0.5,
+
+				StatsExportBucket:       "stats-bucket",
+				StatsExportFilenameRoot: "stats/test",
+			},
+		},
+		{
+			name: "transform_policy",
+			form: &healthAuthorityFormData{
+				Issuer:   "test-iss",
+				Audience: "test-aud",
+				Name:     "test-ha",
+
+				MaxExposureKeys:            "20",
+				MaxIntervalStartAge:        "240h",
+				DefaultSymptomOnsetDaysAgo: " 0 ",
+			},
+			exp: &model.HealthAuthority{
+				Issuer:   "test-iss",
+				Audience: "test-aud",
+				Name:     "test-ha",
+				TransformPolicy: &model.TransformPolicy{
+					MaxExposureKeys:            uintPtr(20),
+					MaxIntervalStartAge:        durationPtr(240 * time.Hour),
+					DefaultSymptomOnsetDaysAgo: new(uint),
+				},
 			},
 		},
+		{
+			name: "invalid_max_keys",
+			form: &healthAuthorityFormData{
+				MaxExposureKeys: "-1",
+			},
+			err: "invalid max exposure keys",
+		},
+		{
+			name: "invalid_max_interval_start_age",
+			form: &healthAuthorityFormData{
+				MaxIntervalStartAge: "ten days",
+			},
+			err: "invalid max interval start age",
+		},
 	}
 
 	for _, tc := range cases {
@@ -76,7 +153,11 @@ func TestPopulateHealthAuthority(t *testing.T) {
 			t.Parallel()
 
 			var ha model.HealthAuthority
-			tc.form.PopulateHealthAuthority(&ha)
+			err := tc.form.PopulateHealthAuthority(&ha)
+			errcmp.MustMatch(t, err, tc.err)
+			if err != nil {
+				return
+			}
 
 			opts := cmp.Options{cmpopts.EquateApproxTime(5 * time.Minute)}
 			if diff := cmp.Diff(tc.exp, &ha, opts); diff != "" {
@@ -123,6 +204,22 @@ YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
 -----END PUBLIC KEY-----`,
 			},
 		},
+		{
+			name: "algorithm_mismatch",
+			form: &keyhealthAuthorityFormData{
+				Version: "123",
+				PEMBlock: `
+-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEml59itec9qzwVojreLXdPNRsUWzf
+YHc1cKvIIi6/H56AJS/kZEYQnfDpxrgyGhdAm+pNN2GAJ3XdnQZ1Sk4amg==
+-----END PUBLIC KEY-----
+`,
+				Algorithm: "PS256",
+				FromDate:  "2021-01-02",
+				FromTime:  "09:23",
+			},
+			err: "unsupported public key type: *ecdsa.PublicKey for algorithm PS256",
+		},
 		{
 			name: "bad_from",
 			form: &keyhealthAuthorityFormData{
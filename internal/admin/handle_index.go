@@ -21,6 +21,8 @@ import (
 	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
 	exdb "github.com/google/exposure-notifications-server/internal/export/database"
 	exportimportdatabase "github.com/google/exposure-notifications-server/internal/exportimport/database"
+	federationindatabase "github.com/google/exposure-notifications-server/internal/federationin/database"
+	federationoutdatabase "github.com/google/exposure-notifications-server/internal/federationout/database"
 	mirrordatabase "github.com/google/exposure-notifications-server/internal/mirror/database"
 	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
 )
@@ -80,6 +82,30 @@ func (s *Server) HandleIndex() func(c *gin.Context) {
 		}
 		m["mirrors"] = mirrors
 
+		// Load federation in queries and their last sync.
+		federationInDB := federationindatabase.New(db)
+		federationInQueries, err := federationInDB.ListFederationInQueries(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["federationInQueries"] = federationInQueries
+
+		federationInSyncs, err := federationInDB.LastFederationInSyncs(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["federationInSyncs"] = federationInSyncs
+
+		// Load federation out authorizations.
+		federationOutAuthorizations, err := federationoutdatabase.New(db).ListFederationOutAuthorizations(ctx)
+		if err != nil {
+			ErrorPage(c, err.Error())
+			return
+		}
+		m["federationOutAuthorizations"] = federationOutAuthorizations
+
 		m.AddTitle("Exposure Notification Key Server - Admin Console")
 		c.HTML(http.StatusOK, "index", m)
 	}
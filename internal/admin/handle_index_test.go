@@ -22,6 +22,7 @@ import (
 	authorizedappmodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	exportmodel "github.com/google/exposure-notifications-server/internal/export/model"
 	exportimportmodel "github.com/google/exposure-notifications-server/internal/exportimport/model"
+	federationinmodel "github.com/google/exposure-notifications-server/internal/federationin/model"
 	mirrormodel "github.com/google/exposure-notifications-server/internal/mirror/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	verificationmodel "github.com/google/exposure-notifications-server/internal/verification/model"
@@ -37,6 +38,9 @@ func TestRenderIndex(t *testing.T) {
 	m["exportImporters"] = []*exportimportmodel.ExportImport{}
 	m["siginfos"] = []*exportmodel.SignatureInfo{}
 	m["mirrors"] = []*mirrormodel.Mirror{}
+	m["federationInQueries"] = []*federationinmodel.FederationInQuery{{QueryID: "query"}}
+	m["federationInSyncs"] = map[string]*federationinmodel.FederationInSync{}
+	m["federationOutAuthorizations"] = []*federationinmodel.FederationOutAuthorization{}
 
 	testRenderTemplate(t, "index", m)
 }
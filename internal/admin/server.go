@@ -69,6 +69,7 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	// Export Config Handling.
 	mux.GET("/exports/:id", s.HandleExportsShow())
 	mux.POST("/exports/:id", s.HandleExportsSave())
+	mux.POST("/exports/:id/backfill", s.HandleExportsBackfill())
 
 	// Export importer configuration
 	mux.GET("/export-importers/:id", s.HandleExportImportersShow())
@@ -83,6 +84,12 @@ func (s *Server) Routes(ctx context.Context) http.Handler {
 	mux.GET("/siginfo/:id", s.HandleSignatureInfosShow())
 	mux.POST("/siginfo/:id", s.HandleSignatureInfosSave())
 
+	// Federation handling.
+	mux.GET("/federation-in", s.HandleFederationInShow())
+	mux.POST("/federation-in", s.HandleFederationInSave())
+	mux.GET("/federation-out", s.HandleFederationOutShow())
+	mux.POST("/federation-out", s.HandleFederationOutSave())
+
 	// Healthz.
 	mux.GET("/health", s.HandleHealthz())
 
@@ -17,6 +17,7 @@ package admin
 import (
 	"fmt"
 	"html/template"
+	"strings"
 	"time"
 )
 
@@ -26,6 +27,7 @@ var TemplateFuncMap = template.FuncMap{
 	"htmlDate":     timestampFormatter("2006-01-02"),
 	"htmlTime":     timestampFormatter("15:04"),
 	"htmlDatetime": timestampFormatter(time.UnixDate),
+	"onePerLine":   onePerLine,
 }
 
 // timestampFormatter returns a function that formats the given timestamp.
@@ -52,6 +54,11 @@ func timestampFormatter(f string) func(i interface{}) (string, error) {
 	}
 }
 
+// onePerLine joins the values with newlines, for display in a textarea.
+func onePerLine(values []string) string {
+	return strings.Join(values, "\n")
+}
+
 // deref dereferences a pointer into its concrete type.
 func deref(i interface{}) (string, error) {
 	switch t := i.(type) {
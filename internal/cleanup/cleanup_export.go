@@ -70,6 +70,7 @@ func (s *ExportServer) Routes(ctx context.Context) *mux.Router {
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleCleanup())
+	r.Handle("/reconcile", s.handleReconcile())
 
 	return r
 }
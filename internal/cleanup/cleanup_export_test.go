@@ -15,15 +15,19 @@
 package cleanup
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/internal/storage"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestNewExportServer(t *testing.T) {
@@ -114,3 +118,113 @@ func TestExportHandler_ServeHTTP(t *testing.T) {
 		t.Errorf("expected %d to be %d: %s", got, want, w.Body.String())
 	}
 }
+
+func TestExportServer_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := exportdatabase.New(testDB)
+
+	bs, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec := &model.ExportConfig{
+		BucketName:   "bucket",
+		FilenameRoot: "root",
+		Period:       time.Hour,
+		OutputRegion: "US",
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{{
+		ConfigID:       ec.ConfigID,
+		BucketName:     ec.BucketName,
+		FilenameRoot:   ec.FilenameRoot,
+		StartTimestamp: time.Now().Add(-2 * time.Hour),
+		EndTimestamp:   time.Now().Add(-time.Hour),
+		OutputRegion:   ec.OutputRegion,
+		Status:         model.ExportBatchOpen,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err := exportDB.LeaseBatch(ctx, time.Hour, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exportDB.FinalizeBatch(ctx, eb, []string{"root/known.zip"}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := map[string]string{
+		"root/known.zip":         "",
+		"root/indexed.zip":       "",
+		"root/orphan.zip":        "",
+		"root/index.txt":         "root/known.zip\nroot/indexed.zip",
+		"root/json.zip":          "",
+		"root/index.json":        `{"files":[{"filename":"root/json.zip"}]}`,
+		"root/rolling/a.zip":     "",
+		"root/rolling/index.txt": "root/rolling/a.zip",
+		"other/unknown.zip":      "",
+	}
+	for name, contents := range objects {
+		if err := bs.CreateObject(ctx, "bucket", name, []byte(contents), false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := &Config{
+		Timeout:         5 * time.Second,
+		ReconcileDryRun: true,
+		ReconcileMinAge: 0,
+	}
+	server, err := NewExportServer(config, serverenv.New(ctx,
+		serverenv.WithDatabase(testDB),
+		serverenv.WithBlobStorage(bs)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orphanNames := func(result *ReconcileResult) []string {
+		names := make([]string, 0, len(result.Orphans))
+		for _, o := range result.Orphans {
+			names = append(names, o.Name)
+		}
+		return names
+	}
+
+	// Dry run only reports.
+	result, err := server.reconcile(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"root/orphan.zip"}, orphanNames(result)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if _, err := bs.GetObject(ctx, "bucket", "root/orphan.zip"); err != nil {
+		t.Errorf("expected orphan to remain after dry run: %v", err)
+	}
+
+	// Deletes the orphans.
+	result, err = server.reconcile(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Deleted, 1; got != want {
+		t.Errorf("expected %d deleted, got %d", want, got)
+	}
+	if _, err := bs.GetObject(ctx, "bucket", "root/orphan.zip"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected orphan to be deleted, got %v", err)
+	}
+	for name := range objects {
+		if name == "root/orphan.zip" {
+			continue
+		}
+		if _, err := bs.GetObject(ctx, "bucket", name); err != nil {
+			t.Errorf("expected %s to remain: %v", name, err)
+		}
+	}
+}
@@ -18,10 +18,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
+	federationindatabase "github.com/google/exposure-notifications-server/internal/federationin/database"
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	verificationdatabase "github.com/google/exposure-notifications-server/internal/verification/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/render"
 	"github.com/google/exposure-notifications-server/pkg/server"
@@ -31,10 +34,12 @@ import (
 )
 
 type ExposureServer struct {
-	config   *Config
-	env      *serverenv.ServerEnv
-	database *database.PublishDB
-	h        *render.Renderer
+	config         *Config
+	env            *serverenv.ServerEnv
+	database       *database.PublishDB
+	verificationDB *verificationdatabase.HealthAuthorityDB
+	federationInDB *federationindatabase.FederationInDB
+	h              *render.Renderer
 }
 
 // NewExposureServer creates a http.Handler for deleting exposure keys
@@ -45,10 +50,12 @@ func NewExposureServer(cfg *Config, env *serverenv.ServerEnv) (*ExposureServer,
 	}
 
 	return &ExposureServer{
-		config:   cfg,
-		env:      env,
-		database: database.New(env.Database()),
-		h:        render.NewRenderer(),
+		config:         cfg,
+		env:            env,
+		database:       database.New(env.Database()),
+		verificationDB: verificationdatabase.New(env.Database()),
+		federationInDB: federationindatabase.New(env.Database()),
+		h:              render.NewRenderer(),
 	}, nil
 }
 
@@ -100,6 +107,19 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 			}
 		}()
 
+		// Federation in sync history, after the exposures that refer to the
+		// syncs have been deleted.
+		func() {
+			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+			defer cancel()
+
+			if count, err := s.federationInDB.DeleteFederationInSyncsBefore(ctx, cutoff); err != nil {
+				merr = multierror.Append(merr, fmt.Errorf("failed to delete federation in syncs: %w", err))
+			} else {
+				logger.Infow("purged federation in syncs", "count", count)
+			}
+		}()
+
 		// Stats
 		func() {
 			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
@@ -112,6 +132,19 @@ func (s *ExposureServer) handleCleanup() http.Handler {
 			}
 		}()
 
+		// Used verification certificates, these are only needed until the
+		// certificates expire so the TTL doesn't apply.
+		func() {
+			ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+			defer cancel()
+
+			if count, err := s.verificationDB.DeleteExpiredCertificateUses(ctx, time.Now()); err != nil {
+				merr = multierror.Append(merr, fmt.Errorf("failed to delete verification certificate uses: %w", err))
+			} else {
+				logger.Infow("purged verification certificate uses", "count", count)
+			}
+		}()
+
 		if errs := merr.WrappedErrors(); len(errs) > 0 {
 			logger.Errorw("failed to cleanup exposures", "errors", errs)
 			s.h.RenderJSON(w, http.StatusInternalServerError, errs)
@@ -45,6 +45,12 @@ type Config struct {
 	TTL     time.Duration `env:"CLEANUP_TTL, default=336h"`
 
 	DebugOverrideCleanupMinDuration bool `env:"DEBUG_OVERRIDE_CLEANUP_MIN_DURATION, default=false"`
+
+	// ReconcileDryRun only reports orphaned export files instead of deleting
+	// them. Objects in export buckets are only considered orphaned once they are
+	// older than ReconcileMinAge, so in-progress exports are not affected.
+	ReconcileDryRun bool          `env:"RECONCILE_DRY_RUN, default=true"`
+	ReconcileMinAge time.Duration `env:"RECONCILE_MIN_AGE, default=24h"`
 }
 
 func (c *Config) BlobstoreConfig() *storage.Config {
@@ -26,6 +26,10 @@ const metricPrefix = metrics.MetricRoot + "cleanup"
 var (
 	mExportSuccess   = stats.Int64(metricPrefix+"/export_success", "successful execution", stats.UnitDimensionless)
 	mExposureSuccess = stats.Int64(metricPrefix+"/exposure_success", "successful execution", stats.UnitDimensionless)
+
+	mReconcileSuccess = stats.Int64(metricPrefix+"/reconcile_success", "successful execution", stats.UnitDimensionless)
+	mOrphansFound     = stats.Int64(metricPrefix+"/orphans_found", "orphaned export files found", stats.UnitDimensionless)
+	mOrphansDeleted   = stats.Int64(metricPrefix+"/orphans_deleted", "orphaned export files deleted", stats.UnitDimensionless)
 )
 
 func init() {
@@ -42,5 +46,23 @@ func init() {
 			Measure:     mExposureSuccess,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/reconcile/success",
+			Description: "Number of export bucket reconciliation successes",
+			Measure:     mReconcileSuccess,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        metricPrefix + "/reconcile/orphans_found",
+			Description: "Total number of orphaned export files found",
+			Measure:     mOrphansFound,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        metricPrefix + "/reconcile/orphans_deleted",
+			Description: "Total number of orphaned export files deleted",
+			Measure:     mOrphansDeleted,
+			Aggregation: view.Sum(),
+		},
 	}...)
 }
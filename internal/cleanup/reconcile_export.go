@@ -0,0 +1,262 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleanup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	v1 "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/stats"
+)
+
+// Orphan is an object in an export bucket that is not tracked in the database
+// and not referenced by an index file.
+type Orphan struct {
+	Bucket  string    `json:"bucket"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Updated time.Time `json:"updated"`
+}
+
+// ReconcileResult is the outcome of reconciling the export buckets.
+type ReconcileResult struct {
+	DryRun  bool      `json:"dryRun"`
+	Orphans []*Orphan `json:"orphans"`
+	Deleted int       `json:"deleted"`
+}
+
+// handleReconcile finds, and unless running in dry-run mode deletes, orphaned
+// files in the export buckets. Dry-run mode can be forced for a single request
+// with the dry_run=true query parameter.
+func (s *ExportServer) handleReconcile() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("cleanup.reconcile")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		dryRun := s.config.ReconcileDryRun
+		if v, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); v {
+			dryRun = true
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+
+		result, err := s.reconcile(ctx, dryRun)
+		if err != nil {
+			logger.Errorw("failed to reconcile export buckets", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		stats.Record(ctx, mReconcileSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, result)
+	})
+}
+
+// reconcile lists the objects under the filename root of every export config
+// and compares them with the ExportFile table and the current index files.
+func (s *ExportServer) reconcile(ctx context.Context, dryRun bool) (*ReconcileResult, error) {
+	logger := logging.FromContext(ctx).Named("reconcile")
+
+	configs, err := s.database.GetAllExportConfigs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list export configs: %w", err)
+	}
+
+	// Multiple export configs may share a filename root, or have nested roots,
+	// so the roots are collected per bucket.
+	rootsByBucket := make(map[string][]string)
+	seenRoots := make(map[string]struct{})
+	for _, ec := range configs {
+		key := ec.BucketName + "/" + ec.FilenameRoot
+		if _, ok := seenRoots[key]; ok {
+			continue
+		}
+		seenRoots[key] = struct{}{}
+		rootsByBucket[ec.BucketName] = append(rootsByBucket[ec.BucketName], ec.FilenameRoot)
+	}
+
+	buckets := make([]string, 0, len(rootsByBucket))
+	for bucket := range rootsByBucket {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	result := &ReconcileResult{DryRun: dryRun}
+	for _, bucket := range buckets {
+		orphans, err := s.findOrphans(ctx, bucket, rootsByBucket[bucket])
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile bucket %s: %w", bucket, err)
+		}
+		result.Orphans = append(result.Orphans, orphans...)
+	}
+	stats.Record(ctx, mOrphansFound.M(int64(len(result.Orphans))))
+
+	if dryRun {
+		for _, o := range result.Orphans {
+			logger.Infow("found orphaned export file", "bucket", o.Bucket, "object", o.Name, "updated", o.Updated)
+		}
+		logger.Infow("reconciled export buckets (dry run)", "orphans", len(result.Orphans))
+		return result, nil
+	}
+
+	// Keep deleting on failure, the remaining orphans will be picked up again
+	// on the next run.
+	var merr *multierror.Error
+	for _, o := range result.Orphans {
+		if err := s.blobstore.DeleteObject(ctx, o.Bucket, o.Name); err != nil {
+			merr = multierror.Append(merr, fmt.Errorf("failed to delete %s/%s: %w", o.Bucket, o.Name, err))
+			continue
+		}
+		logger.Infow("deleted orphaned export file", "bucket", o.Bucket, "object", o.Name, "updated", o.Updated)
+		result.Deleted++
+	}
+	stats.Record(ctx, mOrphansDeleted.M(int64(result.Deleted)))
+	logger.Infow("reconciled export buckets", "orphans", len(result.Orphans), "deleted", result.Deleted)
+
+	if err := merr.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findOrphans returns the orphaned objects under the given roots of a bucket.
+func (s *ExportServer) findOrphans(ctx context.Context, bucket string, roots []string) ([]*Orphan, error) {
+	// Index files, and anything they reference, are never orphans.
+	protected := make(map[string]struct{})
+	for _, root := range roots {
+		for _, indexName := range exportIndexFilenames(root) {
+			protected[indexName] = struct{}{}
+			if err := s.readIndex(ctx, bucket, indexName, protected); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Recently written files may belong to a batch that is still being exported
+	// and hasn't been recorded in the database yet.
+	cutoff := time.Now().Add(-s.config.ReconcileMinAge)
+
+	var orphans []*Orphan
+	seen := make(map[string]struct{})
+	for _, root := range roots {
+		prefix := root + "/"
+
+		files, err := s.database.ListExportFilenames(ctx, bucket, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list export files: %w", err)
+		}
+		known := make(map[string]struct{}, len(files))
+		for _, f := range files {
+			known[f] = struct{}{}
+		}
+
+		if err := s.blobstore.ListObjects(ctx, bucket, prefix, func(attrs *storage.ObjectAttrs) error {
+			// Nested roots are listed more than once.
+			if _, ok := seen[attrs.Name]; ok {
+				return nil
+			}
+			seen[attrs.Name] = struct{}{}
+
+			if _, ok := known[attrs.Name]; ok {
+				return nil
+			}
+			if _, ok := protected[attrs.Name]; ok {
+				return nil
+			}
+			if attrs.Updated.After(cutoff) {
+				return nil
+			}
+
+			orphans = append(orphans, &Orphan{
+				Bucket:  bucket,
+				Name:    attrs.Name,
+				Size:    attrs.Size,
+				Updated: attrs.Updated,
+			})
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+		}
+	}
+
+	return orphans, nil
+}
+
+// readIndex adds each entry of the given text or JSON index file to entries. A
+// missing index file has no entries.
+func (s *ExportServer) readIndex(ctx context.Context, bucket, indexName string, entries map[string]struct{}) error {
+	r, err := s.blobstore.NewReader(ctx, bucket, indexName)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to read index %s: %w", indexName, err)
+	}
+	defer r.Close()
+
+	if strings.HasSuffix(indexName, ".json") {
+		var index v1.ExportIndex
+		if err := json.NewDecoder(r).Decode(&index); err != nil {
+			return fmt.Errorf("failed to read index %s: %w", indexName, err)
+		}
+		for _, f := range index.Files {
+			entries[f.Filename] = struct{}{}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			entries[line] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read index %s: %w", indexName, err)
+	}
+	return nil
+}
+
+// exportIndexFilenames are the names of the index files the export worker may
+// write for a filename root, one per index format and kind of batch.
+func exportIndexFilenames(root string) []string {
+	kinds := []string{model.ExportBatchKindPeriod, model.ExportBatchKindRolling, model.ExportBatchKindDaily}
+
+	names := make([]string, 0, 2*len(kinds))
+	for _, kind := range kinds {
+		kindRoot := model.KindFilenameRoot(root, kind)
+		names = append(names,
+			fmt.Sprintf("%s/index.txt", kindRoot),
+			fmt.Sprintf("%s/index.json", kindRoot))
+	}
+	return names
+}
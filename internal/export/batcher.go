@@ -127,27 +127,7 @@ func (s *Server) maybeCreateBatches(ctx context.Context, ec *model.ExportConfig,
 		return 0, nil
 	}
 
-	batches := make([]*model.ExportBatch, 0, len(ranges))
-	for _, br := range ranges {
-		infoIds := make([]int64, len(ec.SignatureInfoIDs))
-		copy(infoIds, ec.SignatureInfoIDs)
-		batches = append(batches, &model.ExportBatch{
-			ConfigID:           ec.ConfigID,
-			BucketName:         ec.BucketName,
-			FilenameRoot:       ec.FilenameRoot,
-			StartTimestamp:     br.start,
-			EndTimestamp:       br.end,
-			OutputRegion:       ec.OutputRegion,
-			InputRegions:       ec.InputRegions,
-			IncludeTravelers:   ec.IncludeTravelers,
-			OnlyNonTravelers:   ec.OnlyNonTravelers,
-			ExcludeRegions:     ec.ExcludeRegions,
-			Status:             model.ExportBatchOpen,
-			SignatureInfoIDs:   infoIds,
-			MaxRecordsOverride: ec.MaxRecordsOverride,
-		})
-	}
-
+	batches := makeBatches(ec, ranges)
 	if err := exportDB.AddExportBatches(ctx, batches); err != nil {
 		return 0, fmt.Errorf("creating export batches for config %d: %w", ec.ConfigID, err)
 	}
@@ -157,6 +137,53 @@ func (s *Server) maybeCreateBatches(ctx context.Context, ec *model.ExportConfig,
 	return len(batches), nil
 }
 
+// makeBatches creates the period batches for the ranges, along with the
+// roll-ups that end with each period.
+func makeBatches(ec *model.ExportConfig, ranges []batchRange) []*model.ExportBatch {
+	batches := make([]*model.ExportBatch, 0, len(ranges))
+	for _, br := range ranges {
+		batches = append(batches, newExportBatch(ec, br, model.ExportBatchKindPeriod))
+
+		if ec.RollingHours > 0 {
+			rolling := batchRange{
+				start: br.end.Add(-time.Duration(ec.RollingHours) * time.Hour),
+				end:   br.end,
+			}
+			batches = append(batches, newExportBatch(ec, rolling, model.ExportBatchKindRolling))
+		}
+		if ec.DailyRollup && br.end.Equal(br.end.Truncate(24*time.Hour)) {
+			daily := batchRange{
+				start: br.end.Add(-24 * time.Hour),
+				end:   br.end,
+			}
+			batches = append(batches, newExportBatch(ec, daily, model.ExportBatchKindDaily))
+		}
+	}
+	return batches
+}
+
+// newExportBatch creates an open batch of the given kind for the config.
+func newExportBatch(ec *model.ExportConfig, br batchRange, kind string) *model.ExportBatch {
+	infoIds := make([]int64, len(ec.SignatureInfoIDs))
+	copy(infoIds, ec.SignatureInfoIDs)
+	return &model.ExportBatch{
+		ConfigID:           ec.ConfigID,
+		BucketName:         ec.BucketName,
+		FilenameRoot:       model.KindFilenameRoot(ec.FilenameRoot, kind),
+		StartTimestamp:     br.start,
+		EndTimestamp:       br.end,
+		OutputRegion:       ec.OutputRegion,
+		InputRegions:       ec.InputRegions,
+		IncludeTravelers:   ec.IncludeTravelers,
+		OnlyNonTravelers:   ec.OnlyNonTravelers,
+		ExcludeRegions:     ec.ExcludeRegions,
+		Status:             model.ExportBatchOpen,
+		SignatureInfoIDs:   infoIds,
+		MaxRecordsOverride: ec.MaxRecordsOverride,
+		Kind:               kind,
+	}
+}
+
 type batchRange struct {
 	start, end time.Time
 }
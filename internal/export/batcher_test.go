@@ -19,6 +19,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/go-cmp/cmp"
 )
 
 type simpleBatchRange struct {
@@ -137,6 +140,48 @@ func TestMakeBatchRanges(t *testing.T) {
 	}
 }
 
+func TestMakeBatches(t *testing.T) {
+	t.Parallel()
+
+	ec := &model.ExportConfig{
+		ConfigID:     1,
+		FilenameRoot: "root",
+		Period:       12 * time.Hour,
+		RollingHours: 48,
+		DailyRollup:  true,
+	}
+	ranges := []batchRange{
+		{start: fromSimpleTime(t, "12-09 00:00"), end: fromSimpleTime(t, "12-09 12:00")},
+		{start: fromSimpleTime(t, "12-09 12:00"), end: fromSimpleTime(t, "12-10 00:00")},
+	}
+
+	type simpleBatch struct {
+		Kind         string
+		FilenameRoot string
+		Range        simpleBatchRange
+	}
+	want := []simpleBatch{
+		{model.ExportBatchKindPeriod, "root", simpleBatchRange{"12-09 00:00", "12-09 12:00"}},
+		{model.ExportBatchKindRolling, "root/rolling", simpleBatchRange{"12-07 12:00", "12-09 12:00"}},
+		{model.ExportBatchKindPeriod, "root", simpleBatchRange{"12-09 12:00", "12-10 00:00"}},
+		{model.ExportBatchKindRolling, "root/rolling", simpleBatchRange{"12-08 00:00", "12-10 00:00"}},
+		{model.ExportBatchKindDaily, "root/daily", simpleBatchRange{"12-09 00:00", "12-10 00:00"}},
+	}
+
+	batches := makeBatches(ec, ranges)
+	got := make([]simpleBatch, 0, len(batches))
+	for _, eb := range batches {
+		got = append(got, simpleBatch{
+			Kind:         eb.Kind,
+			FilenameRoot: eb.FilenameRoot,
+			Range:        simpleBatchRange{toSimpleTime(t, eb.StartTimestamp), toSimpleTime(t, eb.EndTimestamp)},
+		})
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(simpleBatchRange{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
 func fromSimpleTime(t *testing.T, s string) time.Time {
 	t.Helper()
 	if s == "" {
@@ -18,6 +18,8 @@ package export
 import (
 	"time"
 
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/revision"
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -57,12 +59,45 @@ type Config struct {
 	// ReprocessCount needs to be incremented by one every time you go back and
 	// regenerate previously exported files.
 	ReprocessCount uint `env:"REPROCESS_COUNT, default=0"`
+
+	// ServeBlobstore serves the exported files at /blobs/ when the blobstore
+	// supports it, for deployments without a public bucket. Only objects in
+	// ServeBlobstoreBuckets are served.
+	ServeBlobstore        bool     `env:"SERVE_BLOBSTORE, default=false"`
+	ServeBlobstoreBuckets []string `env:"SERVE_BLOBSTORE_BUCKETS"`
+
+	// Stats export settings. The release settings must match the exposure
+	// service, so that the exported stats are the same as the stats API.
+	StatsExportTimeout      time.Duration        `env:"STATS_EXPORT_TIMEOUT, default=5m"`
+	StatsUploadMinimum      int64                `env:"STATS_UPLOAD_MINIMUM, default=10"`
+	StatsNoisyUploadMinimum int64                `env:"STATS_NOISY_UPLOAD_MINIMUM, default=10"`
+	StatsEmbargoPeriod      time.Duration        `env:"STATS_EMBARGO_PERIOD, default=48h"`
+	StatsNoiseDelta         float64              `env:"STATS_NOISE_DELTA, default=0.00001"`
+	StatsNoiseKey           revision.Base64Bytes `env:"STATS_NOISE_KEY"` // may come from secret://
+	MaxKeysOnPublish        uint                 `env:"MAX_KEYS_ON_PUBLISH, default=30"`
 }
 
 func (c *Config) RepressGeneration() int64 {
 	return int64(c.ReprocessCount)
 }
 
+// Validate returns an error if the configuration is not valid.
+func (c *Config) Validate() error {
+	return c.StatsReleaseConfig().Validate()
+}
+
+// StatsReleaseConfig returns the configuration for releasing stats.
+func (c *Config) StatsReleaseConfig() *publishmodel.StatsReleaseConfig {
+	return &publishmodel.StatsReleaseConfig{
+		UploadMinimum:      c.StatsUploadMinimum,
+		NoisyUploadMinimum: c.StatsNoisyUploadMinimum,
+		EmbargoPeriod:      c.StatsEmbargoPeriod,
+		NoiseDelta:         c.StatsNoiseDelta,
+		NoiseKey:           c.StatsNoiseKey,
+		MaxKeysPerPublish:  int64(c.MaxKeysOnPublish),
+	}
+}
+
 func (c *Config) BlobstoreConfig() *storage.Config {
 	return &c.Storage
 }
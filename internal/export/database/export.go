@@ -55,13 +55,14 @@ func (db *ExportDB) AddExportConfig(ctx context.Context, ec *model.ExportConfig)
 				ExportConfig
 				(bucket_name, filename_root, period_seconds, output_region, from_timestamp, thru_timestamp,
 				 signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers,
-				 max_records_override)
+				 max_records_override, index_formats, rolling_hours, daily_rollup)
 			VALUES
-				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 			RETURNING config_id
 		`, ec.BucketName, ec.FilenameRoot, int(ec.Period.Seconds()), ec.OutputRegion,
 			ec.From, thru, ec.SignatureInfoIDs, ec.InputRegions, ec.IncludeTravelers,
-			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride)
+			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride, ec.IndexFormats,
+			ec.RollingHours, ec.DailyRollup)
 
 		if err := row.Scan(&ec.ConfigID); err != nil {
 			return fmt.Errorf("fetching config_id: %w", err)
@@ -85,11 +86,13 @@ func (db *ExportDB) UpdateExportConfig(ctx context.Context, ec *model.ExportConf
 			SET
 				bucket_name = $1, filename_root = $2, period_seconds = $3, output_region = $4, from_timestamp = $5,
 				thru_timestamp = $6, signature_info_ids = $7, input_regions = $8, include_travelers = $9,
-				exclude_regions = $10, only_non_travelers = $11, max_records_override = $12
-			WHERE config_id = $13
+				exclude_regions = $10, only_non_travelers = $11, max_records_override = $12,
+				index_formats = $13, rolling_hours = $14, daily_rollup = $15
+			WHERE config_id = $16
 		`, ec.BucketName, ec.FilenameRoot, int(ec.Period.Seconds()), ec.OutputRegion,
 			ec.From, thru, ec.SignatureInfoIDs, ec.InputRegions, ec.IncludeTravelers,
-			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride,
+			ec.ExcludeRegions, ec.OnlyNonTravelers, ec.MaxRecordsOverride, ec.IndexFormats,
+			ec.RollingHours, ec.DailyRollup,
 			ec.ConfigID)
 		if err != nil {
 			return fmt.Errorf("updating signatureinfo: %w", err)
@@ -108,7 +111,8 @@ func (db *ExportDB) GetExportConfig(ctx context.Context, id int64) (*model.Expor
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions,
-				include_travelers, exclude_regions, only_non_travelers, max_records_override
+				include_travelers, exclude_regions, only_non_travelers, max_records_override,
+				index_formats, rolling_hours, daily_rollup
 			FROM
 				ExportConfig
 			WHERE
@@ -136,7 +140,8 @@ func (db *ExportDB) GetAllExportConfigs(ctx context.Context) ([]*model.ExportCon
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
-				exclude_regions, only_non_travelers, max_records_override
+				exclude_regions, only_non_travelers, max_records_override, index_formats,
+				rolling_hours, daily_rollup
 			FROM
 				ExportConfig
 			ORDER BY config_id
@@ -175,7 +180,8 @@ func (db *ExportDB) IterateExportConfigs(ctx context.Context, t time.Time, f fun
 			SELECT
 				config_id, bucket_name, filename_root, period_seconds, output_region,
 				from_timestamp, thru_timestamp, signature_info_ids, input_regions, include_travelers,
-				exclude_regions, only_non_travelers, max_records_override
+				exclude_regions, only_non_travelers, max_records_override, index_formats,
+				rolling_hours, daily_rollup
 			FROM
 				ExportConfig
 			WHERE
@@ -218,7 +224,8 @@ func scanOneExportConfig(row pgx.Row) (*model.ExportConfig, error) {
 		thru          *time.Time
 	)
 	if err := row.Scan(&m.ConfigID, &m.BucketName, &m.FilenameRoot, &periodSeconds, &outputRegion, &m.From, &thru,
-		&m.SignatureInfoIDs, &m.InputRegions, &m.IncludeTravelers, &m.ExcludeRegions, &m.OnlyNonTravelers, &m.MaxRecordsOverride); err != nil {
+		&m.SignatureInfoIDs, &m.InputRegions, &m.IncludeTravelers, &m.ExcludeRegions, &m.OnlyNonTravelers, &m.MaxRecordsOverride,
+		&m.IndexFormats, &m.RollingHours, &m.DailyRollup); err != nil {
 		return nil, err
 	}
 
@@ -480,27 +487,110 @@ func (db *ExportDB) ListLatestExportBatchEnds(ctx context.Context) (map[int64]*t
 // AddExportBatches inserts new export batches.
 func (db *ExportDB) AddExportBatches(ctx context.Context, batches []*model.ExportBatch) error {
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
-		const stmtName = "insert export batches"
-		_, err := tx.Prepare(ctx, stmtName, `
-			INSERT INTO
-				ExportBatch
-				(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override)
-			VALUES
-				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		`)
-		if err != nil {
+		return addExportBatches(ctx, tx, batches)
+	})
+}
+
+func addExportBatches(ctx context.Context, tx pgx.Tx, batches []*model.ExportBatch) error {
+	const stmtName = "insert export batches"
+	_, err := tx.Prepare(ctx, stmtName, `
+		INSERT INTO
+			ExportBatch
+			(config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, kind,
+			 generation, supersedes_batch_id)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULLIF($16::INT, 0))
+	`)
+	if err != nil {
+		return err
+	}
+
+	for _, eb := range batches {
+		if _, err := tx.Exec(ctx, stmtName,
+			eb.ConfigID, eb.BucketName, eb.FilenameRoot, eb.StartTimestamp, eb.EndTimestamp, eb.OutputRegion, eb.Status, eb.SignatureInfoIDs,
+			eb.InputRegions, eb.IncludeTravelers, eb.ExcludeRegions, eb.OnlyNonTravelers, eb.MaxRecordsOverride, eb.EffectiveKind(),
+			eb.Generation, eb.SupersedesBatchID); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		for _, eb := range batches {
-			if _, err := tx.Exec(ctx, stmtName,
-				eb.ConfigID, eb.BucketName, eb.FilenameRoot, eb.StartTimestamp, eb.EndTimestamp, eb.OutputRegion, eb.Status, eb.SignatureInfoIDs,
-				eb.InputRegions, eb.IncludeTravelers, eb.ExcludeRegions, eb.OnlyNonTravelers, eb.MaxRecordsOverride); err != nil {
-				return err
+// BackfillBatches creates open batches that regenerate the completed batches
+// of the config that are entirely within the given time range, using the
+// config's current settings and signing keys. Batches that have already been
+// backfilled are skipped, and the files of each replaced batch are marked for
+// deletion once the batch replacing it completes. It returns the number of
+// batches created.
+func (db *ExportDB) BackfillBatches(ctx context.Context, ec *model.ExportConfig, start, end time.Time) (int, error) {
+	var batches []*model.ExportBatch
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				eb.batch_id, eb.start_timestamp, eb.end_timestamp, eb.kind, eb.generation
+			FROM
+				ExportBatch eb
+			WHERE
+				eb.config_id = $1
+			AND
+				eb.start_timestamp >= $2
+			AND
+				eb.end_timestamp <= $3
+			AND
+				eb.status = $4
+			AND
+				NOT EXISTS (SELECT 1 FROM ExportBatch nb WHERE nb.supersedes_batch_id = eb.batch_id)
+			ORDER BY
+				eb.end_timestamp ASC
+			FOR UPDATE
+		`, ec.ConfigID, start, end, model.ExportBatchComplete)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				old  model.ExportBatch
+				kind string
+			)
+			if err := rows.Scan(&old.BatchID, &old.StartTimestamp, &old.EndTimestamp, &kind, &old.Generation); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
 			}
+
+			infoIds := make([]int64, len(ec.SignatureInfoIDs))
+			copy(infoIds, ec.SignatureInfoIDs)
+			batches = append(batches, &model.ExportBatch{
+				ConfigID:           ec.ConfigID,
+				BucketName:         ec.BucketName,
+				FilenameRoot:       model.KindFilenameRoot(ec.FilenameRoot, kind),
+				StartTimestamp:     old.StartTimestamp,
+				EndTimestamp:       old.EndTimestamp,
+				OutputRegion:       ec.OutputRegion,
+				InputRegions:       ec.InputRegions,
+				IncludeTravelers:   ec.IncludeTravelers,
+				OnlyNonTravelers:   ec.OnlyNonTravelers,
+				ExcludeRegions:     ec.ExcludeRegions,
+				Status:             model.ExportBatchOpen,
+				SignatureInfoIDs:   infoIds,
+				MaxRecordsOverride: ec.MaxRecordsOverride,
+				Kind:               kind,
+				Generation:         old.Generation + 1,
+				SupersedesBatchID:  old.BatchID,
+			})
 		}
-		return nil
-	})
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate: %w", err)
+		}
+		rows.Close()
+
+		return addExportBatches(ctx, tx, batches)
+	}); err != nil {
+		return 0, fmt.Errorf("backfill export batches: %w", err)
+	}
+
+	return len(batches), nil
 }
 
 // LeaseBatch returns a leased ExportBatch for the worker to process. If no work to do, nil will be returned.
@@ -631,7 +721,8 @@ type queryRowFn func(ctx context.Context, query string, args ...interface{}) pgx
 func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn) (*model.ExportBatch, error) {
 	row := queryRow(ctx, `
 		SELECT
-			batch_id, config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, lease_expires, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override
+			batch_id, config_id, bucket_name, filename_root, start_timestamp, end_timestamp, output_region, status, lease_expires, signature_info_ids, input_regions, include_travelers, exclude_regions, only_non_travelers, max_records_override, kind,
+			generation, supersedes_batch_id
 		FROM
 			ExportBatch
 		WHERE
@@ -639,9 +730,13 @@ func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn)
 		LIMIT 1
 		`, batchID)
 
-	var expires *time.Time
+	var (
+		expires    *time.Time
+		supersedes *int64
+	)
 	eb := model.ExportBatch{}
-	if err := row.Scan(&eb.BatchID, &eb.ConfigID, &eb.BucketName, &eb.FilenameRoot, &eb.StartTimestamp, &eb.EndTimestamp, &eb.OutputRegion, &eb.Status, &expires, &eb.SignatureInfoIDs, &eb.InputRegions, &eb.IncludeTravelers, &eb.ExcludeRegions, &eb.OnlyNonTravelers, &eb.MaxRecordsOverride); err != nil {
+	if err := row.Scan(&eb.BatchID, &eb.ConfigID, &eb.BucketName, &eb.FilenameRoot, &eb.StartTimestamp, &eb.EndTimestamp, &eb.OutputRegion, &eb.Status, &expires, &eb.SignatureInfoIDs, &eb.InputRegions, &eb.IncludeTravelers, &eb.ExcludeRegions, &eb.OnlyNonTravelers, &eb.MaxRecordsOverride, &eb.Kind,
+		&eb.Generation, &supersedes); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, database.ErrNotFound
 		}
@@ -650,36 +745,71 @@ func lookupExportBatch(ctx context.Context, batchID int64, queryRow queryRowFn)
 	if expires != nil {
 		eb.LeaseExpires = *expires
 	}
+	if supersedes != nil {
+		eb.SupersedesBatchID = *supersedes
+	}
 	return &eb, nil
 }
 
 // FinalizeBatch writes the ExportFile records and marks the ExportBatch as complete.
 func (db *ExportDB) FinalizeBatch(ctx context.Context, eb *model.ExportBatch, files []string, batchSize int) error {
+	efs := make([]*model.ExportFile, 0, len(files))
+	for i, file := range files {
+		efs = append(efs, &model.ExportFile{
+			Filename:  file,
+			BatchNum:  i + 1,
+			BatchSize: batchSize,
+		})
+	}
+	return db.FinalizeBatchFiles(ctx, eb, efs)
+}
+
+// FinalizeBatchFiles is FinalizeBatch for files whose contents are described.
+// The filename, batch number and size, and content fields are taken from each
+// file, the rest are copied from the batch.
+func (db *ExportDB) FinalizeBatchFiles(ctx context.Context, eb *model.ExportBatch, files []*model.ExportFile) error {
 	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		// Update ExportFile for the files created.
-		for i, file := range files {
+		for _, file := range files {
 			ef := model.ExportFile{
 				BucketName:       eb.BucketName,
-				Filename:         file,
+				Filename:         file.Filename,
 				BatchID:          eb.BatchID,
 				OutputRegion:     eb.OutputRegion,
 				InputRegions:     eb.InputRegions,
 				IncludeTravelers: eb.IncludeTravelers,
 				OnlyNonTravelers: eb.OnlyNonTravelers,
 				ExcludeRegions:   eb.ExcludeRegions,
-				BatchNum:         i + 1,
-				BatchSize:        batchSize,
+				BatchNum:         file.BatchNum,
+				BatchSize:        file.BatchSize,
 				Status:           model.ExportBatchComplete,
+				KeyCount:         file.KeyCount,
+				SHA256:           file.SHA256,
+				Size:             file.Size,
 			}
 			if err := addExportFile(ctx, tx, &ef); err != nil {
 				if errors.Is(err, database.ErrKeyConflict) {
-					logging.FromContext(ctx).Infof("ExportFile %q already exists in database, skipping without overwriting. This can occur when reprocessing a failed batch.", file)
+					logging.FromContext(ctx).Infof("ExportFile %q already exists in database, skipping without overwriting. This can occur when reprocessing a failed batch.", file.Filename)
 				} else {
 					return fmt.Errorf("adding export file entry: %w", err)
 				}
 			}
 		}
 
+		// The files of a backfilled batch are replaced by the new files.
+		if eb.SupersedesBatchID != 0 {
+			if _, err := tx.Exec(ctx, `
+				UPDATE
+					ExportFile
+				SET
+					status = $1
+				WHERE
+					batch_id = $2 AND status = $3
+				`, model.ExportBatchDeletePending, eb.SupersedesBatchID, model.ExportBatchComplete); err != nil {
+				return fmt.Errorf("marking files of batch %v for deletion: %w", eb.SupersedesBatchID, err)
+			}
+		}
+
 		// Update ExportBatch to mark it complete.
 		if err := completeBatch(ctx, tx, eb.BatchID); err != nil {
 			return fmt.Errorf("marking batch %v complete: %w", eb.BatchID, err)
@@ -772,6 +902,120 @@ func (db *ExportDB) LookupExportFiles(ctx context.Context, configID int64, ttl t
 	return files, nil
 }
 
+// LookupIndexFiles returns the index entries for the completed and unexpired
+// export files of a specific config and kind of batch. For period batches,
+// these are the same files as LookupExportFiles.
+func (db *ExportDB) LookupIndexFiles(ctx context.Context, configID int64, kind string, ttl time.Duration) ([]*model.IndexFile, error) {
+	var files []*model.IndexFile
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		minTime := time.Now().Add(-1 * ttl)
+
+		rows, err := tx.Query(ctx, `
+			SELECT
+				ef.filename, ef.batch_num, ef.batch_size, ef.key_count, ef.sha256, ef.size,
+				eb.batch_id, eb.start_timestamp, eb.end_timestamp
+			FROM
+				ExportFile ef
+			INNER JOIN
+				ExportBatch eb ON (eb.batch_id = ef.batch_id)
+			WHERE
+				eb.config_id = $1
+			AND
+				eb.start_timestamp > $2
+			AND
+				(eb.status = $3 OR eb.status = $4)
+			AND
+				ef.status = $5
+			AND
+				eb.kind = $6
+			ORDER BY
+				ef.filename
+		`,
+			configID, minTime, model.ExportBatchComplete, model.ExportBatchDeleted, model.ExportBatchComplete, kind,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var (
+				ef       model.ExportFile
+				eb       model.ExportBatch
+				keyCount *int
+				size     *int64
+			)
+			if err := rows.Scan(&ef.Filename, &ef.BatchNum, &ef.BatchSize, &keyCount, &ef.SHA256, &size,
+				&eb.BatchID, &eb.StartTimestamp, &eb.EndTimestamp); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			if keyCount != nil {
+				ef.KeyCount = *keyCount
+			}
+			if size != nil {
+				ef.Size = *size
+			}
+			files = append(files, model.NewIndexFile(&eb, &ef))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("lookup index files: %w", err)
+	}
+
+	return files, nil
+}
+
+// ListExportFilenames returns the names of the export files in the given
+// bucket whose names start with prefix, and which have not been deleted.
+func (db *ExportDB) ListExportFilenames(ctx context.Context, bucketName, prefix string) ([]string, error) {
+	var files []string
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				filename
+			FROM
+				ExportFile
+			WHERE
+				bucket_name = $1
+			AND
+				LEFT(filename, LENGTH($2)) = $2
+			AND
+				status != $3
+			ORDER BY
+				filename
+		`, bucketName, prefix, model.ExportBatchDeleted)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var file string
+			if err := rows.Scan(&file); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			files = append(files, file)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list export filenames: %w", err)
+	}
+
+	return files, nil
+}
+
 type joinedExportBatchFile struct {
 	bucketName  string
 	filename    string
@@ -903,11 +1147,13 @@ func addExportFile(ctx context.Context, tx pgx.Tx, ef *model.ExportFile) error {
 	tag, err := tx.Exec(ctx, `
 		INSERT INTO
 			ExportFile
-			(bucket_name, filename, batch_id, output_region, batch_num, batch_size, status, input_regions, include_travelers, exclude_regions, only_non_travelers)
+			(bucket_name, filename, batch_id, output_region, batch_num, batch_size, status, input_regions, include_travelers, exclude_regions, only_non_travelers,
+			 key_count, sha256, size)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12::INT, 0), $13, NULLIF($14::BIGINT, 0))
 		ON CONFLICT (filename) DO NOTHING
-		`, ef.BucketName, ef.Filename, ef.BatchID, ef.OutputRegion, ef.BatchNum, ef.BatchSize, ef.Status, ef.InputRegions, ef.IncludeTravelers, ef.ExcludeRegions, ef.OnlyNonTravelers)
+		`, ef.BucketName, ef.Filename, ef.BatchID, ef.OutputRegion, ef.BatchNum, ef.BatchSize, ef.Status, ef.InputRegions, ef.IncludeTravelers, ef.ExcludeRegions, ef.OnlyNonTravelers,
+		ef.KeyCount, ef.SHA256, ef.Size)
 	if err != nil {
 		return fmt.Errorf("inserting to ExportFile: %w", err)
 	}
@@ -16,6 +16,7 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"testing"
@@ -476,7 +477,204 @@ func TestFinalizeBatch(t *testing.T) {
 	}
 }
 
+func TestFinalizeBatchFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().Truncate(time.Second)
+
+	ec := &model.ExportConfig{
+		BucketName:   "some-bucket",
+		FilenameRoot: "filename-root",
+		Period:       time.Minute,
+		OutputRegion: "US",
+		IndexFormats: []string{model.IndexFormatText, model.IndexFormatJSON},
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+	gotConfig, err := exportDB.GetExportConfig(ctx, ec.ConfigID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(ec.IndexFormats, gotConfig.IndexFormats); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	eb := &model.ExportBatch{
+		ConfigID:       ec.ConfigID,
+		BucketName:     ec.BucketName,
+		FilenameRoot:   ec.FilenameRoot,
+		StartTimestamp: now.Add(-2 * time.Hour),
+		EndTimestamp:   now.Add(-time.Hour),
+		OutputRegion:   ec.OutputRegion,
+		Status:         model.ExportBatchOpen,
+	}
+	if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{eb}); err != nil {
+		t.Fatal(err)
+	}
+	eb, err = exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []*model.ExportFile{
+		{Filename: "file1.zip", BatchNum: 1, BatchSize: 2, KeyCount: 10, SHA256: []byte{1, 2, 3}, Size: 100},
+		{Filename: "file2.zip", BatchNum: 2, BatchSize: 2, KeyCount: 5, SHA256: []byte{4, 5, 6}, Size: 50},
+	}
+	if err := exportDB.FinalizeBatchFiles(ctx, eb, files); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := exportDB.LookupIndexFiles(ctx, eb.ConfigID, model.ExportBatchKindPeriod, 20*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*model.IndexFile{
+		{
+			Filename:       "file1.zip",
+			BatchID:        eb.BatchID,
+			StartTimestamp: eb.StartTimestamp,
+			EndTimestamp:   eb.EndTimestamp.Add(time.Second),
+			BatchNum:       1,
+			BatchSize:      2,
+			KeyCount:       10,
+			SHA256:         []byte{1, 2, 3},
+			Size:           100,
+		},
+		{
+			Filename:       "file2.zip",
+			BatchID:        eb.BatchID,
+			StartTimestamp: eb.StartTimestamp,
+			EndTimestamp:   eb.EndTimestamp.Add(2 * time.Second),
+			BatchNum:       2,
+			BatchSize:      2,
+			KeyCount:       5,
+			SHA256:         []byte{4, 5, 6},
+			Size:           50,
+		},
+	}
+	opts := cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
 // TestTravelerKeys ensures traveler keys are pulled in when necessary.
+func TestBackfillBatches(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	exportDB := New(testDB)
+	now := time.Now().Truncate(time.Hour)
+
+	ec := &model.ExportConfig{
+		BucketName:   "some-bucket",
+		FilenameRoot: "filename-root",
+		Period:       time.Hour,
+		OutputRegion: "US",
+	}
+	if err := exportDB.AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+
+	// Complete three batches, the backfill covers the last two.
+	var batches []*model.ExportBatch
+	for i := 3; i > 0; i-- {
+		if err := exportDB.AddExportBatches(ctx, []*model.ExportBatch{{
+			ConfigID:       ec.ConfigID,
+			BucketName:     ec.BucketName,
+			FilenameRoot:   ec.FilenameRoot,
+			StartTimestamp: now.Add(-time.Duration(i+1) * time.Hour),
+			EndTimestamp:   now.Add(-time.Duration(i) * time.Hour),
+			OutputRegion:   ec.OutputRegion,
+			Status:         model.ExportBatchOpen,
+		}}); err != nil {
+			t.Fatal(err)
+		}
+		eb, err := exportDB.LeaseBatch(ctx, time.Hour, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := exportDB.FinalizeBatch(ctx, eb, []string{fmt.Sprintf("file%d.zip", i)}, 1); err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, eb)
+	}
+
+	// Backfill uses the current config.
+	ec.SignatureInfoIDs = []int64{}
+	ec.InputRegions = []string{"CA"}
+	start, end := now.Add(-3*time.Hour), now
+	n, err := exportDB.BackfillBatches(ctx, ec, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 2; got != want {
+		t.Fatalf("expected %d batches, got %d", want, got)
+	}
+
+	// Batches that are already backfilled are skipped.
+	n, err = exportDB.BackfillBatches(ctx, ec, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 0; got != want {
+		t.Fatalf("expected %d batches, got %d", want, got)
+	}
+
+	eb, err := exportDB.LeaseBatch(ctx, time.Hour, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old *model.ExportBatch
+	for _, b := range batches {
+		if b.StartTimestamp.Equal(eb.StartTimestamp) {
+			old = b
+		}
+	}
+	if old == nil || old == batches[0] {
+		t.Fatalf("unexpected backfill batch for %v-%v", eb.StartTimestamp, eb.EndTimestamp)
+	}
+	if got, want := eb.SupersedesBatchID, old.BatchID; got != want {
+		t.Errorf("expected batch to supersede %d, got %d", want, got)
+	}
+	if got, want := eb.Generation, int64(1); got != want {
+		t.Errorf("expected generation %d, got %d", want, got)
+	}
+	if diff := cmp.Diff([]string{"CA"}, eb.InputRegions); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Completing the backfill replaces the superseded file.
+	supersededName := fmt.Sprintf("file%d.zip", int(now.Sub(old.EndTimestamp).Hours()))
+	if err := exportDB.FinalizeBatch(ctx, eb, []string{"regenerated.zip"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	files, err := exportDB.LookupExportFiles(ctx, ec.ConfigID, 20*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f == supersededName {
+			t.Errorf("expected %s to be replaced, got %v", supersededName, files)
+		}
+	}
+	if got, want := len(files), 3; got != want {
+		t.Errorf("expected %d files, got %v", want, files)
+	}
+	superseded, err := exportDB.LookupExportFile(ctx, supersededName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := superseded.Status, model.ExportBatchDeletePending; got != want {
+		t.Errorf("expected status %q to be %q", got, want)
+	}
+}
+
 func TestTravelerKeys(t *testing.T) {
 	t.Parallel()
 
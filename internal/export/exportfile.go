@@ -197,23 +197,27 @@ func marshalContents(eb *model.ExportBatch, exposures, revisedExposures []*publi
 	sortExposures(exposures)
 	pbeks := make([]*export.TemporaryExposureKey, 0, len(exposures))
 	for _, exp := range exposures {
-		pbek := makeTEK(exp)
-		assignReportType(&exp.ReportType, pbek)
-		if exp.HasDaysSinceSymptomOnset() {
-			pbek.DaysSinceOnsetOfSymptoms = proto.Int32(*exp.DaysSinceSymptomOnset)
-		}
-		pbeks = append(pbeks, pbek)
+		pbeks = append(pbeks, makePrimaryTEK(exp))
 	}
 
 	sortExposures(revisedExposures)
 	pbRevisedKeys := make([]*export.TemporaryExposureKey, 0, len(revisedExposures))
 	for _, exp := range revisedExposures {
-		pbek := makeTEK(exp)
-		assignReportType(exp.RevisedReportType, pbek)
-		pbek.DaysSinceOnsetOfSymptoms = exp.RevisedDaysSinceSymptomOnset
-		pbRevisedKeys = append(pbRevisedKeys, pbek)
+		pbRevisedKeys = append(pbRevisedKeys, makeRevisedTEK(exp))
+	}
+
+	pbeke := makeExportHeader(eb, fileNum, splitBatch, signers)
+	pbeke.Keys = pbeks
+	pbeke.RevisedKeys = pbRevisedKeys
+	protoBytes, err := proto.Marshal(pbeke)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal exposure keys: %w", err)
 	}
+	return append(exportBytes, protoBytes...), nil
+}
 
+// makeExportHeader builds the export message for a file without any keys.
+func makeExportHeader(eb *model.ExportBatch, fileNum int32, splitBatch bool, signers []*Signer) *export.TemporaryExposureKeyExport {
 	exportSigInfos := make([]*export.SignatureInfo, 0, len(signers))
 	for _, si := range signers {
 		exportSigInfos = append(exportSigInfos, createSignatureInfo(si.SignatureInfo))
@@ -223,21 +227,32 @@ func marshalContents(eb *model.ExportBatch, exposures, revisedExposures []*publi
 	if splitBatch {
 		offset = int64(fileNum)
 	}
-	pbeke := export.TemporaryExposureKeyExport{
+	return &export.TemporaryExposureKeyExport{
 		StartTimestamp: proto.Uint64(uint64(eb.StartTimestamp.Unix())),
 		EndTimestamp:   proto.Uint64(uint64(eb.EndTimestamp.Unix() + offset)),
 		Region:         proto.String(eb.OutputRegion),
 		BatchNum:       proto.Int32(1), // all batches are now size 1 (single file)
 		BatchSize:      proto.Int32(1), // so it's always 1 of 1.
-		Keys:           pbeks,
-		RevisedKeys:    pbRevisedKeys,
 		SignatureInfos: exportSigInfos,
 	}
-	protoBytes, err := proto.Marshal(&pbeke)
-	if err != nil {
-		return nil, fmt.Errorf("unable to marshal exposure keys: %w", err)
+}
+
+// makePrimaryTEK converts a newly published exposure into its export form.
+func makePrimaryTEK(exp *publishmodel.Exposure) *export.TemporaryExposureKey {
+	pbek := makeTEK(exp)
+	assignReportType(&exp.ReportType, pbek)
+	if exp.HasDaysSinceSymptomOnset() {
+		pbek.DaysSinceOnsetOfSymptoms = proto.Int32(*exp.DaysSinceSymptomOnset)
 	}
-	return append(exportBytes, protoBytes...), nil
+	return pbek
+}
+
+// makeRevisedTEK converts a revised exposure into its export form.
+func makeRevisedTEK(exp *publishmodel.Exposure) *export.TemporaryExposureKey {
+	pbek := makeTEK(exp)
+	assignReportType(exp.RevisedReportType, pbek)
+	pbek.DaysSinceOnsetOfSymptoms = exp.RevisedDaysSinceSymptomOnset
+	return pbek
 }
 
 func createSignatureInfo(si *model.SignatureInfo) *export.SignatureInfo {
@@ -289,9 +304,16 @@ func unmarshalSignatureContent(file *zip.File) (*export.TEKSignatureList, error)
 }
 
 func marshalSignature(exportContents []byte, signers []*Signer) ([]byte, error) {
+	digest := sha256.Sum256(exportContents)
+	return marshalDigestSignature(digest[:], signers)
+}
+
+// marshalDigestSignature builds the signature file from the SHA256 digest of
+// the export contents.
+func marshalDigestSignature(digest []byte, signers []*Signer) ([]byte, error) {
 	signatures := make([]*export.TEKSignature, 0, len(signers))
 	for _, s := range signers {
-		sig, err := generateSignature(exportContents, s.Signer)
+		sig, err := signDigest(digest, s.Signer)
 		if err != nil {
 			return nil, fmt.Errorf("unable to generate signature: %w", err)
 		}
@@ -313,9 +335,8 @@ func marshalSignature(exportContents []byte, signers []*Signer) ([]byte, error)
 	return protoBytes, nil
 }
 
-func generateSignature(data []byte, signer crypto.Signer) ([]byte, error) {
-	digest := sha256.Sum256(data)
-	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+func signDigest(digest []byte, signer crypto.Signer) ([]byte, error) {
+	sig, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
 	if err != nil {
 		return nil, fmt.Errorf("unable to sign: %w", err)
 	}
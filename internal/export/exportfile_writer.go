@@ -0,0 +1,138 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/pb/export"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Field numbers of the repeated key fields in TemporaryExposureKeyExport.
+var (
+	exportFields           = (&export.TemporaryExposureKeyExport{}).ProtoReflect().Descriptor().Fields()
+	keysFieldNumber        = exportFields.ByName("keys").Number()
+	revisedKeysFieldNumber = exportFields.ByName("revised_keys").Number()
+)
+
+// FileWriter streams a single export file archive. Keys are encoded and
+// compressed one at a time, so memory use does not depend on the number of
+// keys in the file. The export.bin contents are hashed as they are written, so
+// export.sig can be generated without holding them in memory.
+//
+// The output is identical to MarshalExportFile when keys are written in the
+// same (sorted) order. All primary keys must be written before any revised
+// keys.
+type FileWriter struct {
+	zw      *zip.Writer
+	bin     io.Writer
+	digest  hash.Hash
+	signers []*Signer
+
+	revised bool
+	keyBuf  []byte
+	buf     []byte
+}
+
+// NewFileWriter starts a new export file on w. The archive is not complete
+// until Close is called.
+func NewFileWriter(w io.Writer, eb *model.ExportBatch, fileNum int32, splitBatch bool, signers []*Signer) (*FileWriter, error) {
+	zw := zip.NewWriter(w)
+	zf, err := zw.Create(exportBinaryName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create zip entry for export: %w", err)
+	}
+
+	digest := sha256.New()
+	fw := &FileWriter{
+		zw:      zw,
+		bin:     io.MultiWriter(zf, digest),
+		digest:  digest,
+		signers: signers,
+	}
+
+	// The header fields all have lower field numbers than the keys, so writing
+	// them first keeps the encoding identical to marshaling the full message.
+	header, err := proto.Marshal(makeExportHeader(eb, fileNum, splitBatch, signers))
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal export header: %w", err)
+	}
+	if _, err := fw.bin.Write(fixedHeader); err != nil {
+		return nil, fmt.Errorf("unable to write export to archive: %w", err)
+	}
+	if _, err := fw.bin.Write(header); err != nil {
+		return nil, fmt.Errorf("unable to write export to archive: %w", err)
+	}
+	return fw, nil
+}
+
+// WriteExposure appends a newly published key to the export file.
+func (w *FileWriter) WriteExposure(exp *publishmodel.Exposure) error {
+	if w.revised {
+		return fmt.Errorf("primary keys must be written before revised keys")
+	}
+	return w.writeKey(keysFieldNumber, makePrimaryTEK(exp))
+}
+
+// WriteRevisedExposure appends a revised key to the export file.
+func (w *FileWriter) WriteRevisedExposure(exp *publishmodel.Exposure) error {
+	w.revised = true
+	return w.writeKey(revisedKeysFieldNumber, makeRevisedTEK(exp))
+}
+
+func (w *FileWriter) writeKey(num protowire.Number, pbek *export.TemporaryExposureKey) error {
+	var err error
+	w.keyBuf, err = proto.MarshalOptions{}.MarshalAppend(w.keyBuf[:0], pbek)
+	if err != nil {
+		return fmt.Errorf("unable to marshal exposure key: %w", err)
+	}
+
+	w.buf = protowire.AppendTag(w.buf[:0], num, protowire.BytesType)
+	w.buf = protowire.AppendBytes(w.buf, w.keyBuf)
+	if _, err := w.bin.Write(w.buf); err != nil {
+		return fmt.Errorf("unable to write export to archive: %w", err)
+	}
+	return nil
+}
+
+// Close signs the export contents, writes the signature file and finishes the
+// archive. It does not close the underlying writer.
+func (w *FileWriter) Close() error {
+	sigContents, err := marshalDigestSignature(w.digest.Sum(nil), w.signers)
+	if err != nil {
+		return fmt.Errorf("unable to marshal signature file: %w", err)
+	}
+
+	zf, err := w.zw.Create(exportSignatureName)
+	if err != nil {
+		return fmt.Errorf("unable to create zip entry for signature: %w", err)
+	}
+	if _, err := zf.Write(sigContents); err != nil {
+		return fmt.Errorf("unable to write signature to archive: %w", err)
+	}
+	if err := w.zw.Close(); err != nil {
+		return fmt.Errorf("unable to close archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFileWriter(t *testing.T) {
+	t.Parallel()
+
+	batchStartTime := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	batchEndTime := batchStartTime.Add(1 * time.Hour)
+
+	batch := &model.ExportBatch{
+		BatchID:        1,
+		ConfigID:       1,
+		BucketName:     "test-bucket",
+		FilenameRoot:   "files",
+		StartTimestamp: batchStartTime,
+		EndTimestamp:   batchEndTime,
+		OutputRegion:   "US",
+	}
+
+	exposures := make([]*publishmodel.Exposure, 0, 50)
+	for i := 0; i < cap(exposures); i++ {
+		exposures = append(exposures, &publishmodel.Exposure{
+			ExposureKey:           randomTEK(t),
+			IntervalNumber:        int32(100 + i),
+			IntervalCount:         144,
+			TransmissionRisk:      i % 8,
+			DaysSinceSymptomOnset: proto.Int32(int32(i%10 - 5)),
+			ReportType:            verifyapi.ReportTypeConfirmed,
+		})
+	}
+	revisedExposures := make([]*publishmodel.Exposure, 0, 10)
+	for i := 0; i < cap(revisedExposures); i++ {
+		revisedExposures = append(revisedExposures, &publishmodel.Exposure{
+			ExposureKey:                  randomTEK(t),
+			IntervalNumber:               int32(200 + i),
+			IntervalCount:                144,
+			ReportType:                   verifyapi.ReportTypeClinical,
+			RevisedReportType:            proto.String(verifyapi.ReportTypeConfirmed),
+			RevisedDaysSinceSymptomOnset: proto.Int32(int32(i)),
+		})
+	}
+	sortExposures(exposures)
+	sortExposures(revisedExposures)
+
+	signers := []*Signer{
+		{
+			SignatureInfo: &model.SignatureInfo{
+				SigningKey:        "/kms/project/key/1",
+				SigningKeyVersion: "1",
+				SigningKeyID:      "310",
+			},
+			Signer: &customTestSigner{sig: []byte("signature")},
+		},
+	}
+
+	for _, splitBatch := range []bool{false, true} {
+		want, err := MarshalExportFile(batch, exposures, revisedExposures, 2, splitBatch, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		fw, err := NewFileWriter(&buf, batch, 2, splitBatch, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, exp := range exposures {
+			if err := fw.WriteExposure(exp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, exp := range revisedExposures {
+			if err := fw.WriteRevisedExposure(exp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := fw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("splitBatch=%t: streamed export file does not match MarshalExportFile", splitBatch)
+		}
+	}
+
+	t.Run("revised_before_primary", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		fw, err := NewFileWriter(&buf, batch, 1, false, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.WriteRevisedExposure(revisedExposures[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := fw.WriteExposure(exposures[0]); err == nil {
+			t.Errorf("expected error writing primary key after revised key")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	v1 "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// latestIndexFiles returns the files of the most recent batch. All files of a
+// batch share its start timestamp.
+func latestIndexFiles(files []*model.IndexFile) []*model.IndexFile {
+	var latest time.Time
+	for _, f := range files {
+		if f.StartTimestamp.After(latest) {
+			latest = f.StartTimestamp
+		}
+	}
+
+	ret := make([]*model.IndexFile, 0, len(files))
+	for _, f := range files {
+		if f.StartTimestamp.Equal(latest) {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// marshalTextIndex builds the contents of index.txt, one filename per line.
+func marshalTextIndex(files []*model.IndexFile) []byte {
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Filename)
+	}
+	return []byte(strings.Join(names, "\n"))
+}
+
+// marshalJSONIndex builds the contents of index.json.
+func marshalJSONIndex(files []*model.IndexFile) ([]byte, error) {
+	index := &v1.ExportIndex{
+		Files: make([]*v1.ExportIndexFile, 0, len(files)),
+	}
+	for _, f := range files {
+		index.Files = append(index.Files, &v1.ExportIndexFile{
+			Filename:       f.Filename,
+			StartTimestamp: f.StartTimestamp.Unix(),
+			EndTimestamp:   f.EndTimestamp.Unix(),
+			BatchNum:       f.BatchNum,
+			BatchSize:      f.BatchSize,
+			KeyCount:       f.KeyCount,
+			SHA256:         hex.EncodeToString(f.SHA256),
+			Size:           f.Size,
+		})
+	}
+	return json.Marshal(index)
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+)
+
+func TestMarshalIndex(t *testing.T) {
+	t.Parallel()
+
+	start := time.Unix(1614556800, 0)
+	files := []*model.IndexFile{
+		{
+			Filename:       "root/a.zip",
+			StartTimestamp: start,
+			EndTimestamp:   start.Add(4 * time.Hour),
+			BatchNum:       1,
+			BatchSize:      1,
+			KeyCount:       10,
+			SHA256:         []byte{0xde, 0xad, 0xbe, 0xef},
+			Size:           1234,
+		},
+		{
+			// Exported before the file contents were recorded.
+			Filename:       "root/b.zip",
+			StartTimestamp: start.Add(4 * time.Hour),
+			EndTimestamp:   start.Add(8 * time.Hour),
+			BatchNum:       1,
+			BatchSize:      1,
+		},
+	}
+
+	if got, want := string(marshalTextIndex(files)), "root/a.zip\nroot/b.zip"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	b, err := marshalJSONIndex(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"files":[` +
+		`{"filename":"root/a.zip","start_timestamp":1614556800,"end_timestamp":1614571200,"batch_num":1,"batch_size":1,"key_count":10,"sha256":"deadbeef","size":1234},` +
+		`{"filename":"root/b.zip","start_timestamp":1614571200,"end_timestamp":1614585600,"batch_num":1,"batch_size":1}` +
+		`]}`
+	if got := string(b); got != want {
+		t.Errorf("expected\n%s\nto be\n%s", got, want)
+	}
+}
@@ -29,18 +29,33 @@ var (
 	ExportConfigIDTagKey  = tag.MustNewKey("config_id")
 	ExportRegionTagKey    = tag.MustNewKey("region")
 	ExportTravelersTagKey = tag.MustNewKey("includes_travelers")
+
+	// ExportOutputRegionTagKey is set on the context of each batch export, along
+	// with ExportConfigIDTagKey, so that export volume can be broken down by
+	// region.
+	ExportOutputRegionTagKey = tag.MustNewKey("output_region")
 )
 
 var (
 	mBatcherSuccess = stats.Int64(metricPrefix+"/batcher/success", "successful batcher execution", stats.UnitDimensionless)
 	mWorkerSuccess  = stats.Int64(metricPrefix+"/worker/success", "successful worker execution", stats.UnitDimensionless)
 
+	mStatsExportSuccess = stats.Int64(metricPrefix+"/stats_export/success", "successful stats export execution", stats.UnitDimensionless)
+
 	mBatcherNoWork         = stats.Int64(metricPrefix+"/batcher_no_work", "Instances of export batcher having no work", stats.UnitDimensionless)
 	mBatcherCreated        = stats.Int64(metricPrefix+"/batches_created", "Number of export batchers created", stats.UnitDimensionless)
 	mWorkerBadKeyLength    = stats.Int64(metricPrefix+"/worker_bad_key_length", "Number of dropped keys caused by bad key length", stats.UnitDimensionless)
 	mExportBatchCompletion = stats.Int64(metricPrefix+"/batch_completion", "Number of batches complete by output region", stats.UnitDimensionless)
+
+	mExportKeys           = stats.Int64(metricPrefix+"/keys", "Number of keys exported", stats.UnitDimensionless)
+	mExportPaddingKeys    = stats.Int64(metricPrefix+"/padding_keys", "Number of generated padding keys exported", stats.UnitDimensionless)
+	mExportRevisedKeys    = stats.Int64(metricPrefix+"/revised_keys", "Number of revised keys exported", stats.UnitDimensionless)
+	mExportFileBytes      = stats.Int64(metricPrefix+"/file_bytes", "Size of export files", stats.UnitBytes)
+	mExportSigningLatency = stats.Float64(metricPrefix+"/signing_latency", "Latency of signing an export file", stats.UnitMilliseconds)
 )
 
+var exportVolumeTagKeys = []tag.Key{ExportConfigIDTagKey, ExportOutputRegionTagKey}
+
 func init() {
 	observability.CollectViews([]*view.View{
 		{
@@ -55,6 +70,12 @@ func init() {
 			Measure:     mWorkerSuccess,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/stats_export/success",
+			Description: "Number of stats export successes",
+			Measure:     mStatsExportSuccess,
+			Aggregation: view.Count(),
+		},
 		{
 			Name:        metrics.MetricRoot + "/batcher_no_work_count",
 			Description: "Total count for instances of export batcher having no work",
@@ -80,5 +101,47 @@ func init() {
 			Aggregation: view.Sum(),
 			TagKeys:     []tag.Key{ExportConfigIDTagKey, ExportRegionTagKey, ExportTravelersTagKey},
 		},
+		{
+			Name:        metricPrefix + "/keys_count",
+			Description: "Total count of keys exported",
+			Measure:     mExportKeys,
+			Aggregation: view.Sum(),
+			TagKeys:     exportVolumeTagKeys,
+		},
+		{
+			Name:        metricPrefix + "/padding_keys_count",
+			Description: "Total count of generated padding keys exported",
+			Measure:     mExportPaddingKeys,
+			Aggregation: view.Sum(),
+			TagKeys:     exportVolumeTagKeys,
+		},
+		{
+			Name:        metricPrefix + "/revised_keys_count",
+			Description: "Total count of revised keys exported",
+			Measure:     mExportRevisedKeys,
+			Aggregation: view.Sum(),
+			TagKeys:     exportVolumeTagKeys,
+		},
+		{
+			Name:        metricPrefix + "/file_bytes_count",
+			Description: "Total size of export files",
+			Measure:     mExportFileBytes,
+			Aggregation: view.Sum(),
+			TagKeys:     exportVolumeTagKeys,
+		},
+		{
+			Name:        metricPrefix + "/file_bytes",
+			Description: "Distribution of export file sizes",
+			Measure:     mExportFileBytes,
+			Aggregation: view.Distribution(1<<10, 10<<10, 50<<10, 100<<10, 250<<10, 500<<10, 1<<20, 5<<20),
+			TagKeys:     exportVolumeTagKeys,
+		},
+		{
+			Name:        metricPrefix + "/signing_latency",
+			Description: "Distribution of export file signing latency",
+			Measure:     mExportSigningLatency,
+			Aggregation: view.Distribution(1, 10, 50, 100, 250, 500, 1000, 5000),
+			TagKeys:     exportVolumeTagKeys,
+		},
 	}...)
 }
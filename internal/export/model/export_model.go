@@ -17,6 +17,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -33,6 +34,27 @@ const (
 	oneDay = 24 * time.Hour
 )
 
+// Kinds of ExportBatch. Period batches are created for each period of an
+// ExportConfig. Rolling and daily batches are optional roll-ups that aggregate
+// the keys of several periods into a single bundle, so clients that have been
+// offline don't need to download every period file.
+const (
+	ExportBatchKindPeriod  = "PERIOD"
+	ExportBatchKindRolling = "ROLLING"
+	ExportBatchKindDaily   = "DAILY"
+)
+
+// MaxRollingHours is the longest rolling window, the same as the default
+// retention of export files.
+const MaxRollingHours = 14 * 24
+
+// Index formats that can be generated for an ExportConfig. The text index
+// lists one filename per line, and the JSON index also describes each file.
+const (
+	IndexFormatText = "txt"
+	IndexFormatJSON = "json"
+)
+
 // ExportConfig describes what goes into an export, and how frequently.
 // These are used to periodically generate an ExportBatch.
 type ExportConfig struct {
@@ -49,6 +71,14 @@ type ExportConfig struct {
 	Thru               time.Time
 	SignatureInfoIDs   []int64
 	MaxRecordsOverride *int
+	IndexFormats       []string
+
+	// RollingHours, if positive, generates a rolling bundle of the keys from the
+	// last RollingHours hours at the end of each period.
+	RollingHours int
+
+	// DailyRollup generates a bundle of all keys for each UTC day.
+	DailyRollup bool
 }
 
 // EffectiveInputRegions either returns `InputRegions` or if that array is
@@ -57,6 +87,26 @@ func (ec *ExportConfig) EffectiveInputRegions() []string {
 	return effectiveInputRegions(ec.OutputRegion, ec.InputRegions)
 }
 
+// EffectiveIndexFormats returns the index formats to generate. If none are
+// set, only the text index is generated.
+func (ec *ExportConfig) EffectiveIndexFormats() []string {
+	if len(ec.IndexFormats) > 0 {
+		return ec.IndexFormats
+	}
+	return []string{IndexFormatText}
+}
+
+// HasIndexFormat returns true if the given index format is generated for this
+// export.
+func (ec *ExportConfig) HasIndexFormat(format string) bool {
+	for _, f := range ec.EffectiveIndexFormats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
 func (ec *ExportConfig) InputRegionsOnePerLine() string {
 	return strings.Join(ec.InputRegions, "\n")
 }
@@ -75,6 +125,20 @@ func (ec *ExportConfig) Validate() error {
 	if int64(oneDay.Seconds())%int64(ec.Period.Seconds()) != 0 {
 		return errors.New("period must divide equally into 24 hours (e.g., 2h, 4h, 12h, 15m, 30m)")
 	}
+	if ec.RollingHours < 0 || ec.RollingHours > MaxRollingHours {
+		return fmt.Errorf("rolling hours must be between 0 and %d", MaxRollingHours)
+	}
+	if ec.RollingHours > 0 && time.Duration(ec.RollingHours)*time.Hour <= ec.Period {
+		return errors.New("rolling window must be longer than the period")
+	}
+	if ec.DailyRollup && ec.Period >= oneDay {
+		return errors.New("daily roll-up requires a period shorter than 24h")
+	}
+	for _, f := range ec.IndexFormats {
+		if f != IndexFormatText && f != IndexFormatJSON {
+			return fmt.Errorf("unknown index format %q", f)
+		}
+	}
 	return nil
 }
 
@@ -95,6 +159,15 @@ type ExportBatch struct {
 	LeaseExpires       time.Time
 	SignatureInfoIDs   []int64
 	MaxRecordsOverride *int
+	Kind               string
+
+	// Generation is incremented each time the batch is backfilled, and offsets
+	// the timestamps in its filenames so regenerated files get new names.
+	Generation int64
+
+	// SupersedesBatchID is the batch that this batch regenerates, if any. Its
+	// files are marked for deletion when this batch completes.
+	SupersedesBatchID int64
 }
 
 // EffectiveMaxRecords returns either the provided value or the override
@@ -114,6 +187,29 @@ func (eb *ExportBatch) EffectiveInputRegions() []string {
 	return effectiveInputRegions(eb.OutputRegion, eb.InputRegions)
 }
 
+// EffectiveKind returns the batch's kind, batches created before kinds were
+// introduced are period batches.
+func (eb *ExportBatch) EffectiveKind() string {
+	if eb.Kind == "" {
+		return ExportBatchKindPeriod
+	}
+	return eb.Kind
+}
+
+// KindFilenameRoot returns the filename root for the files and index of the
+// given kind of batch. Roll-ups are written to a subdirectory of the export's
+// filename root, so that each kind has its own index.
+func KindFilenameRoot(root, kind string) string {
+	switch kind {
+	case ExportBatchKindRolling:
+		return root + "/rolling"
+	case ExportBatchKindDaily:
+		return root + "/daily"
+	default:
+		return root
+	}
+}
+
 type ExportFile struct {
 	BucketName       string
 	Filename         string
@@ -126,6 +222,12 @@ type ExportFile struct {
 	BatchNum         int
 	BatchSize        int
 	Status           string
+
+	// KeyCount, SHA256 and Size describe the file's contents. They are not set
+	// for files exported before they were recorded.
+	KeyCount int
+	SHA256   []byte
+	Size     int64
 }
 
 // EffectiveInputRegions either returns `InputRegions` or if that array is
@@ -134,6 +236,42 @@ func (ef *ExportFile) EffectiveInputRegions() []string {
 	return effectiveInputRegions(ef.OutputRegion, ef.InputRegions)
 }
 
+// IndexFile describes an export file as it is listed in the JSON index.
+type IndexFile struct {
+	Filename string
+	BatchID  int64
+
+	// StartTimestamp and EndTimestamp are the timestamps in the export file's
+	// header. For a split batch, the end timestamp is offset by the file number.
+	StartTimestamp time.Time
+	EndTimestamp   time.Time
+
+	BatchNum  int
+	BatchSize int
+	KeyCount  int
+	SHA256    []byte
+	Size      int64
+}
+
+// NewIndexFile returns the index entry for a file in the given batch.
+func NewIndexFile(eb *ExportBatch, ef *ExportFile) *IndexFile {
+	end := eb.EndTimestamp
+	if ef.BatchSize > 1 {
+		end = end.Add(time.Duration(ef.BatchNum) * time.Second)
+	}
+	return &IndexFile{
+		Filename:       ef.Filename,
+		BatchID:        eb.BatchID,
+		StartTimestamp: eb.StartTimestamp,
+		EndTimestamp:   end,
+		BatchNum:       ef.BatchNum,
+		BatchSize:      ef.BatchSize,
+		KeyCount:       ef.KeyCount,
+		SHA256:         ef.SHA256,
+		Size:           ef.Size,
+	}
+}
+
 type SignatureInfo struct {
 	ID                int64
 	SigningKey        string
@@ -16,6 +16,7 @@ package model
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -78,3 +79,108 @@ func TestEffectiveMaxRecords(t *testing.T) {
 		t.Fatalf("mismatch want: %v got: %v", want, got)
 	}
 }
+
+func TestIndexFormats(t *testing.T) {
+	t.Parallel()
+
+	ec := &ExportConfig{Period: time.Hour}
+	if diff := cmp.Diff([]string{IndexFormatText}, ec.EffectiveIndexFormats()); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if ec.HasIndexFormat(IndexFormatJSON) {
+		t.Errorf("expected the JSON index to be disabled by default")
+	}
+
+	ec.IndexFormats = []string{IndexFormatJSON}
+	if !ec.HasIndexFormat(IndexFormatJSON) || ec.HasIndexFormat(IndexFormatText) {
+		t.Errorf("expected only the JSON index, got %v", ec.EffectiveIndexFormats())
+	}
+	if err := ec.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ec.IndexFormats = []string{"xml"}
+	if err := ec.Validate(); err == nil {
+		t.Errorf("expected error for unknown index format")
+	}
+}
+
+func TestRollups(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		ec      *ExportConfig
+		wantErr bool
+	}{
+		{
+			name: "rolling",
+			ec:   &ExportConfig{Period: 4 * time.Hour, RollingHours: 24},
+		},
+		{
+			name:    "rolling too long",
+			ec:      &ExportConfig{Period: 4 * time.Hour, RollingHours: MaxRollingHours + 1},
+			wantErr: true,
+		},
+		{
+			name:    "rolling not longer than period",
+			ec:      &ExportConfig{Period: 4 * time.Hour, RollingHours: 4},
+			wantErr: true,
+		},
+		{
+			name: "daily",
+			ec:   &ExportConfig{Period: 4 * time.Hour, DailyRollup: true},
+		},
+		{
+			name:    "daily with daily period",
+			ec:      &ExportConfig{Period: 24 * time.Hour, DailyRollup: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.ec.Validate(); (err != nil) != tc.wantErr {
+				t.Errorf("expected error: %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	if got, want := (&ExportBatch{}).EffectiveKind(), ExportBatchKindPeriod; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := KindFilenameRoot("root", ExportBatchKindPeriod), "root"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := KindFilenameRoot("root", ExportBatchKindRolling), "root/rolling"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+	if got, want := KindFilenameRoot("root", ExportBatchKindDaily), "root/daily"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
+
+func TestNewIndexFile(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	eb := &ExportBatch{
+		StartTimestamp: start,
+		EndTimestamp:   start.Add(time.Hour),
+	}
+
+	got := NewIndexFile(eb, &ExportFile{Filename: "a.zip", BatchNum: 1, BatchSize: 1})
+	if want := start.Add(time.Hour); !got.EndTimestamp.Equal(want) {
+		t.Errorf("expected %v to be %v", got.EndTimestamp, want)
+	}
+
+	// Files in a split batch have the end timestamp offset by the file number.
+	got = NewIndexFile(eb, &ExportFile{Filename: "b.zip", BatchNum: 3, BatchSize: 4})
+	if want := start.Add(time.Hour + 3*time.Second); !got.EndTimestamp.Equal(want) {
+		t.Errorf("expected %v to be %v", got.EndTimestamp, want)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// maxPreviewWindow is the longest time window that can be previewed.
+const maxPreviewWindow = model.MaxRollingHours * time.Hour
+
+// PreviewRequest selects the keys to preview. Either ConfigID selects an
+// existing export config, or Config describes an ad-hoc one.
+type PreviewRequest struct {
+	ConfigID int64          `json:"configID,omitempty"`
+	Config   *PreviewConfig `json:"config,omitempty"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// PreviewConfig is the subset of an export config that selects keys.
+type PreviewConfig struct {
+	OutputRegion     string   `json:"outputRegion"`
+	InputRegions     []string `json:"inputRegions,omitempty"`
+	IncludeTravelers bool     `json:"includeTravelers,omitempty"`
+	OnlyNonTravelers bool     `json:"onlyNonTravelers,omitempty"`
+	ExcludeRegions   []string `json:"excludeRegions,omitempty"`
+}
+
+// PreviewResult describes the keys an export batch would contain. The
+// breakdowns include both new and revised keys, using the revised values for
+// revised keys. Padding keys, which are only generated when a batch is
+// exported, are not included.
+type PreviewResult struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	Keys        int `json:"keys"`
+	RevisedKeys int `json:"revisedKeys"`
+	InvalidKeys int `json:"invalidKeys"`
+
+	// Regions counts keys by region. A key in several regions is counted once
+	// for each.
+	Regions map[string]int `json:"regions"`
+
+	Travelers    int `json:"travelers"`
+	NonTravelers int `json:"nonTravelers"`
+
+	ReportTypes map[string]int `json:"reportTypes"`
+
+	// DaysSinceOnset counts keys by days since symptom onset, keys without a
+	// symptom onset are counted as "unknown".
+	DaysSinceOnset map[string]int `json:"daysSinceOnset"`
+}
+
+// handlePreview reports what an export batch for a config and time window
+// would contain, without leasing a batch or writing any files.
+func (s *Server) handlePreview() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("handlePreview")
+
+		if r.Method != http.MethodPost {
+			s.h.RenderJSON(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		var request PreviewRequest
+		if code, err := jsonutil.Unmarshal(w, r, &request); err != nil {
+			s.h.RenderJSON(w, code, err)
+			return
+		}
+
+		eb, err := s.previewBatch(ctx, &request)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				s.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("export config %d not found", request.ConfigID))
+				return
+			}
+			var reqErr *previewRequestError
+			if errors.As(err, &reqErr) {
+				s.h.RenderJSON(w, http.StatusBadRequest, err)
+				return
+			}
+			logger.Errorw("failed to build preview batch", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, s.config.WorkerTimeout)
+		defer cancel()
+
+		result, err := s.preview(ctx, eb)
+		if err != nil {
+			logger.Errorw("failed to preview export batch", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, nil)
+			return
+		}
+		s.h.RenderJSON(w, http.StatusOK, result)
+	})
+}
+
+// previewRequestError is an invalid preview request.
+type previewRequestError struct {
+	msg string
+}
+
+func (e *previewRequestError) Error() string {
+	return e.msg
+}
+
+func invalidPreviewf(format string, args ...interface{}) error {
+	return &previewRequestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// previewBatch builds the batch that would be exported for the request, it is
+// never saved. Invalid requests return a *previewRequestError.
+func (s *Server) previewBatch(ctx context.Context, request *PreviewRequest) (*model.ExportBatch, error) {
+	if !request.End.After(request.Start) {
+		return nil, invalidPreviewf("end must be after start")
+	}
+	if request.End.Sub(request.Start) > maxPreviewWindow {
+		return nil, invalidPreviewf("time window must be at most %v", maxPreviewWindow)
+	}
+
+	var ec *model.ExportConfig
+	switch {
+	case request.ConfigID != 0 && request.Config != nil:
+		return nil, invalidPreviewf("only one of configID and config can be given")
+	case request.ConfigID != 0:
+		var err error
+		ec, err = exportdatabase.New(s.env.Database()).GetExportConfig(ctx, request.ConfigID)
+		if err != nil {
+			return nil, fmt.Errorf("loading export config: %w", err)
+		}
+	case request.Config != nil:
+		if request.Config.OutputRegion == "" {
+			return nil, invalidPreviewf("config must have an outputRegion")
+		}
+		ec = &model.ExportConfig{
+			OutputRegion:     request.Config.OutputRegion,
+			InputRegions:     request.Config.InputRegions,
+			IncludeTravelers: request.Config.IncludeTravelers,
+			OnlyNonTravelers: request.Config.OnlyNonTravelers,
+			ExcludeRegions:   request.Config.ExcludeRegions,
+		}
+	default:
+		return nil, invalidPreviewf("one of configID or config is required")
+	}
+
+	br := batchRange{start: request.Start.UTC(), end: request.End.UTC()}
+	return newExportBatch(ec, br, model.ExportBatchKindPeriod), nil
+}
+
+// preview counts the keys that would be exported for the batch.
+func (s *Server) preview(ctx context.Context, eb *model.ExportBatch) (*PreviewResult, error) {
+	result := &PreviewResult{
+		Start:          eb.StartTimestamp,
+		End:            eb.EndTimestamp,
+		Regions:        make(map[string]int),
+		ReportTypes:    make(map[string]int),
+		DaysSinceOnset: make(map[string]int),
+	}
+	publishDB := publishdatabase.New(s.env.Database())
+
+	criteria := exportCriteria(eb)
+	if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			result.InvalidKeys++
+			return nil
+		}
+		result.Keys++
+		result.add(exp, exp.ReportType, exp.DaysSinceSymptomOnset)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating exposures: %w", err)
+	}
+
+	criteria.OnlyRevisedKeys = true
+	if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			result.InvalidKeys++
+			return nil
+		}
+		result.RevisedKeys++
+
+		reportType := exp.ReportType
+		if exp.RevisedReportType != nil {
+			reportType = *exp.RevisedReportType
+		}
+		daysSinceOnset := exp.DaysSinceSymptomOnset
+		if exp.RevisedDaysSinceSymptomOnset != nil {
+			daysSinceOnset = exp.RevisedDaysSinceSymptomOnset
+		}
+		result.add(exp, reportType, daysSinceOnset)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("iterating revised exposures: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *PreviewResult) add(exp *publishmodel.Exposure, reportType string, daysSinceOnset *int32) {
+	for _, region := range exp.Regions {
+		r.Regions[region]++
+	}
+
+	if exp.Traveler {
+		r.Travelers++
+	} else {
+		r.NonTravelers++
+	}
+
+	if reportType == "" {
+		reportType = "unknown"
+	}
+	r.ReportTypes[reportType]++
+
+	days := "unknown"
+	if daysSinceOnset != nil {
+		days = strconv.Itoa(int(*daysSinceOnset))
+	}
+	r.DaysSinceOnset[days]++
+}
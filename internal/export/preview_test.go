@@ -0,0 +1,206 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHandlePreview(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	baseTime := time.Date(2020, 10, 28, 1, 0, 0, 0, time.UTC)
+	onset := int32(2)
+	exposures := []*publishmodel.Exposure{
+		{Regions: []string{"US"}, ReportType: verifyapi.ReportTypeClinical, DaysSinceSymptomOnset: &onset},
+		{Regions: []string{"US"}, ReportType: verifyapi.ReportTypeConfirmed},
+		{Regions: []string{"CA"}, ReportType: verifyapi.ReportTypeConfirmed, Traveler: true},
+		{Regions: []string{"MX"}, ReportType: verifyapi.ReportTypeConfirmed},
+	}
+	for _, exp := range exposures {
+		exp.ExposureKey = randomTEK(t)
+		exp.IntervalNumber = 100
+		exp.IntervalCount = 144
+		exp.CreatedAt = baseTime
+		exp.LocalProvenance = true
+	}
+	if _, err := publishdb.New(testDB).InsertAndReviseExposures(ctx, &publishdb.InsertAndReviseExposuresRequest{
+		Incoming:     exposures,
+		RequireToken: false,
+	}); err != nil {
+		t.Fatalf("inserting exposures: %v", err)
+	}
+
+	ec := &model.ExportConfig{
+		BucketName:       "bucket",
+		FilenameRoot:     "root",
+		Period:           time.Hour,
+		OutputRegion:     "US",
+		IncludeTravelers: true,
+		From:             baseTime,
+	}
+	if err := exportdatabase.New(testDB).AddExportConfig(ctx, ec); err != nil {
+		t.Fatal(err)
+	}
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		config: &Config{WorkerTimeout: time.Minute},
+		env: serverenv.New(ctx,
+			serverenv.WithDatabase(testDB),
+			serverenv.WithBlobStorage(blobstore)),
+		h: render.NewRenderer(),
+	}
+
+	start, end := baseTime.Add(-time.Hour), baseTime.Add(time.Hour)
+	cases := []struct {
+		name    string
+		request *PreviewRequest
+		code    int
+		want    *PreviewResult
+	}{
+		{
+			name:    "config",
+			request: &PreviewRequest{ConfigID: ec.ConfigID, Start: start, End: end},
+			code:    http.StatusOK,
+			want: &PreviewResult{
+				Start:          start,
+				End:            end,
+				Keys:           3,
+				Regions:        map[string]int{"US": 2, "CA": 1},
+				Travelers:      1,
+				NonTravelers:   2,
+				ReportTypes:    map[string]int{verifyapi.ReportTypeClinical: 1, verifyapi.ReportTypeConfirmed: 2},
+				DaysSinceOnset: map[string]int{"2": 1, "unknown": 2},
+			},
+		},
+		{
+			name: "ad_hoc",
+			request: &PreviewRequest{
+				Config: &PreviewConfig{OutputRegion: "US", InputRegions: []string{"US", "MX"}},
+				Start:  start,
+				End:    end,
+			},
+			code: http.StatusOK,
+			want: &PreviewResult{
+				Start:          start,
+				End:            end,
+				Keys:           3,
+				Regions:        map[string]int{"US": 2, "MX": 1},
+				NonTravelers:   3,
+				ReportTypes:    map[string]int{verifyapi.ReportTypeClinical: 1, verifyapi.ReportTypeConfirmed: 2},
+				DaysSinceOnset: map[string]int{"2": 1, "unknown": 2},
+			},
+		},
+		{
+			name:    "missing_config",
+			request: &PreviewRequest{ConfigID: 1000, Start: start, End: end},
+			code:    http.StatusNotFound,
+		},
+		{
+			name:    "no_config",
+			request: &PreviewRequest{Start: start, End: end},
+			code:    http.StatusBadRequest,
+		},
+		{
+			name:    "bad_window",
+			request: &PreviewRequest{ConfigID: ec.ConfigID, Start: end, End: start},
+			code:    http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := json.Marshal(tc.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/preview", bytes.NewReader(b))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.handlePreview().ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.code; got != want {
+				t.Fatalf("expected %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.want == nil {
+				return
+			}
+
+			var got PreviewResult
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, &got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandlePreview_DatabaseError(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	// Closing the pool makes every query fail.
+	testDB.Close(ctx)
+
+	server := &Server{
+		config: &Config{WorkerTimeout: time.Minute},
+		env:    serverenv.New(ctx, serverenv.WithDatabase(testDB)),
+		h:      render.NewRenderer(),
+	}
+
+	now := time.Now()
+	b, err := json.Marshal(&PreviewRequest{ConfigID: 1, Start: now.Add(-time.Hour), End: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/preview", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.handlePreview().ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusInternalServerError; got != want {
+		t.Errorf("expected %d to be %d: %s", got, want, w.Body.String())
+	}
+}
@@ -17,6 +17,8 @@ package export
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
@@ -47,6 +49,12 @@ func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
 	if cfg.MinWindowAge < 0 {
 		return nil, fmt.Errorf("MIN_WINDOW_AGE must be a duration of >= 0")
 	}
+	if cfg.ServeBlobstore && len(cfg.ServeBlobstoreBuckets) == 0 {
+		return nil, fmt.Errorf("SERVE_BLOBSTORE_BUCKETS is required when SERVE_BLOBSTORE is enabled")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return &Server{
 		config: cfg,
@@ -68,6 +76,34 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/create-batches", s.handleCreateBatches())
 	r.Handle("/do-work", s.handleDoWork())
+	r.Handle("/preview", s.handlePreview())
+	r.Handle("/export-stats", s.handleExportStats())
+
+	if s.config.ServeBlobstore {
+		if h, ok := s.env.Blobstore().(http.Handler); ok {
+			r.PathPrefix("/blobs/").Handler(http.StripPrefix("/blobs", serveBuckets(s.config.ServeBlobstoreBuckets, h)))
+		} else {
+			logger.Warnw("blobstore does not support serving files, ignoring SERVE_BLOBSTORE")
+		}
+	}
 
 	return r
 }
+
+// serveBuckets returns a handler that passes requests for /<bucket>/... to h
+// if the bucket is one of the given buckets, and returns 404 otherwise.
+func serveBuckets(buckets []string, h http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(buckets))
+	for _, b := range buckets {
+		allowed[b] = struct{}{}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bucket := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		if _, ok := allowed[bucket]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -16,6 +16,8 @@ package export
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/google/exposure-notifications-server/internal/project"
@@ -23,6 +25,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/storage"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/sethvargo/go-envconfig"
 )
 
 // TestNewServer tests NewServer().
@@ -35,9 +38,10 @@ func TestNewServer(t *testing.T) {
 	ctx := project.TestContext(t)
 
 	testCases := []struct {
-		name string
-		env  *serverenv.ServerEnv
-		err  error
+		name   string
+		env    *serverenv.ServerEnv
+		config *Config
+		err    error
 	}{
 		{
 			name: "nil Blobstore",
@@ -68,6 +72,26 @@ func TestNewServer(t *testing.T) {
 			),
 			err: nil,
 		},
+		{
+			name: "serve blobstore without buckets",
+			env: serverenv.New(ctx,
+				serverenv.WithBlobStorage(emptyStorage),
+				serverenv.WithDatabase(emptyDB),
+				serverenv.WithKeyManager(emptyKMS),
+			),
+			config: &Config{ServeBlobstore: true},
+			err:    fmt.Errorf("SERVE_BLOBSTORE_BUCKETS is required when SERVE_BLOBSTORE is enabled"),
+		},
+		{
+			name: "invalid stats config",
+			env: serverenv.New(ctx,
+				serverenv.WithBlobStorage(emptyStorage),
+				serverenv.WithDatabase(emptyDB),
+				serverenv.WithKeyManager(emptyKMS),
+			),
+			config: &Config{StatsNoiseDelta: 2},
+			err:    fmt.Errorf("invalid config: 1 error occurred:\n\t* env var `STATS_NOISE_DELTA` must be > 0 and < 1, got: 2\n\n"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -76,7 +100,14 @@ func TestNewServer(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := NewServer(&Config{}, tc.env)
+			config := tc.config
+			if config == nil {
+				config = &Config{}
+			}
+			if err := envconfig.ProcessWith(ctx, config, envconfig.MapLookuper(map[string]string{})); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewServer(config, tc.env)
 			if tc.err != nil {
 				if err.Error() != tc.err.Error() {
 					t.Fatalf("got %+v: want %v", err, tc.err)
@@ -89,3 +120,36 @@ func TestNewServer(t *testing.T) {
 		})
 	}
 }
+
+func TestServeBuckets(t *testing.T) {
+	t.Parallel()
+
+	h := serveBuckets([]string{"exports"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		path string
+		code int
+	}{
+		{path: "/exports/index.txt", code: http.StatusOK},
+		{path: "/exports/us/1.zip", code: http.StatusOK},
+		{path: "/stats/2021-01-01.json", code: http.StatusNotFound},
+		{path: "/exportsx/index.txt", code: http.StatusNotFound},
+		{path: "/", code: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if got, want := w.Code, tc.code; got != want {
+				t.Errorf("expected %d to be %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	verdatabase "github.com/google/exposure-notifications-server/internal/verification/database"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/hashicorp/go-multierror"
+	"go.opencensus.io/stats"
+)
+
+const exportStatsLock = "export_stats"
+
+// statsExportFile is a file written for each health authority by the stats
+// export.
+type statsExportFile struct {
+	name        string
+	contentType string
+	marshal     func(verifyapi.StatsDays) ([]byte, error)
+}
+
+var statsExportFiles = []*statsExportFile{
+	{
+		name:        "stats.csv",
+		contentType: storage.ContentTypeCSV,
+		marshal:     verifyapi.StatsDays.MarshalCSV,
+	},
+	{
+		name:        "stats.jsonl",
+		contentType: storage.ContentTypeJSONLines,
+		marshal:     verifyapi.StatsDays.MarshalJSONLines,
+	},
+	{
+		name:        verifyapi.StatsDistributionTEKAge + ".csv",
+		contentType: storage.ContentTypeCSV,
+		marshal: func(days verifyapi.StatsDays) ([]byte, error) {
+			return days.MarshalDistributionCSV(verifyapi.StatsDistributionTEKAge)
+		},
+	},
+	{
+		name:        verifyapi.StatsDistributionOnsetToUpload + ".csv",
+		contentType: storage.ContentTypeCSV,
+		marshal: func(days verifyapi.StatsDays) ([]byte, error) {
+			return days.MarshalDistributionCSV(verifyapi.StatsDistributionOnsetToUpload)
+		},
+	},
+}
+
+// handleExportStats is a handler that writes the released stats of each
+// health authority with a stats export bucket to the blobstore. It is intended
+// to be invoked once a day.
+func (s *Server) handleExportStats() http.Handler {
+	db := s.env.Database()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		logger := logging.FromContext(ctx).Named("handleExportStats")
+		logger.Debugw("starting")
+		defer logger.Debugw("finishing")
+
+		ctx, cancel := context.WithTimeout(ctx, s.config.StatsExportTimeout)
+		defer cancel()
+
+		// Obtain lock to make sure there are no other processes exporting stats.
+		unlockFn, err := db.Lock(ctx, exportStatsLock, s.config.StatsExportTimeout)
+		if err != nil {
+			if errors.Is(err, database.ErrAlreadyLocked) {
+				logger.Debugw("skipping (already locked)")
+				s.h.RenderJSON(w, http.StatusOK, fmt.Errorf("too early"))
+				return
+			}
+			logger.Errorw("failed to obtain lock", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		defer func() {
+			if err := unlockFn(); err != nil {
+				logger.Errorw("failed to unlock", "error", err)
+			}
+		}()
+
+		healthAuthorities, err := verdatabase.New(db).ListAllHealthAuthoritiesWithoutKeys(ctx)
+		if err != nil {
+			logger.Errorw("failed to list health authorities", "error", err)
+			s.h.RenderJSON(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		now := time.Now().UTC()
+		exported := 0
+		var merr *multierror.Error
+		for _, ha := range healthAuthorities {
+			if !ha.StatsExportEnabled() {
+				continue
+			}
+
+			if err := s.exportStats(ctx, ha, now); err != nil {
+				// Immediately stop if the context is expired.
+				if err := ctx.Err(); err != nil {
+					merr = multierror.Append(merr, err)
+					break
+				}
+
+				// Keep exporting the stats of the other health authorities.
+				merr = multierror.Append(merr, fmt.Errorf("failed to export stats for %s: %w", ha.Issuer, err))
+				continue
+			}
+			exported++
+		}
+		logger.Debugw("exported stats", "health_authorities", exported)
+
+		if errs := merr.WrappedErrors(); len(errs) > 0 {
+			logger.Errorw("failed to export stats", "errors", errs)
+			s.h.RenderJSON(w, http.StatusInternalServerError, errs)
+			return
+		}
+
+		stats.Record(ctx, mStatsExportSuccess.M(1))
+		s.h.RenderJSON(w, http.StatusOK, nil)
+	})
+}
+
+// exportStats writes the stats that are released to the health authority at
+// now to a directory for the current day under the health authority's stats
+// export filename root. Nothing is written if no stats are released.
+func (s *Server) exportStats(ctx context.Context, ha *vermodel.HealthAuthority, now time.Time) error {
+	hourly, err := publishdatabase.New(s.env.Database()).ReadStats(ctx, ha.ID)
+	if err != nil {
+		return fmt.Errorf("reading stats: %w", err)
+	}
+
+	// Nothing from the current hour can be released.
+	days, err := publishmodel.ReleaseStats(hourly, ha, s.config.StatsReleaseConfig(), now.Truncate(time.Hour),
+		verifyapi.StatsWindowDay, nil)
+	if err != nil {
+		return fmt.Errorf("releasing stats: %w", err)
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	dir := path.Join(ha.StatsExportFilenameRoot, now.Format("2006-01-02"))
+	for _, file := range statsExportFiles {
+		contents, err := file.marshal(days)
+		if err != nil {
+			return fmt.Errorf("marshalling %s: %w", file.name, err)
+		}
+
+		name := path.Join(dir, file.name)
+		if err := s.env.Blobstore().CreateObject(ctx, ha.StatsExportBucket, name, contents, false, file.contentType); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
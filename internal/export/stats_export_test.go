@@ -0,0 +1,136 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	verdatabase "github.com/google/exposure-notifications-server/internal/verification/database"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/render"
+	"github.com/google/exposure-notifications-server/pkg/timeutils"
+)
+
+func TestHandleExportStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	haDB := verdatabase.New(testDB)
+	exported := &vermodel.HealthAuthority{
+		Issuer:                  "exported",
+		Audience:                "aud",
+		Name:                    "Exported",
+		StatsExportBucket:       "stats",
+		StatsExportFilenameRoot: "root/exported",
+	}
+	notExported := &vermodel.HealthAuthority{
+		Issuer:   "not-exported",
+		Audience: "aud",
+		Name:     "Not exported",
+	}
+	for _, ha := range []*vermodel.HealthAuthority{exported, notExported} {
+		if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two days ago has enough publish requests to be released, yesterday
+	// doesn't.
+	pubDB := publishdb.New(testDB)
+	twoDaysAgo := timeutils.UTCMidnight(time.Now().UTC()).Add(-48 * time.Hour)
+	for i, publishes := range map[time.Duration]int{0: 12, 24 * time.Hour: 3} {
+		for j := 0; j < publishes; j++ {
+			info := &publishmodel.PublishInfo{
+				Platform:     publishmodel.PlatformAndroid,
+				NumTEKs:      14,
+				OldestDays:   2,
+				OnsetDaysAgo: 3,
+			}
+			for _, ha := range []*vermodel.HealthAuthority{exported, notExported} {
+				if err := pubDB.UpdateStats(ctx, twoDaysAgo.Add(i), ha.ID, info); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		config: &Config{
+			StatsExportTimeout: time.Minute,
+			StatsUploadMinimum: 10,
+			MaxKeysOnPublish:   30,
+		},
+		env: serverenv.New(ctx,
+			serverenv.WithDatabase(testDB),
+			serverenv.WithBlobStorage(blobstore)),
+		h: render.NewRenderer(),
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/export-stats", nil)
+	w := httptest.NewRecorder()
+	server.handleExportStats().ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("expected %d to be %d: %s", got, want, w.Body.String())
+	}
+
+	dir := path.Join("root/exported", time.Now().UTC().Format("2006-01-02"))
+	for _, file := range statsExportFiles {
+		b, err := blobstore.GetObject(ctx, "stats", path.Join(dir, file.name))
+		if err != nil {
+			t.Fatalf("reading %s: %v", file.name, err)
+		}
+
+		// Only the released day is exported.
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		want := 1
+		if strings.HasSuffix(file.name, ".csv") {
+			want++ // header
+		}
+		if got := len(lines); got != want {
+			t.Errorf("%s: expected %d lines to be %d:\n%s", file.name, got, want, b)
+		}
+		if day := twoDaysAgo.Format("2006-01-02"); !strings.Contains(string(b), day) {
+			t.Errorf("%s: expected %q to contain %q", file.name, b, day)
+		}
+	}
+
+	var names []string
+	if err := blobstore.ListObjects(ctx, "stats", "", func(attrs *storage.ObjectAttrs) error {
+		names = append(names, attrs.Name)
+		return nil
+	}); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		t.Fatal(err)
+	}
+	if got, want := len(names), len(statsExportFiles); got != want {
+		t.Errorf("expected %d files to be %d: %v", got, want, names)
+	}
+}
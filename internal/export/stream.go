@@ -0,0 +1,290 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/google/exposure-notifications-server/internal/export/model"
+	publishdatabase "github.com/google/exposure-notifications-server/internal/publish/database"
+	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
+	"github.com/google/exposure-notifications-server/internal/storage"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"go.opencensus.io/stats"
+	"go.uber.org/zap"
+)
+
+// errCountLimitReached stops the iteration in countExposures.
+var errCountLimitReached = errors.New("count limit reached")
+
+// countExposures counts the exposures with a valid key matching the criteria,
+// stopping once limit is reached. Like batchExposures, this avoids SELECT COUNT.
+func countExposures(ctx context.Context, publishDB *publishdatabase.PublishDB, criteria publishdatabase.IterateExposuresCriteria, limit int) (int, error) {
+	count := 0
+	if limit <= 0 {
+		return count, nil
+	}
+
+	if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			return nil
+		}
+		count++
+		if count >= limit {
+			return errCountLimitReached
+		}
+		return nil
+	}); err != nil && !errors.Is(err, errCountLimitReached) {
+		return 0, err
+	}
+	return count, nil
+}
+
+// planBatch decides how the export files for a batch are built, reading no
+// more keys than necessary.
+//
+// Batches with fewer than MinRecords new keys need padding, and are built in
+// memory by batchExposures so the generated keys can be mixed in. Those are
+// small by definition. All other batches are streamed, and splitBatch reports
+// whether the keys span more than one file.
+func (s *Server) planBatch(ctx context.Context, criteria publishdatabase.IterateExposuresCriteria, maxRecords int) (stream, splitBatch bool, err error) {
+	publishDB := publishdatabase.New(s.env.Database())
+
+	limit := maxRecords + 1
+	if s.config.MinRecords > limit {
+		limit = s.config.MinRecords
+	}
+	primary, err := countExposures(ctx, publishDB, criteria, limit)
+	if err != nil {
+		return false, false, fmt.Errorf("counting exposures: %w", err)
+	}
+	if primary < s.config.MinRecords {
+		return false, false, nil
+	}
+	if primary > maxRecords {
+		return true, true, nil
+	}
+
+	criteria.OnlyRevisedKeys = true
+	revised, err := countExposures(ctx, publishDB, criteria, maxRecords+1-primary)
+	if err != nil {
+		return false, false, fmt.Errorf("counting revised exposures: %w", err)
+	}
+	return true, primary+revised > maxRecords, nil
+}
+
+// streamBatch writes the export files for a batch directly from the database
+// into the blobstore. Keys are read in the order they appear in the export
+// files, so only the key being written is held in memory.
+func (s *Server) streamBatch(ctx context.Context, eb *model.ExportBatch, criteria publishdatabase.IterateExposuresCriteria, maxRecords int, splitBatch bool, signers []*Signer) ([]*model.ExportFile, *keyCounts, error) {
+	logger := logging.FromContext(ctx)
+	publishDB := publishdatabase.New(s.env.Database())
+
+	fs := &fileStreamer{
+		logger:     logger,
+		blobstore:  s.env.Blobstore(),
+		eb:         eb,
+		signers:    signers,
+		maxRecords: maxRecords,
+		splitBatch: splitBatch,
+		regenCount: s.config.RepressGeneration(),
+	}
+	defer fs.abort()
+
+	droppedKeys := 0
+	defer func() {
+		if droppedKeys > 0 {
+			logger.Errorw("export found keys of invalid length", "dropped_keys", droppedKeys)
+			stats.Record(ctx, mWorkerBadKeyLength.M(int64(droppedKeys)))
+		}
+	}()
+
+	criteria.OrderByExposureKey = true
+	if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			droppedKeys++
+			return nil
+		}
+		return fs.write(ctx, exp, false)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("iterating exposures: %w", err)
+	}
+
+	// go get the revised keys.
+	criteria.OnlyRevisedKeys = true
+	if _, err := publishDB.IterateExposures(ctx, criteria, func(exp *publishmodel.Exposure) error {
+		if len(exp.ExposureKey) != verifyapi.KeyLength {
+			droppedKeys++
+			return nil
+		}
+		return fs.write(ctx, exp, true)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("iterating revised exposures: %w", err)
+	}
+
+	// If the last file has anything, finish it.
+	if err := fs.finish(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(fs.files) == 0 {
+		logger.Infof("No records for export batch")
+	}
+	return fs.files, &fs.counts, nil
+}
+
+// fileStreamer splits a stream of keys into export files of at most
+// maxRecords keys each, writing every file to the blobstore as it goes.
+type fileStreamer struct {
+	logger     *zap.SugaredLogger
+	blobstore  storage.Blobstore
+	eb         *model.ExportBatch
+	signers    []*Signer
+	maxRecords int
+	splitBatch bool
+	regenCount int64
+
+	fileNum int32
+	files   []*model.ExportFile
+	counts  keyCounts
+
+	// The file currently being written, if any.
+	objectName string
+	count      int
+	fw         *FileWriter
+	blob       io.WriteCloser
+	hw         *hashingWriter
+	cancel     context.CancelFunc
+}
+
+// write adds the key to the current file, starting a new one if needed.
+func (fs *fileStreamer) write(ctx context.Context, exp *publishmodel.Exposure, revised bool) error {
+	if fs.fw == nil {
+		if err := fs.open(ctx); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if revised {
+		err = fs.fw.WriteRevisedExposure(exp)
+	} else {
+		err = fs.fw.WriteExposure(exp)
+	}
+	if err != nil {
+		return fmt.Errorf("writing export file %d: %w", fs.fileNum, err)
+	}
+
+	fs.count++
+	if revised {
+		fs.counts.revised++
+	} else {
+		fs.counts.keys++
+	}
+	if fs.count >= fs.maxRecords {
+		return fs.finish()
+	}
+	return nil
+}
+
+func (fs *fileStreamer) open(ctx context.Context) error {
+	// 20201120 - Batch num/size changed to always be 1/1, see createFile.
+	fs.fileNum++
+	fs.objectName = exportFilename(fs.eb, fs.fileNum, fs.regenCount)
+
+	// The blob is only created if the writer is closed before the context is
+	// cancelled, which lets abort discard partial files.
+	blobCtx, cancel := context.WithCancel(ctx)
+	blob, err := fs.blobstore.NewWriter(blobCtx, fs.eb.BucketName, fs.objectName, true, storage.ContentTypeZip)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("creating file %s in bucket %s: %w", fs.objectName, fs.eb.BucketName, err)
+	}
+	fs.blob = blob
+	fs.hw = &hashingWriter{w: blob, h: sha256.New()}
+	fs.cancel = cancel
+
+	fw, err := NewFileWriter(fs.hw, fs.eb, fs.fileNum, fs.splitBatch, fs.signers)
+	if err != nil {
+		fs.abort()
+		return fmt.Errorf("marshaling export file: %w", err)
+	}
+	fs.fw = fw
+	fs.count = 0
+	return nil
+}
+
+// finish signs and uploads the current file, if any.
+func (fs *fileStreamer) finish() error {
+	if fs.blob == nil {
+		return nil
+	}
+
+	if err := fs.fw.Close(); err != nil {
+		fs.abort()
+		return fmt.Errorf("marshaling export file: %w", err)
+	}
+
+	err := fs.blob.Close()
+	hw := fs.hw
+	fs.cancel()
+	fs.fw, fs.blob, fs.hw, fs.cancel = nil, nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("creating file %s in bucket %s: %w", fs.objectName, fs.eb.BucketName, err)
+	}
+
+	fs.logger.Infof("Wrote export file %q for batch %d, signed with %v keys", fs.objectName, fs.eb.BatchID, len(fs.signers))
+	fs.files = append(fs.files, &model.ExportFile{
+		Filename: fs.objectName,
+		KeyCount: fs.count,
+		SHA256:   hw.h.Sum(nil),
+		Size:     hw.size,
+	})
+	return nil
+}
+
+// abort discards the current file, if any, without creating it.
+func (fs *fileStreamer) abort() {
+	if fs.blob == nil {
+		return
+	}
+
+	fs.cancel()
+	if err := fs.blob.Close(); err != nil && !errors.Is(err, context.Canceled) {
+		fs.logger.Warnw("failed to discard partial export file", "object", fs.objectName, "error", err)
+	}
+	fs.fw, fs.blob, fs.hw, fs.cancel = nil, nil, nil, nil
+}
+
+// hashingWriter records the size and digest of the data written through it,
+// for the export index.
+type hashingWriter struct {
+	w    io.Writer
+	h    hash.Hash
+	size int64
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.h.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
@@ -16,13 +16,15 @@ package export
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
 	"sort"
-	"strings"
 	"time"
 
 	exportdatabase "github.com/google/exposure-notifications-server/internal/export/database"
@@ -66,7 +68,7 @@ func (s *Server) handleDoWork() http.Handler {
 
 		var merr *multierror.Error
 
-		indexesWritten := make(map[int64]struct{})
+		indexesWritten := make(map[indexKey]struct{})
 
 		for {
 			if ctx.Err() != nil {
@@ -106,7 +108,13 @@ func (s *Server) handleDoWork() http.Handler {
 	})
 }
 
-func (s *Server) processBatch(ctx context.Context, batch *model.ExportBatch, indexesWritten map[int64]struct{}) error {
+// indexKey identifies an index file, each config has one per kind of batch.
+type indexKey struct {
+	configID int64
+	kind     string
+}
+
+func (s *Server) processBatch(ctx context.Context, batch *model.ExportBatch, indexesWritten map[indexKey]struct{}) error {
 	db := s.env.Database()
 
 	// Obtain the necessary locks for this export batch. Ensure that only
@@ -149,11 +157,12 @@ func (s *Server) processBatch(ctx context.Context, batch *model.ExportBatch, ind
 	// single worker processes a number of empty batches quickly, we want to
 	// avoid writing the same file repeatedly and hitting a rate limit. This
 	// ensures that we write the index file for an empty batch at most once
-	// per processed config each round.
+	// per processed config and kind each round.
 	emitIndexForEmptyBatch := false
-	if _, ok := indexesWritten[batch.ConfigID]; !ok {
+	key := indexKey{configID: batch.ConfigID, kind: batch.EffectiveKind()}
+	if _, ok := indexesWritten[key]; !ok {
 		emitIndexForEmptyBatch = true
-		indexesWritten[batch.ConfigID] = struct{}{}
+		indexesWritten[key] = struct{}{}
 	}
 
 	// Ensure that the locks are released on either success or failure path.
@@ -167,13 +176,21 @@ func (s *Server) processBatch(ctx context.Context, batch *model.ExportBatch, ind
 type group struct {
 	exposures []*publishmodel.Exposure
 	revised   []*publishmodel.Exposure
+
+	// padding is the number of generated keys in exposures.
+	padding int
 }
 
 func (g *group) Length() int {
 	return len(g.exposures) + len(g.revised)
 }
 
-func (s *Server) batchExposures(ctx context.Context, criteria publishdatabase.IterateExposuresCriteria, maxRecords int, outputRegion string) ([]*group, error) {
+// batchExposures reads the exposures for the criteria and breaks them into
+// groups of up to maxRecords. If there are fewer than MinRecords new keys, the
+// last group is padded with generated keys, which are saved if savePadding is
+// true. Roll-up batches don't save their padding, since their window overlaps
+// period batches that already saved theirs.
+func (s *Server) batchExposures(ctx context.Context, criteria publishdatabase.IterateExposuresCriteria, maxRecords int, outputRegion string, savePadding bool) ([]*group, error) {
 	logger := logging.FromContext(ctx)
 	db := s.env.Database()
 
@@ -265,10 +282,11 @@ func (s *Server) batchExposures(ctx context.Context, criteria publishdatabase.It
 		if err != nil {
 			return nil, fmt.Errorf("ensureMinNumExposures: %w", err)
 		}
+		lastGroup.padding = len(generated)
 		// padding revised keys doesn't provide any useful protection as one can work backwords and figure out which
 		// keys appeared as primary keys in a previous export.
 
-		if length := len(generated); length > 0 {
+		if length := len(generated); length > 0 && savePadding {
 			// we generated some data in order to pad out this export. This data needs to be persisted.
 			insertRequest := &publishdatabase.InsertAndReviseExposuresRequest{
 				RequireToken:  false,
@@ -306,24 +324,16 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 
 	logger.Infof("Processing export batch %d (root: %q, region: %s), max records per file %d", eb.BatchID, eb.FilenameRoot, eb.OutputRegion, maxRecords)
 
-	// Criteria starts w/ non-revised keys.
-	// Will be changed later to grab the revised keys.
-	criteria := publishdatabase.IterateExposuresCriteria{
-		SinceTimestamp:      eb.StartTimestamp,
-		UntilTimestamp:      eb.EndTimestamp,
-		IncludeRegions:      eb.EffectiveInputRegions(),
-		IncludeTravelers:    eb.IncludeTravelers, // Travelers are included from "any" region.
-		OnlyNonTravelers:    eb.OnlyNonTravelers,
-		ExcludeRegions:      eb.ExcludeRegions,
-		OnlyLocalProvenance: false, // include federated ids
-		OnlyRevisedKeys:     false,
-	}
-
-	groups, err := s.batchExposures(ctx, criteria, maxRecords, eb.OutputRegion)
+	// Tag everything recorded while exporting the batch, including signing.
+	ctx, err := tag.New(ctx,
+		tag.Upsert(ExportConfigIDTagKey, fmt.Sprintf("%d", eb.ConfigID)),
+		tag.Upsert(ExportOutputRegionTagKey, eb.OutputRegion))
 	if err != nil {
-		return fmt.Errorf("reading exposures for batch: %w", err)
+		return fmt.Errorf("failed to tag context: %w", err)
 	}
 
+	criteria := exportCriteria(eb)
+
 	exportDB := exportdatabase.New(db)
 	// Load the non-expired signature infos associated with this export batch.
 	sigInfos, err := exportDB.LookupSignatureInfos(ctx, eb.SignatureInfoIDs, time.Now())
@@ -331,44 +341,81 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 		return fmt.Errorf("error loading signature info for batch %d, %w", eb.BatchID, err)
 	}
 
-	// Create the export files.
-	batchSize := len(groups)
-	splitBatch := batchSize > 1
-	objectNames := make([]string, 0, len(groups))
-	for i, group := range groups {
-		if ctx.Err() != nil {
-			logger.Infof("Timed out writing export files for batch %s, the entire batch will be retried once the batch lease expires on %v", eb.BatchID, eb.LeaseExpires)
-			return nil
+	stream, splitBatch, err := s.planBatch(ctx, criteria, maxRecords)
+	if err != nil {
+		return fmt.Errorf("sizing batch: %w", err)
+	}
+
+	var (
+		files  []*model.ExportFile
+		counts *keyCounts
+	)
+	if stream {
+		signers, err := s.exportSigners(ctx, sigInfos)
+		if err != nil {
+			return err
 		}
 
-		// 20201120 - Batch num/size changed to always be 1/1.
-		// The batch numbering being deemed unnecessary.
-		// However timing adjustments are put in place for variable batch sizes.
-		objectName, err := s.createFile(ctx,
-			&createFileInfo{
-				exposures:        group.exposures,
-				revisedExposures: group.revised,
-				exportBatch:      eb,
-				signatureInfos:   sigInfos,
-				fileNum:          int32(i + 1), // the batchNum and batchSize are flattened to 1 and 1 when
-				splitBatch:       splitBatch,
-			})
+		files, counts, err = s.streamBatch(ctx, eb, criteria, maxRecords, splitBatch, signers)
 		if err != nil {
-			return fmt.Errorf("creating export file %d for batch %d: %w", i+1, eb.BatchID, err)
+			if ctx.Err() != nil {
+				logger.Infof("Timed out writing export files for batch %s, the entire batch will be retried once the batch lease expires on %v", eb.BatchID, eb.LeaseExpires)
+				return nil
+			}
+			return fmt.Errorf("streaming export files for batch %d: %w", eb.BatchID, err)
+		}
+	} else {
+		savePadding := eb.EffectiveKind() == model.ExportBatchKindPeriod
+		groups, err := s.batchExposures(ctx, criteria, maxRecords, eb.OutputRegion, savePadding)
+		if err != nil {
+			return fmt.Errorf("reading exposures for batch: %w", err)
+		}
+
+		// Create the export files.
+		splitBatch := len(groups) > 1
+		files = make([]*model.ExportFile, 0, len(groups))
+		counts = &keyCounts{}
+		for i, group := range groups {
+			if ctx.Err() != nil {
+				logger.Infof("Timed out writing export files for batch %s, the entire batch will be retried once the batch lease expires on %v", eb.BatchID, eb.LeaseExpires)
+				return nil
+			}
+
+			// 20201120 - Batch num/size changed to always be 1/1.
+			// The batch numbering being deemed unnecessary.
+			// However timing adjustments are put in place for variable batch sizes.
+			file, err := s.createFile(ctx,
+				&createFileInfo{
+					exposures:        group.exposures,
+					revisedExposures: group.revised,
+					exportBatch:      eb,
+					signatureInfos:   sigInfos,
+					fileNum:          int32(i + 1), // the batchNum and batchSize are flattened to 1 and 1 when
+					splitBatch:       splitBatch,
+				})
+			if err != nil {
+				return fmt.Errorf("creating export file %d for batch %d: %w", i+1, eb.BatchID, err)
+			}
+			logger.Infof("Wrote export file %q for batch %d", file.Filename, eb.BatchID)
+			files = append(files, file)
+			counts.add(group)
 		}
-		logger.Infof("Wrote export file %q for batch %d", objectName, eb.BatchID)
-		objectNames = append(objectNames, objectName)
+	}
+	batchSize := len(files)
+	for i, file := range files {
+		file.BatchNum = i + 1
+		file.BatchSize = batchSize
 	}
 
 	// Emit the index file if needed.
 	if batchSize > 0 || emitIndexForEmptyBatch {
-		if err := s.retryingCreateIndex(ctx, eb, objectNames); err != nil {
+		if err := s.retryingCreateIndex(ctx, eb, files); err != nil {
 			return err
 		}
 	}
 
 	// Write the files records in database and complete the batch.
-	if err := exportDB.FinalizeBatch(ctx, eb, objectNames, batchSize); err != nil {
+	if err := exportDB.FinalizeBatchFiles(ctx, eb, files); err != nil {
 		return fmt.Errorf("completing batch: %w", err)
 	}
 	logger.Infof("Batch %d completed", eb.BatchID)
@@ -382,9 +429,45 @@ func (s *Server) exportBatch(ctx context.Context, eb *model.ExportBatch, emitInd
 		logger.Errorw("failed to record export batch completion", "error", err)
 	}
 
+	stats.Record(ctx,
+		mExportKeys.M(int64(counts.keys)),
+		mExportPaddingKeys.M(int64(counts.padding)),
+		mExportRevisedKeys.M(int64(counts.revised)))
+	for _, file := range files {
+		stats.Record(ctx, mExportFileBytes.M(file.Size))
+	}
+
 	return nil
 }
 
+// keyCounts tallies the keys written to the files of a batch.
+type keyCounts struct {
+	keys    int
+	padding int
+	revised int
+}
+
+func (c *keyCounts) add(g *group) {
+	c.keys += len(g.exposures) - g.padding
+	c.padding += g.padding
+	c.revised += len(g.revised)
+}
+
+// exportCriteria returns the criteria for the keys in the batch. It starts with
+// the non-revised keys, and is changed later to grab the revised keys.
+func exportCriteria(eb *model.ExportBatch) publishdatabase.IterateExposuresCriteria {
+	return publishdatabase.IterateExposuresCriteria{
+		SinceTimestamp:      eb.StartTimestamp,
+		UntilTimestamp:      eb.EndTimestamp,
+		IncludeRegions:      eb.EffectiveInputRegions(),
+		IncludeTravelers:    eb.IncludeTravelers, // Travelers are included from "any" region.
+		OnlyNonTravelers:    eb.OnlyNonTravelers,
+		ExcludeRegions:      eb.ExcludeRegions,
+		OnlyLocalProvenance: false, // include federated ids
+		OnlyRevisedKeys:     false,
+	}
+}
+
 type createFileInfo struct {
 	exposures        []*publishmodel.Exposure
 	revisedExposures []*publishmodel.Exposure
@@ -394,22 +477,18 @@ type createFileInfo struct {
 	splitBatch       bool  // Did this batch contain more than 1 file due to too many keys?
 }
 
-func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (string, error) {
+func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (*model.ExportFile, error) {
 	logger := logging.FromContext(ctx)
 
-	signers := make([]*Signer, 0, len(cfi.signatureInfos))
-	for _, si := range cfi.signatureInfos {
-		signer, err := s.env.GetSignerForKey(ctx, si.SigningKey)
-		if err != nil {
-			return "", fmt.Errorf("unable to get signer for key %v: %w", si.SigningKey, err)
-		}
-		signers = append(signers, &Signer{SignatureInfo: si, Signer: signer})
+	signers, err := s.exportSigners(ctx, cfi.signatureInfos)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate exposure key export file.
 	data, err := MarshalExportFile(cfi.exportBatch, cfi.exposures, cfi.revisedExposures, cfi.fileNum, cfi.splitBatch, signers)
 	if err != nil {
-		return "", fmt.Errorf("marshaling export file: %w", err)
+		return nil, fmt.Errorf("marshaling export file: %w", err)
 	}
 
 	objectName := exportFilename(cfi.exportBatch, cfi.fileNum, s.config.RepressGeneration())
@@ -417,15 +496,50 @@ func (s *Server) createFile(ctx context.Context, cfi *createFileInfo) (string, e
 	ctx, cancel := context.WithTimeout(ctx, blobOperationTimeout)
 	defer cancel()
 	if err := s.env.Blobstore().CreateObject(ctx, cfi.exportBatch.BucketName, objectName, data, true, storage.ContentTypeZip); err != nil {
-		return "", fmt.Errorf("creating file %s in bucket %s: %w", objectName, cfi.exportBatch.BucketName, err)
+		return nil, fmt.Errorf("creating file %s in bucket %s: %w", objectName, cfi.exportBatch.BucketName, err)
 	}
-	return objectName, nil
+
+	digest := sha256.Sum256(data)
+	return &model.ExportFile{
+		Filename: objectName,
+		KeyCount: len(cfi.exposures) + len(cfi.revisedExposures),
+		SHA256:   digest[:],
+		Size:     int64(len(data)),
+	}, nil
+}
+
+// exportSigners resolves the signers for the given signature infos.
+func (s *Server) exportSigners(ctx context.Context, sigInfos []*model.SignatureInfo) ([]*Signer, error) {
+	signers := make([]*Signer, 0, len(sigInfos))
+	for _, si := range sigInfos {
+		signer, err := s.env.GetSignerForKey(ctx, si.SigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get signer for key %v: %w", si.SigningKey, err)
+		}
+		signers = append(signers, &Signer{SignatureInfo: si, Signer: &timedSigner{ctx: ctx, Signer: signer}})
+	}
+	return signers, nil
+}
+
+// timedSigner records the latency of each signature to the context it was
+// created with.
+type timedSigner struct {
+	ctx context.Context
+	crypto.Signer
+}
+
+func (s *timedSigner) Sign(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	start := time.Now()
+	defer func() {
+		stats.Record(s.ctx, mExportSigningLatency.M(float64(time.Since(start))/float64(time.Millisecond)))
+	}()
+	return s.Signer.Sign(r, digest, opts)
 }
 
 // retryingCreateIndex create the index file. The index file includes _all_
 // batches for an ExportConfig, so multiple workers may be racing to update it.
 // We use a lock to make them line up after one another.
-func (s *Server) retryingCreateIndex(ctx context.Context, eb *model.ExportBatch, objectNames []string) error {
+func (s *Server) retryingCreateIndex(ctx context.Context, eb *model.ExportBatch, files []*model.ExportFile) error {
 	logger := logging.FromContext(ctx)
 	db := s.env.Database()
 
@@ -454,7 +568,7 @@ func (s *Server) retryingCreateIndex(ctx context.Context, eb *model.ExportBatch,
 			return fmt.Errorf("marking expired: %w", err)
 		}
 
-		indexName, entries, err := s.createIndex(ctx, eb, objectNames)
+		indexNames, entries, err := s.createIndex(ctx, eb, files)
 		if err != nil {
 			if err1 := unlock(); err1 != nil {
 				return fmt.Errorf("releasing lock: %v (original error: %w)", err1, err)
@@ -462,7 +576,7 @@ func (s *Server) retryingCreateIndex(ctx context.Context, eb *model.ExportBatch,
 			return fmt.Errorf("creating index file for batch %d: %w", eb.BatchID, err)
 		}
 
-		logger.Infof("Wrote index file %q with %d entries (triggered by batch %d)", indexName, entries, eb.BatchID)
+		logger.Infof("Wrote index files %q with %d entries (triggered by batch %d)", indexNames, entries, eb.BatchID)
 		if err := unlock(); err != nil {
 			return fmt.Errorf("releasing lock: %w", err)
 		}
@@ -484,42 +598,88 @@ func (s *Server) markExpiredFiles(ctx context.Context, eb *model.ExportBatch) er
 	return nil
 }
 
-func (s *Server) createIndex(ctx context.Context, eb *model.ExportBatch, newObjectNames []string) (string, int, error) {
-	db := s.env.Database()
+// createIndex writes the index files for the batch's export config and kind,
+// in each of the config's index formats. It returns the names of the index
+// files written.
+func (s *Server) createIndex(ctx context.Context, eb *model.ExportBatch, newFiles []*model.ExportFile) ([]string, int, error) {
+	exportDB := exportdatabase.New(s.env.Database())
 
-	objects, err := exportdatabase.New(db).LookupExportFiles(ctx, eb.ConfigID, s.config.TTL)
+	ec, err := exportDB.GetExportConfig(ctx, eb.ConfigID)
 	if err != nil {
-		return "", 0, fmt.Errorf("lookup available export files: %w", err)
+		return nil, 0, fmt.Errorf("lookup export config: %w", err)
 	}
 
-	// Add the new objects (they haven't been committed to the database yet).
-	objects = append(objects, newObjectNames...)
+	files, err := exportDB.LookupIndexFiles(ctx, eb.ConfigID, eb.EffectiveKind(), s.config.TTL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("lookup available export files: %w", err)
+	}
 
-	// Remove duplicates, sort.
-	m := map[string]struct{}{}
-	for _, o := range objects {
-		m[o] = struct{}{}
+	// Add the new files (they haven't been committed to the database yet). If
+	// the batch is a backfill, the files it replaces are left out.
+	m := make(map[string]*model.IndexFile, len(files)+len(newFiles))
+	for _, f := range files {
+		if eb.SupersedesBatchID != 0 && f.BatchID == eb.SupersedesBatchID {
+			continue
+		}
+		m[f.Filename] = f
 	}
-	objects = nil
-	for k := range m {
-		objects = append(objects, k)
+	for _, f := range newFiles {
+		m[f.Filename] = model.NewIndexFile(eb, f)
 	}
-	sort.Strings(objects)
 
-	data := []byte(strings.Join(objects, "\n"))
+	// Remove duplicates, sort.
+	files = make([]*model.IndexFile, 0, len(m))
+	for _, f := range m {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Filename < files[j].Filename
+	})
+
+	// Each rolling bundle supersedes the previous ones, so only the latest is
+	// listed.
+	if eb.EffectiveKind() == model.ExportBatchKindRolling {
+		files = latestIndexFiles(files)
+	}
 
-	indexObjectName := exportIndexFilename(eb)
 	ctx, cancel := context.WithTimeout(ctx, blobOperationTimeout)
 	defer cancel()
-	if err := s.env.Blobstore().CreateObject(ctx, eb.BucketName, indexObjectName, data, false, storage.ContentTypeTextPlain); err != nil {
-		return "", 0, fmt.Errorf("creating index file %s in bucket %s: %w", indexObjectName, eb.BucketName, err)
+
+	var indexNames []string
+	for _, format := range ec.EffectiveIndexFormats() {
+		var (
+			indexObjectName string
+			data            []byte
+			contentType     string
+		)
+		switch format {
+		case model.IndexFormatText:
+			indexObjectName = exportIndexFilename(eb)
+			data = marshalTextIndex(files)
+			contentType = storage.ContentTypeTextPlain
+		case model.IndexFormatJSON:
+			indexObjectName = exportJSONIndexFilename(eb)
+			data, err = marshalJSONIndex(files)
+			if err != nil {
+				return nil, 0, fmt.Errorf("marshaling index: %w", err)
+			}
+			contentType = storage.ContentTypeJSON
+		default:
+			return nil, 0, fmt.Errorf("unknown index format %q", format)
+		}
+
+		if err := s.env.Blobstore().CreateObject(ctx, eb.BucketName, indexObjectName, data, false, contentType); err != nil {
+			return nil, 0, fmt.Errorf("creating index file %s in bucket %s: %w", indexObjectName, eb.BucketName, err)
+		}
+		indexNames = append(indexNames, indexObjectName)
 	}
-	return indexObjectName, len(objects), nil
+	return indexNames, len(files), nil
 }
 
 // The batchNum is still needed in the filename to preserve a stable filename sort
 // order when generating the index file.
 func exportFilename(eb *model.ExportBatch, fileNum int32, regenCount int64) string {
+	regenCount += eb.Generation
 	sTime := eb.StartTimestamp.Unix() + regenCount
 	eTime := eb.EndTimestamp.Unix() + regenCount
 	return fmt.Sprintf("%s/%d-%d-%05d%s", eb.FilenameRoot, sTime, eTime, fileNum, filenameSuffix)
@@ -529,6 +689,10 @@ func exportIndexFilename(eb *model.ExportBatch) string {
 	return fmt.Sprintf("%s/index.txt", eb.FilenameRoot)
 }
 
+func exportJSONIndexFilename(eb *model.ExportBatch) string {
+	return fmt.Sprintf("%s/index.json", eb.FilenameRoot)
+}
+
 // randomInt is inclusive, [min:max].
 func randomInt(min, max int) (int, error) {
 	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
@@ -15,6 +15,8 @@
 package export
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"testing"
 	"time"
 
@@ -23,6 +25,7 @@ import (
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	publishmodel "github.com/google/exposure-notifications-server/internal/publish/model"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
+	"github.com/google/exposure-notifications-server/internal/storage"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/util"
 	"github.com/google/go-cmp/cmp"
@@ -132,6 +135,25 @@ func TestEnsureMinExposures(t *testing.T) {
 	}
 }
 
+func TestKeyCounts(t *testing.T) {
+	t.Parallel()
+
+	counts := &keyCounts{}
+	counts.add(&group{
+		exposures: make([]*publishmodel.Exposure, 4),
+		revised:   make([]*publishmodel.Exposure, 1),
+	})
+	counts.add(&group{
+		exposures: make([]*publishmodel.Exposure, 5),
+		padding:   3,
+	})
+
+	want := &keyCounts{keys: 6, padding: 3, revised: 1}
+	if diff := cmp.Diff(want, counts, cmp.AllowUnexported(keyCounts{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
 func TestBatchExposures(t *testing.T) {
 	t.Parallel()
 
@@ -255,7 +277,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyLocalProvenance: true,
 				}
 
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US")
+				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US", true)
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -286,7 +308,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyLocalProvenance: false,
 				}
 
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE")
+				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE", true)
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -314,7 +336,7 @@ func TestBatchExposures(t *testing.T) {
 					IncludeTravelers:    true,
 					OnlyLocalProvenance: true,
 				}
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US")
+				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US", true)
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -347,7 +369,7 @@ func TestBatchExposures(t *testing.T) {
 					OnlyNonTravelers:    true,
 					OnlyLocalProvenance: false,
 				}
-				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE")
+				groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "REMOTE", true)
 				if err != nil {
 					t.Fatalf("failed to read exposures: %v", err)
 				}
@@ -429,7 +451,7 @@ func TestVariableBatchMaxSize(t *testing.T) {
 			OnlyLocalProvenance: true,
 		}
 
-		groups, err := server.batchExposures(ctx, criteria, batchSize, "REMOTE")
+		groups, err := server.batchExposures(ctx, criteria, batchSize, "REMOTE", true)
 		if err != nil {
 			t.Fatalf("failed to read exposures: %v", err)
 		}
@@ -454,6 +476,214 @@ func TestVariableBatchMaxSize(t *testing.T) {
 	}
 }
 
+func TestStreamBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	testPublishDB := publishdb.New(testDB)
+
+	blobstore, err := storage.NewMemory(ctx, &storage.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2020, 10, 28, 1, 0, 0, 0, time.UTC).Truncate(time.Hour)
+	exposures := make([]*publishmodel.Exposure, 10)
+	for i := range exposures {
+		exposures[i] = &publishmodel.Exposure{
+			ExposureKey:     randomTEK(t),
+			Regions:         []string{"US"},
+			IntervalNumber:  100,
+			IntervalCount:   144,
+			CreatedAt:       baseTime,
+			LocalProvenance: true,
+			ReportType:      verifyapi.ReportTypeClinical,
+		}
+	}
+	if _, err := testPublishDB.InsertAndReviseExposures(ctx, &publishdb.InsertAndReviseExposuresRequest{
+		Incoming:     exposures,
+		RequireToken: false,
+	}); err != nil {
+		t.Fatalf("inserting exposures: %v", err)
+	}
+
+	config := Config{
+		MinRecords:         1,
+		PaddingRange:       0,
+		MaxRecords:         4,
+		TruncateWindow:     time.Hour,
+		MaxInsertBatchSize: 100,
+	}
+	server := Server{
+		config: &config,
+		env: serverenv.New(ctx,
+			serverenv.WithDatabase(testDB),
+			serverenv.WithBlobStorage(blobstore)),
+	}
+
+	eb := &model.ExportBatch{
+		BatchID:        1,
+		ConfigID:       1,
+		BucketName:     "test-bucket",
+		FilenameRoot:   "files",
+		StartTimestamp: baseTime,
+		EndTimestamp:   baseTime.Add(time.Hour),
+		OutputRegion:   "US",
+	}
+	criteria := publishdb.IterateExposuresCriteria{
+		SinceTimestamp: eb.StartTimestamp,
+		UntilTimestamp: eb.EndTimestamp,
+		IncludeRegions: []string{"US"},
+	}
+	signers := []*Signer{
+		{
+			SignatureInfo: &model.SignatureInfo{SigningKeyID: "310", SigningKeyVersion: "1"},
+			Signer:        &customTestSigner{sig: []byte("signature")},
+		},
+	}
+
+	stream, splitBatch, err := server.planBatch(ctx, criteria, config.MaxRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stream || !splitBatch {
+		t.Fatalf("expected a streamed, split batch, got stream=%t split=%t", stream, splitBatch)
+	}
+
+	files, counts, err := server.streamBatch(ctx, eb, criteria, config.MaxRecords, splitBatch, signers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&keyCounts{keys: len(exposures)}, counts, cmp.AllowUnexported(keyCounts{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The streamed files must match the files built in memory.
+	groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, eb.OutputRegion, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(files), len(groups); got != want {
+		t.Fatalf("expected %d files, got %d", want, got)
+	}
+	for i, group := range groups {
+		want, err := MarshalExportFile(eb, group.exposures, group.revised, int32(i+1), splitBatch, signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := blobstore.GetObject(ctx, eb.BucketName, files[i].Filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("file %d: streamed export file does not match MarshalExportFile", i+1)
+		}
+
+		digest := sha256.Sum256(want)
+		if got, want := files[i].SHA256, digest[:]; !bytes.Equal(got, want) {
+			t.Errorf("file %d: expected sha256 %x to be %x", i+1, got, want)
+		}
+		if got, want := files[i].Size, int64(len(want)); got != want {
+			t.Errorf("file %d: expected size %d to be %d", i+1, got, want)
+		}
+		if got, want := files[i].KeyCount, group.Length(); got != want {
+			t.Errorf("file %d: expected key count %d to be %d", i+1, got, want)
+		}
+	}
+}
+
+func TestBatchExposuresSavePadding(t *testing.T) {
+	t.Parallel()
+
+	baseTime := time.Date(2020, 10, 28, 1, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		savePadding bool
+		wantSaved   int
+	}{
+		{
+			name:        "period",
+			savePadding: true,
+			wantSaved:   10,
+		},
+		{
+			name:        "rollup",
+			savePadding: false,
+			wantSaved:   1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			testDB, _ := testDatabaseInstance.NewDatabase(t)
+			testPublishDB := publishdb.New(testDB)
+
+			if _, err := testPublishDB.InsertAndReviseExposures(ctx, &publishdb.InsertAndReviseExposuresRequest{
+				Incoming: []*publishmodel.Exposure{
+					{
+						ExposureKey:     randomTEK(t),
+						Regions:         []string{"US"},
+						IntervalNumber:  100,
+						IntervalCount:   144,
+						CreatedAt:       baseTime,
+						LocalProvenance: true,
+						ReportType:      verifyapi.ReportTypeClinical,
+					},
+				},
+				RequireToken: false,
+			}); err != nil {
+				t.Fatalf("inserting exposures: %v", err)
+			}
+
+			config := Config{
+				MinRecords:         10,
+				PaddingRange:       0,
+				MaxRecords:         100,
+				TruncateWindow:     time.Hour,
+				MaxInsertBatchSize: 100,
+			}
+			server := Server{
+				config: &config,
+				env:    serverenv.New(ctx, serverenv.WithDatabase(testDB)),
+			}
+
+			criteria := publishdb.IterateExposuresCriteria{
+				SinceTimestamp: baseTime,
+				UntilTimestamp: baseTime.Add(time.Hour),
+				IncludeRegions: []string{"US"},
+			}
+			groups, err := server.batchExposures(ctx, criteria, config.MaxRecords, "US", tc.savePadding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(groups), 1; got != want {
+				t.Fatalf("expected %d groups, got %d", want, got)
+			}
+			if got, want := len(groups[0].exposures), config.MinRecords; got != want {
+				t.Errorf("expected %d padded keys, got %d", want, got)
+			}
+
+			saved := 0
+			if _, err := testPublishDB.IterateExposures(ctx, criteria, func(*publishmodel.Exposure) error {
+				saved++
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := saved, tc.wantSaved; got != want {
+				t.Errorf("expected %d saved keys, got %d", want, got)
+			}
+		})
+	}
+}
+
 // randomTEK is like util.RandomTEK, but handles the error from tb.
 func randomTEK(tb testing.TB) []byte {
 	tb.Helper()
@@ -508,6 +738,18 @@ func TestExportFilename(t *testing.T) {
 			regenCount: 3,
 			exp:        "v2/103-303-00001.zip",
 		},
+		{
+			name: "backfill",
+			m: &model.ExportBatch{
+				FilenameRoot:   "v2",
+				StartTimestamp: time.Unix(100, 0),
+				EndTimestamp:   time.Unix(300, 0),
+				Generation:     2,
+			},
+			num:        1,
+			regenCount: 3,
+			exp:        "v2/105-305-00001.zip",
+		},
 	}
 
 	for _, tc := range cases {
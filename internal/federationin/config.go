@@ -15,6 +15,7 @@
 package federationin
 
 import (
+	"fmt"
 	"regexp"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"github.com/google/exposure-notifications-server/pkg/secrets"
+	"github.com/hashicorp/go-multierror"
 )
 
 const (
@@ -74,6 +76,21 @@ type Config struct {
 	// empty.
 	CredentialsFile string `env:"CREDENTIALS_FILE"`
 
+	// TLSClientCertFile and TLSClientKeyFile are a certificate and key that are
+	// presented to servers that request a client certificate (mutual TLS).
+	TLSClientCertFile string `env:"TLS_CLIENT_CERT_FILE"`
+	TLSClientKeyFile  string `env:"TLS_CLIENT_KEY_FILE"`
+
+	// OIDCTokenURL, OIDCClientID and OIDCClientSecret are used to obtain tokens
+	// with the client credentials grant for queries with the OIDC credential
+	// type. The query audience is sent as the `audience` parameter.
+	OIDCTokenURL     string `env:"OIDC_TOKEN_URL"`
+	OIDCClientID     string `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string `env:"OIDC_CLIENT_SECRET"`
+
+	// StaticBearerToken is sent for queries with the STATIC credential type.
+	StaticBearerToken string `env:"STATIC_BEARER_TOKEN"`
+
 	// Sync configuration.
 	// If accepted, both self report and recursive will be sent as clinical,
 	// otherwise they will be dropped.
@@ -81,6 +98,22 @@ type Config struct {
 	AcceptRecursive  bool `env:"ACCEPT_RECURSIVE, default=false"`
 }
 
+// Validate checks that the credential settings are consistent.
+func (c *Config) Validate() error {
+	var result *multierror.Error
+
+	if (c.TLSClientCertFile == "") != (c.TLSClientKeyFile == "") {
+		result = multierror.Append(result,
+			fmt.Errorf("env vars `TLS_CLIENT_CERT_FILE` and `TLS_CLIENT_KEY_FILE` must be set together"))
+	}
+	if c.OIDCTokenURL != "" && c.OIDCClientID == "" {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `OIDC_TOKEN_URL` requires `OIDC_CLIENT_ID`"))
+	}
+
+	return result.ErrorOrNil()
+}
+
 func (c *Config) DatabaseConfig() *database.Config {
 	return &c.Database
 }
@@ -0,0 +1,102 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/api/idtoken"
+)
+
+// tlsConfig returns the TLS configuration used to dial federation servers. It
+// includes the client certificate, if one is configured.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	cp, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access system cert pool: %w", err)
+	}
+
+	if s.config.TLSCertFile != "" {
+		b, err := os.ReadFile(s.config.TLSCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cert file %q: %w", s.config.TLSCertFile, err)
+		}
+		if !cp.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("failed to append credentials")
+		}
+	}
+
+	tlsConfig := &tls.Config{RootCAs: cp, InsecureSkipVerify: s.config.TLSSkipVerify}
+	if s.config.TLSClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.config.TLSClientCertFile, s.config.TLSClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// tokenSource returns the source of the bearer tokens sent on requests for the
+// query, or nil if no token is sent.
+func (s *Server) tokenSource(ctx context.Context, query *model.FederationInQuery) (oauth2.TokenSource, error) {
+	switch typ := query.EffectiveCredentialType(); typ {
+	case model.CredentialTypeGoogle:
+		var clientOpts []idtoken.ClientOption
+		if s.config.CredentialsFile != "" {
+			clientOpts = append(clientOpts, idtoken.WithCredentialsFile(s.config.CredentialsFile))
+		}
+		ts, err := idtoken.NewTokenSource(ctx, query.Audience, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create token source: %w", err)
+		}
+		return ts, nil
+
+	case model.CredentialTypeOIDC:
+		if s.config.OIDCTokenURL == "" {
+			return nil, fmt.Errorf("credential type %s requires OIDC_TOKEN_URL", typ)
+		}
+		cfg := &clientcredentials.Config{
+			ClientID:     s.config.OIDCClientID,
+			ClientSecret: s.config.OIDCClientSecret,
+			TokenURL:     s.config.OIDCTokenURL,
+		}
+		if query.Audience != "" {
+			cfg.EndpointParams = url.Values{"audience": []string{query.Audience}}
+		}
+		return cfg.TokenSource(ctx), nil
+
+	case model.CredentialTypeStatic:
+		if s.config.StaticBearerToken == "" {
+			return nil, fmt.Errorf("credential type %s requires STATIC_BEARER_TOKEN", typ)
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.config.StaticBearerToken}), nil
+
+	case model.CredentialTypeNone:
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown credential type %q", typ)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestTokenSource(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" || clientID != "puller" || clientSecret != "secret" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "oidc-token-for-" + r.PostForm.Get("audience"),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}); err != nil {
+			panic(err)
+		}
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	cases := []struct {
+		name      string
+		config    *Config
+		query     *model.FederationInQuery
+		wantToken string
+		wantErr   string
+	}{
+		{
+			name:      "oidc",
+			config:    &Config{OIDCTokenURL: tokenServer.URL, OIDCClientID: "puller", OIDCClientSecret: "secret"},
+			query:     &model.FederationInQuery{Audience: "https://partner", CredentialType: model.CredentialTypeOIDC},
+			wantToken: "oidc-token-for-https://partner",
+		},
+		{
+			name:    "oidc_not_configured",
+			config:  &Config{},
+			query:   &model.FederationInQuery{CredentialType: model.CredentialTypeOIDC},
+			wantErr: "requires OIDC_TOKEN_URL",
+		},
+		{
+			name:      "static",
+			config:    &Config{StaticBearerToken: "static-token"},
+			query:     &model.FederationInQuery{CredentialType: model.CredentialTypeStatic},
+			wantToken: "static-token",
+		},
+		{
+			name:    "static_not_configured",
+			config:  &Config{},
+			query:   &model.FederationInQuery{CredentialType: model.CredentialTypeStatic},
+			wantErr: "requires STATIC_BEARER_TOKEN",
+		},
+		{
+			name:   "none",
+			config: &Config{StaticBearerToken: "static-token"},
+			query:  &model.FederationInQuery{CredentialType: model.CredentialTypeNone},
+		},
+		{
+			name:    "unknown",
+			config:  &Config{},
+			query:   &model.FederationInQuery{CredentialType: "KERBEROS"},
+			wantErr: `unknown credential type "KERBEROS"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Server{config: tc.config}
+			ts, err := s.tokenSource(ctx, tc.query)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if tc.wantErr != "" {
+				return
+			}
+
+			if tc.wantToken == "" {
+				if ts != nil {
+					t.Fatalf("expected no token source, got %v", ts)
+				}
+				return
+			}
+			token, err := ts.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := token.AccessToken, tc.wantToken; got != want {
+				t.Errorf("expected token %q to be %q", got, want)
+			}
+			if got, want := token.Type(), "Bearer"; got != want {
+				t.Errorf("expected token type %q to be %q", got, want)
+			}
+		})
+	}
+}
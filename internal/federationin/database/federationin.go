@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
@@ -37,11 +38,26 @@ func New(db *database.DB) *FederationInDB {
 	}
 }
 
-// FinalizeSyncFn is used to finalize a historical sync record.
-type FinalizeSyncFn func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int) error
+// maxSyncErrorLength is the longest error stored on a failed sync record.
+const maxSyncErrorLength = 1024
+
+// failedSyncTimeout is the time allowed to record a failed sync. Failures are
+// recorded with their own context, as the sync may have failed because its
+// context expired.
+const failedSyncTimeout = 30 * time.Second
+
+// FinalizeSyncFn is used to finalize a historical sync record. If syncErr is
+// not nil, the sync is recorded as failed and the query state is not updated.
+type FinalizeSyncFn func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int, syncErr error) error
 
 type queryRowFn func(ctx context.Context, query string, args ...interface{}) pgx.Row
 
+// QueryLockID returns the ID of the lock that is held while a query is synced,
+// or while its cursor is changed.
+func QueryLockID(queryID string) string {
+	return "query_" + queryID
+}
+
 // Lock acquires lock with given name that times out after ttl. Returns an UnlockFn that can be used to unlock the lock. ErrAlreadyLocked will be returned if there is already a lock in use.
 func (db *FederationInDB) Lock(ctx context.Context, lockID string, ttl time.Duration) (database.UnlockFn, error) {
 	return db.db.Lock(ctx, lockID, ttl)
@@ -65,8 +81,8 @@ func (db *FederationInDB) GetFederationInQuery(ctx context.Context, queryID stri
 func getFederationInQuery(ctx context.Context, queryID string, queryRow queryRowFn) (*model.FederationInQuery, error) {
 	row := queryRow(ctx, `
 		SELECT
-			query_id, server_addr, oidc_audience, include_regions, exclude_regions,
-			only_local_provenance, only_travelers,
+			query_id, server_addr, oidc_audience, credential_type, transport, include_regions, exclude_regions,
+			only_local_provenance, only_travelers, disabled,
 			last_timestamp, primary_cursor, last_revised_timestamp, revised_cursor
 		FROM
 			FederationInQuery
@@ -74,18 +90,65 @@ func getFederationInQuery(ctx context.Context, queryID string, queryRow queryRow
 			query_id=$1
 		`, queryID)
 
+	q, err := scanOneFederationInQuery(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("scanning results: %w", err)
+	}
+	return q, nil
+}
+
+// ListFederationInQueries returns all queries, ordered by query ID.
+func (db *FederationInDB) ListFederationInQueries(ctx context.Context) ([]*model.FederationInQuery, error) {
+	var queries []*model.FederationInQuery
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				query_id, server_addr, oidc_audience, credential_type, transport, include_regions, exclude_regions,
+				only_local_provenance, only_travelers, disabled,
+				last_timestamp, primary_cursor, last_revised_timestamp, revised_cursor
+			FROM
+				FederationInQuery
+			ORDER BY
+				query_id
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			q, err := scanOneFederationInQuery(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			queries = append(queries, q)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list federation in queries: %w", err)
+	}
+
+	return queries, nil
+}
+
+func scanOneFederationInQuery(row pgx.Row) (*model.FederationInQuery, error) {
 	var lastTimestamp, revisedTimestamp *time.Time
 	var lastCursor, revisedCursor *string
 
 	// See https://www.opsdash.com/blog/postgres-arrays-golang.html for working with Postgres arrays in Go.
 	q := model.FederationInQuery{}
-	if err := row.Scan(&q.QueryID, &q.ServerAddr, &q.Audience, &q.IncludeRegions, &q.ExcludeRegions,
-		&q.OnlyLocalProvenance, &q.OnlyTravelers,
+	if err := row.Scan(&q.QueryID, &q.ServerAddr, &q.Audience, &q.CredentialType, &q.Transport, &q.IncludeRegions, &q.ExcludeRegions,
+		&q.OnlyLocalProvenance, &q.OnlyTravelers, &q.Disabled,
 		&lastTimestamp, &lastCursor, &revisedTimestamp, &revisedCursor); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, database.ErrNotFound
-		}
-		return nil, fmt.Errorf("scanning results: %w", err)
+		return nil, err
 	}
 	if lastTimestamp != nil {
 		q.LastTimestamp = *lastTimestamp
@@ -109,15 +172,15 @@ func (db *FederationInDB) AddFederationInQuery(ctx context.Context, q *model.Fed
 		query := `
 			INSERT INTO
 				FederationInQuery
-				(query_id, server_addr, oidc_audience, include_regions, exclude_regions, only_local_provenance, only_travelers)
+				(query_id, server_addr, oidc_audience, include_regions, exclude_regions, only_local_provenance, only_travelers, credential_type, transport, disabled)
 			VALUES
-				($1, $2, $3, $4, $5, $6, $7)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 			ON CONFLICT
 				(query_id)
 			DO UPDATE
-				SET server_addr = $2, oidc_audience = $3, include_regions = $4, exclude_regions = $5, only_local_provenance = $6, only_travelers = $7
+				SET server_addr = $2, oidc_audience = $3, include_regions = $4, exclude_regions = $5, only_local_provenance = $6, only_travelers = $7, credential_type = $8, transport = $9, disabled = $10
 		`
-		_, err := tx.Exec(ctx, query, q.QueryID, q.ServerAddr, q.Audience, q.IncludeRegions, q.ExcludeRegions, q.OnlyLocalProvenance, q.OnlyTravelers)
+		_, err := tx.Exec(ctx, query, q.QueryID, q.ServerAddr, q.Audience, q.IncludeRegions, q.ExcludeRegions, q.OnlyLocalProvenance, q.OnlyTravelers, q.CredentialType, q.Transport, q.Disabled)
 		if err != nil {
 			return fmt.Errorf("upserting federation query: %w", err)
 		}
@@ -125,6 +188,35 @@ func (db *FederationInDB) AddFederationInQuery(ctx context.Context, q *model.Fed
 	})
 }
 
+// ResetFederationInQueryCursor clears the cursors of the query, so the next
+// pull starts from the given time. A zero time pulls everything the remote
+// server has. If the query is not found, ErrNotFound will be returned.
+func (db *FederationInDB) ResetFederationInQueryCursor(ctx context.Context, queryID string, since time.Time) error {
+	var lastTimestamp *time.Time
+	if !since.IsZero() {
+		lastTimestamp = &since
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			UPDATE
+				FederationInQuery
+			SET
+				last_timestamp = $1, primary_cursor = NULL,
+				last_revised_timestamp = $1, revised_cursor = NULL
+			WHERE
+				query_id = $2
+		`, lastTimestamp, queryID)
+		if err != nil {
+			return fmt.Errorf("resetting federation query cursor: %w", err)
+		}
+		if result.RowsAffected() != 1 {
+			return database.ErrNotFound
+		}
+		return nil
+	})
+}
+
 // GetFederationInSync returns a federation sync record for given syncID. If not found, ErrNotFound will be returned.
 func (db *FederationInDB) GetFederationInSync(ctx context.Context, syncID int64) (*model.FederationInSync, error) {
 	var sync *model.FederationInSync
@@ -143,23 +235,111 @@ func (db *FederationInDB) GetFederationInSync(ctx context.Context, syncID int64)
 func getFederationInSync(ctx context.Context, syncID int64, queryRowContext queryRowFn) (*model.FederationInSync, error) {
 	row := queryRowContext(ctx, `
 		SELECT
-			sync_id, query_id, started, completed, insertions, max_timestamp, max_revised_timestamp
+			sync_id, query_id, started, completed, insertions, max_timestamp, max_revised_timestamp, error
 		FROM
 			FederationInSync
 		WHERE
 			sync_id=$1
 		`, syncID)
 
+	s, err := scanOneFederationInSync(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, database.ErrNotFound
+		}
+		return nil, fmt.Errorf("scanning results: %w", err)
+	}
+	return s, nil
+}
+
+// ListFederationInSyncs returns the most recent syncs of the query, newest
+// first.
+func (db *FederationInDB) ListFederationInSyncs(ctx context.Context, queryID string, limit int) ([]*model.FederationInSync, error) {
+	var syncs []*model.FederationInSync
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				sync_id, query_id, started, completed, insertions, max_timestamp, max_revised_timestamp, error
+			FROM
+				FederationInSync
+			WHERE
+				query_id = $1
+			ORDER BY
+				sync_id DESC
+			LIMIT $2
+		`, queryID, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			s, err := scanOneFederationInSync(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			syncs = append(syncs, s)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list federation in syncs: %w", err)
+	}
+
+	return syncs, nil
+}
+
+// LastFederationInSyncs returns the most recent sync of each query, keyed by
+// query ID.
+func (db *FederationInDB) LastFederationInSyncs(ctx context.Context) (map[string]*model.FederationInSync, error) {
+	syncs := make(map[string]*model.FederationInSync)
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT DISTINCT ON (query_id)
+				sync_id, query_id, started, completed, insertions, max_timestamp, max_revised_timestamp, error
+			FROM
+				FederationInSync
+			ORDER BY
+				query_id, sync_id DESC
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			s, err := scanOneFederationInSync(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			syncs[s.QueryID] = s
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list last federation in syncs: %w", err)
+	}
+
+	return syncs, nil
+}
+
+func scanOneFederationInSync(row pgx.Row) (*model.FederationInSync, error) {
 	s := model.FederationInSync{}
 	var (
 		completed, max, maxRevised *time.Time
 		insertions                 *int
+		syncErr                    *string
 	)
-	if err := row.Scan(&s.SyncID, &s.QueryID, &s.Started, &completed, &insertions, &max, &maxRevised); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, database.ErrNotFound
-		}
-		return nil, fmt.Errorf("scanning results: %w", err)
+	if err := row.Scan(&s.SyncID, &s.QueryID, &s.Started, &completed, &insertions, &max, &maxRevised, &syncErr); err != nil {
+		return nil, err
 	}
 	if completed != nil {
 		s.Completed = *completed
@@ -173,6 +353,9 @@ func getFederationInSync(ctx context.Context, syncID int64, queryRowContext quer
 	if insertions != nil {
 		s.Insertions = *insertions
 	}
+	if syncErr != nil {
+		s.Error = *syncErr
+	}
 	return &s, nil
 }
 
@@ -207,9 +390,15 @@ func (db *FederationInDB) StartFederationInSync(ctx context.Context, q *model.Fe
 		return 0, nil, fmt.Errorf("fetching sync_id: %w", err)
 	}
 
-	finalize := func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int) error {
+	finalize := func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int, syncErr error) error {
 		completed := started.Add(time.Since(startedTimer))
 
+		if syncErr != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), failedSyncTimeout)
+			defer cancel()
+			return db.failFederationInSync(ctx, syncID, completed, totalInserted, syncErr)
+		}
+
 		return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 			// Special case: when no keys are pulled, the maxTimestamp will be 0, so we don't update the
 			// FederationQuery in this case to prevent it from going back and fetching old keys from the past.
@@ -254,3 +443,150 @@ func (db *FederationInDB) StartFederationInSync(ctx context.Context, q *model.Fe
 
 	return syncID, finalize, nil
 }
+
+// truncateSyncError shortens msg to at most maxSyncErrorLength bytes, without
+// splitting a UTF-8 character.
+func truncateSyncError(msg string) string {
+	if len(msg) <= maxSyncErrorLength {
+		return msg
+	}
+	n := maxSyncErrorLength
+	for n > 0 && !utf8.RuneStart(msg[n]) {
+		n--
+	}
+	return msg[:n]
+}
+
+// failFederationInSync records a sync as failed.
+func (db *FederationInDB) failFederationInSync(ctx context.Context, syncID int64, completed time.Time, totalInserted int, syncErr error) error {
+	msg := truncateSyncError(syncErr.Error())
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE
+				FederationInSync
+			SET
+				completed = $1,
+				insertions = $2,
+				error = $3
+			WHERE
+				sync_id = $4
+		`, completed, totalInserted, msg, syncID)
+		if err != nil {
+			return fmt.Errorf("updating failed federation sync: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListFederationInStatuses returns the sync status of every query, ordered by
+// query ID.
+func (db *FederationInDB) ListFederationInStatuses(ctx context.Context) ([]*model.FederationInStatus, error) {
+	return db.federationInStatuses(ctx, "")
+}
+
+// GetFederationInStatus returns the sync status of the query. If the query is
+// not found, ErrNotFound will be returned.
+func (db *FederationInDB) GetFederationInStatus(ctx context.Context, queryID string) (*model.FederationInStatus, error) {
+	statuses, err := db.federationInStatuses(ctx, queryID)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, database.ErrNotFound
+	}
+	return statuses[0], nil
+}
+
+// federationInStatuses returns the status of the given query, or of all
+// queries if queryID is empty.
+func (db *FederationInDB) federationInStatuses(ctx context.Context, queryID string) ([]*model.FederationInStatus, error) {
+	var statuses []*model.FederationInStatus
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		// The query's cursor is the newest key pulled if the syncs that pulled
+		// keys have been cleaned up.
+		rows, err := tx.Query(ctx, `
+			SELECT
+				q.query_id, q.disabled,
+				GREATEST(q.last_timestamp, (SELECT MAX(max_timestamp) FROM FederationInSync WHERE query_id = q.query_id)),
+				success.completed,
+				(SELECT COUNT(*) FROM FederationInSync
+					WHERE query_id = q.query_id AND error IS NOT NULL AND sync_id > COALESCE(success.sync_id, 0))
+			FROM
+				FederationInQuery q
+			LEFT JOIN LATERAL (
+				SELECT sync_id, completed FROM FederationInSync
+				WHERE query_id = q.query_id AND completed IS NOT NULL AND error IS NULL
+				ORDER BY sync_id DESC
+				LIMIT 1
+			) success ON true
+			WHERE
+				$1 = '' OR q.query_id = $1
+			ORDER BY
+				q.query_id
+		`, queryID)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var status model.FederationInStatus
+			var maxTimestamp, lastSuccess *time.Time
+			if err := rows.Scan(&status.QueryID, &status.Disabled, &maxTimestamp, &lastSuccess, &status.ConsecutiveFailures); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			if maxTimestamp != nil {
+				status.MaxTimestamp = *maxTimestamp
+			}
+			if lastSuccess != nil {
+				status.LastSuccess = *lastSuccess
+			}
+			statuses = append(statuses, &status)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list federation in statuses: %w", err)
+	}
+
+	lastSyncs, err := db.LastFederationInSyncs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		status.LastSync = lastSyncs[status.QueryID]
+	}
+
+	return statuses, nil
+}
+
+// DeleteFederationInSyncsBefore deletes the sync records that started before
+// the given time, except for syncs that exposures still refer to.
+func (db *FederationInDB) DeleteFederationInSyncsBefore(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM
+				FederationInSync s
+			WHERE
+				s.started < $1
+			AND
+				NOT EXISTS (SELECT 1 FROM Exposure WHERE sync_id = s.sync_id)
+		`, before)
+		if err != nil {
+			return fmt.Errorf("deleting federation in syncs: %w", err)
+		}
+		count = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
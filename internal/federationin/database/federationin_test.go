@@ -16,8 +16,10 @@ package database
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
@@ -39,6 +41,8 @@ func TestFederationIn(t *testing.T) {
 	want := &model.FederationInQuery{
 		QueryID:             "qid",
 		ServerAddr:          "addr",
+		CredentialType:      model.CredentialTypeOIDC,
+		Transport:           model.TransportHTTP,
 		IncludeRegions:      []string{"MX"},
 		ExcludeRegions:      []string{"CA"},
 		OnlyLocalProvenance: true,
@@ -114,7 +118,7 @@ func TestFederationIn(t *testing.T) {
 
 	// The completed time will be close to the start time.
 	wantSync.Completed = now
-	if err := finalize(&state, want, wantSync.Insertions); err != nil {
+	if err := finalize(&state, want, wantSync.Insertions, nil); err != nil {
 		t.Fatal(err)
 	}
 	gotSync, err = db.GetFederationInSync(ctx, syncID)
@@ -133,4 +137,252 @@ func TestFederationIn(t *testing.T) {
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Errorf("mismatch (-want, +got):\n%s", diff)
 	}
+
+	// List the syncs of the query, newest first.
+	secondSyncID, _, err := db.StartFederationInSync(ctx, want, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncs, err := db.ListFederationInSyncs(ctx, want.QueryID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(syncs), 2; got != want {
+		t.Fatalf("expected %d syncs, got %d", want, got)
+	}
+	if got, want := syncs[0].SyncID, secondSyncID; got != want {
+		t.Errorf("expected newest sync %d, got %d", want, got)
+	}
+	if diff := cmp.Diff(wantSync, syncs[1], cmpopts.EquateApproxTime(time.Minute)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	lastSyncs, err := db.LastFederationInSyncs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(lastSyncs), 1; got != want {
+		t.Fatalf("expected %d last syncs, got %d", want, got)
+	}
+	lastSync, ok := lastSyncs[want.QueryID]
+	if !ok {
+		t.Fatalf("expected last sync of %q", want.QueryID)
+	}
+	if got, want := lastSync.SyncID, secondSyncID; got != want {
+		t.Errorf("expected last sync %d, got %d", want, got)
+	}
+
+	// Reset the cursor.
+	since := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := db.ResetFederationInQueryCursor(ctx, want.QueryID, since); err != nil {
+		t.Fatal(err)
+	}
+	want.ResetFetchState(since)
+	got, err = db.GetFederationInQuery(ctx, want.QueryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if err := db.ResetFederationInQueryCursor(ctx, "unknown", since); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+
+	// Disable the query, and list all queries.
+	want.Disabled = true
+	if err := db.AddFederationInQuery(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	other := &model.FederationInQuery{
+		QueryID:    "aaa",
+		ServerAddr: "addr3",
+	}
+	if err := db.AddFederationInQuery(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := db.ListFederationInQueries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.FederationInQuery{other, want}, queries); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+// TestFederationInStatus tests failed syncs and the sync status of queries.
+func TestFederationInStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	db := New(testDB)
+
+	query := &model.FederationInQuery{
+		QueryID:    "qid",
+		ServerAddr: "addr",
+	}
+	if err := db.AddFederationInQuery(ctx, query); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddFederationInQuery(ctx, &model.FederationInQuery{QueryID: "never", ServerAddr: "addr"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.GetFederationInStatus(ctx, "unknown"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+
+	// A successful sync.
+	now := time.Now().Truncate(time.Second)
+	maxTimestamp := now.Add(-time.Hour).UTC()
+	_, finalize, err := db.StartFederationInSync(ctx, query, now.Add(-3*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := federation.FetchState{
+		KeyCursor:        &federation.Cursor{Timestamp: maxTimestamp.Unix()},
+		RevisedKeyCursor: &federation.Cursor{Timestamp: maxTimestamp.Unix()},
+	}
+	if err := finalize(&state, query, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two failed syncs, which must not move the cursor.
+	var failedSyncID int64
+	for i := 2; i > 0; i-- {
+		syncID, finalize, err := db.StartFederationInSync(ctx, query, now.Add(-time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		failedState := federation.FetchState{
+			KeyCursor:        &federation.Cursor{Timestamp: now.Unix()},
+			RevisedKeyCursor: &federation.Cursor{Timestamp: now.Unix()},
+		}
+		if err := finalize(&failedState, query, 1, errors.New("remote unavailable")); err != nil {
+			t.Fatal(err)
+		}
+		failedSyncID = syncID
+	}
+
+	failedSync, err := db.GetFederationInSync(ctx, failedSyncID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !failedSync.Failed() || failedSync.Error != "remote unavailable" {
+		t.Errorf("expected failed sync with error, got %q", failedSync.Error)
+	}
+	if failedSync.Completed.IsZero() {
+		t.Errorf("expected failed sync to be completed")
+	}
+	if got, want := failedSync.Insertions, 1; got != want {
+		t.Errorf("expected %d insertions, got %d", want, got)
+	}
+
+	gotQuery, err := db.GetFederationInQuery(ctx, query.QueryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gotQuery.LastTimestamp, maxTimestamp; !got.Equal(want) {
+		t.Errorf("expected cursor %v, got %v", want, got)
+	}
+
+	status, err := db.GetFederationInStatus(ctx, query.QueryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := status.ConsecutiveFailures, 2; got != want {
+		t.Errorf("expected %d consecutive failures, got %d", want, got)
+	}
+	if got, want := status.MaxTimestamp, maxTimestamp; !got.Equal(want) {
+		t.Errorf("expected max timestamp %v, got %v", want, got)
+	}
+	if status.LastSuccess.IsZero() {
+		t.Errorf("expected last success to be set")
+	}
+	if status.LastSync == nil || status.LastSync.SyncID != failedSyncID {
+		t.Errorf("expected last sync %d, got %v", failedSyncID, status.LastSync)
+	}
+
+	statuses, err := db.ListFederationInStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(statuses), 2; got != want {
+		t.Fatalf("expected %d statuses, got %d", want, got)
+	}
+	if got := statuses[0]; got.QueryID != "never" || got.LastSync != nil || got.ConsecutiveFailures != 0 || !got.LastSuccess.IsZero() {
+		t.Errorf("unexpected status of query that never synced: %+v", got)
+	}
+
+	// Delete the old syncs. There are no exposures referring to them.
+	count, err := db.DeleteFederationInSyncsBefore(ctx, now.Add(-90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, int64(2); got != want {
+		t.Errorf("expected %d deleted syncs, got %d", want, got)
+	}
+	syncs, err := db.ListFederationInSyncs(ctx, query.QueryID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(syncs), 1; got != want {
+		t.Fatalf("expected %d syncs, got %d", want, got)
+	}
+	if got, want := syncs[0].SyncID, failedSyncID; got != want {
+		t.Errorf("expected remaining sync %d, got %d", want, got)
+	}
+
+	// The lag falls back to the query's cursor once the syncs are deleted.
+	status, err = db.GetFederationInStatus(ctx, query.QueryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := status.MaxTimestamp, maxTimestamp; !got.Equal(want) {
+		t.Errorf("expected max timestamp %v, got %v", want, got)
+	}
+}
+
+func TestTruncateSyncError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{
+			name: "short",
+			msg:  "remote unavailable",
+			want: "remote unavailable",
+		},
+		{
+			name: "long",
+			msg:  strings.Repeat("a", maxSyncErrorLength+1),
+			want: strings.Repeat("a", maxSyncErrorLength),
+		},
+		{
+			// The 3 byte character would cross the limit, so it is dropped.
+			name: "multibyte",
+			msg:  strings.Repeat("a", maxSyncErrorLength-1) + "€",
+			want: strings.Repeat("a", maxSyncErrorLength-1),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := truncateSyncError(tc.msg)
+			if got != tc.want {
+				t.Errorf("expected %q to be %q", got, tc.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("expected %q to be valid UTF-8", got)
+			}
+		})
+	}
 }
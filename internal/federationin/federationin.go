@@ -17,12 +17,10 @@ package federationin
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"sort"
 	"strings"
 	"time"
@@ -37,7 +35,6 @@ import (
 	coredb "github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 
-	"google.golang.org/api/idtoken"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
@@ -59,6 +56,7 @@ var (
 
 type (
 	fetchFn               func(context.Context, *federation.FederationFetchRequest, ...grpc.CallOption) (*federation.FederationFetchResponse, error)
+	fetchStreamFn         func(context.Context, *federation.FederationFetchRequest, ...grpc.CallOption) (federation.Federation_FetchStreamClient, error)
 	insertExposuresFn     func(context.Context, *publishdb.InsertAndReviseExposuresRequest) (*publishdb.InsertAndReviseExposuresResponse, error)
 	startFederationSyncFn func(context.Context, *model.FederationInQuery, time.Time) (int64, database.FinalizeSyncFn, error)
 )
@@ -67,6 +65,9 @@ type pullDependencies struct {
 	fetch               fetchFn
 	insertExposures     insertExposuresFn
 	startFederationSync startFederationSyncFn
+
+	// fetchStream is used instead of fetch if set.
+	fetchStream fetchStreamFn
 }
 
 func (s *Server) handleSync() http.Handler {
@@ -97,7 +98,7 @@ func (s *Server) handleSync() http.Handler {
 		}
 
 		// Obtain lock to make sure there are no other processes working on this batch.
-		lock := "query_" + queryID
+		lock := database.QueryLockID(queryID)
 		logger = logger.With("lock", lock)
 		unlockFn, err := s.db.Lock(ctx, lock, s.config.Timeout)
 		if err != nil {
@@ -125,62 +126,78 @@ func (s *Server) handleSync() http.Handler {
 			internalErrorf(ctx, w, "Failed getting query %q: %v", queryID, err)
 			return
 		}
-
-		cp, err := x509.SystemCertPool()
-		if err != nil {
-			internalErrorf(ctx, w, "Failed to access system cert pool: %v", err)
+		if query.Disabled {
+			logger.Infow("query is disabled, skipping", "query", queryID)
+			w.WriteHeader(http.StatusOK) // We return status 200 here so that Cloud Scheduler does not retry.
 			return
 		}
 
-		if s.config.TLSCertFile != "" {
-			b, err := os.ReadFile(s.config.TLSCertFile)
-			if err != nil {
-				internalErrorf(ctx, w, "Failed to read cert file %q: %v", s.config.TLSCertFile, err)
-				return
-			}
-			if !cp.AppendCertsFromPEM(b) {
-				internalErrorf(ctx, w, "Failed to append credentials")
-				return
-			}
-		}
-
-		tlsConfig := &tls.Config{RootCAs: cp, InsecureSkipVerify: s.config.TLSSkipVerify}
-		dialOpts := []grpc.DialOption{
-			grpc.WithStatsHandler(&ocgrpc.ClientHandler{}),
-			grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
-		}
-
-		var clientOpts []idtoken.ClientOption
-		if s.config.CredentialsFile != "" {
-			clientOpts = append(clientOpts, idtoken.WithCredentialsFile(s.config.CredentialsFile))
-		}
-		ts, err := idtoken.NewTokenSource(ctx, query.Audience, clientOpts...)
+		tlsConfig, err := s.tlsConfig()
 		if err != nil {
-			internalErrorf(ctx, w, "Failed to create token source: %v", err)
+			s.failSync(ctx, query, fmt.Errorf("creating TLS config: %w", err))
+			internalErrorf(ctx, w, "Failed to create TLS config: %v", err)
 			return
 		}
-		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(oauth.TokenSource{
-			TokenSource: ts,
-		}))
 
-		logger.Infof("Dialing %s", query.ServerAddr)
-		conn, err := grpc.Dial(query.ServerAddr, dialOpts...)
+		ts, err := s.tokenSource(ctx, query)
 		if err != nil {
-			internalErrorf(ctx, w, "Failed to dial for query %q %s: %v", queryID, query.ServerAddr, err)
+			s.failSync(ctx, query, fmt.Errorf("creating credentials: %w", err))
+			internalErrorf(ctx, w, "Failed to create credentials for query %q: %v", queryID, err)
 			return
 		}
-		defer conn.Close()
-		client := federation.NewFederationClient(conn)
 
 		timeoutContext, cancel := context.WithTimeout(ctx, s.config.Timeout)
 		defer cancel()
 
+		deps := pullDependencies{
+			insertExposures:     s.publishdb.InsertAndReviseExposures,
+			startFederationSync: s.db.StartFederationInSync,
+		}
+
+		switch transport := query.EffectiveTransport(); transport {
+		case model.TransportHTTP:
+			logger.Infof("Using HTTP gateway of %s", query.ServerAddr)
+			deps.fetch = newHTTPFetcher(query.ServerAddr, tlsConfig, ts).fetch
+
+		case model.TransportGRPC:
+			dialOpts := []grpc.DialOption{
+				grpc.WithStatsHandler(&ocgrpc.ClientHandler{}),
+				grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+			}
+			if ts != nil {
+				dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(oauth.TokenSource{
+					TokenSource: ts,
+				}))
+			}
+
+			logger.Infof("Dialing %s", query.ServerAddr)
+			conn, err := grpc.Dial(query.ServerAddr, dialOpts...)
+			if err != nil {
+				s.failSync(ctx, query, fmt.Errorf("dialing %s: %w", query.ServerAddr, err))
+				internalErrorf(ctx, w, "Failed to dial for query %q %s: %v", queryID, query.ServerAddr, err)
+				return
+			}
+			defer conn.Close()
+			client := federation.NewFederationClient(conn)
+			deps.fetch = client.Fetch
+
+			// Servers that predate GetCapabilities return Unimplemented, and only
+			// support the unary Fetch.
+			capabilities, err := client.GetCapabilities(timeoutContext, &federation.FederationCapabilitiesRequest{})
+			if err != nil {
+				logger.Infow("failed to get capabilities, using unary fetch", "error", err)
+			} else if capabilities.FetchStream {
+				deps.fetchStream = client.FetchStream
+			}
+
+		default:
+			s.failSync(ctx, query, fmt.Errorf("unknown transport %q", transport))
+			internalErrorf(ctx, w, "Unknown transport %q for query %q", transport, queryID)
+			return
+		}
+
 		opts := pullOptions{
-			deps: pullDependencies{
-				fetch:               client.Fetch,
-				insertExposures:     s.publishdb.InsertAndReviseExposures,
-				startFederationSync: s.db.StartFederationInSync,
-			},
+			deps:                         deps,
 			query:                        query,
 			batchStart:                   time.Now(),
 			truncateWindow:               s.config.TruncateWindow,
@@ -188,7 +205,9 @@ func (s *Server) handleSync() http.Handler {
 			maxMagnitudeSymptomOnsetDays: s.config.MaxMagnitudeSymptomOnsetDays,
 			debugReleaseSameDay:          s.config.ReleaseSameDayKeys,
 		}
-		if err := pull(timeoutContext, &opts); err != nil {
+		err = pull(timeoutContext, &opts)
+		s.recordStatus(ctx, queryID)
+		if err != nil {
 			internalErrorf(ctx, w, "Federation query %q failed: %v", queryID, err)
 			return
 		}
@@ -199,6 +218,31 @@ func (s *Server) handleSync() http.Handler {
 	})
 }
 
+// failSync records a sync of the query that failed before pulling started, so
+// the failure shows in the sync history and the failure and lag gauges like
+// failures during the pull.
+func (s *Server) failSync(ctx context.Context, query *model.FederationInQuery, syncErr error) {
+	logger := logging.FromContext(ctx).Named("failSync")
+
+	recordSyncResult(ctx, query.QueryID, 0, syncErr)
+	if _, finalizeFn, err := s.db.StartFederationInSync(ctx, query, time.Now()); err != nil {
+		logger.Errorw("failed to start failed federation sync", "error", err, "query", query.QueryID)
+	} else if err := finalizeFn(nil, query, 0, syncErr); err != nil {
+		logger.Errorw("failed to record failed federation sync", "error", err, "query", query.QueryID)
+	}
+	s.recordStatus(ctx, query.QueryID)
+}
+
+// recordStatus records the lag and failure gauges of the query.
+func (s *Server) recordStatus(ctx context.Context, queryID string) {
+	status, err := s.db.GetFederationInStatus(ctx, queryID)
+	if err != nil {
+		logging.FromContext(ctx).Errorw("failed to get sync status", "error", err, "query", queryID)
+		return
+	}
+	recordSyncStatus(ctx, status, time.Now())
+}
+
 type pullOptions struct {
 	deps                         pullDependencies
 	query                        *model.FederationInQuery
@@ -315,6 +359,19 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 		logger.Infof("Inserted %d keys", total)
 	}()
 
+	// Record failures on the sync record, so they are visible in the sync
+	// history. Keys inserted before the failure are kept, but the query's
+	// cursor is not advanced.
+	defer func() {
+		if err == nil {
+			return
+		}
+		recordSyncResult(ctx, opts.query.QueryID, total, err)
+		if ferr := finalizeFn(request.State, opts.query, total, err); ferr != nil {
+			logger.Errorw("failed to record failed federation sync", "error", ferr)
+		}
+	}()
+
 	createdAt := publishmodel.TruncateWindow(opts.batchStart, opts.truncateWindow)
 	// Create the transform / validation settings
 	transformSettings := publishmodel.KeyTransform{
@@ -329,6 +386,14 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 		BatchWindow:           opts.truncateWindow,
 	}
 
+	fetchPage := func() (*federation.FederationFetchResponse, error) {
+		return opts.deps.fetch(ctx, request)
+	}
+	if opts.deps.fetchStream != nil {
+		span.AddAttributes(trace.BoolAttribute("stream", true))
+		fetchPage = streamPages(ctx, opts.deps.fetchStream, request)
+	}
+
 	partial := true
 	nPartials := int64(0)
 	for partial {
@@ -337,7 +402,7 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 
 		// TODO(mikehelmick): react to the context timeout and complete a chunk of work so next invocation can pick up where left off.
 
-		response, err := opts.deps.fetch(ctx, request)
+		response, err := fetchPage()
 		if err != nil {
 			return fmt.Errorf("fetching query %s: %w", opts.query.QueryID, err)
 		}
@@ -424,14 +489,49 @@ func pull(ctx context.Context, opts *pullOptions) (err error) {
 		request.State = response.NextFetchState
 	}
 
-	if err := finalizeFn(request.State, opts.query, total); err != nil {
+	if err := finalizeFn(request.State, opts.query, total, nil); err != nil {
 		// TODO(mikehelmick): how do we clean up here? Just leave the records in and have the exporter eliminate them? Other?
 		return fmt.Errorf("finalizing federation sync for query %s: %w", opts.query.QueryID, err)
 	}
+	recordSyncResult(ctx, opts.query.QueryID, total, nil)
 
 	return nil
 }
 
+// streamPages returns a function that reads the next page from FetchStream. The
+// server ends the stream early if it times out, in that case a new stream is
+// opened from the state in the request, which the caller updates after each
+// page.
+func streamPages(ctx context.Context, fetchStream fetchStreamFn, request *federation.FederationFetchRequest) func() (*federation.FederationFetchResponse, error) {
+	var stream federation.Federation_FetchStreamClient
+	pages := 0
+	return func() (*federation.FederationFetchResponse, error) {
+		for {
+			if stream == nil {
+				s, err := fetchStream(ctx, request)
+				if err != nil {
+					return nil, err
+				}
+				stream, pages = s, 0
+			}
+
+			response, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				if pages == 0 {
+					return nil, errors.New("stream ended without a response")
+				}
+				stream = nil
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			pages++
+			return response, nil
+		}
+	}
+}
+
 func badRequestf(ctx context.Context, w http.ResponseWriter, format string, args ...interface{}) {
 	msg := fmt.Sprintf(format, args...)
 	logging.FromContext(ctx).Debug(msg)
@@ -16,6 +16,9 @@ package federationin
 
 import (
 	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -120,13 +123,15 @@ type syncDB struct {
 	maxTimestamp        time.Time
 	maxRevisedTimestamp time.Time
 	totalInserted       int
+	syncErr             error
 }
 
 func (sdb *syncDB) startFederationSync(ctx context.Context, query *model.FederationInQuery, start time.Time) (int64, database.FinalizeSyncFn, error) {
 	sdb.syncStarted = true
 	timerStart := time.Now()
-	return syncID, func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int) error {
+	return syncID, func(state *federation.FetchState, q *model.FederationInQuery, totalInserted int, syncErr error) error {
 		sdb.syncCompleted = true
+		sdb.syncErr = syncErr
 		sdb.completed = start.Add(time.Since(timerStart))
 		if state.KeyCursor != nil {
 			sdb.maxTimestamp = time.Unix(state.KeyCursor.Timestamp, 0).UTC()
@@ -474,6 +479,193 @@ func TestFederationPull(t *testing.T) {
 	}
 }
 
+// TestFederationPullFailed tests that a failed pull finalizes the sync with the
+// error.
+func TestFederationPullFailed(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	fetchErr := errors.New("remote unavailable")
+	sdb := syncDB{}
+	opts := pullOptions{
+		deps: pullDependencies{
+			fetch: func(ctx context.Context, req *federation.FederationFetchRequest, opts ...grpc.CallOption) (*federation.FederationFetchResponse, error) {
+				return nil, fetchErr
+			},
+			insertExposures:     (&publishDB{}).insertExposures,
+			startFederationSync: sdb.startFederationSync,
+		},
+		query:               &model.FederationInQuery{QueryID: queryID},
+		batchStart:          time.Now(),
+		truncateWindow:      time.Hour,
+		maxIntervalStartAge: 14 * 24 * time.Hour,
+	}
+
+	if err := pull(ctx, &opts); !errors.Is(err, fetchErr) {
+		t.Fatalf("pull returned err=%v, want %v", err, fetchErr)
+	}
+	if !sdb.syncCompleted {
+		t.Fatalf("startFederationSync completion callback not called")
+	}
+	if !errors.Is(sdb.syncErr, fetchErr) {
+		t.Errorf("federation sync error got %v, want %v", sdb.syncErr, fetchErr)
+	}
+	if sdb.totalInserted != 0 {
+		t.Errorf("federation sync total inserted got %d, want 0", sdb.totalInserted)
+	}
+}
+
+// remoteStreamServer mocks FetchStream on the remote federation server. Each
+// stream sends the next group of responses and then ends.
+type remoteStreamServer struct {
+	streams   [][]*federation.FederationFetchResponse
+	gotTokens []string
+}
+
+func (r *remoteStreamServer) fetchStream(ctx context.Context, req *federation.FederationFetchRequest, opts ...grpc.CallOption) (federation.Federation_FetchStreamClient, error) {
+	r.gotTokens = append(r.gotTokens, req.GetState().GetKeyCursor().GetNextToken())
+	if len(r.streams) == 0 {
+		return nil, errors.New("unexpected stream")
+	}
+	stream := &fetchStreamClient{responses: r.streams[0]}
+	r.streams = r.streams[1:]
+	return stream, nil
+}
+
+// fetchStreamClient is a federation.Federation_FetchStreamClient that returns
+// canned responses.
+type fetchStreamClient struct {
+	grpc.ClientStream
+	responses []*federation.FederationFetchResponse
+}
+
+func (c *fetchStreamClient) Recv() (*federation.FederationFetchResponse, error) {
+	if len(c.responses) == 0 {
+		return nil, io.EOF
+	}
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return response, nil
+}
+
+// TestFederationPullStream tests pull() with FetchStream.
+func TestFederationPullStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	intervalNumber := publishmodel.IntervalNumber(time.Now().Add(-2 * 24 * time.Hour))
+	eee := &federation.ExposureKey{ExposureKey: []byte("eeeeeeeeeeeeeeee"), IntervalNumber: intervalNumber, IntervalCount: 144}
+	fff := &federation.ExposureKey{ExposureKey: []byte("ffffffffffffffff"), IntervalNumber: intervalNumber, IntervalCount: 144}
+
+	firstPage := &federation.FederationFetchResponse{
+		Keys: []*federation.ExposureKey{
+			setRegions(setReportType(copyExposureKey(eee), federation.ExposureKey_CONFIRMED_TEST), "US"),
+		},
+		PartialResponse: true,
+		NextFetchState: &federation.FetchState{
+			KeyCursor:        &federation.Cursor{Timestamp: 100, NextToken: "page2"},
+			RevisedKeyCursor: &federation.Cursor{},
+		},
+	}
+	lastPage := &federation.FederationFetchResponse{
+		Keys: []*federation.ExposureKey{
+			setRegions(setReportType(copyExposureKey(fff), federation.ExposureKey_CONFIRMED_TEST), "US"),
+		},
+		NextFetchState: &federation.FetchState{
+			KeyCursor:        &federation.Cursor{Timestamp: 200},
+			RevisedKeyCursor: &federation.Cursor{},
+		},
+	}
+
+	cases := []struct {
+		name          string
+		streams       [][]*federation.FederationFetchResponse
+		wantErr       string
+		wantExposures []*publishmodel.Exposure
+		wantTokens    []string
+	}{
+		{
+			name: "single_stream",
+			streams: [][]*federation.FederationFetchResponse{
+				{firstPage, lastPage},
+			},
+			wantExposures: []*publishmodel.Exposure{
+				makeRemoteExposure(eee, "confirmed", []string{"US"}, false, time.Now()),
+				makeRemoteExposure(fff, "confirmed", []string{"US"}, false, time.Now()),
+			},
+			wantTokens: []string{""},
+		},
+		{
+			name: "resumes_ended_stream",
+			streams: [][]*federation.FederationFetchResponse{
+				{firstPage},
+				{lastPage},
+			},
+			wantExposures: []*publishmodel.Exposure{
+				makeRemoteExposure(eee, "confirmed", []string{"US"}, false, time.Now()),
+				makeRemoteExposure(fff, "confirmed", []string{"US"}, false, time.Now()),
+			},
+			wantTokens: []string{"", "page2"},
+		},
+		{
+			name: "empty_stream",
+			streams: [][]*federation.FederationFetchResponse{
+				{},
+			},
+			wantErr:    "stream ended without a response",
+			wantTokens: []string{""},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			remote := remoteStreamServer{streams: tc.streams}
+			idb := publishDB{}
+			sdb := syncDB{}
+
+			opts := pullOptions{
+				deps: pullDependencies{
+					fetchStream:         remote.fetchStream,
+					insertExposures:     idb.insertExposures,
+					startFederationSync: sdb.startFederationSync,
+				},
+				query: &model.FederationInQuery{
+					QueryID: queryID,
+				},
+				batchStart:                   time.Now(),
+				truncateWindow:               time.Hour,
+				maxIntervalStartAge:          14 * 24 * time.Hour,
+				maxMagnitudeSymptomOnsetDays: 14,
+			}
+
+			err := pull(ctx, &opts)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("pull returned err=%v, want %q", err, tc.wantErr)
+				}
+				if sdb.syncCompleted {
+					t.Errorf("federation sync completed after error")
+				}
+			} else if err != nil {
+				t.Fatalf("pull returned err=%v, want err=nil", err)
+			}
+
+			if diff := cmp.Diff(tc.wantExposures, idb.exposures, cmpopts.IgnoreFields(publishmodel.Exposure{}, "CreatedAt"), cmpopts.IgnoreUnexported(publishmodel.Exposure{})); diff != "" {
+				t.Errorf("exposures mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantTokens, remote.gotTokens); diff != "" {
+				t.Errorf("stream tokens mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 // makeRemoteExposure returns a mock publishmodel.Exposure with LocalProvenance=false.
 func makeRemoteExposure(diagKey *federation.ExposureKey, reportType string, regions []string, traveler bool, createdAt time.Time) *publishmodel.Exposure {
 	inf := makeExposure(diagKey, reportType, regions, traveler)
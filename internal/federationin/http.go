@@ -0,0 +1,94 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+
+	"go.opencensus.io/plugin/ochttp"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxFetchResponseBytes is the largest FederationFetchResponse read from the
+// HTTP+JSON gateway.
+const maxFetchResponseBytes = 64 << 20
+
+// httpFetcher calls Fetch on the HTTP+JSON gateway of a federation server.
+type httpFetcher struct {
+	client *http.Client
+	url    string
+}
+
+// newHTTPFetcher returns an httpFetcher for the server at serverAddr, in the
+// form some-server:some-port. If ts is not nil, its tokens are sent as bearer
+// tokens.
+func newHTTPFetcher(serverAddr string, tlsConfig *tls.Config, ts oauth2.TokenSource) *httpFetcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var rt http.RoundTripper = transport
+	if ts != nil {
+		rt = &oauth2.Transport{Source: ts, Base: rt}
+	}
+
+	return &httpFetcher{
+		client: &http.Client{Transport: &ochttp.Transport{Base: rt}},
+		url:    "https://" + serverAddr + "/v1/fetch",
+	}
+}
+
+// fetch implements fetchFn. The call options are ignored.
+func (f *httpFetcher) fetch(ctx context.Context, req *federation.FederationFetchRequest, _ ...grpc.CallOption) (*federation.FederationFetchResponse, error) {
+	b, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := f.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", f.url, err)
+	}
+	defer httpResp.Body.Close()
+
+	b, err = io.ReadAll(io.LimitReader(httpResp.Body, maxFetchResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d: %s", f.url, httpResp.StatusCode, bytes.TrimSpace(b))
+	}
+
+	// Ignore fields added to the response by newer servers.
+	var resp federation.FederationFetchResponse
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	response := &federation.FederationFetchResponse{
+		Keys: []*federation.ExposureKey{
+			{ExposureKey: []byte("aaaaaaaaaaaaaaaa"), IntervalNumber: 1, IntervalCount: 144, Regions: []string{"US"}},
+		},
+		PartialResponse: true,
+		NextFetchState: &federation.FetchState{
+			KeyCursor:        &federation.Cursor{Timestamp: 100, NextToken: "next"},
+			RevisedKeyCursor: &federation.Cursor{},
+		},
+	}
+
+	fedServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/fetch" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			http.Error(w, `{"error":"Missing credentials"}`, http.StatusUnauthorized)
+			return
+		}
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var req federation.FederationFetchRequest
+		if err := protojson.Unmarshal(b, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if got, want := req.GetState().GetKeyCursor().GetNextToken(), "start"; got != want {
+			http.Error(w, "unexpected cursor "+got, http.StatusBadRequest)
+			return
+		}
+
+		b, err = protojson.Marshal(response)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(b); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(fedServer.Close)
+
+	tlsConfig := fedServer.Client().Transport.(*http.Transport).TLSClientConfig
+
+	cases := []struct {
+		name    string
+		token   string
+		want    *federation.FederationFetchResponse
+		wantErr string
+	}{
+		{
+			name:  "success",
+			token: "token",
+			want:  response,
+		},
+		{
+			name:    "unauthorized",
+			token:   "other-token",
+			wantErr: "returned 401: {\"error\":\"Missing credentials\"}",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tc.token})
+			fetcher := newHTTPFetcher(fedServer.Listener.Addr().String(), tlsConfig, ts)
+
+			req := &federation.FederationFetchRequest{
+				IncludeRegions: []string{"US"},
+				State: &federation.FetchState{
+					KeyCursor: &federation.Cursor{NextToken: "start"},
+				},
+			}
+			got, err := fetcher.fetch(ctx, req)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -16,10 +16,16 @@
 package federationin
 
 import (
+	"context"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
 	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 var (
@@ -34,8 +40,45 @@ var (
 		"Pull revision", stats.UnitDimensionless)
 	mPullDropped = stats.Int64(publishMetricsPrefix+"pull_dropped",
 		"Pull dropped", stats.UnitDimensionless)
+	mSyncInsertions = stats.Int64(publishMetricsPrefix+"sync_insertions",
+		"Keys inserted or revised by a sync", stats.UnitDimensionless)
+	mSyncFailed = stats.Int64(publishMetricsPrefix+"sync_failed",
+		"Failed syncs", stats.UnitDimensionless)
+	mSyncLag = stats.Int64(publishMetricsPrefix+"sync_lag",
+		"Time since the newest key pulled for a query", stats.UnitSeconds)
+	mSyncConsecutiveFailures = stats.Int64(publishMetricsPrefix+"sync_consecutive_failures",
+		"Syncs that failed since the last successful sync of a query", stats.UnitDimensionless)
+
+	queryIDTag     = tag.MustNewKey("query_id")
+	queryIDTagKeys = []tag.Key{
+		queryIDTag,
+	}
 )
 
+// recordSyncResult records the outcome of a sync of the query.
+func recordSyncResult(ctx context.Context, queryID string, inserted int, syncErr error) {
+	measurements := []stats.Measurement{mSyncInsertions.M(int64(inserted))}
+	if syncErr != nil {
+		measurements = append(measurements, mSyncFailed.M(1))
+	}
+
+	tags := []tag.Mutator{tag.Upsert(queryIDTag, queryID)}
+	if err := stats.RecordWithTags(ctx, tags, measurements...); err != nil {
+		logging.FromContext(ctx).Errorw("failed to record sync result", "error", err, "query", queryID)
+	}
+}
+
+// recordSyncStatus records the lag and consecutive failures of the query, for
+// alerting.
+func recordSyncStatus(ctx context.Context, status *model.FederationInStatus, now time.Time) {
+	tags := []tag.Mutator{tag.Upsert(queryIDTag, status.QueryID)}
+	if err := stats.RecordWithTags(ctx, tags,
+		mSyncLag.M(int64(status.Lag(now).Seconds())),
+		mSyncConsecutiveFailures.M(int64(status.ConsecutiveFailures))); err != nil {
+		logging.FromContext(ctx).Errorw("failed to record sync status", "error", err, "query", status.QueryID)
+	}
+}
+
 func init() {
 	observability.CollectViews([]*view.View{
 		{
@@ -68,5 +111,33 @@ func init() {
 			Measure:     mPullDropped,
 			Aggregation: view.LastValue(),
 		},
+		{
+			Name:        metrics.MetricRoot + "federationin_sync_insertions_latest",
+			Description: "Keys inserted or revised by the last sync of each query",
+			Measure:     mSyncInsertions,
+			Aggregation: view.LastValue(),
+			TagKeys:     queryIDTagKeys,
+		},
+		{
+			Name:        metrics.MetricRoot + "federationin_sync_failed_count",
+			Description: "Total count of failed syncs of each query",
+			Measure:     mSyncFailed,
+			Aggregation: view.Sum(),
+			TagKeys:     queryIDTagKeys,
+		},
+		{
+			Name:        metrics.MetricRoot + "federationin_sync_lag_latest",
+			Description: "Seconds since the newest key pulled for each query",
+			Measure:     mSyncLag,
+			Aggregation: view.LastValue(),
+			TagKeys:     queryIDTagKeys,
+		},
+		{
+			Name:        metrics.MetricRoot + "federationin_sync_consecutive_failures_latest",
+			Description: "Failed syncs since the last successful sync of each query",
+			Measure:     mSyncConsecutiveFailures,
+			Aggregation: view.LastValue(),
+			TagKeys:     queryIDTagKeys,
+		},
 	}...)
 }
@@ -16,20 +16,69 @@
 package model
 
 import (
+	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/pb/federation"
 )
 
+var (
+	// ValidQueryIDStr is the regexp string of a valid query ID.
+	ValidQueryIDStr = `\A[a-z][a-z0-9-_]*[a-z0-9]\z`
+	// ValidQueryIDRegexp is the compiled regexp of a valid query ID.
+	ValidQueryIDRegexp = regexp.MustCompile(ValidQueryIDStr)
+
+	// ValidServerAddrStr is the regexp string of a valid server address.
+	ValidServerAddrStr = `\A[a-z0-9.-]+(:\d+)?\z`
+	// ValidServerAddrRegexp is the compiled regexp of a valid server address.
+	ValidServerAddrRegexp = regexp.MustCompile(ValidServerAddrStr)
+)
+
+// Credential types of a FederationInQuery, which determine how the puller
+// authenticates to the remote server.
+const (
+	// CredentialTypeGoogle sends a Google-signed ID token for the audience.
+	CredentialTypeGoogle = "GOOGLE"
+	// CredentialTypeOIDC sends a token obtained from the configured OpenID
+	// Connect token endpoint with the client credentials grant.
+	CredentialTypeOIDC = "OIDC"
+	// CredentialTypeStatic sends the configured static bearer token.
+	CredentialTypeStatic = "STATIC"
+	// CredentialTypeNone sends no token, for servers that authenticate the
+	// puller by its TLS client certificate.
+	CredentialTypeNone = "NONE"
+)
+
+// CredentialTypes are the valid credential types.
+var CredentialTypes = []string{CredentialTypeGoogle, CredentialTypeOIDC, CredentialTypeStatic, CredentialTypeNone}
+
+// Transports of a FederationInQuery, which determine how the puller calls the
+// remote server.
+const (
+	// TransportGRPC calls the Federation gRPC service.
+	TransportGRPC = "GRPC"
+	// TransportHTTP calls the HTTP+JSON gateway of the remote server, for
+	// proxies that don't support gRPC.
+	TransportHTTP = "HTTP"
+)
+
+// Transports are the valid transports.
+var Transports = []string{TransportGRPC, TransportHTTP}
+
 // FederationInQuery represents a configuration to pull federation results from other servers.
 type FederationInQuery struct {
 	QueryID             string
 	ServerAddr          string
 	Audience            string
+	CredentialType      string
+	Transport           string
 	IncludeRegions      []string
 	ExcludeRegions      []string
 	OnlyLocalProvenance bool
 	OnlyTravelers       bool
+	// Disabled queries are not pulled.
+	Disabled bool
 
 	// FetchState items.
 	LastTimestamp        time.Time
@@ -38,6 +87,43 @@ type FederationInQuery struct {
 	LastRevisedCursor    string
 }
 
+// Validate returns the problems with the query configuration, if any. This is
+// a utility method for the admin console.
+func (q *FederationInQuery) Validate() []string {
+	errors := make([]string, 0)
+	if !ValidQueryIDRegexp.MatchString(q.QueryID) {
+		errors = append(errors, fmt.Sprintf("Query ID must match %s", ValidQueryIDStr))
+	}
+	if !ValidServerAddrRegexp.MatchString(q.ServerAddr) {
+		errors = append(errors, fmt.Sprintf("Server address must match %s", ValidServerAddrStr))
+	}
+	if !oneOf(q.EffectiveCredentialType(), CredentialTypes) {
+		errors = append(errors, fmt.Sprintf("Credential type must be one of %v", CredentialTypes))
+	}
+	if !oneOf(q.EffectiveTransport(), Transports) {
+		errors = append(errors, fmt.Sprintf("Transport must be one of %v", Transports))
+	}
+	return errors
+}
+
+// EffectiveCredentialType returns the credential type of the query. Queries
+// without one use Google ID tokens.
+func (q *FederationInQuery) EffectiveCredentialType() string {
+	if q.CredentialType == "" {
+		return CredentialTypeGoogle
+	}
+	return q.CredentialType
+}
+
+// EffectiveTransport returns the transport of the query. Queries without one
+// use gRPC.
+func (q *FederationInQuery) EffectiveTransport() string {
+	if q.Transport == "" {
+		return TransportGRPC
+	}
+	return q.Transport
+}
+
 // UpdateFetchState updates the query state based on the fetch state returned from a federation pull.
 func (q *FederationInQuery) UpdateFetchState(fs *federation.FetchState) {
 	if fs.KeyCursor == nil {
@@ -57,6 +143,15 @@ func (q *FederationInQuery) UpdateFetchState(fs *federation.FetchState) {
 	}
 }
 
+// ResetFetchState clears the cursors, so the next pull starts from the given
+// time. A zero time pulls everything the remote server has.
+func (q *FederationInQuery) ResetFetchState(since time.Time) {
+	q.LastTimestamp = since
+	q.LastCursor = ""
+	q.LastRevisedTimestamp = since
+	q.LastRevisedCursor = ""
+}
+
 // FetchState returns the federation fetch state based on query state.
 func (q *FederationInQuery) FetchState() *federation.FetchState {
 	return &federation.FetchState{
@@ -80,6 +175,47 @@ type FederationInSync struct {
 	Insertions          int
 	MaxTimestamp        time.Time
 	MaxRevisedTimestamp time.Time
+	// Error is the reason the sync failed, empty if it succeeded.
+	Error string
+}
+
+// Failed returns true if the sync completed with an error.
+func (s *FederationInSync) Failed() bool {
+	return s.Error != ""
+}
+
+// Duration returns how long the sync took, or zero if it has not completed.
+func (s *FederationInSync) Duration() time.Duration {
+	if s.Completed.IsZero() {
+		return 0
+	}
+	return s.Completed.Sub(s.Started)
+}
+
+// FederationInStatus summarizes the syncs of a FederationInQuery, for
+// monitoring.
+type FederationInStatus struct {
+	QueryID  string
+	Disabled bool
+	// LastSync is the most recent sync of the query, or nil if it has never
+	// been synced.
+	LastSync *FederationInSync
+	// LastSuccess is when the most recent successful sync completed.
+	LastSuccess time.Time
+	// MaxTimestamp is the newest key timestamp pulled for the query.
+	MaxTimestamp time.Time
+	// ConsecutiveFailures is the number of syncs that failed since the last
+	// successful one.
+	ConsecutiveFailures int
+}
+
+// Lag returns how far behind the remote server the query is, the time since
+// the newest key pulled. It is zero if no keys have been pulled.
+func (s *FederationInStatus) Lag(now time.Time) time.Duration {
+	if s.MaxTimestamp.IsZero() || s.MaxTimestamp.After(now) {
+		return 0
+	}
+	return now.Sub(s.MaxTimestamp)
 }
 
 // FederationOutAuthorization is an authorized client that reads federation data from this server.
@@ -91,4 +227,28 @@ type FederationOutAuthorization struct {
 	Note           string
 	IncludeRegions []string
 	ExcludeRegions []string
+	// Disabled authorizations are rejected.
+	Disabled bool
+}
+
+// Validate returns the problems with the authorization, if any. This is a
+// utility method for the admin console.
+func (a *FederationOutAuthorization) Validate() []string {
+	errors := make([]string, 0)
+	if a.Issuer == "" {
+		errors = append(errors, "Issuer cannot be empty")
+	}
+	if a.Subject == "" {
+		errors = append(errors, "Subject cannot be empty")
+	}
+	return errors
+}
+
+func oneOf(value string, valid []string) bool {
+	for _, v := range valid {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,60 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFederationInStatusLag(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		maxTimestamp time.Time
+		want         time.Duration
+	}{
+		{
+			name: "never_pulled",
+			want: 0,
+		},
+		{
+			name:         "behind",
+			maxTimestamp: now.Add(-90 * time.Minute),
+			want:         90 * time.Minute,
+		},
+		{
+			name:         "future",
+			maxTimestamp: now.Add(time.Minute),
+			want:         0,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			status := &FederationInStatus{MaxTimestamp: tc.maxTimestamp}
+			if got := status.Lag(now); got != tc.want {
+				t.Errorf("Lag got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
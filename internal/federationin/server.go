@@ -16,12 +16,14 @@ package federationin
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/exposure-notifications-server/internal/federationin/database"
 	"github.com/google/exposure-notifications-server/internal/middleware"
 	publishdb "github.com/google/exposure-notifications-server/internal/publish/database"
 	"github.com/google/exposure-notifications-server/internal/serverenv"
 	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/render"
 	"github.com/google/exposure-notifications-server/pkg/server"
 	"github.com/gorilla/mux"
 )
@@ -31,14 +33,20 @@ type Server struct {
 	db        *database.FederationInDB
 	publishdb *publishdb.PublishDB
 	config    *Config
+	h         *render.Renderer
 }
 
 func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &Server{
 		env:       env,
 		db:        database.New(env.Database()),
 		publishdb: publishdb.New(env.Database()),
 		config:    cfg,
+		h:         render.NewRenderer(),
 	}, nil
 }
 
@@ -53,6 +61,7 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 
 	r.Handle("/health", server.HandleHealthz(s.env.Database()))
 	r.Handle("/", s.handleSync())
+	r.Handle("/status", s.handleStatus())
 
 	return r
 }
@@ -0,0 +1,151 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	coredb "github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// statusSyncHistory is the number of recent syncs returned for a single query.
+const statusSyncHistory = 20
+
+// SyncStatus is a sync of a federation in query.
+type SyncStatus struct {
+	SyncID              int64      `json:"syncID"`
+	Started             time.Time  `json:"started"`
+	Completed           *time.Time `json:"completed,omitempty"`
+	Insertions          int        `json:"insertions"`
+	MaxTimestamp        *time.Time `json:"maxTimestamp,omitempty"`
+	MaxRevisedTimestamp *time.Time `json:"maxRevisedTimestamp,omitempty"`
+	Error               string     `json:"error,omitempty"`
+}
+
+// QueryStatus is the sync status of a federation in query.
+type QueryStatus struct {
+	QueryID             string      `json:"queryID"`
+	Disabled            bool        `json:"disabled"`
+	LastSync            *SyncStatus `json:"lastSync,omitempty"`
+	LastSuccess         *time.Time  `json:"lastSuccess,omitempty"`
+	MaxTimestamp        *time.Time  `json:"maxTimestamp,omitempty"`
+	LagSeconds          int64       `json:"lagSeconds"`
+	ConsecutiveFailures int         `json:"consecutiveFailures"`
+
+	// RecentSyncs is only returned when the status of a single query is
+	// requested.
+	RecentSyncs []*SyncStatus `json:"recentSyncs,omitempty"`
+}
+
+// StatusResponse is the response of the status API.
+type StatusResponse struct {
+	Queries []*QueryStatus `json:"queries"`
+}
+
+// handleStatus returns the sync status of every query, or of the query in the
+// query-id parameter along with its recent syncs. The lag and failure gauges
+// of the returned queries are recorded too, so polling the status keeps them
+// current when syncs stop running.
+func (s *Server) handleStatus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.FromContext(ctx).Named("handleStatus")
+		now := time.Now()
+
+		var resp StatusResponse
+		if queryID := r.URL.Query().Get(queryParam); queryID != "" {
+			status, err := s.db.GetFederationInStatus(ctx, queryID)
+			if err != nil {
+				if errors.Is(err, coredb.ErrNotFound) {
+					s.h.RenderJSON(w, http.StatusNotFound, fmt.Errorf("unknown %s", queryParam))
+					return
+				}
+				logger.Errorw("failed to get sync status", "error", err, "query", queryID)
+				s.h.RenderJSON(w, http.StatusInternalServerError, nil)
+				return
+			}
+
+			syncs, err := s.db.ListFederationInSyncs(ctx, queryID, statusSyncHistory)
+			if err != nil {
+				logger.Errorw("failed to list syncs", "error", err, "query", queryID)
+				s.h.RenderJSON(w, http.StatusInternalServerError, nil)
+				return
+			}
+
+			recordSyncStatus(ctx, status, now)
+			queryStatus := newQueryStatus(status, now)
+			for _, sync := range syncs {
+				queryStatus.RecentSyncs = append(queryStatus.RecentSyncs, newSyncStatus(sync))
+			}
+			resp.Queries = []*QueryStatus{queryStatus}
+		} else {
+			statuses, err := s.db.ListFederationInStatuses(ctx)
+			if err != nil {
+				logger.Errorw("failed to list sync statuses", "error", err)
+				s.h.RenderJSON(w, http.StatusInternalServerError, nil)
+				return
+			}
+
+			resp.Queries = make([]*QueryStatus, 0, len(statuses))
+			for _, status := range statuses {
+				recordSyncStatus(ctx, status, now)
+				resp.Queries = append(resp.Queries, newQueryStatus(status, now))
+			}
+		}
+
+		s.h.RenderJSON(w, http.StatusOK, &resp)
+	})
+}
+
+func newQueryStatus(status *model.FederationInStatus, now time.Time) *QueryStatus {
+	qs := &QueryStatus{
+		QueryID:             status.QueryID,
+		Disabled:            status.Disabled,
+		LastSuccess:         timePtr(status.LastSuccess),
+		MaxTimestamp:        timePtr(status.MaxTimestamp),
+		LagSeconds:          int64(status.Lag(now).Seconds()),
+		ConsecutiveFailures: status.ConsecutiveFailures,
+	}
+	if status.LastSync != nil {
+		qs.LastSync = newSyncStatus(status.LastSync)
+	}
+	return qs
+}
+
+func newSyncStatus(sync *model.FederationInSync) *SyncStatus {
+	return &SyncStatus{
+		SyncID:              sync.SyncID,
+		Started:             sync.Started,
+		Completed:           timePtr(sync.Completed),
+		Insertions:          sync.Insertions,
+		MaxTimestamp:        timePtr(sync.MaxTimestamp),
+		MaxRevisedTimestamp: timePtr(sync.MaxRevisedTimestamp),
+		Error:               sync.Error,
+	}
+}
+
+// timePtr returns a pointer to t, or nil if t is zero, so zero times are
+// omitted from the JSON.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
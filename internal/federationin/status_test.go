@@ -0,0 +1,87 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/federationin/model"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewQueryStatus(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	started := now.Add(-time.Minute)
+	completed := now.Add(-30 * time.Second)
+	lastSuccess := now.Add(-time.Hour)
+	maxTimestamp := now.Add(-2 * time.Hour)
+
+	cases := []struct {
+		name   string
+		status *model.FederationInStatus
+		want   *QueryStatus
+	}{
+		{
+			name:   "never_synced",
+			status: &model.FederationInStatus{QueryID: "qid", Disabled: true},
+			want:   &QueryStatus{QueryID: "qid", Disabled: true},
+		},
+		{
+			name: "failing",
+			status: &model.FederationInStatus{
+				QueryID: "qid",
+				LastSync: &model.FederationInSync{
+					SyncID:     7,
+					Started:    started,
+					Completed:  completed,
+					Insertions: 2,
+					Error:      "remote unavailable",
+				},
+				LastSuccess:         lastSuccess,
+				MaxTimestamp:        maxTimestamp,
+				ConsecutiveFailures: 3,
+			},
+			want: &QueryStatus{
+				QueryID: "qid",
+				LastSync: &SyncStatus{
+					SyncID:     7,
+					Started:    started,
+					Completed:  &completed,
+					Insertions: 2,
+					Error:      "remote unavailable",
+				},
+				LastSuccess:         &lastSuccess,
+				MaxTimestamp:        &maxTimestamp,
+				LagSeconds:          7200,
+				ConsecutiveFailures: 3,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tc.want, newQueryStatus(tc.status, now)); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"google.golang.org/api/idtoken"
+	"google.golang.org/grpc/metadata"
+)
+
+// ErrNoCredentials is returned by an Authenticator when a request has no
+// credentials of the kind it accepts, so the next one can be tried.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Identity is the authenticated caller of a federation request. It is matched
+// to a FederationOutAuthorization by issuer and subject.
+type Identity struct {
+	Issuer    string
+	Subject   string
+	Audiences []string
+}
+
+func (i *Identity) hasAudience(aud string) bool {
+	for _, a := range i.Audiences {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator establishes the identity of the caller of a federation
+// request.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (*Identity, error)
+}
+
+// Authenticators tries each Authenticator in order, using the first one that
+// finds credentials on the request.
+type Authenticators []Authenticator
+
+// Authenticate implements Authenticator.
+func (a Authenticators) Authenticate(ctx context.Context) (*Identity, error) {
+	for _, auth := range a {
+		id, err := auth.Authenticate(ctx)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// NewAuthenticator returns the Authenticator for the config. Client
+// certificates are checked first, then the static bearer token, then tokens
+// from the configured OIDC issuer, then Google ID tokens.
+func NewAuthenticator(config *Config) Authenticator {
+	var auths Authenticators
+	if config.TLSClientCAFile != "" {
+		auths = append(auths, MTLSAuthenticator{})
+	}
+	if config.StaticBearerToken != "" {
+		auths = append(auths, &StaticAuthenticator{
+			Token:   config.StaticBearerToken,
+			Issuer:  config.StaticBearerIssuer,
+			Subject: config.StaticBearerSubject,
+		})
+	}
+	if config.OIDCIssuer != "" {
+		auths = append(auths, NewOIDCAuthenticator(config.OIDCIssuer, config.OIDCJWKSURL, config.OIDCJWKSRefresh))
+	}
+	return append(auths, GoogleAuthenticator{})
+}
+
+// StaticAuthenticator accepts a single configured bearer token, and identifies
+// its caller by the configured issuer and subject. There is no audience, so
+// authorizations for this caller must not require one.
+type StaticAuthenticator struct {
+	Token   string
+	Issuer  string
+	Subject string
+}
+
+// Authenticate implements Authenticator. Other bearer tokens are left to the
+// next Authenticator.
+func (a *StaticAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	raw, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(raw), []byte(a.Token)) != 1 {
+		return nil, ErrNoCredentials
+	}
+	return &Identity{
+		Issuer:  a.Issuer,
+		Subject: a.Subject,
+	}, nil
+}
+
+// GoogleAuthenticator accepts OIDC ID tokens signed by Google.
+type GoogleAuthenticator struct{}
+
+// Authenticate implements Authenticator.
+func (GoogleAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	raw, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := idtoken.Validate(ctx, raw, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		Issuer:    token.Issuer,
+		Subject:   token.Subject,
+		Audiences: []string{token.Audience},
+	}, nil
+}
+
+// bearerToken returns the bearer token of the request, or ErrNoCredentials if
+// there is no authorization header.
+func bearerToken(ctx context.Context) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); !ok || len(md[authHeader]) == 0 {
+		return "", ErrNoCredentials
+	}
+	return rawToken(ctx)
+}
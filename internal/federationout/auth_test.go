@@ -0,0 +1,572 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type authenticatorFunc func(ctx context.Context) (*Identity, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context) (*Identity, error) {
+	return f(ctx)
+}
+
+func TestAuthenticators(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	none := authenticatorFunc(func(context.Context) (*Identity, error) {
+		return nil, ErrNoCredentials
+	})
+	invalid := authenticatorFunc(func(context.Context) (*Identity, error) {
+		return nil, fmt.Errorf("invalid")
+	})
+	identity := func(iss string) Authenticator {
+		return authenticatorFunc(func(context.Context) (*Identity, error) {
+			return &Identity{Issuer: iss}, nil
+		})
+	}
+
+	cases := []struct {
+		name    string
+		auths   Authenticators
+		want    *Identity
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:    "no_credentials",
+			auths:   Authenticators{none, none},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:  "first_with_credentials",
+			auths: Authenticators{none, identity("a"), identity("b")},
+			want:  &Identity{Issuer: "a"},
+		},
+		{
+			name:    "invalid_stops",
+			auths:   Authenticators{invalid, identity("a")},
+			wantErr: "invalid",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tc.auths.Authenticate(ctx)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testJWKS serves a JWKS document with the given RSA keys, and counts the
+// requests for it.
+type testJWKS struct {
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	fail     bool
+	requests int
+}
+
+func (j *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.requests++
+	if j.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	keys := make([]map[string]string, 0, len(j.keys))
+	for kid, key := range j.keys {
+		keys = append(keys, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"n":   b64(key.N.Bytes()),
+			"e":   b64(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys}); err != nil {
+		panic(err)
+	}
+}
+
+func (j *testJWKS) set(fn func(j *testJWKS)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(j)
+}
+
+func bearerContext(ctx context.Context, token string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(authHeader, bearer+" "+token))
+}
+
+func TestOIDCAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&testJWKS{keys: map[string]*rsa.PublicKey{"k1": &key.PublicKey}})
+	t.Cleanup(srv.Close)
+
+	const issuer = "https://oidc.example.com"
+	auth := NewOIDCAuthenticator(issuer, srv.URL, time.Hour)
+
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": issuer,
+			"sub": "partner",
+			"aud": []string{"aud1", "aud2"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+	}
+	sign := func(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+		t.Helper()
+
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	cases := []struct {
+		name    string
+		token   func(t *testing.T) string
+		want    *Identity
+		wantErr string
+	}{
+		{
+			name: "valid",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", validClaims(), key)
+			},
+			want: &Identity{Issuer: issuer, Subject: "partner", Audiences: []string{"aud1", "aud2"}},
+		},
+		{
+			name: "valid_ps256_single_audience",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodPS256, "k1", withClaim("aud", "aud1"), key)
+			},
+			want: &Identity{Issuer: issuer, Subject: "partner", Audiences: []string{"aud1"}},
+		},
+		{
+			name: "no_token",
+			token: func(t *testing.T) string {
+				return ""
+			},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name: "not_a_jwt",
+			token: func(t *testing.T) string {
+				return "abc"
+			},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name: "other_issuer",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", withClaim("iss", "https://accounts.google.com"), key)
+			},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", withClaim("exp", now.Add(-time.Hour).Unix()), key)
+			},
+			wantErr: "invalid token",
+		},
+		{
+			name: "no_expiry",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", withClaim("exp", nil), key)
+			},
+			wantErr: "token has no expiry time",
+		},
+		{
+			name: "no_subject",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", withClaim("sub", nil), key)
+			},
+			wantErr: "token has no subject",
+		},
+		{
+			name: "no_kid",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "", validClaims(), key)
+			},
+			wantErr: "missing 'kid' header",
+		},
+		{
+			name: "unknown_kid",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k2", validClaims(), key)
+			},
+			wantErr: `unknown key ID "k2"`,
+		},
+		{
+			name: "wrong_key",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "k1", validClaims(), otherKey)
+			},
+			wantErr: "invalid token",
+		},
+		{
+			name: "key_type_mismatch",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodES256, "k1", validClaims(), ecKey)
+			},
+			wantErr: "invalid token",
+		},
+		{
+			name: "hmac",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodHS256, "k1", validClaims(), []byte("secret"))
+			},
+			wantErr: "unsupported signing method HS256",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := ctx
+			if token := tc.token(t); token != "" {
+				ctx = bearerContext(ctx, token)
+			}
+
+			got, err := auth.Authenticate(ctx)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := &testJWKS{keys: map[string]*rsa.PublicKey{"k1": &key1.PublicKey}}
+	srv := httptest.NewServer(jwks)
+	t.Cleanup(srv.Close)
+
+	start := time.Now()
+	now := start
+	ks := NewOIDCAuthenticator("issuer", srv.URL, time.Hour).keys
+	ks.now = func() time.Time { return now }
+
+	steps := []struct {
+		name     string
+		after    time.Duration
+		update   func(j *testJWKS)
+		kid      string
+		want     *rsa.PublicKey
+		wantErr  string
+		requests int
+	}{
+		{
+			name:     "initial_fetch",
+			kid:      "k1",
+			want:     &key1.PublicKey,
+			requests: 1,
+		},
+		{
+			name: "rotated_too_soon",
+			update: func(j *testJWKS) {
+				j.keys = map[string]*rsa.PublicKey{"k2": &key2.PublicKey}
+			},
+			after:    30 * time.Second,
+			kid:      "k2",
+			wantErr:  `unknown key ID "k2"`,
+			requests: 1,
+		},
+		{
+			name:     "rotated_refetch",
+			after:    2 * time.Minute,
+			kid:      "k2",
+			want:     &key2.PublicKey,
+			requests: 2,
+		},
+		{
+			name:     "cached",
+			after:    3 * time.Minute,
+			kid:      "k2",
+			want:     &key2.PublicKey,
+			requests: 2,
+		},
+		{
+			name:     "removed_key",
+			after:    4 * time.Minute,
+			kid:      "k1",
+			wantErr:  `unknown key ID "k1"`,
+			requests: 3,
+		},
+		{
+			name: "refresh_fails",
+			update: func(j *testJWKS) {
+				j.fail = true
+			},
+			after:    2 * time.Hour,
+			kid:      "k2",
+			want:     &key2.PublicKey,
+			requests: 4,
+		},
+	}
+
+	for _, step := range steps {
+		if step.update != nil {
+			jwks.set(step.update)
+		}
+		now = start.Add(step.after)
+
+		got, err := ks.key(ctx, step.kid)
+		if step.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+				t.Errorf("%s: expected error %q, got %v", step.name, step.wantErr, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %v", step.name, err)
+		} else if pub, ok := got.(*rsa.PublicKey); !ok || !pub.Equal(step.want) {
+			t.Errorf("%s: expected key %v, got %v", step.name, step.want, got)
+		}
+
+		jwks.mu.Lock()
+		requests := jwks.requests
+		jwks.mu.Unlock()
+		if requests != step.requests {
+			t.Errorf("%s: expected %d jwks requests, got %d", step.name, step.requests, requests)
+		}
+	}
+}
+
+func TestMTLSAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	leaf := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "puller", Organization: []string{"Partner"}},
+		Issuer:  pkix.Name{CommonName: "Partner CA"},
+	}
+
+	cases := []struct {
+		name    string
+		peer    *peer.Peer
+		want    *Identity
+		wantErr string
+	}{
+		{
+			name:    "no_peer",
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:    "no_tls",
+			peer:    &peer.Peer{},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name: "unverified_certificate",
+			peer: &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{leaf},
+			}}},
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name: "verified_certificate",
+			peer: &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{leaf},
+				VerifiedChains:   [][]*x509.Certificate{{leaf}},
+			}}},
+			want: &Identity{Issuer: "CN=Partner CA", Subject: "CN=puller,O=Partner"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := ctx
+			if tc.peer != nil {
+				ctx = peer.NewContext(ctx, tc.peer)
+			}
+
+			got, err := MTLSAuthenticator{}.Authenticate(ctx)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStaticAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	auth := &StaticAuthenticator{Token: "secret", Issuer: "static", Subject: "partner"}
+
+	cases := []struct {
+		name    string
+		token   string
+		want    *Identity
+		wantErr string
+	}{
+		{
+			name:    "no_token",
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:    "other_token",
+			token:   "other",
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:    "prefix",
+			token:   "secre",
+			wantErr: ErrNoCredentials.Error(),
+		},
+		{
+			name:  "valid",
+			token: "secret",
+			want:  &Identity{Issuer: "static", Subject: "partner"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := ctx
+			if tc.token != "" {
+				ctx = bearerContext(ctx, tc.token)
+			}
+
+			got, err := auth.Authenticate(ctx)
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticator_Static(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	auth := NewAuthenticator(&Config{
+		StaticBearerToken:   "secret",
+		StaticBearerIssuer:  "static",
+		StaticBearerSubject: "partner",
+	})
+
+	got, err := auth.Authenticate(bearerContext(ctx, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&Identity{Issuer: "static", Subject: "partner"}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
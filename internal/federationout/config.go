@@ -15,12 +15,14 @@
 package federationout
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/setup"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"github.com/google/exposure-notifications-server/pkg/secrets"
+	"github.com/hashicorp/go-multierror"
 )
 
 // Compile-time check to assert this config matches requirements.
@@ -41,6 +43,10 @@ type Config struct {
 	Timeout        time.Duration `env:"RPC_TIMEOUT, default=5m"`
 	TruncateWindow time.Duration `env:"TRUNCATE_WINDOW, default=1h"`
 
+	// HTTPPort, if set, is the port of the HTTP+JSON gateway, for partners
+	// that can't use gRPC. It uses the same TLS settings as the gRPC server.
+	HTTPPort string `env:"HTTP_PORT"`
+
 	// AllowAnyClient, if true, removes authentication requirements on the
 	// federation endpoint. In practice, this is only useful in local testing.
 	AllowAnyClient bool `env:"ALLOW_ANY_CLIENT"`
@@ -51,6 +57,59 @@ type Config struct {
 	// handled by the environment.
 	TLSCertFile string `env:"TLS_CERT_FILE"`
 	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	// TLSClientCAFile is a PEM file of the certificate authorities whose client
+	// certificates are accepted. If set, clients can authenticate with mutual
+	// TLS instead of a bearer token, and are identified by the issuer and
+	// subject distinguished names of their certificate. This requires
+	// TLSCertFile and TLSKeyFile.
+	TLSClientCAFile string `env:"TLS_CLIENT_CA_FILE"`
+
+	// OIDCIssuer and OIDCJWKSURL configure an OpenID Connect issuer, other than
+	// Google, whose tokens are accepted. Its signing keys are read from the JWKS
+	// URL and refreshed every OIDCJWKSRefresh, or sooner if a token is signed
+	// with an unknown key.
+	OIDCIssuer      string        `env:"OIDC_ISSUER"`
+	OIDCJWKSURL     string        `env:"OIDC_JWKS_URL"`
+	OIDCJWKSRefresh time.Duration `env:"OIDC_JWKS_REFRESH, default=1h"`
+
+	// StaticBearerToken, if set, is a bearer token that is accepted as the
+	// caller StaticBearerIssuer and StaticBearerSubject, for partners that
+	// send a STATIC credential.
+	StaticBearerToken   string `env:"STATIC_BEARER_TOKEN"` // may come from secret://
+	StaticBearerIssuer  string `env:"STATIC_BEARER_ISSUER, default=static"`
+	StaticBearerSubject string `env:"STATIC_BEARER_SUBJECT"`
+}
+
+// Validate checks that the server settings are consistent.
+func (c *Config) Validate() error {
+	var result *multierror.Error
+
+	if c.TLSClientCAFile != "" && (c.TLSCertFile == "" || c.TLSKeyFile == "") {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `TLS_CLIENT_CA_FILE` requires `TLS_CERT_FILE` and `TLS_KEY_FILE`"))
+	}
+
+	if c.HTTPPort != "" && c.HTTPPort == c.Port {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `HTTP_PORT` must be different from `PORT`"))
+	}
+
+	if (c.OIDCIssuer == "") != (c.OIDCJWKSURL == "") {
+		result = multierror.Append(result,
+			fmt.Errorf("env vars `OIDC_ISSUER` and `OIDC_JWKS_URL` must be set together"))
+	}
+	if c.OIDCJWKSRefresh <= 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `OIDC_JWKS_REFRESH` must be > 0, got: %v", c.OIDCJWKSRefresh))
+	}
+
+	if c.StaticBearerToken != "" && (c.StaticBearerIssuer == "" || c.StaticBearerSubject == "") {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATIC_BEARER_TOKEN` requires `STATIC_BEARER_ISSUER` and `STATIC_BEARER_SUBJECT`"))
+	}
+
+	return result.ErrorOrNil()
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
@@ -41,15 +41,15 @@ func (db *FederationOutDB) AddFederationOutAuthorization(ctx context.Context, au
 		q := `
 			INSERT INTO
 				FederationOutAuthorization
-				(oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions)
+				(oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions, disabled)
 			VALUES
-				($1, $2, $3, $4, $5, $6)
+				($1, $2, $3, $4, $5, $6, $7)
 			ON CONFLICT ON CONSTRAINT
 				federation_authorization_pk
 			DO UPDATE
-				SET oidc_audience = $3, note = $4, include_regions = $5, exclude_regions = $6
+				SET oidc_audience = $3, note = $4, include_regions = $5, exclude_regions = $6, disabled = $7
 		`
-		_, err := tx.Exec(ctx, q, auth.Issuer, auth.Subject, auth.Audience, auth.Note, auth.IncludeRegions, auth.ExcludeRegions)
+		_, err := tx.Exec(ctx, q, auth.Issuer, auth.Subject, auth.Audience, auth.Note, auth.IncludeRegions, auth.ExcludeRegions, auth.Disabled)
 		if err != nil {
 			return fmt.Errorf("upserting federation authorization: %w", err)
 		}
@@ -59,12 +59,12 @@ func (db *FederationOutDB) AddFederationOutAuthorization(ctx context.Context, au
 
 // GetFederationOutAuthorization returns a FederationOutAuthorization record, or ErrNotFound if not found.
 func (db *FederationOutDB) GetFederationOutAuthorization(ctx context.Context, issuer, subject string) (*model.FederationOutAuthorization, error) {
-	var auth model.FederationOutAuthorization
+	var auth *model.FederationOutAuthorization
 
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions
+				oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions, disabled
 			FROM
 				FederationOutAuthorization
 			WHERE
@@ -74,7 +74,9 @@ func (db *FederationOutDB) GetFederationOutAuthorization(ctx context.Context, is
 			LIMIT 1
 		`, issuer, subject)
 
-		if err := row.Scan(&auth.Issuer, &auth.Subject, &auth.Audience, &auth.Note, &auth.IncludeRegions, &auth.ExcludeRegions); err != nil {
+		var err error
+		auth, err = scanOneFederationOutAuthorization(row)
+		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return database.ErrNotFound
 			}
@@ -85,5 +87,51 @@ func (db *FederationOutDB) GetFederationOutAuthorization(ctx context.Context, is
 		return nil, fmt.Errorf("get authorized app: %w", err)
 	}
 
+	return auth, nil
+}
+
+// ListFederationOutAuthorizations returns all FederationOutAuthorization
+// records, ordered by issuer and subject.
+func (db *FederationOutDB) ListFederationOutAuthorizations(ctx context.Context) ([]*model.FederationOutAuthorization, error) {
+	var auths []*model.FederationOutAuthorization
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				oidc_issuer, oidc_subject, oidc_audience, note, include_regions, exclude_regions, disabled
+			FROM
+				FederationOutAuthorization
+			ORDER BY
+				oidc_issuer, oidc_subject
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			auth, err := scanOneFederationOutAuthorization(rows)
+			if err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			auths = append(auths, auth)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list federation out authorizations: %w", err)
+	}
+
+	return auths, nil
+}
+
+func scanOneFederationOutAuthorization(row pgx.Row) (*model.FederationOutAuthorization, error) {
+	var auth model.FederationOutAuthorization
+	if err := row.Scan(&auth.Issuer, &auth.Subject, &auth.Audience, &auth.Note, &auth.IncludeRegions, &auth.ExcludeRegions, &auth.Disabled); err != nil {
+		return nil, err
+	}
 	return &auth, nil
 }
@@ -59,6 +59,7 @@ func TestFederationOutAuthorization(t *testing.T) {
 
 	// AddFederationOutAuthorization should overwrite.
 	want.Note = "a different note"
+	want.Disabled = true
 	if err := New(testDB).AddFederationOutAuthorization(ctx, want); err != nil {
 		t.Fatal(err)
 	}
@@ -69,4 +70,20 @@ func TestFederationOutAuthorization(t *testing.T) {
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Errorf("mismatch (-want, +got):\n%s", diff)
 	}
+
+	// List all authorizations.
+	other := &model.FederationOutAuthorization{
+		Issuer:  "aaa",
+		Subject: "sub",
+	}
+	if err := New(testDB).AddFederationOutAuthorization(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	auths, err := New(testDB).ListFederationOutAuthorizations(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.FederationOutAuthorization{other, want}, auths); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
 }
@@ -35,7 +35,6 @@ import (
 	"go.opencensus.io/stats"
 
 	"github.com/google/exposure-notifications-server/internal/serverenv"
-	"google.golang.org/api/idtoken"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -53,20 +52,26 @@ var _ federation.FederationServer = (*Server)(nil)
 type iterateExposuresFunc func(context.Context, publishdb.IterateExposuresCriteria, publishdb.IteratorFunction) (string, error)
 
 // NewServer builds a new FederationServer.
-func NewServer(env *serverenv.ServerEnv, config *Config) federation.FederationServer {
-	return &Server{
-		env:       env,
-		db:        database.New(env.Database()),
-		publishdb: publishdb.New(env.Database()),
-		config:    config,
+func NewServer(env *serverenv.ServerEnv, config *Config) (federation.FederationServer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+
+	return &Server{
+		env:           env,
+		db:            database.New(env.Database()),
+		publishdb:     publishdb.New(env.Database()),
+		config:        config,
+		authenticator: NewAuthenticator(config),
+	}, nil
 }
 
 type Server struct {
-	env       *serverenv.ServerEnv
-	db        *database.FederationOutDB
-	publishdb *publishdb.PublishDB
-	config    *Config
+	env           *serverenv.ServerEnv
+	db            *database.FederationOutDB
+	publishdb     *publishdb.PublishDB
+	config        *Config
+	authenticator Authenticator
 }
 
 type authKey struct{}
@@ -87,6 +92,47 @@ func (s Server) Fetch(ctx context.Context, req *federation.FederationFetchReques
 	return response, nil
 }
 
+// FetchStream implements the FederationServer FetchStream endpoint. Pages are
+// sent until the caller has caught up or the request times out.
+func (s Server) FetchStream(req *federation.FederationFetchRequest, stream federation.Federation_FetchStreamServer) error {
+	ctx, cancel := context.WithTimeout(stream.Context(), s.config.Timeout)
+	defer cancel()
+
+	// Don't fetch the current window, which isn't complete yet.
+	fetchUntil := publishmodel.TruncateWindow(time.Now(), s.config.TruncateWindow)
+	return s.fetchStream(ctx, req, stream.Send, s.publishdb.IterateExposures, fetchUntil)
+}
+
+func (s Server) fetchStream(ctx context.Context, req *federation.FederationFetchRequest, send func(*federation.FederationFetchResponse) error, itFunc iterateExposuresFunc, fetchUntil time.Time) error {
+	logger := logging.FromContext(ctx).Named("federationout.FetchStream")
+
+	for pages := 1; ; pages++ {
+		response, err := s.fetch(ctx, req, itFunc, fetchUntil)
+		if err != nil {
+			stats.Record(ctx, mFetchFailed.M(1))
+			logger.Errorw("failed to fetch", "page", pages, "error", err)
+			return errors.New("internal error")
+		}
+		if err := send(response); err != nil {
+			return fmt.Errorf("failed to send page %d: %w", pages, err)
+		}
+		stats.Record(ctx, mFetchStreamPages.M(1))
+
+		// A fetch that timed out returns a partial response, the caller resumes
+		// from its state in a new request.
+		if !response.PartialResponse || ctx.Err() != nil {
+			logger.Infow("finished stream", "pages", pages, "partial", response.PartialResponse)
+			return nil
+		}
+		req.State = response.NextFetchState
+	}
+}
+
+// GetCapabilities implements the FederationServer GetCapabilities endpoint.
+func (s Server) GetCapabilities(ctx context.Context, req *federation.FederationCapabilitiesRequest) (*federation.FederationCapabilities, error) {
+	return &federation.FederationCapabilities{FetchStream: true}, nil
+}
+
 func (s Server) fetch(ctx context.Context, req *federation.FederationFetchRequest, itFunc iterateExposuresFunc, fetchUntil time.Time) (*federation.FederationFetchResponse, error) {
 	logger := logging.FromContext(ctx).Named("federationout.fetch")
 
@@ -333,45 +379,82 @@ func buildIteratorFunction(request *BuildIteratorRequest) publishdb.IteratorFunc
 	}
 }
 
-// AuthInterceptor validates incoming OIDC bearer token and adds corresponding FederationAuthorization record to the context.
+// AuthInterceptor authenticates the caller and adds the corresponding FederationAuthorization record to the context.
 func (s Server) AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	logger := logging.FromContext(ctx).Named("federationout.AuthInterceptor")
-
-	raw, err := rawToken(ctx)
+	ctx, err := s.authorize(ctx)
 	if err != nil {
-		logger.Infof("Invalid headers: %v", err)
 		return nil, err
 	}
+	return handler(ctx, req)
+}
 
-	token, err := idtoken.Validate(ctx, raw, "")
+// StreamAuthInterceptor is the AuthInterceptor for streaming RPCs.
+func (s Server) StreamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(ss.Context())
 	if err != nil {
-		logger.Infof("Invalid token: %v", err)
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorizedStream is a grpc.ServerStream with the FederationAuthorization on
+// its context.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize authenticates the caller, and returns a context with the
+// corresponding FederationAuthorization record.
+func (s Server) authorize(ctx context.Context) (context.Context, error) {
+	logger := logging.FromContext(ctx).Named("federationout.AuthInterceptor")
+
+	id, err := s.authenticator.Authenticate(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNoCredentials) {
+			logger.Infof("Missing credentials")
+			return nil, status.Errorf(codes.Unauthenticated, "Missing credentials")
+		}
+		if _, ok := status.FromError(err); ok {
+			logger.Infof("Invalid headers: %v", err)
+			return nil, err
+		}
+		logger.Infof("Invalid credentials: %v", err)
 		stats.Record(ctx, mFetchInvalidAuthToken.M(1))
 		return nil, status.Errorf(codes.Unauthenticated, "Invalid token")
 	}
 
-	auth, err := s.db.GetFederationOutAuthorization(ctx, token.Issuer, token.Subject)
+	auth, err := s.db.GetFederationOutAuthorization(ctx, id.Issuer, id.Subject)
 	if err != nil {
 		if errors.Is(err, coredb.ErrNotFound) {
 			stats.Record(ctx, mFetchUnauthorized.M(1))
-			logger.Infof("Authorization not found (issuer %q, subject %s)", token.Issuer, token.Subject)
+			logger.Infof("Authorization not found (issuer %q, subject %s)", id.Issuer, id.Subject)
 			return nil, status.Errorf(codes.Unauthenticated, "Invalid issuer/subject")
 		}
-		logger.Errorw("failed to fetch authorization", "issuer", token.Issuer, "subject", token.Subject, "error", err)
+		logger.Errorw("failed to fetch authorization", "issuer", id.Issuer, "subject", id.Subject, "error", err)
 		stats.Record(ctx, mFetchInternalError.M(1))
 		return nil, status.Errorf(codes.Internal, "Internal error")
 	}
 
-	if auth.Audience != "" && auth.Audience != token.Audience {
+	if auth.Disabled {
+		stats.Record(ctx, mFetchUnauthorized.M(1))
+		logger.Infof("Authorization disabled (issuer %q, subject %s)", id.Issuer, id.Subject)
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid issuer/subject")
+	}
+
+	if auth.Audience != "" && !id.hasAudience(auth.Audience) {
 		stats.Record(ctx, mFetchInvalidAudience.M(1))
-		logger.Infof("Invalid audience, got %q, want %q", token.Audience, auth.Audience)
+		logger.Infof("Invalid audience, got %q, want %q", id.Audiences, auth.Audience)
 		return nil, status.Errorf(codes.Unauthenticated, "Invalid audience")
 	}
 
 	// Store the FederationAuthorization on the context.
 	logger.Infof("Caller: issuer %q subject %q", auth.Issuer, auth.Subject)
-	ctx = context.WithValue(ctx, authKey{}, auth)
-	return handler(ctx, req)
+	return context.WithValue(ctx, authKey{}, auth), nil
 }
 
 func rawToken(ctx context.Context) (string, error) {
@@ -17,6 +17,8 @@ package federationout
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -546,6 +548,131 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+// pagedIterator serves exposures a page at a time, using the exposure index as
+// the cursor. Revised keys are never returned.
+type pagedIterator struct {
+	exposures []*model.Exposure
+}
+
+func (p *pagedIterator) provideInput(ctx context.Context, criteria publishdb.IterateExposuresCriteria, f publishdb.IteratorFunction) (string, error) {
+	if criteria.OnlyRevisedKeys {
+		return "", nil
+	}
+
+	start := 0
+	if criteria.LastCursor != "" {
+		var err error
+		if start, err = strconv.Atoi(criteria.LastCursor); err != nil {
+			return "", err
+		}
+	}
+
+	cursor := criteria.LastCursor
+	for i := start; i < len(p.exposures) && i < start+int(criteria.Limit); i++ {
+		if err := ctx.Err(); err != nil {
+			return cursor, err
+		}
+		if err := f(p.exposures[i]); err != nil {
+			return cursor, err
+		}
+		cursor = strconv.Itoa(i + 1)
+	}
+	return cursor, nil
+}
+
+// TestFetchStream tests the fetchStream() function.
+func TestFetchStream(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	eee := &federation.ExposureKey{ExposureKey: []byte("eeeeeeeeeeeeeeee"), IntervalNumber: 5}
+
+	cases := []struct {
+		name       string
+		cancel     bool
+		sendErr    error
+		wantErr    string
+		wantKeys   []int
+		wantTokens []string
+	}{
+		{
+			name:       "all_pages",
+			wantKeys:   []int{2, 2, 1},
+			wantTokens: []string{"2", "4", ""},
+		},
+		{
+			name:       "timeout",
+			cancel:     true,
+			wantKeys:   []int{0},
+			wantTokens: []string{""},
+		},
+		{
+			name:     "send_error",
+			sendErr:  fmt.Errorf("stream closed"),
+			wantErr:  "failed to send page 1: stream closed",
+			wantKeys: []int{2},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+
+			server := Server{
+				env: serverenv.New(ctx),
+				config: &Config{
+					MaxRecords: 2,
+				},
+			}
+			fakeDB := pagedIterator{
+				exposures: []*model.Exposure{
+					makeExposure(aaa, verifyapi.ReportTypeConfirmed, "US", false),
+					makeExposure(bbb, verifyapi.ReportTypeConfirmed, "US", false),
+					makeExposure(ccc, verifyapi.ReportTypeConfirmed, "US", false),
+					makeExposure(ddd, verifyapi.ReportTypeConfirmed, "US", false),
+					makeExposure(eee, verifyapi.ReportTypeConfirmed, "US", false),
+				},
+			}
+
+			var gotKeys []int
+			var gotTokens []string
+			send := func(resp *federation.FederationFetchResponse) error {
+				gotKeys = append(gotKeys, len(resp.Keys))
+				if tc.sendErr != nil {
+					return tc.sendErr
+				}
+				gotTokens = append(gotTokens, resp.NextFetchState.KeyCursor.NextToken)
+				return nil
+			}
+
+			req := &federation.FederationFetchRequest{}
+			err := server.fetchStream(ctx, req, send, fakeDB.provideInput, time.Now())
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("fetchStream() returned err=%v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("fetchStream() returned err=%v, want err=nil", err)
+			}
+
+			if diff := cmp.Diff(tc.wantKeys, gotKeys); diff != "" {
+				t.Errorf("page keys mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantTokens, gotTokens); diff != "" {
+				t.Errorf("page tokens mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 // TestRawToken tests rawToken().
 func TestRawToken(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,132 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/pb/federation"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/google/exposure-notifications-server/pkg/server"
+	"github.com/gorilla/mux"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxFetchRequestBytes is the largest FederationFetchRequest accepted over
+// HTTP. Requests only hold regions and cursors.
+const maxFetchRequestBytes = 64_000
+
+// httpError is the body of HTTP error responses.
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// Routes returns the HTTP+JSON gateway, for partners that can't use gRPC.
+// POST /v1/fetch behaves like the Fetch RPC, with the FederationFetchRequest
+// and FederationFetchResponse in the protocol buffers JSON mapping.
+func (s *Server) Routes(ctx context.Context) *mux.Router {
+	logger := logging.FromContext(ctx).Named("federationout")
+
+	r := mux.NewRouter()
+	r.Use(middleware.Recovery())
+	r.Use(middleware.PopulateRequestID())
+	r.Use(middleware.PopulateObservability())
+	r.Use(middleware.PopulateLogger(logger))
+
+	r.Handle("/health", server.HandleHealthz(s.env.Database()))
+	r.Handle("/v1/fetch", s.handleFetch()).Methods(http.MethodPost)
+
+	return r
+}
+
+func (s *Server) handleFetch() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := incomingContext(r)
+		logger := logging.FromContext(ctx).Named("federationout.handleFetch")
+
+		if !s.config.AllowAnyClient {
+			var err error
+			if ctx, err = s.authorize(ctx); err != nil {
+				code := http.StatusInternalServerError
+				if status.Code(err) == codes.Unauthenticated {
+					code = http.StatusUnauthorized
+				}
+				jsonutil.MarshalResponse(w, code, &httpError{Error: status.Convert(err).Message()})
+				return
+			}
+		}
+
+		if t := r.Header.Get("content-type"); !strings.HasPrefix(t, "application/json") {
+			jsonutil.MarshalResponse(w, http.StatusUnsupportedMediaType, &httpError{Error: "content-type is not application/json"})
+			return
+		}
+		b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFetchRequestBytes))
+		if err != nil {
+			jsonutil.MarshalResponse(w, http.StatusRequestEntityTooLarge, &httpError{Error: "failed to read body"})
+			return
+		}
+		// Unknown fields are ignored, like they are in gRPC.
+		var req federation.FederationFetchRequest
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, &req); err != nil {
+			jsonutil.MarshalResponse(w, http.StatusBadRequest, &httpError{Error: err.Error()})
+			return
+		}
+
+		resp, err := s.Fetch(ctx, &req)
+		if err != nil {
+			jsonutil.MarshalResponse(w, http.StatusInternalServerError, &httpError{Error: err.Error()})
+			return
+		}
+
+		b, err = protojson.Marshal(resp)
+		if err != nil {
+			logger.Errorw("failed to marshal response", "error", err)
+			jsonutil.MarshalResponse(w, http.StatusInternalServerError, &httpError{Error: "internal error"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(b); err != nil {
+			logger.Infow("failed to write response", "error", err)
+		}
+	})
+}
+
+// incomingContext returns the context of the HTTP request with its credentials
+// where the authenticators look for them in gRPC requests: the authorization
+// header in the incoming metadata, and the TLS connection state on the peer.
+func incomingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if h := r.Header.Values(authHeader); len(h) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.MD{authHeader: h})
+	}
+	if r.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: *r.TLS},
+		})
+	}
+	return ctx
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHandleFetch(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	cases := []struct {
+		name           string
+		allowAnyClient bool
+		contentType    string
+		body           string
+		wantCode       int
+		wantErr        string
+	}{
+		{
+			name:        "no_credentials",
+			contentType: "application/json",
+			body:        "{}",
+			wantCode:    http.StatusUnauthorized,
+			wantErr:     "Missing credentials",
+		},
+		{
+			name:           "wrong_content_type",
+			allowAnyClient: true,
+			contentType:    "text/plain",
+			body:           "{}",
+			wantCode:       http.StatusUnsupportedMediaType,
+			wantErr:        "content-type is not application/json",
+		},
+		{
+			name:           "invalid_json",
+			allowAnyClient: true,
+			contentType:    "application/json",
+			body:           `{"includeRegions": "US"}`,
+			wantCode:       http.StatusBadRequest,
+			wantErr:        `unexpected token "US"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := &Server{
+				config:        &Config{AllowAnyClient: tc.allowAnyClient},
+				authenticator: Authenticators{},
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/fetch", strings.NewReader(tc.body)).WithContext(ctx)
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			server.handleFetch().ServeHTTP(w, r)
+
+			if got := w.Code; got != tc.wantCode {
+				t.Errorf("expected status %d, got %d", tc.wantCode, got)
+			}
+			var got httpError
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got.Error, tc.wantErr) {
+				t.Errorf("expected error to contain %q, got %q", tc.wantErr, got.Error)
+			}
+		})
+	}
+}
+
+func TestIncomingContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	leaf := &x509.Certificate{
+		Subject: pkix.Name{CommonName: "puller", Organization: []string{"Partner"}},
+		Issuer:  pkix.Name{CommonName: "Partner CA"},
+	}
+
+	t.Run("no_credentials", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/fetch", nil).WithContext(ctx)
+		// httptest sets a TLS state for https URLs only.
+		r.TLS = nil
+
+		_, err := Authenticators{MTLSAuthenticator{}, GoogleAuthenticator{}}.Authenticate(incomingContext(r))
+		errcmp.MustMatch(t, err, ErrNoCredentials.Error())
+	})
+
+	t.Run("bearer_token", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/fetch", nil).WithContext(ctx)
+		r.Header.Set("Authorization", "Bearer abc123")
+
+		got, err := bearerToken(incomingContext(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "abc123"; got != want {
+			t.Errorf("expected token %q, got %q", want, got)
+		}
+	})
+
+	t.Run("client_certificate", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodPost, "https://example.com/v1/fetch", nil).WithContext(ctx)
+		r.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{leaf},
+			VerifiedChains:   [][]*x509.Certificate{{leaf}},
+		}
+
+		got, err := MTLSAuthenticator{}.Authenticate(incomingContext(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &Identity{Issuer: "CN=Partner CA", Subject: "CN=puller,O=Partner"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want, +got):\n%s", diff)
+		}
+	})
+}
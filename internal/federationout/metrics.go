@@ -35,6 +35,8 @@ var (
 		"Instances of fetch errors", stats.UnitDimensionless)
 	mFetchCount = stats.Int64(federationoutMetricsPrefix+"fetch_count",
 		"Fetch count value", stats.UnitDimensionless)
+	mFetchStreamPages = stats.Int64(federationoutMetricsPrefix+"fetch_stream_pages",
+		"Pages sent by FetchStream", stats.UnitDimensionless)
 	mFetchInvalidAuthToken = stats.Int64(federationoutMetricsPrefix+"fetch_invalid_auth_token",
 		"Instances of invalid auth tokens during fetch operations", stats.UnitDimensionless)
 	mFetchUnauthorized = stats.Int64(federationoutMetricsPrefix+"fetch_unauthorized",
@@ -77,6 +79,12 @@ func init() {
 			Measure:     mFetchCount,
 			Aggregation: view.LastValue(),
 		},
+		{
+			Name:        metrics.MetricRoot + "fetch_stream_pages_count",
+			Description: "Total count of pages sent by FetchStream",
+			Measure:     mFetchStreamPages,
+			Aggregation: view.Sum(),
+		},
 		{
 			Name:        metrics.MetricRoot + "fetch_invalid_auth_token_count",
 			Description: "Total count fo invalid auth tokens during fetch operations",
@@ -0,0 +1,82 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// MTLSAuthenticator identifies callers by the client certificate of a mutual
+// TLS connection. The issuer and subject of the Identity are the RFC 2253
+// distinguished names of the certificate's issuer and subject. There is no
+// audience, so authorizations for these callers must not require one.
+type MTLSAuthenticator struct{}
+
+// Authenticate implements Authenticator. Only certificates that were verified
+// against the configured client certificate authorities are used.
+func (MTLSAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	chains := info.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	leaf := chains[0][0]
+	return &Identity{
+		Issuer:  leaf.Issuer.String(),
+		Subject: leaf.Subject.String(),
+	}, nil
+}
+
+// ServerTLSConfig returns the TLS configuration of the server. If
+// TLSClientCAFile is set, clients may present a certificate issued by one of
+// those authorities, and connections with other certificates are rejected.
+// Clients without a certificate are still accepted, to authenticate with a
+// token.
+func ServerTLSConfig(config *Config) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if config.TLSClientCAFile != "" {
+		b, err := os.ReadFile(config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file %q: %w", config.TLSClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in client CA file %q", config.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
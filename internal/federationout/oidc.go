@@ -0,0 +1,210 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package federationout
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/exposure-notifications-server/internal/jwks"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+const (
+	// minKeySetFetchInterval limits how often the key set is fetched, so tokens
+	// with unknown key IDs can't be used to flood the JWKS endpoint.
+	minKeySetFetchInterval = time.Minute
+
+	// maxKeySetSize is the largest JWKS document that is read.
+	maxKeySetSize = 1 << 20
+)
+
+// oidcSigningMethods are the signing algorithms accepted for OIDC tokens.
+var oidcSigningMethods = []jwt.SigningMethod{
+	jwt.SigningMethodRS256,
+	jwt.SigningMethodPS256,
+	jwt.SigningMethodES256,
+}
+
+// OIDCAuthenticator accepts JWTs from a single OpenID Connect issuer, verified
+// with the keys published at its JWKS URL. Tokens from other issuers are left
+// to the next Authenticator.
+type OIDCAuthenticator struct {
+	issuer string
+	keys   *keySet
+}
+
+// NewOIDCAuthenticator creates an OIDCAuthenticator for the issuer, whose keys
+// are fetched from jwksURL and refreshed after the given interval.
+func NewOIDCAuthenticator(issuer, jwksURL string, refresh time.Duration) *OIDCAuthenticator {
+	return &OIDCAuthenticator{
+		issuer: issuer,
+		keys: &keySet{
+			url:     jwksURL,
+			client:  &http.Client{Timeout: 30 * time.Second},
+			refresh: refresh,
+			now:     time.Now,
+		},
+	}
+}
+
+// Authenticate implements Authenticator. The token must be signed by one of
+// the issuer's keys, have an expiry time and a subject.
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context) (*Identity, error) {
+	raw, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unverified := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(raw, unverified); err != nil {
+		return nil, ErrNoCredentials
+	}
+	if iss, _ := unverified["iss"].(string); iss != a.issuer {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if !isOIDCSigningMethod(token.Method) {
+			return nil, fmt.Errorf("unsupported signing method %v", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing 'kid' header in token")
+		}
+		return a.keys.key(ctx, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("token has no expiry time")
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &Identity{
+		Issuer:    a.issuer,
+		Subject:   sub,
+		Audiences: claimAudiences(claims),
+	}, nil
+}
+
+func isOIDCSigningMethod(method jwt.SigningMethod) bool {
+	for _, m := range oidcSigningMethods {
+		if m.Alg() == method.Alg() {
+			return true
+		}
+	}
+	return false
+}
+
+// claimAudiences returns the `aud` claim, which can be a string or a list of
+// strings.
+func claimAudiences(claims jwt.MapClaims) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		auds := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	default:
+		return nil
+	}
+}
+
+// keySet caches the keys of a JWKS URL.
+type keySet struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+	now     func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// key returns the public key with the given key ID. The keys are fetched again
+// once they are older than the refresh interval, or if the key ID is unknown.
+// If fetching fails, the previous keys continue to be used.
+func (ks *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+	pub, ok := ks.keys[kid]
+	stale := !ok || now.Sub(ks.fetchedAt) >= ks.refresh
+	if stale && now.Sub(ks.lastAttempt) >= minKeySetFetchInterval {
+		ks.lastAttempt = now
+		keys, err := ks.fetch(ctx)
+		if err != nil {
+			if ks.keys == nil {
+				return nil, err
+			}
+			logging.FromContext(ctx).Warnw("failed to refresh jwks, using previous keys", "url", ks.url, "error", err)
+		} else {
+			ks.keys = keys
+			ks.fetchedAt = now
+			pub, ok = keys[kid]
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return pub, nil
+}
+
+func (ks *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating jwks request: %w", err)
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks: unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading jwks: %w", err)
+	}
+
+	keys, err := jwks.ParseKeySet(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing jwks: %w", err)
+	}
+	return keys, nil
+}
@@ -33,12 +33,18 @@ const (
 
 // Unmarshal provides a common implementation of JSON unmarshalling with well defined error handling.
 func Unmarshal(w http.ResponseWriter, r *http.Request, data interface{}) (int, error) {
+	return UnmarshalWithLimit(w, r, data, maxBodyBytes)
+}
+
+// UnmarshalWithLimit is like Unmarshal, but reads at most maxBytes from the
+// request body instead of the default limit.
+func UnmarshalWithLimit(w http.ResponseWriter, r *http.Request, data interface{}, maxBytes int64) (int, error) {
 	if t := r.Header.Get("content-type"); len(t) < 16 || t[:16] != "application/json" {
 		return http.StatusUnsupportedMediaType, fmt.Errorf("content-type is not application/json")
 	}
 
 	defer r.Body.Close()
-	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
 
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
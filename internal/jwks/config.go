@@ -47,6 +47,15 @@ type Config struct {
 
 	// MaxWorkers is the number of parallel JWKS updates that can occur.
 	MaxWorkers uint `env:"MAX_WORKERS, default=5"`
+
+	// MaxRetries is how many times a failed JWKS fetch is retried, waiting
+	// RetryBackoff before the first retry and doubling it after each one.
+	MaxRetries   uint          `env:"MAX_RETRIES, default=3"`
+	RetryBackoff time.Duration `env:"RETRY_BACKOFF, default=1s"`
+
+	// FailureThreshold is the number of consecutive failed updates of a health
+	// authority's keys before they are reported as failing repeatedly.
+	FailureThreshold uint `env:"FAILURE_THRESHOLD, default=3"`
 }
 
 func (c *Config) DatabaseConfig() *database.Config {
@@ -0,0 +1,158 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/sethvargo/go-retry"
+)
+
+// maxCacheAge caps how long a JWKS document is considered fresh, regardless of
+// its caching headers, so that key rotations are picked up promptly.
+const maxCacheAge = time.Hour
+
+// fetchResult is the response to a JWKS document request.
+type fetchResult struct {
+	// body is the document, it is nil if notModified is true.
+	body        []byte
+	notModified bool
+
+	etag         string
+	lastModified string
+
+	// expires is when the document should be fetched again, it is zero if the
+	// document can't be cached.
+	expires time.Time
+}
+
+// fetchKeys reads the JWKS document of a single HealthAuthority. If status has
+// validators from a previous fetch, the request is conditional. Transient
+// failures are retried with exponential backoff.
+func (mgr *Manager) fetchKeys(ctx context.Context, ha *model.HealthAuthority, status *model.JWKSStatus) (*fetchResult, error) {
+	if !ha.JWKSEnabled() {
+		return &fetchResult{}, nil
+	}
+	jwksURI := *ha.JwksURI
+
+	b, err := retry.NewExponential(mgr.retryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure backoff: %w", err)
+	}
+	b = retry.WithMaxRetries(uint64(mgr.maxRetries), b)
+
+	var result *fetchResult
+	if err := retry.Do(ctx, b, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+		if err != nil {
+			return fmt.Errorf("creating connection: %w", err)
+		}
+		if status != nil {
+			if status.ETag != "" {
+				req.Header.Set("If-None-Match", status.ETag)
+			}
+			if status.LastModified != "" {
+				req.Header.Set("If-Modified-Since", status.LastModified)
+			}
+		}
+
+		resp, err := mgr.client.Do(req)
+		if err != nil {
+			return retry.RetryableError(fmt.Errorf("reading connection: %w", err))
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+		case resp.StatusCode == http.StatusNotModified && status != nil:
+			result = newFetchResult(resp, nil)
+			result.notModified = true
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return retry.RetryableError(fmt.Errorf("resp (%v) != %v", resp.StatusCode, http.StatusOK))
+		default:
+			return fmt.Errorf("resp (%v) != %v", resp.StatusCode, http.StatusOK)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return retry.RetryableError(fmt.Errorf("error reading: %w", err))
+		}
+		result = newFetchResult(resp, body)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func newFetchResult(resp *http.Response, body []byte) *fetchResult {
+	return &fetchResult{
+		body:         body,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		expires:      cacheExpiry(resp.Header, time.Now().UTC()),
+	}
+}
+
+// cacheExpiry returns when a response with the given headers, received at
+// now, goes stale. The Cache-Control max-age directive takes precedence over
+// the Expires header. A zero time is returned if the response can't be cached.
+func cacheExpiry(header http.Header, now time.Time) time.Time {
+	var expires time.Time
+	maxAgeSet := false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return time.Time{}
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(directive, "max-age="), 10, 64)
+			if err != nil || seconds <= 0 {
+				return time.Time{}
+			}
+			maxAgeSet = true
+			age := maxCacheAge
+			if seconds < int64(maxCacheAge/time.Second) {
+				age = time.Duration(seconds) * time.Second
+			}
+			expires = now.Add(age)
+		}
+	}
+
+	if !maxAgeSet {
+		v := header.Get("Expires")
+		if v == "" {
+			return time.Time{}
+		}
+		t, err := http.ParseTime(v)
+		if err != nil || !t.After(now) {
+			return time.Time{}
+		}
+		expires = t.UTC()
+	}
+
+	if limit := now.Add(maxCacheAge); expires.After(limit) {
+		expires = limit
+	}
+	return expires
+}
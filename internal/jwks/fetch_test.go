@@ -0,0 +1,346 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestCacheExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		header http.Header
+		want   time.Time
+	}{
+		{
+			name:   "none",
+			header: http.Header{},
+		},
+		{
+			name:   "max_age",
+			header: http.Header{"Cache-Control": []string{"public, max-age=300"}},
+			want:   now.Add(5 * time.Minute),
+		},
+		{
+			name:   "max_age_capped",
+			header: http.Header{"Cache-Control": []string{"max-age=86400"}},
+			want:   now.Add(maxCacheAge),
+		},
+		{
+			name:   "no_cache",
+			header: http.Header{"Cache-Control": []string{"max-age=300, no-cache"}},
+		},
+		{
+			name:   "invalid_max_age",
+			header: http.Header{"Cache-Control": []string{"max-age=soon"}},
+		},
+		{
+			name:   "expires",
+			header: http.Header{"Expires": []string{now.Add(10 * time.Minute).Format(http.TimeFormat)}},
+			want:   now.Add(10 * time.Minute),
+		},
+		{
+			name: "max_age_over_expires",
+			header: http.Header{
+				"Cache-Control": []string{"max-age=60"},
+				"Expires":       []string{now.Add(10 * time.Minute).Format(http.TimeFormat)},
+			},
+			want: now.Add(time.Minute),
+		},
+		{
+			name:   "expired",
+			header: http.Header{"Expires": []string{now.Add(-time.Minute).Format(http.TimeFormat)}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := cacheExpiry(tc.header, now); !got.Equal(tc.want) {
+				t.Errorf("expected %v to be %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFetchKeys(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name         string
+		failures     int32
+		failureCode  int
+		maxRetries   uint
+		status       *model.JWKSStatus
+		wantRequests int32
+		wantModified bool
+		err          string
+	}{
+		{
+			name:         "success",
+			wantRequests: 1,
+			wantModified: true,
+		},
+		{
+			name:         "not_modified",
+			status:       &model.JWKSStatus{ETag: `"v1"`},
+			wantRequests: 1,
+		},
+		{
+			name:         "changed",
+			status:       &model.JWKSStatus{ETag: `"v0"`},
+			wantRequests: 1,
+			wantModified: true,
+		},
+		{
+			name:         "retried",
+			failures:     2,
+			failureCode:  http.StatusServiceUnavailable,
+			maxRetries:   2,
+			wantRequests: 3,
+			wantModified: true,
+		},
+		{
+			name:         "retries_exhausted",
+			failures:     2,
+			failureCode:  http.StatusServiceUnavailable,
+			maxRetries:   1,
+			wantRequests: 2,
+			err:          "resp (503) != 200",
+		},
+		{
+			name:         "not_retried",
+			failures:     1,
+			failureCode:  http.StatusNotFound,
+			maxRetries:   2,
+			wantRequests: 1,
+			err:          "resp (404) != 200",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if n := atomic.AddInt32(&requests, 1); n <= tc.failures {
+					w.WriteHeader(tc.failureCode)
+					return
+				}
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Cache-Control", "max-age=60")
+				fmt.Fprint(w, encodeKeys(key1))
+			}))
+			defer ts.Close()
+
+			ctx := project.TestContext(t)
+			mgr, err := NewManager(nil, time.Minute, 5*time.Second, 1, WithRetries(tc.maxRetries, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ha := &model.HealthAuthority{JwksURI: &ts.URL}
+			resp, err := mgr.fetchKeys(ctx, ha, tc.status)
+			errcmp.MustMatch(t, err, tc.err)
+
+			if got, want := atomic.LoadInt32(&requests), tc.wantRequests; got != want {
+				t.Errorf("expected %d requests to be %d", got, want)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := !resp.notModified, tc.wantModified; got != want {
+				t.Errorf("expected modified %t to be %t", got, want)
+			}
+			if resp.expires.IsZero() {
+				t.Errorf("expected response to be cacheable")
+			}
+			if tc.wantModified {
+				if got, want := resp.etag, `"v1"`; got != want {
+					t.Errorf("expected %q to be %q", got, want)
+				}
+				if got, want := string(resp.body), encodeKeys(key1); got != want {
+					t.Errorf("expected %q to be %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateHA_Status(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := hadb.New(testDB)
+
+	var (
+		fail     int32
+		document atomic.Value
+		requests int32
+	)
+	document.Store(encodeKeys(key1))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		etag := fmt.Sprintf("%q", fmt.Sprint(len(document.Load().(string))))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, document.Load().(string))
+	}))
+	defer ts.Close()
+
+	mgr, err := NewManager(testDB, time.Minute, 5*time.Second, 1, WithFailureThreshold(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ha := &model.HealthAuthority{Issuer: "iss", Audience: "aud", Name: "name", JwksURI: &ts.URL}
+	if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+
+	getStatus := func(t *testing.T) *model.JWKSStatus {
+		t.Helper()
+		status, err := haDB.GetJWKSStatus(ctx, ha.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	// The first fetch records the key set.
+	if err := mgr.updateHA(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+	status := getStatus(t)
+	if status.LastSuccessAt.IsZero() || status.ETag == "" || status.KeyFingerprint == "" {
+		t.Errorf("expected successful fetch to be recorded, got %#v", status)
+	}
+	firstFingerprint := status.KeyFingerprint
+
+	// Unchanged documents aren't processed again.
+	if err := mgr.updateHA(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+	if got := getStatus(t).KeyFingerprint; got != firstFingerprint {
+		t.Errorf("expected %q to be %q", got, firstFingerprint)
+	}
+
+	// Failures are counted.
+	atomic.StoreInt32(&fail, 1)
+	for i := 1; i <= 2; i++ {
+		if err := mgr.updateHA(ctx, ha); err == nil {
+			t.Fatal("expected error")
+		}
+		status := getStatus(t)
+		if got, want := status.ConsecutiveFailures, i; got != want {
+			t.Errorf("expected %d to be %d", got, want)
+		}
+		if status.LastError == "" || status.LastErrorAt.IsZero() {
+			t.Errorf("expected failure to be recorded, got %#v", status)
+		}
+	}
+
+	// A changed key set is added to the history, and resets the failures.
+	atomic.StoreInt32(&fail, 0)
+	document.Store(encodeKeys(key1, key2))
+	if err := mgr.updateHA(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+	status = getStatus(t)
+	if got, want := status.ConsecutiveFailures, 0; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if status.KeyFingerprint == firstFingerprint {
+		t.Errorf("expected fingerprint to change")
+	}
+
+	keySets, err := haDB.ListJWKSKeySets(ctx, ha.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(keySets), 2; got != want {
+		t.Fatalf("expected %d key sets to be %d", got, want)
+	}
+	if got, want := keySets[0].KeyCount, 2; got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := keySets[1].Fingerprint, firstFingerprint; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	// Documents that can be cached aren't fetched until they expire, but revoked
+	// keys are still purged.
+	status.NextFetchAt = time.Now().Add(time.Hour)
+	if err := haDB.SaveJWKSStatus(ctx, status, nil); err != nil {
+		t.Fatal(err)
+	}
+	revoked := &model.HealthAuthorityKey{
+		Version:      "revoked",
+		From:         time.Now().Add(-2 * time.Hour),
+		Thru:         time.Now().Add(-1 * time.Hour),
+		PublicKeyPEM: encodeKey(enc1),
+	}
+	if err := haDB.AddHealthAuthorityKey(ctx, ha, revoked); err != nil {
+		t.Fatal(err)
+	}
+	before := atomic.LoadInt32(&requests)
+	if err := mgr.updateHA(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&requests); got != before {
+		t.Errorf("expected no request while cached, got %d requests", got-before)
+	}
+	keys, err := haDB.GetHealthAuthorityKeys(ctx, ha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if key.Version == revoked.Version {
+			t.Errorf("expected revoked key to be purged")
+		}
+	}
+}
@@ -18,11 +18,18 @@ package jwks
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
 	"sort"
@@ -38,6 +45,7 @@ import (
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/hashicorp/go-multierror"
 	"github.com/rakutentech/jwk-go/jwk"
+	"go.opencensus.io/stats"
 	"golang.org/x/sync/semaphore"
 )
 
@@ -48,10 +56,34 @@ type Manager struct {
 	client     *http.Client
 	cleanupTTL time.Duration
 	maxWorkers uint
+
+	maxRetries       uint
+	retryBackoff     time.Duration
+	failureThreshold uint
+}
+
+// Option configures optional Manager behavior.
+type Option func(*Manager)
+
+// WithRetries sets how many times a failed fetch is retried, and the initial
+// backoff between attempts, which doubles after each one.
+func WithRetries(maxRetries uint, backoff time.Duration) Option {
+	return func(mgr *Manager) {
+		mgr.maxRetries = maxRetries
+		mgr.retryBackoff = backoff
+	}
+}
+
+// WithFailureThreshold sets how many consecutive failed fetches for a health
+// authority are tolerated before they are reported as repeated failures.
+func WithFailureThreshold(threshold uint) Option {
+	return func(mgr *Manager) {
+		mgr.failureThreshold = threshold
+	}
 }
 
 // NewManager creates a new Manager.
-func NewManager(db *database.DB, cleanupTTL, requestTimeout time.Duration, maxWorkers uint) (*Manager, error) {
+func NewManager(db *database.DB, cleanupTTL, requestTimeout time.Duration, maxWorkers uint, opts ...Option) (*Manager, error) {
 	if cleanupTTL < 0 {
 		cleanupTTL *= -1
 	}
@@ -60,76 +92,183 @@ func NewManager(db *database.DB, cleanupTTL, requestTimeout time.Duration, maxWo
 		Timeout: requestTimeout,
 	}
 
-	return &Manager{
+	mgr := &Manager{
 		db:         db,
 		client:     client,
 		cleanupTTL: cleanupTTL,
 		maxWorkers: maxWorkers,
-	}, nil
-}
 
-// getKeys reads the keys for a single HealthAuthority from its jwks server.
-func (mgr *Manager) getKeys(ctx context.Context, ha *model.HealthAuthority) ([]byte, error) {
-	if ha.JwksURI == nil {
-		return nil, nil
-	}
-	jwksURI := *ha.JwksURI
-	if len(jwksURI) == 0 {
-		return nil, nil
+		maxRetries:       0,
+		retryBackoff:     time.Second,
+		failureThreshold: 3,
 	}
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating connection: %w", err)
+	for _, opt := range opts {
+		opt(mgr)
 	}
-
-	resp, err := mgr.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("reading connection: %w", err)
+	if mgr.retryBackoff <= 0 {
+		return nil, fmt.Errorf("retry backoff must be positive, got %v", mgr.retryBackoff)
 	}
-	defer resp.Body.Close()
+	return mgr, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("resp (%v) != %v", resp.StatusCode, http.StatusOK)
-	}
+// keyInfo is the metadata of a key read from a JWKS endpoint.
+type keyInfo struct {
+	version   string
+	algorithm string
+}
 
-	var bytes []byte
-	bytes, err = io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading: %w", err)
-	}
-	return bytes, nil
+// jwkFields are the fields of a JWK that are read directly, rather than through
+// jwk.JWK, which does not support Ed25519 keys.
+type jwkFields struct {
+	KeyID   string `json:"kid"`
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Alg     string `json:"alg"`
 }
 
 // parseKeys parses the json response, returning the pem encoded public keys,
-// and versions.
-func parseKeys(data []byte) ([]string, map[string]string, error) {
+// and their versions and algorithms. Keys that can't be parsed, or whose
+// algorithm isn't supported, are skipped with a warning so they don't affect
+// the other keys. If the response has keys but none of them can be used, an
+// error is returned, rather than revoking every key.
+func parseKeys(ctx context.Context, data []byte) ([]string, map[string]*keyInfo, error) {
+	logger := logging.FromContext(ctx).Named("parseKeys")
+
 	if len(data) == 0 {
 		return nil, nil, nil
 	}
 
-	var jwks []jwk.JWK
+	var jwks []json.RawMessage
 	if err := json.Unmarshal(data, &jwks); err != nil {
 		return nil, nil, fmt.Errorf("unmarshal error: %w", err)
 	}
 
-	keys := make([]string, len(jwks))
-	versions := make(map[string]string, len(jwks))
+	keys := make([]string, 0, len(jwks))
+	infos := make(map[string]*keyInfo, len(jwks))
+	var skipErr error
 	for i := range jwks {
-		spec, err := jwks[i].ParseKeySpec()
+		var fields jwkFields
+		if err := json.Unmarshal(jwks[i], &fields); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal error: %w", err)
+		}
+
+		pub, kid, err := parseKey(jwks[i], &fields)
 		if err != nil {
-			return nil, nil, fmt.Errorf("parse error: %w", err)
+			skipErr = fmt.Errorf("parse error: %w", err)
+			logger.Warnw("skipping jwks key", "kid", fields.KeyID, "error", skipErr)
+			continue
+		}
+		alg, err := keyAlgorithm(fields.Alg, pub)
+		if err != nil {
+			skipErr = fmt.Errorf("key %v: %w", kid, err)
+			logger.Warnw("skipping jwks key", "kid", kid, "error", skipErr)
+			continue
 		}
 
 		var encoded []byte
-		encoded, err = x509.MarshalPKIXPublicKey(spec.Key)
+		encoded, err = x509.MarshalPKIXPublicKey(pub)
 		if err != nil {
 			return nil, nil, fmt.Errorf("marshal error: %w", err)
 		}
-		keys[i] = project.TrimSpaceAndNonPrintable(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encoded})))
-		versions[keys[i]] = spec.KeyID
+		key := project.TrimSpaceAndNonPrintable(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: encoded})))
+		keys = append(keys, key)
+		infos[key] = &keyInfo{version: kid, algorithm: alg}
+	}
+	if len(keys) == 0 && skipErr != nil {
+		return nil, nil, fmt.Errorf("no supported keys: %w", skipErr)
+	}
+	return keys, infos, nil
+}
+
+// parseKey returns the public key and key ID of a single JWK. Ed25519 keys
+// ("kty": "OKP") are decoded here, all other key types are left to jwk.JWK.
+func parseKey(raw json.RawMessage, fields *jwkFields) (crypto.PublicKey, string, error) {
+	if fields.KeyType == "OKP" {
+		if fields.Curve != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported OKP curve: %q", fields.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(fields.X)
+		if err != nil {
+			return nil, "", fmt.Errorf("decoding x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, "", fmt.Errorf("invalid Ed25519 key size %d", len(x))
+		}
+		return ed25519.PublicKey(x), fields.KeyID, nil
+	}
+
+	var key jwk.JWK
+	if err := json.Unmarshal(raw, &key); err != nil {
+		return nil, "", err
+	}
+	spec, err := key.ParseKeySpec()
+	if err != nil {
+		return nil, "", err
+	}
+	return spec.Key, spec.KeyID, nil
+}
+
+// ParseKeySet parses a standard JWKS document, `{"keys": [...]}`, returning
+// the public keys by key ID. Every key must have a unique, non-empty key ID.
+func ParseKeySet(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, raw := range set.Keys {
+		var fields jwkFields
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("unmarshal error: %w", err)
+		}
+
+		pub, kid, err := parseKey(raw, &fields)
+		if err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		}
+		if kid == "" {
+			return nil, fmt.Errorf("key is missing a key ID")
+		}
+		if _, ok := keys[kid]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", kid)
+		}
+		keys[kid] = pub
 	}
-	return keys, versions, nil
+	return keys, nil
+}
+
+// keyAlgorithm returns the signing algorithm for a key. The JWK "alg" is used
+// if present, and must match the key type, otherwise the algorithm is chosen
+// from the key type.
+func keyAlgorithm(alg string, pub crypto.PublicKey) (string, error) {
+	var keyAlg string
+	switch typ := pub.(type) {
+	case *ecdsa.PublicKey:
+		if typ.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported curve: %s", typ.Curve.Params().Name)
+		}
+		keyAlg = model.AlgorithmES256
+	case *rsa.PublicKey:
+		keyAlg = model.AlgorithmPS256
+	case ed25519.PublicKey:
+		keyAlg = model.AlgorithmEdDSA
+	default:
+		return "", fmt.Errorf("unsupported key type: %T", typ)
+	}
+
+	if alg == "" || alg == keyAlg {
+		return keyAlg, nil
+	}
+	for _, a := range model.Algorithms {
+		if a == alg {
+			return "", fmt.Errorf("alg %q does not match key type %T", alg, pub)
+		}
+	}
+	return "", fmt.Errorf("unsupported alg: %q", alg)
 }
 
 // stripKey strips pem signature from a key.
@@ -170,7 +309,25 @@ func findKeyMods(ha *model.HealthAuthority, rxKeys []string) (deadKeys []int, ne
 	return
 }
 
-// updateHA updates HealthAuthority's keys.
+// keySetFingerprint returns an identifier for a set of PEM encoded keys, which
+// doesn't depend on their order.
+func keySetFingerprint(keys []string) string {
+	stripped := make([]string, len(keys))
+	for i, key := range keys {
+		stripped[i] = stripKey(key)
+	}
+	sort.Strings(stripped)
+
+	h := sha256.New()
+	for _, key := range stripped {
+		h.Write([]byte(key))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// updateHA updates HealthAuthority's keys, and records the outcome in the
+// health authority's JWKS status.
 func (mgr *Manager) updateHA(ctx context.Context, ha *model.HealthAuthority) error {
 	logger := logging.FromContext(ctx).Named("updateHA").
 		With("health_authority_name", ha.Name).
@@ -183,31 +340,77 @@ func (mgr *Manager) updateHA(ctx context.Context, ha *model.HealthAuthority) err
 	// Create the hadb once to save allocations
 	haDB := hadb.New(mgr.db)
 
-	purgeBefore := time.Now().UTC().Add(-1 * mgr.cleanupTTL)
+	status, err := haDB.GetJWKSStatus(ctx, ha.ID)
+	if err != nil {
+		return fmt.Errorf("error getting jwks status: %w", err)
+	}
+	now := time.Now().UTC()
+
+	// Revoked keys are purged even while the cached document is fresh, so that
+	// long cache lifetimes don't delay the cleanup.
+	purgeBefore := now.Add(-1 * mgr.cleanupTTL)
 	if count, err := haDB.PurgeHealthAuthorityKeys(ctx, ha, purgeBefore); err != nil {
 		logger.Errorw("error purging expired health authority keys", "error", err)
 	} else if count > 0 {
 		logger.Infow("purged health authority keys", "count", count)
 	}
 
+	if now.Before(status.NextFetchAt) {
+		logger.Debugw("skipping, cached jwks is still fresh", "next_fetch_at", status.NextFetchAt)
+		return nil
+	}
+
+	keySet, err := mgr.syncKeys(ctx, haDB, ha, status)
+	if err != nil {
+		return mgr.recordFailure(ctx, haDB, ha, status, err)
+	}
+
+	status.LastSuccessAt = now
+	status.ConsecutiveFailures = 0
+	if err := haDB.SaveJWKSStatus(ctx, status, keySet); err != nil {
+		return fmt.Errorf("error saving jwks status: %w", err)
+	}
+	return nil
+}
+
+// syncKeys fetches the JWKS document and updates the health authority's keys
+// to match it. On success, the status is updated with the document's
+// validators and key fingerprint. If the key set differs from the last one
+// seen, it is returned so it can be added to the history.
+func (mgr *Manager) syncKeys(ctx context.Context, haDB *hadb.HealthAuthorityDB, ha *model.HealthAuthority, status *model.JWKSStatus) (*model.JWKSKeySet, error) {
+	logger := logging.FromContext(ctx).Named("syncKeys").
+		With("health_authority_name", ha.Name).
+		With("health_authority_id", ha.ID)
+
 	// Get the keys for the health authority
 	keys, err := haDB.GetHealthAuthorityKeys(ctx, ha)
 	if err != nil {
-		return fmt.Errorf("error getting keys: %w", err)
+		return nil, fmt.Errorf("error getting keys: %w", err)
 	}
 
 	ha.Keys = keys
 
-	resp, err := mgr.getKeys(ctx, ha)
+	// Without a fingerprint, the keys have never been processed, so the
+	// document must be read in full.
+	conditional := status
+	if status.KeyFingerprint == "" {
+		conditional = nil
+	}
+	resp, err := mgr.fetchKeys(ctx, ha, conditional)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	status.NextFetchAt = resp.expires
+	if resp.notModified {
+		logger.Debugw("jwks not modified", "uri", ha.JwksURI)
+		return nil, nil
 	}
 
 	var rxKeys []string
-	var versions map[string]string
-	rxKeys, versions, err = parseKeys(resp)
+	var infos map[string]*keyInfo
+	rxKeys, infos, err = parseKeys(ctx, resp.body)
 	if err != nil {
-		return fmt.Errorf("error parsing key: %w", err)
+		return nil, fmt.Errorf("error parsing key: %w", err)
 	}
 
 	// Get the modifications we need to make.
@@ -220,7 +423,7 @@ func (mgr *Manager) updateHA(ctx context.Context, ha *model.HealthAuthority) err
 		hak := ha.Keys[i]
 		hak.Revoke()
 		if err := haDB.UpdateHealthAuthorityKey(ctx, hak); err != nil {
-			return fmt.Errorf("error updating key: %w", err)
+			return nil, fmt.Errorf("error updating key: %w", err)
 		}
 	}
 
@@ -228,21 +431,74 @@ func (mgr *Manager) updateHA(ctx context.Context, ha *model.HealthAuthority) err
 	for _, key := range newKeys {
 		hak := &model.HealthAuthorityKey{
 			AuthorityID:  ha.ID,
-			Version:      versions[key],
+			Version:      infos[key].version,
 			From:         time.Now(),
 			PublicKeyPEM: project.TrimSpaceAndNonPrintable(key),
+			Algorithm:    infos[key].algorithm,
 		}
 		if err := haDB.AddHealthAuthorityKey(ctx, ha, hak); err != nil {
-			return fmt.Errorf("error adding key: %w", err)
+			return nil, fmt.Errorf("error adding key: %w", err)
 		}
 	}
 
-	logger.Infow("updated jwks",
-		"uri", ha.JwksURI,
-		"new", len(newKeys),
-		"deleted", len(deadKeys))
+	if len(newKeys) > 0 || len(deadKeys) > 0 {
+		logger.Warnw("jwks key set changed",
+			"uri", ha.JwksURI,
+			"new", len(newKeys),
+			"deleted", len(deadKeys))
+		if err := stats.RecordWithTags(ctx, healthAuthorityTags(ha), mKeySetChanged.M(1)); err != nil {
+			logger.Errorw("failed to record metric", "error", err)
+		}
+	}
 
-	return nil
+	// The validators are only stored once the keys are saved, a failure above
+	// means the next fetch reads the whole document again.
+	status.ETag = resp.etag
+	status.LastModified = resp.lastModified
+
+	fingerprint := keySetFingerprint(rxKeys)
+	if fingerprint == status.KeyFingerprint {
+		return nil, nil
+	}
+	status.KeyFingerprint = fingerprint
+	return &model.JWKSKeySet{
+		Fingerprint: fingerprint,
+		KeyCount:    len(rxKeys),
+		ObservedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// recordFailure records a failed update in the health authority's JWKS
+// status, and returns the error.
+func (mgr *Manager) recordFailure(ctx context.Context, haDB *hadb.HealthAuthorityDB, ha *model.HealthAuthority, status *model.JWKSStatus, updateErr error) error {
+	logger := logging.FromContext(ctx).Named("recordFailure").
+		With("health_authority_name", ha.Name).
+		With("health_authority_id", ha.ID)
+
+	status.LastError = updateErr.Error()
+	status.LastErrorAt = time.Now().UTC()
+	status.ConsecutiveFailures++
+	// Retry on the next run, even if the last document could be cached.
+	status.NextFetchAt = time.Time{}
+
+	tags := healthAuthorityTags(ha)
+	if err := stats.RecordWithTags(ctx, tags, mFetchFailed.M(1)); err != nil {
+		logger.Errorw("failed to record metric", "error", err)
+	}
+	if mgr.failureThreshold > 0 && status.ConsecutiveFailures >= int(mgr.failureThreshold) {
+		logger.Errorw("jwks fetch failing repeatedly",
+			"uri", ha.JwksURI,
+			"consecutive_failures", status.ConsecutiveFailures,
+			"error", updateErr)
+		if err := stats.RecordWithTags(ctx, tags, mFetchFailingRepeatedly.M(1)); err != nil {
+			logger.Errorw("failed to record metric", "error", err)
+		}
+	}
+
+	if err := haDB.SaveJWKSStatus(ctx, status, nil); err != nil {
+		logger.Errorw("failed to save jwks status", "error", err)
+	}
+	return updateErr
 }
 
 // UpdateAll reads the JWKS keys for all HealthAuthorities.
@@ -15,7 +15,13 @@
 package jwks
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -94,15 +100,17 @@ func TestUpdateHA(t *testing.T) {
 	}
 
 	// version maps
-	v1 := map[string]string{encodePublic(enc1)[0]: "r2v1"}
-	v2 := map[string]string{encodePublic(enc2)[0]: "r2v2"}
-	v12 := map[string]string{encodePublic(enc1)[0]: "r2v1", encodePublic(enc2)[0]: "r2v2"}
+	info1 := &keyInfo{version: "r2v1", algorithm: model.AlgorithmES256}
+	info2 := &keyInfo{version: "r2v2", algorithm: model.AlgorithmES256}
+	v1 := map[string]*keyInfo{encodePublic(enc1)[0]: info1}
+	v2 := map[string]*keyInfo{encodePublic(enc2)[0]: info2}
+	v12 := map[string]*keyInfo{encodePublic(enc1)[0]: info1, encodePublic(enc2)[0]: info2}
 
 	tests := []struct {
 		name     string
 		resp     string
 		encoded  []string
-		versions map[string]string
+		infos    map[string]*keyInfo
 		ha       model.HealthAuthority
 		deadKeys []int
 		newKeys  []string
@@ -151,10 +159,11 @@ func TestUpdateHA(t *testing.T) {
 			ha := &model.HealthAuthority{JwksURI: &jwksURI}
 
 			// Test networking.
-			rxKeys, err := mgr.getKeys(ctx, ha)
+			resp, err := mgr.fetchKeys(ctx, ha, nil)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+			rxKeys := resp.body
 			if string(rxKeys) != tc.resp {
 				t.Fatalf("expected %v, got %v", tc.resp, rxKeys)
 			}
@@ -162,16 +171,16 @@ func TestUpdateHA(t *testing.T) {
 			// Test the encoding of the keys/versions. (basically, can we decode the
 			// JWKS strings.)
 			var encoded []string
-			var versions map[string]string
-			encoded, versions, err = parseKeys(rxKeys)
+			var infos map[string]*keyInfo
+			encoded, infos, err = parseKeys(ctx, rxKeys)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
 			if !reflect.DeepEqual(encoded, tc.encoded) {
 				t.Fatalf("encoded strings aren't equal expected %v, got %v", tc.encoded, encoded)
 			}
-			if !reflect.DeepEqual(versions, tc.versions) {
-				t.Fatalf("versions aren't equal expected %v, got %v", tc.versions, versions)
+			if !reflect.DeepEqual(infos, tc.infos) {
+				t.Fatalf("key infos aren't equal expected %v, got %v", tc.infos, infos)
 			}
 
 			// Test we have correctly identified if keys are deleted or new, etc.
@@ -237,6 +246,179 @@ func TestUpdateHA(t *testing.T) {
 	}
 }
 
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := func(alg string) string {
+		e := big.NewInt(int64(rsaKey.E)).Bytes()
+		return fmt.Sprintf(`{"kid":"rsa","kty":"RSA","alg":%q,"n":%q,"e":%q}`, alg, b64(rsaKey.N.Bytes()), b64(e))
+	}
+	edJWK := fmt.Sprintf(`{"kid":"ed","kty":"OKP","crv":"Ed25519","x":%q}`, b64(edKey))
+
+	cases := []struct {
+		name    string
+		resp    string
+		key     crypto.PublicKey
+		want    *keyInfo
+		wantErr string
+	}{
+		{
+			name: "ec",
+			resp: encodeKeys(key1),
+			want: &keyInfo{version: "r2v1", algorithm: model.AlgorithmES256},
+		},
+		{
+			name: "rsa",
+			resp: encodeKeys(rsaJWK("PS256")),
+			key:  &rsaKey.PublicKey,
+			want: &keyInfo{version: "rsa", algorithm: model.AlgorithmPS256},
+		},
+		{
+			name:    "rsa_unsupported_alg",
+			resp:    encodeKeys(rsaJWK("RS256")),
+			wantErr: `unsupported alg: "RS256"`,
+		},
+		{
+			name:    "rsa_mismatched_alg",
+			resp:    encodeKeys(rsaJWK("ES256")),
+			wantErr: `alg "ES256" does not match key type *rsa.PublicKey`,
+		},
+		{
+			name: "skips_unsupported",
+			resp: encodeKeys(rsaJWK("RS256"), key1),
+			want: &keyInfo{version: "r2v1", algorithm: model.AlgorithmES256},
+		},
+		{
+			name: "ed25519",
+			resp: encodeKeys(edJWK),
+			key:  edKey,
+			want: &keyInfo{version: "ed", algorithm: model.AlgorithmEdDSA},
+		},
+		{
+			name:    "x25519",
+			resp:    encodeKeys(`{"kid":"x","kty":"OKP","crv":"X25519","x":"AAAA"}`),
+			wantErr: `unsupported OKP curve: "X25519"`,
+		},
+		{
+			name:    "ed25519_short",
+			resp:    encodeKeys(`{"kid":"ed","kty":"OKP","crv":"Ed25519","x":"AAAA"}`),
+			wantErr: "invalid Ed25519 key size 3",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+			keys, infos, err := parseKeys(ctx, []byte(tc.resp))
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if tc.wantErr != "" {
+				return
+			}
+			if len(keys) != 1 {
+				t.Fatalf("expected 1 key, got %d", len(keys))
+			}
+			if !reflect.DeepEqual(infos[keys[0]], tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, infos[keys[0]])
+			}
+
+			// Keys must be accepted for their algorithm.
+			hak := &model.HealthAuthorityKey{PublicKeyPEM: keys[0], Algorithm: tc.want.algorithm}
+			pub, err := hak.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.key != nil {
+				if eq, ok := pub.(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(tc.key) {
+					t.Errorf("expected %v to be %v", pub, tc.key)
+				}
+			}
+		})
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := func(kid string) string {
+		e := big.NewInt(int64(rsaKey.E)).Bytes()
+		return fmt.Sprintf(`{"kid":%q,"kty":"RSA","alg":"RS256","n":%q,"e":%q}`, kid, b64(rsaKey.N.Bytes()), b64(e))
+	}
+
+	cases := []struct {
+		name     string
+		data     string
+		wantKIDs []string
+		wantErr  string
+	}{
+		{
+			name:     "keys",
+			data:     `{"keys":[` + rsaJWK("a") + `,` + rsaJWK("b") + `]}`,
+			wantKIDs: []string{"a", "b"},
+		},
+		{
+			name: "empty",
+			data: `{"keys":[]}`,
+		},
+		{
+			name:    "missing_kid",
+			data:    `{"keys":[` + rsaJWK("") + `]}`,
+			wantErr: "key is missing a key ID",
+		},
+		{
+			name:    "duplicate_kid",
+			data:    `{"keys":[` + rsaJWK("a") + `,` + rsaJWK("a") + `]}`,
+			wantErr: `duplicate key ID "a"`,
+		},
+		{
+			name:    "not_a_set",
+			data:    `[` + rsaJWK("a") + `]`,
+			wantErr: "unmarshal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys, err := ParseKeySet([]byte(tc.data))
+			errcmp.MustMatch(t, err, tc.wantErr)
+			if tc.wantErr != "" {
+				return
+			}
+			if got, want := len(keys), len(tc.wantKIDs); got != want {
+				t.Fatalf("expected %d keys, got %d", want, got)
+			}
+			for _, kid := range tc.wantKIDs {
+				pub, ok := keys[kid].(*rsa.PublicKey)
+				if !ok || !pub.Equal(&rsaKey.PublicKey) {
+					t.Errorf("expected key %q to be %v, got %v", kid, &rsaKey.PublicKey, keys[kid])
+				}
+			}
+		})
+	}
+}
+
 func TestStrip(t *testing.T) {
 	t.Parallel()
 
@@ -15,15 +15,32 @@
 package jwks
 
 import (
+	"strconv"
+
 	"github.com/google/exposure-notifications-server/internal/metrics"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
 	"github.com/google/exposure-notifications-server/pkg/observability"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 )
 
 const metricPrefix = metrics.MetricRoot + "jwks"
 
-var mSuccess = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+var (
+	mSuccess = stats.Int64(metricPrefix+"/success", "successful execution", stats.UnitDimensionless)
+
+	mKeySetChanged          = stats.Int64(metricPrefix+"/key_set_changed", "health authority key set changed", stats.UnitDimensionless)
+	mFetchFailed            = stats.Int64(metricPrefix+"/fetch_failed", "failed jwks fetch", stats.UnitDimensionless)
+	mFetchFailingRepeatedly = stats.Int64(metricPrefix+"/fetch_failing_repeatedly", "jwks fetch failed repeatedly", stats.UnitDimensionless)
+
+	healthAuthorityIDTag = tag.MustNewKey("healthAuthorityID")
+)
+
+// healthAuthorityTags returns the tags to record health authority metrics with.
+func healthAuthorityTags(ha *model.HealthAuthority) []tag.Mutator {
+	return []tag.Mutator{tag.Upsert(healthAuthorityIDTag, strconv.FormatInt(ha.ID, 10))}
+}
 
 func init() {
 	observability.CollectViews([]*view.View{
@@ -33,5 +50,26 @@ func init() {
 			Measure:     mSuccess,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        metricPrefix + "/key_set_changed",
+			Description: "Number of times a health authority's key set changed",
+			Measure:     mKeySetChanged,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{healthAuthorityIDTag},
+		},
+		{
+			Name:        metricPrefix + "/fetch_failed",
+			Description: "Number of failed jwks fetches",
+			Measure:     mFetchFailed,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{healthAuthorityIDTag},
+		},
+		{
+			Name:        metricPrefix + "/fetch_failing_repeatedly",
+			Description: "Number of failed jwks fetches after the failure threshold was reached",
+			Measure:     mFetchFailingRepeatedly,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{healthAuthorityIDTag},
+		},
 	}...)
 }
@@ -41,7 +41,9 @@ func NewServer(cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
 		return nil, fmt.Errorf("missing database in server env")
 	}
 
-	manager, err := NewManager(env.Database(), cfg.KeyCleanupTTL, cfg.RequestTimeout, cfg.MaxWorkers)
+	manager, err := NewManager(env.Database(), cfg.KeyCleanupTTL, cfg.RequestTimeout, cfg.MaxWorkers,
+		WithRetries(cfg.MaxRetries, cfg.RetryBackoff),
+		WithFailureThreshold(cfg.FailureThreshold))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create manager: %w", err)
 	}
@@ -0,0 +1,248 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// RateLimitTypeNone disables rate limiting.
+	RateLimitTypeNone = "NONE"
+	// RateLimitTypeMemory keeps the token buckets in memory, so each instance
+	// of a service enforces the limits separately.
+	RateLimitTypeMemory = "MEMORY"
+	// RateLimitTypePostgres keeps the token buckets in the database, so the
+	// limits are shared by all instances of a service.
+	RateLimitTypePostgres = "POSTGRES"
+)
+
+// RateLimitConfig selects where token buckets are stored.
+type RateLimitConfig struct {
+	Type string `env:"RATE_LIMIT_TYPE, default=NONE"`
+}
+
+// RateLimitPolicy is a token bucket that holds up to Tokens tokens and is
+// refilled at a rate of Tokens per Interval. Each request takes one token.
+type RateLimitPolicy struct {
+	Tokens   uint64
+	Interval time.Duration
+}
+
+// RateLimitResult is the outcome of taking a token.
+type RateLimitResult struct {
+	// Allowed is true if a token was taken.
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining uint64
+	// RetryAfter is how long until the next token is available, it is zero if
+	// the request was allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter stores token buckets. Implementations must be safe for
+// concurrent use. Shared stores, like Redis, can be added by implementing this
+// interface.
+type RateLimiter interface {
+	// Take takes a token from the bucket for key, creating a full bucket if it
+	// doesn't exist.
+	Take(ctx context.Context, key string, policy *RateLimitPolicy) (*RateLimitResult, error)
+}
+
+// NewRateLimiter creates the RateLimiter selected by the config. The database
+// is only used by the POSTGRES type. It returns nil if rate limiting is
+// disabled.
+func NewRateLimiter(cfg *RateLimitConfig, db *database.DB) (RateLimiter, error) {
+	switch typ := strings.ToUpper(cfg.Type); typ {
+	case "", RateLimitTypeNone:
+		return nil, nil
+	case RateLimitTypeMemory:
+		return NewMemoryRateLimiter(), nil
+	case RateLimitTypePostgres:
+		if db == nil {
+			return nil, fmt.Errorf("rate limit type %s requires a database", typ)
+		}
+		return NewPostgresRateLimiter(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit type: %v", cfg.Type)
+	}
+}
+
+// RateLimitKeyFunc returns the key of the bucket a request takes a token from.
+// It must leave the request usable by the next handler.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimit takes a token from the bucket selected by keyFn for each request,
+// and rejects the request with 429 and a Retry-After header if the bucket is
+// empty. The rejection uses the error and code fields shared by the v1 API
+// responses. If limiter is nil, requests are not limited. If the limiter fails,
+// the request is allowed.
+func RateLimit(limiter RateLimiter, policy *RateLimitPolicy, keyFn RateLimitKeyFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			logger := logging.FromContext(ctx).Named("middleware.RateLimit")
+
+			key := keyFn(r)
+			result, err := limiter.Take(ctx, key, policy)
+			if err != nil {
+				logger.Errorw("failed to take rate limit token", "key", key, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			SetRateLimitHeaders(w, policy, result)
+
+			if !result.Allowed {
+				logger.Warnw("rate limit exceeded", "key", key)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprintf(w, `{"error": %q, "code": %q}`, result.Message(), verifyapi.ErrorRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRateLimitHeaders sets the X-RateLimit headers for the result of taking a
+// token, and the Retry-After header if the request was not allowed. It is used
+// by handlers that take tokens themselves, instead of using RateLimit.
+func SetRateLimitHeaders(w http.ResponseWriter, policy *RateLimitPolicy, result *RateLimitResult) {
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatUint(policy.Tokens, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatUint(result.Remaining, 10))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.FormatInt(result.retryAfterSeconds(), 10))
+	}
+}
+
+// Message returns the error message for a request that was not allowed.
+func (r *RateLimitResult) Message() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %d seconds", r.retryAfterSeconds())
+}
+
+// retryAfterSeconds returns RetryAfter rounded up to whole seconds.
+func (r *RateLimitResult) retryAfterSeconds() int64 {
+	return int64(math.Ceil(r.RetryAfter.Seconds()))
+}
+
+// tokenBucket is the state of a bucket, it is refilled lazily when a token is
+// taken.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newTokenBucket returns a full bucket.
+func newTokenBucket(policy *RateLimitPolicy, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		tokens:  float64(policy.Tokens),
+		updated: now,
+	}
+}
+
+// take refills the bucket up to now and then takes a token if there is one.
+func (b *tokenBucket) take(policy *RateLimitPolicy, now time.Time) *RateLimitResult {
+	capacity := float64(policy.Tokens)
+	interval := policy.Interval.Seconds()
+
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed.Seconds()*capacity/interval)
+		b.updated = now
+	}
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) * interval / capacity
+		return &RateLimitResult{
+			RetryAfter: time.Duration(wait * float64(time.Second)),
+		}
+	}
+
+	b.tokens--
+	return &RateLimitResult{
+		Allowed:   true,
+		Remaining: uint64(b.tokens),
+	}
+}
+
+// Compile-time check to verify implements interface.
+var _ RateLimiter = (*MemoryRateLimiter)(nil)
+
+// MemoryRateLimiter keeps token buckets in memory.
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter creates a new in-memory RateLimiter.
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+}
+
+// memoryBucket is a token bucket along with the refill interval of the policy
+// it was last used with.
+type memoryBucket struct {
+	*tokenBucket
+	interval time.Duration
+}
+
+// Take takes a token from the bucket for key. Buckets that have been refilled
+// since they were last used are dropped, as they are the same as new buckets.
+func (l *MemoryRateLimiter) Take(_ context.Context, key string, policy *RateLimitPolicy) (*RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > policy.Interval {
+		for k, b := range l.buckets {
+			if now.Sub(b.updated) > b.interval {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokenBucket: newTokenBucket(policy, now)}
+		l.buckets[key] = b
+	}
+	b.interval = policy.Interval
+	return b.take(policy, now), nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/database"
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// Compile-time check to verify implements interface.
+var _ RateLimiter = (*PostgresRateLimiter)(nil)
+
+// PostgresRateLimiter keeps token buckets in a table in the database, so that
+// they are shared by every instance of a service. Each token taken is a short
+// transaction that locks the bucket's row.
+type PostgresRateLimiter struct {
+	db  *database.DB
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresRateLimiter creates a RateLimiter that stores token buckets in
+// the database.
+func NewPostgresRateLimiter(db *database.DB) *PostgresRateLimiter {
+	return &PostgresRateLimiter{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Take takes a token from the bucket for key. Buckets that have been refilled
+// since they were last used are periodically deleted, as they are the same as
+// new buckets.
+func (l *PostgresRateLimiter) Take(ctx context.Context, key string, policy *RateLimitPolicy) (*RateLimitResult, error) {
+	now := l.now().UTC()
+	if err := l.maybeSweep(ctx, now, policy.Interval); err != nil {
+		return nil, err
+	}
+
+	var result *RateLimitResult
+	if err := l.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var b tokenBucket
+		row := tx.QueryRow(ctx, `
+			SELECT
+				tokens, updated_at
+			FROM
+				RateLimitBucket
+			WHERE
+				key = $1
+			FOR UPDATE
+		`, key)
+		if err := row.Scan(&b.tokens, &b.updated); err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("reading bucket: %w", err)
+			}
+			b = *newTokenBucket(policy, now)
+		}
+
+		result = b.take(policy, now)
+
+		// Concurrent requests for a new bucket can both insert it, in which case
+		// the last write wins. This is fine as it only happens for full buckets.
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				RateLimitBucket
+				(key, tokens, updated_at, full_at)
+			VALUES
+				($1, $2, $3, $4)
+			ON CONFLICT (key) DO UPDATE SET
+				tokens = EXCLUDED.tokens,
+				updated_at = EXCLUDED.updated_at,
+				full_at = EXCLUDED.full_at
+		`, key, b.tokens, b.updated, b.updated.Add(policy.Interval)); err != nil {
+			return fmt.Errorf("updating bucket: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// maybeSweep deletes the buckets that are full, at most once per interval for
+// each instance.
+func (l *PostgresRateLimiter) maybeSweep(ctx context.Context, now time.Time, interval time.Duration) error {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) <= interval {
+		l.mu.Unlock()
+		return nil
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+
+	return l.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				RateLimitBucket
+			WHERE
+				full_at < $1
+		`, now); err != nil {
+			return fmt.Errorf("deleting full buckets: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	testRateLimiter(ctx, t, limiter, func(d time.Duration) { now = now.Add(d) })
+
+	// Full buckets are dropped.
+	now = now.Add(2 * time.Minute)
+	if _, err := limiter.Take(ctx, "c", &RateLimitPolicy{Tokens: 1, Interval: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(limiter.buckets), 1; got != want {
+		t.Errorf("expected %d buckets to be %d", got, want)
+	}
+}
+
+// testRateLimiter checks the token bucket behavior of a limiter, advance moves
+// the limiter's clock forward.
+func testRateLimiter(ctx context.Context, t *testing.T, limiter RateLimiter, advance func(time.Duration)) {
+	t.Helper()
+
+	policy := &RateLimitPolicy{Tokens: 3, Interval: time.Minute}
+	take := func(key string) *RateLimitResult {
+		t.Helper()
+
+		result, err := limiter.Take(ctx, key, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for i := uint64(0); i < policy.Tokens; i++ {
+		result := take("a")
+		if !result.Allowed {
+			t.Fatalf("expected token %d to be allowed", i)
+		}
+		if got, want := result.Remaining, policy.Tokens-i-1; got != want {
+			t.Errorf("expected %d remaining to be %d", got, want)
+		}
+	}
+
+	result := take("a")
+	if result.Allowed {
+		t.Fatal("expected empty bucket to be limited")
+	}
+	if got, want := result.RetryAfter, 20*time.Second; got != want {
+		t.Errorf("expected retry after %v to be %v", got, want)
+	}
+
+	// Other keys have their own bucket.
+	if result := take("b"); !result.Allowed {
+		t.Error("expected other key to be allowed")
+	}
+
+	// One token is refilled every 20 seconds.
+	advance(15 * time.Second)
+	result = take("a")
+	if result.Allowed {
+		t.Fatal("expected partially refilled bucket to be limited")
+	}
+	if got, want := result.RetryAfter, 5*time.Second; got != want {
+		t.Errorf("expected retry after %v to be %v", got, want)
+	}
+
+	advance(5 * time.Second)
+	if result := take("a"); !result.Allowed {
+		t.Error("expected refilled bucket to be allowed")
+	}
+}
+
+type fakeRateLimiter struct {
+	result *RateLimitResult
+	err    error
+	keys   []string
+}
+
+func (l *fakeRateLimiter) Take(_ context.Context, key string, _ *RateLimitPolicy) (*RateLimitResult, error) {
+	l.keys = append(l.keys, key)
+	return l.result, l.err
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	policy := &RateLimitPolicy{Tokens: 10, Interval: time.Minute}
+	keyFn := func(r *http.Request) string {
+		return r.URL.Query().Get("ha")
+	}
+
+	cases := []struct {
+		name       string
+		limiter    *fakeRateLimiter
+		code       int
+		retryAfter string
+		remaining  string
+	}{
+		{
+			name:    "disabled",
+			limiter: nil,
+			code:    http.StatusOK,
+		},
+		{
+			name:      "allowed",
+			limiter:   &fakeRateLimiter{result: &RateLimitResult{Allowed: true, Remaining: 4}},
+			code:      http.StatusOK,
+			remaining: "4",
+		},
+		{
+			name:       "limited",
+			limiter:    &fakeRateLimiter{result: &RateLimitResult{RetryAfter: 1500 * time.Millisecond}},
+			code:       http.StatusTooManyRequests,
+			retryAfter: "2",
+			remaining:  "0",
+		},
+		{
+			name:    "limiter_error",
+			limiter: &fakeRateLimiter{err: errors.New("oops")},
+			code:    http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var limiter RateLimiter
+			if tc.limiter != nil {
+				limiter = tc.limiter
+			}
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/?ha=ha1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			RateLimit(limiter, policy, keyFn)(next).ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.code; got != want {
+				t.Fatalf("expected %d to be %d", got, want)
+			}
+			if got, want := w.Header().Get("Retry-After"), tc.retryAfter; got != want {
+				t.Errorf("expected Retry-After %q to be %q", got, want)
+			}
+			if got, want := w.Header().Get("X-RateLimit-Remaining"), tc.remaining; got != want {
+				t.Errorf("expected X-RateLimit-Remaining %q to be %q", got, want)
+			}
+			if tc.limiter != nil {
+				if got, want := tc.limiter.keys, []string{"ha1"}; len(got) != 1 || got[0] != want[0] {
+					t.Errorf("expected keys %v to be %v", got, want)
+				}
+			}
+
+			if tc.code == http.StatusTooManyRequests {
+				var resp verifyapi.PublishResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if got, want := resp.Code, verifyapi.ErrorRateLimited; got != want {
+					t.Errorf("expected code %q to be %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		typ     string
+		wantNil bool
+		err     bool
+	}{
+		{typ: "", wantNil: true},
+		{typ: RateLimitTypeNone, wantNil: true},
+		{typ: RateLimitTypeMemory},
+		{typ: "memory"},
+		{typ: RateLimitTypePostgres, err: true}, // no database
+		{typ: "REDIS", err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.typ, func(t *testing.T) {
+			t.Parallel()
+
+			limiter, err := NewRateLimiter(&RateLimitConfig{Type: tc.typ}, nil)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := limiter == nil; got != tc.wantNil {
+				t.Errorf("expected nil limiter %t to be %t", got, tc.wantNil)
+			}
+		})
+	}
+}
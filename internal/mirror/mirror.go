@@ -260,13 +260,6 @@ func (s *Server) processMirror(ctx context.Context, deadline time.Time, mirror *
 			"file", filename,
 			"download_path", status.DownloadPath)
 
-		b, err := downloadFile(ctx, status.DownloadPath, s.config.ExportFileDownloadTimeout, s.config.MaxZipBytes)
-		if err != nil {
-			merr = multierror.Append(merr, fmt.Errorf("failed to download export file %s: %w", filename, err))
-			status.Failed = true
-			continue
-		}
-
 		// See if we need to rewrite the filename.
 		writeFilename, err := mirror.RewriteFilename(filename)
 		if err != nil {
@@ -276,16 +269,31 @@ func (s *Server) processMirror(ctx context.Context, deadline time.Time, mirror *
 		}
 		status.LocalFilename = writeFilename
 
-		// Write this file to the blobstore - use a function so defers run when
-		// expected.
+		// Stream the download into the blobstore - use a function so defers run
+		// when expected.
 		if err := func() error {
-			ctx, cancel := context.WithTimeout(ctx, s.config.ExportFileUploadTimeout)
+			ctx, cancel := context.WithTimeout(ctx, s.config.ExportFileDownloadTimeout+s.config.ExportFileUploadTimeout)
 			defer cancel()
 
 			objName := urlJoin(mirror.FilenameRoot, writeFilename)
-			return blobstore.CreateObject(ctx, mirror.CloudStorageBucket, objName, b, true, storage.ContentTypeZip)
+			w, err := blobstore.NewWriter(ctx, mirror.CloudStorageBucket, objName, true, storage.ContentTypeZip)
+			if err != nil {
+				return fmt.Errorf("failed to write %s to blobstore: %w", filename, err)
+			}
+
+			if err := downloadFile(ctx, status.DownloadPath, s.config.ExportFileDownloadTimeout, s.config.MaxZipBytes, w); err != nil {
+				// Cancel before closing, so the partial object is not created.
+				cancel()
+				w.Close()
+				return fmt.Errorf("failed to download export file %s: %w", filename, err)
+			}
+
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("failed to write %s to blobstore: %w", filename, err)
+			}
+			return nil
 		}(); err != nil {
-			merr = multierror.Append(merr, fmt.Errorf("failed to write %s to blobstore: %w", filename, err))
+			merr = multierror.Append(merr, err)
 			status.Failed = true
 			continue
 		}
@@ -372,45 +380,44 @@ func computeActions(knownFiles []*model.MirrorFile, indexFiles []string) map[str
 	return actions
 }
 
-// downloadFile downloads the file from the given URL u up to maxBytes. If the
-// URL does not return a 200, an error is returned. If the process takes longer
-// than the provided timeout, an error is returned. If more bytes remain after
-// maxBytes, an error is returned. Otherwise, the raw bytes are returned.
-func downloadFile(ctx context.Context, u string, timeout time.Duration, maxBytes int64) ([]byte, error) {
+// downloadFile downloads the file from the given URL u up to maxBytes, writing
+// it to w. If the URL does not return a 200, an error is returned. If the
+// process takes longer than the provided timeout, an error is returned. If more
+// bytes remain after maxBytes, an error is returned, after up to maxBytes have
+// already been written to w.
+func downloadFile(ctx context.Context, u string, timeout time.Duration, maxBytes int64, w io.Writer) error {
 	client := &http.Client{Timeout: timeout}
 
 	// Start the download.
 	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request %s: %w", u, err)
+		return fmt.Errorf("failed to create request %s: %w", u, err)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download %s: %w", u, err)
+		return fmt.Errorf("failed to download %s: %w", u, err)
 	}
 	defer resp.Body.Close()
 
 	// Ensure a 200 response.
 	if code := resp.StatusCode; code != http.StatusOK {
-		return nil, fmt.Errorf("failed to download %s: status %d", u, code)
+		return fmt.Errorf("failed to download %s: status %d", u, code)
 	}
 
 	// Create the limited reader.
-	var b bytes.Buffer
 	r := &io.LimitedReader{R: resp.Body, N: maxBytes}
-	if _, err := io.Copy(&b, r); err != nil {
-		return nil, fmt.Errorf("failed to download %s: %w", u, err)
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("failed to download %s: %w", u, err)
 	}
 	if r.N == 0 {
 		// Check if there's more data to be read and return an error if so.
 		if _, err := r.R.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
-			return nil, fmt.Errorf("failed to read %s: response exceeds %d bytes", u, maxBytes)
+			return fmt.Errorf("failed to read %s: response exceeds %d bytes", u, maxBytes)
 		}
 	}
 
-	// Return the bytes.
-	return b.Bytes(), nil
+	return nil
 }
 
 // downloadIndex downloads the index files and returns the list of entries of
@@ -424,13 +431,13 @@ func downloadFile(ctx context.Context, u string, timeout time.Duration, maxBytes
 // The values are returned in the order in which they appear in the file, joined
 // with the configured mirror ExportRoot.
 func (s *Server) downloadIndex(ctx context.Context, mirror *model.Mirror) ([]string, error) {
-	b, err := downloadFile(ctx, mirror.IndexFile, s.config.IndexFileDownloadTimeout, s.config.MaxIndexBytes)
-	if err != nil {
+	var b bytes.Buffer
+	if err := downloadFile(ctx, mirror.IndexFile, s.config.IndexFileDownloadTimeout, s.config.MaxIndexBytes, &b); err != nil {
 		return nil, err
 	}
 
 	currentFiles := make([]string, 0, 64)
-	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner := bufio.NewScanner(&b)
 	for scanner.Scan() {
 		fullName := urlJoin(mirror.ExportRoot, scanner.Text())
 		currentFiles = append(currentFiles, fullName)
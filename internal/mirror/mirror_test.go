@@ -15,6 +15,7 @@
 package mirror
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
@@ -252,6 +253,55 @@ func TestServer_ProcessMirror(t *testing.T) {
 	}
 }
 
+func TestDownloadFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data data data")
+	}))
+	t.Cleanup(ts.Close)
+
+	cases := []struct {
+		name     string
+		maxBytes int64
+		err      string
+	}{
+		{
+			name:     "streams",
+			maxBytes: 1024,
+		},
+		{
+			name:     "exact",
+			maxBytes: 14,
+		},
+		{
+			name:     "too_large",
+			maxBytes: 4,
+			err:      "response exceeds 4 bytes",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var b bytes.Buffer
+			err := downloadFile(ctx, ts.URL, time.Minute, tc.maxBytes, &b)
+			errcmp.MustMatch(t, err, tc.err)
+			if err != nil {
+				return
+			}
+			if got, want := b.String(), "data data data"; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
 func TestServer_DownloadIndex(t *testing.T) {
 	t.Parallel()
 
@@ -485,6 +485,93 @@ func (x *ExposureKey) GetRegions() []string {
 	return nil
 }
 
+type FederationCapabilitiesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *FederationCapabilitiesRequest) Reset() {
+	*x = FederationCapabilitiesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_pb_federation_federation_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FederationCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationCapabilitiesRequest) ProtoMessage() {}
+
+func (x *FederationCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_federation_federation_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*FederationCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_internal_pb_federation_federation_proto_rawDescGZIP(), []int{5}
+}
+
+// FederationCapabilities are the optional features a server supports. Servers
+// that don't implement GetCapabilities support none of them.
+type FederationCapabilities struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FetchStream bool `protobuf:"varint,1,opt,name=fetchStream,proto3" json:"fetchStream,omitempty"` // FetchStream is implemented.
+}
+
+func (x *FederationCapabilities) Reset() {
+	*x = FederationCapabilities{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_internal_pb_federation_federation_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FederationCapabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationCapabilities) ProtoMessage() {}
+
+func (x *FederationCapabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_pb_federation_federation_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationCapabilities.ProtoReflect.Descriptor instead.
+func (*FederationCapabilities) Descriptor() ([]byte, []int) {
+	return file_internal_pb_federation_federation_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FederationCapabilities) GetFetchStream() bool {
+	if x != nil {
+		return x.FetchStream
+	}
+	return false
+}
+
 var File_internal_pb_federation_federation_proto protoreflect.FileDescriptor
 
 var file_internal_pb_federation_federation_proto_rawDesc = []byte{
@@ -563,17 +650,32 @@ var file_internal_pb_federation_federation_proto_rawDesc = []byte{
 	0x47, 0x4e, 0x4f, 0x53, 0x49, 0x53, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x53, 0x45, 0x4c, 0x46,
 	0x5f, 0x52, 0x45, 0x50, 0x4f, 0x52, 0x54, 0x10, 0x03, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x45, 0x43,
 	0x55, 0x52, 0x53, 0x49, 0x56, 0x45, 0x10, 0x04, 0x12, 0x0b, 0x0a, 0x07, 0x52, 0x45, 0x56, 0x4f,
-	0x4b, 0x45, 0x44, 0x10, 0x05, 0x32, 0x4a, 0x0a, 0x0a, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74,
-	0x69, 0x6f, 0x6e, 0x12, 0x3c, 0x0a, 0x05, 0x46, 0x65, 0x74, 0x63, 0x68, 0x12, 0x17, 0x2e, 0x46,
-	0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x46, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x00, 0x42, 0x53, 0x5a, 0x51, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x65, 0x78, 0x70, 0x6f, 0x73, 0x75, 0x72, 0x65, 0x2d,
-	0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2d, 0x73, 0x65,
-	0x72, 0x76, 0x65, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x62,
-	0x2f, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3b, 0x66, 0x65, 0x64, 0x65,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x4b, 0x45, 0x44, 0x10, 0x05, 0x22, 0x1f, 0x0a, 0x1d, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x3a, 0x0a, 0x16, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x12, 0x20, 0x0a, 0x0b, 0x66, 0x65, 0x74, 0x63, 0x68, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x66, 0x65, 0x74, 0x63, 0x68, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x32, 0xde, 0x01, 0x0a, 0x0a, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x3c, 0x0a, 0x05, 0x46, 0x65, 0x74, 0x63, 0x68, 0x12, 0x17, 0x2e, 0x46, 0x65, 0x64,
+	0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x46, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
+	0x44, 0x0a, 0x0b, 0x46, 0x65, 0x74, 0x63, 0x68, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x17,
+	0x2e, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x65, 0x74, 0x63, 0x68,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x46, 0x65, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x4c, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x43, 0x61, 0x70, 0x61,
+	0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x46, 0x65, 0x64, 0x65, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65,
+	0x73, 0x22, 0x00, 0x42, 0x53, 0x5a, 0x51, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x65, 0x78, 0x70, 0x6f, 0x73, 0x75, 0x72,
+	0x65, 0x2d, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2d,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f,
+	0x70, 0x62, 0x2f, 0x66, 0x65, 0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x3b, 0x66, 0x65,
+	0x64, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -589,30 +691,36 @@ func file_internal_pb_federation_federation_proto_rawDescGZIP() []byte {
 }
 
 var file_internal_pb_federation_federation_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_internal_pb_federation_federation_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_internal_pb_federation_federation_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_internal_pb_federation_federation_proto_goTypes = []interface{}{
-	(ExposureKey_ReportType)(0),     // 0: ExposureKey.ReportType
-	(*FederationFetchRequest)(nil),  // 1: FederationFetchRequest
-	(*FederationFetchResponse)(nil), // 2: FederationFetchResponse
-	(*FetchState)(nil),              // 3: FetchState
-	(*Cursor)(nil),                  // 4: Cursor
-	(*ExposureKey)(nil),             // 5: ExposureKey
+	(ExposureKey_ReportType)(0),           // 0: ExposureKey.ReportType
+	(*FederationFetchRequest)(nil),        // 1: FederationFetchRequest
+	(*FederationFetchResponse)(nil),       // 2: FederationFetchResponse
+	(*FetchState)(nil),                    // 3: FetchState
+	(*Cursor)(nil),                        // 4: Cursor
+	(*ExposureKey)(nil),                   // 5: ExposureKey
+	(*FederationCapabilitiesRequest)(nil), // 6: FederationCapabilitiesRequest
+	(*FederationCapabilities)(nil),        // 7: FederationCapabilities
 }
 var file_internal_pb_federation_federation_proto_depIdxs = []int32{
-	3, // 0: FederationFetchRequest.state:type_name -> FetchState
-	5, // 1: FederationFetchResponse.keys:type_name -> ExposureKey
-	5, // 2: FederationFetchResponse.revisedKeys:type_name -> ExposureKey
-	3, // 3: FederationFetchResponse.nextFetchState:type_name -> FetchState
-	4, // 4: FetchState.keyCursor:type_name -> Cursor
-	4, // 5: FetchState.revisedKeyCursor:type_name -> Cursor
-	0, // 6: ExposureKey.reportType:type_name -> ExposureKey.ReportType
-	1, // 7: Federation.Fetch:input_type -> FederationFetchRequest
-	2, // 8: Federation.Fetch:output_type -> FederationFetchResponse
-	8, // [8:9] is the sub-list for method output_type
-	7, // [7:8] is the sub-list for method input_type
-	7, // [7:7] is the sub-list for extension type_name
-	7, // [7:7] is the sub-list for extension extendee
-	0, // [0:7] is the sub-list for field type_name
+	3,  // 0: FederationFetchRequest.state:type_name -> FetchState
+	5,  // 1: FederationFetchResponse.keys:type_name -> ExposureKey
+	5,  // 2: FederationFetchResponse.revisedKeys:type_name -> ExposureKey
+	3,  // 3: FederationFetchResponse.nextFetchState:type_name -> FetchState
+	4,  // 4: FetchState.keyCursor:type_name -> Cursor
+	4,  // 5: FetchState.revisedKeyCursor:type_name -> Cursor
+	0,  // 6: ExposureKey.reportType:type_name -> ExposureKey.ReportType
+	1,  // 7: Federation.Fetch:input_type -> FederationFetchRequest
+	1,  // 8: Federation.FetchStream:input_type -> FederationFetchRequest
+	6,  // 9: Federation.GetCapabilities:input_type -> FederationCapabilitiesRequest
+	2,  // 10: Federation.Fetch:output_type -> FederationFetchResponse
+	2,  // 11: Federation.FetchStream:output_type -> FederationFetchResponse
+	7,  // 12: Federation.GetCapabilities:output_type -> FederationCapabilities
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_internal_pb_federation_federation_proto_init() }
@@ -681,6 +789,30 @@ func file_internal_pb_federation_federation_proto_init() {
 				return nil
 			}
 		}
+		file_internal_pb_federation_federation_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FederationCapabilitiesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_internal_pb_federation_federation_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FederationCapabilities); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -688,7 +820,7 @@ func file_internal_pb_federation_federation_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_internal_pb_federation_federation_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   5,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -716,6 +848,13 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type FederationClient interface {
 	Fetch(ctx context.Context, in *FederationFetchRequest, opts ...grpc.CallOption) (*FederationFetchResponse, error)
+	// FetchStream returns the same keys as repeated Fetch calls, as a stream of
+	// pages of at most maxExposureKeys keys. The nextFetchState of each page is
+	// a checkpoint that covers all keys sent so far, and partialResponse is set
+	// on every page except the last. If the stream ends after a partial page,
+	// it can be resumed by sending the last checkpoint as the state.
+	FetchStream(ctx context.Context, in *FederationFetchRequest, opts ...grpc.CallOption) (Federation_FetchStreamClient, error)
+	GetCapabilities(ctx context.Context, in *FederationCapabilitiesRequest, opts ...grpc.CallOption) (*FederationCapabilities, error)
 }
 
 type federationClient struct {
@@ -735,9 +874,57 @@ func (c *federationClient) Fetch(ctx context.Context, in *FederationFetchRequest
 	return out, nil
 }
 
+func (c *federationClient) FetchStream(ctx context.Context, in *FederationFetchRequest, opts ...grpc.CallOption) (Federation_FetchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Federation_serviceDesc.Streams[0], "/Federation/FetchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &federationFetchStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Federation_FetchStreamClient interface {
+	Recv() (*FederationFetchResponse, error)
+	grpc.ClientStream
+}
+
+type federationFetchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *federationFetchStreamClient) Recv() (*FederationFetchResponse, error) {
+	m := new(FederationFetchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *federationClient) GetCapabilities(ctx context.Context, in *FederationCapabilitiesRequest, opts ...grpc.CallOption) (*FederationCapabilities, error) {
+	out := new(FederationCapabilities)
+	err := c.cc.Invoke(ctx, "/Federation/GetCapabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FederationServer is the server API for Federation service.
 type FederationServer interface {
 	Fetch(context.Context, *FederationFetchRequest) (*FederationFetchResponse, error)
+	// FetchStream returns the same keys as repeated Fetch calls, as a stream of
+	// pages of at most maxExposureKeys keys. The nextFetchState of each page is
+	// a checkpoint that covers all keys sent so far, and partialResponse is set
+	// on every page except the last. If the stream ends after a partial page,
+	// it can be resumed by sending the last checkpoint as the state.
+	FetchStream(*FederationFetchRequest, Federation_FetchStreamServer) error
+	GetCapabilities(context.Context, *FederationCapabilitiesRequest) (*FederationCapabilities, error)
 }
 
 // UnimplementedFederationServer can be embedded to have forward compatible implementations.
@@ -747,6 +934,12 @@ type UnimplementedFederationServer struct {
 func (*UnimplementedFederationServer) Fetch(context.Context, *FederationFetchRequest) (*FederationFetchResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Fetch not implemented")
 }
+func (*UnimplementedFederationServer) FetchStream(*FederationFetchRequest, Federation_FetchStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method FetchStream not implemented")
+}
+func (*UnimplementedFederationServer) GetCapabilities(context.Context, *FederationCapabilitiesRequest) (*FederationCapabilities, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
 
 func RegisterFederationServer(s *grpc.Server, srv FederationServer) {
 	s.RegisterService(&_Federation_serviceDesc, srv)
@@ -770,6 +963,45 @@ func _Federation_Fetch_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Federation_FetchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FederationFetchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FederationServer).FetchStream(m, &federationFetchStreamServer{stream})
+}
+
+type Federation_FetchStreamServer interface {
+	Send(*FederationFetchResponse) error
+	grpc.ServerStream
+}
+
+type federationFetchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *federationFetchStreamServer) Send(m *FederationFetchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Federation_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FederationCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Federation/GetCapabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).GetCapabilities(ctx, req.(*FederationCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Federation_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Federation",
 	HandlerType: (*FederationServer)(nil),
@@ -778,7 +1010,17 @@ var _Federation_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Fetch",
 			Handler:    _Federation_Fetch_Handler,
 		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _Federation_GetCapabilities_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FetchStream",
+			Handler:       _Federation_FetchStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/pb/federation/federation.proto",
 }
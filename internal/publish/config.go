@@ -89,6 +89,15 @@ type Config struct {
 	// API Versions.
 	EnableV1Alpha1API bool `env:"ENABLE_V1ALPHA1_API, default=false"`
 
+	// Batch publish API config.
+	// Maximum number of publish requests that may be sent in a single batch.
+	MaxPublishBatchSize uint `env:"MAX_PUBLISH_BATCH_SIZE, default=500"`
+	// Maximum size of a batch publish request body, in bytes.
+	MaxPublishBatchBytes int64 `env:"MAX_PUBLISH_BATCH_BYTES, default=16000000"`
+	// Number of publish requests from a batch that are written to the database
+	// in a single transaction.
+	PublishBatchTransactionSize uint `env:"PUBLISH_BATCH_TRANSACTION_SIZE, default=50"`
+
 	// If set and if a publish request has no regions (v1alpha1) and the health authority
 	// has no regions configured, then this default will be assumed.
 	// This is present for an upgrade edgecase where empty region list used to mean "all regions"
@@ -116,6 +125,23 @@ type Config struct {
 	StatsEmbargoPeriod           time.Duration `env:"STATS_EMBARGO_PERIOD, default=48h"`
 	StatsResponsePaddingMinBytes int64         `env:"RESPONSE_PADDING_MIN_BYTES, default=2048"`
 	StatsResponsePaddingRange    int64         `env:"RESPONSE_PADDING_RANGE, default=1024"`
+
+	// Differential privacy noise for health authorities that enable it.
+	// Minimum number of (noisy) publish requests needed to see stats for a
+	// given day when noise is added. This must be between 1 and STATS_UPLOAD_MINIMUM.
+	StatsNoisyUploadMinimum int64 `env:"STATS_NOISY_UPLOAD_MINIMUM, default=10"`
+	// Delta used by the Gaussian mechanism.
+	StatsNoiseDelta float64 `env:"STATS_NOISE_DELTA, default=0.00001"`
+	// Key used to seed the noise, must be base64 encoded and kept secret. It is
+	// required if any health authority enables stats noise.
+	StatsNoiseKey revision.Base64Bytes `env:"STATS_NOISE_KEY"` // may come from secret://
+
+	// Rate limiting, per health authority. Each health authority can make up
+	// to the given number of publish or stats requests in RATE_LIMIT_INTERVAL.
+	RateLimit         middleware.RateLimitConfig
+	RateLimitInterval time.Duration `env:"RATE_LIMIT_INTERVAL, default=1m"`
+	PublishRateLimit  uint64        `env:"PUBLISH_RATE_LIMIT, default=6000"`
+	StatsRateLimit    uint64        `env:"STATS_RATE_LIMIT, default=60"`
 }
 
 func (c *Config) MaintenanceMode() bool {
@@ -134,19 +160,51 @@ func (c *Config) Validate() error {
 			fmt.Errorf("env var `MAX_VALID_SYMPTOM_ONSET_REPORT_DAYS` must be > 0, got: %v", c.MaxSymptomOnsetReportDays))
 	}
 
-	if c.StatsUploadMinimum < 10 {
+	if c.MaxPublishBatchSize == 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `MAX_PUBLISH_BATCH_SIZE` must be > 0, got: %v", c.MaxPublishBatchSize))
+	}
+	if c.MaxPublishBatchBytes <= 0 {
 		result = multierror.Append(result,
-			fmt.Errorf("env var `STATS_UPLOAD_MINIMUM` must be >= 10, got: %v", c.StatsUploadMinimum))
+			fmt.Errorf("env var `MAX_PUBLISH_BATCH_BYTES` must be > 0, got: %v", c.MaxPublishBatchBytes))
+	}
+	if c.PublishBatchTransactionSize == 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `PUBLISH_BATCH_TRANSACTION_SIZE` must be > 0, got: %v", c.PublishBatchTransactionSize))
+	}
+
+	if err := c.StatsReleaseConfig().Validate(); err != nil {
+		result = multierror.Append(result, err)
 	}
 
-	if ep := c.StatsEmbargoPeriod; !(ep >= (48*time.Hour) || ep <= 0) {
+	if c.RateLimitInterval <= 0 {
 		result = multierror.Append(result,
-			fmt.Errorf("env var `STATS_EMBARGO_PERIOD` must be >= 48h or <= 0 to disable release of stats days below the threshold"))
+			fmt.Errorf("env var `RATE_LIMIT_INTERVAL` must be > 0, got: %v", c.RateLimitInterval))
+	}
+	if c.PublishRateLimit == 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `PUBLISH_RATE_LIMIT` must be > 0, got: %v", c.PublishRateLimit))
+	}
+	if c.StatsRateLimit == 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATS_RATE_LIMIT` must be > 0, got: %v", c.StatsRateLimit))
 	}
 
 	return result.ErrorOrNil()
 }
 
+// StatsReleaseConfig returns the configuration for releasing stats.
+func (c *Config) StatsReleaseConfig() *model.StatsReleaseConfig {
+	return &model.StatsReleaseConfig{
+		UploadMinimum:      c.StatsUploadMinimum,
+		NoisyUploadMinimum: c.StatsNoisyUploadMinimum,
+		EmbargoPeriod:      c.StatsEmbargoPeriod,
+		NoiseDelta:         c.StatsNoiseDelta,
+		NoiseKey:           c.StatsNoiseKey,
+		MaxKeysPerPublish:  int64(c.MaxKeysOnPublish),
+	}
+}
+
 func (c *Config) MaxExposureKeys() uint {
 	return c.MaxKeysOnPublish
 }
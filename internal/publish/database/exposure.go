@@ -27,6 +27,8 @@ import (
 
 	"github.com/google/exposure-notifications-server/internal/pb"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/base64util"
 	"github.com/google/exposure-notifications-server/pkg/database"
@@ -86,6 +88,11 @@ type IterateExposuresCriteria struct {
 
 	// If limit is > 0, a limit query will be set on the database query.
 	Limit uint32
+
+	// OrderByExposureKey orders the results by the raw exposure key bytes
+	// instead of by time. This is the order in which keys are written to
+	// export files.
+	OrderByExposureKey bool
 }
 
 type IteratorFunction func(*model.Exposure) error
@@ -243,7 +250,9 @@ func generateExposureQuery(criteria IterateExposuresCriteria) (string, []interfa
 		q += fmt.Sprintf(" AND traveler = $%d", len(args))
 	}
 
-	if criteria.OnlyRevisedKeys {
+	if criteria.OrderByExposureKey {
+		q += " ORDER BY decode(exposure_key, 'base64')"
+	} else if criteria.OnlyRevisedKeys {
 		q += " ORDER BY revised_at"
 	} else {
 		q += " ORDER BY created_at"
@@ -401,6 +410,11 @@ type InsertAndReviseExposuresRequest struct {
 	// Optional: if provided stats will be updated transactionally with the TEKs.
 	PublishInfo *model.PublishInfo
 
+	// Optional: if provided, the use of the verification certificate is
+	// recorded transactionally with the TEKs, so it is only used up if they are
+	// saved. If it was already used, hadb.ErrCertificateReused is returned.
+	CertificateUse *hamodel.CertificateUse
+
 	// RequireToken requires that the request supply a revision token to re-upload
 	// existing keys.
 	RequireToken bool
@@ -445,15 +459,115 @@ type InsertAndReviseExposuresResponse struct {
 // InsertAndReviseExposures transactionally revises and inserts a set of keys as
 // necessary.
 func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAndReviseExposuresRequest) (*InsertAndReviseExposuresResponse, error) {
-	logger := logging.FromContext(ctx).Named("InsertAndReviseExposures")
+	if err := validateInsertAndReviseExposuresRequest(req); err != nil {
+		return nil, err
+	}
+
+	var resp *InsertAndReviseExposuresResponse
+	var healthAuthorityID *int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		resp, healthAuthorityID, err = db.insertAndReviseExposuresInTx(ctx, tx, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	db.updateStatsAsync(ctx, req.PublishInfo, healthAuthorityID)
+	return resp, nil
+}
+
+// InsertAndReviseExposuresBatch processes multiple InsertAndReviseExposures
+// requests in a single database transaction. Each request runs inside its own
+// savepoint, so a request that fails (for example due to an invalid revision
+// token) is rolled back without affecting the others in the group.
+//
+// The returned responses and errors are index-aligned with reqs; for each
+// index exactly one of them is non-nil. The final error is only returned if
+// the transaction as a whole could not be completed, in which case nothing
+// was written.
+func (db *PublishDB) InsertAndReviseExposuresBatch(ctx context.Context, reqs []*InsertAndReviseExposuresRequest) ([]*InsertAndReviseExposuresResponse, []error, error) {
+	responses := make([]*InsertAndReviseExposuresResponse, len(reqs))
+	errs := make([]error, len(reqs))
+	healthAuthorityIDs := make([]*int64, len(reqs))
+
+	if len(reqs) == 0 {
+		return responses, errs, nil
+	}
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		for i, req := range reqs {
+			if err := validateInsertAndReviseExposuresRequest(req); err != nil {
+				errs[i] = err
+				continue
+			}
+
+			// Starting a transaction on an existing transaction creates a savepoint.
+			sp, err := tx.Begin(ctx)
+			if err != nil {
+				return fmt.Errorf("creating savepoint: %w", err)
+			}
+
+			resp, haID, err := db.insertAndReviseExposuresInTx(ctx, sp, req)
+			if err != nil {
+				if err := sp.Rollback(ctx); err != nil {
+					return fmt.Errorf("rolling back savepoint: %w", err)
+				}
+				errs[i] = err
+				continue
+			}
 
+			if err := sp.Commit(ctx); err != nil {
+				return fmt.Errorf("releasing savepoint: %w", err)
+			}
+			responses[i] = resp
+			healthAuthorityIDs[i] = haID
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	for i, req := range reqs {
+		if errs[i] == nil {
+			db.updateStatsAsync(ctx, req.PublishInfo, healthAuthorityIDs[i])
+		}
+	}
+	return responses, errs, nil
+}
+
+func validateInsertAndReviseExposuresRequest(req *InsertAndReviseExposuresRequest) error {
 	if req == nil {
-		return nil, fmt.Errorf("missing request")
+		return fmt.Errorf("missing request")
 	}
 	if req.SkipRevisions && req.OnlyRevisions {
-		return nil, fmt.Errorf("configuration paradox: skipRevisions and onlyRevisions are both set to true")
+		return fmt.Errorf("configuration paradox: skipRevisions and onlyRevisions are both set to true")
+	}
+	return nil
+}
+
+// updateStatsAsync records the publish stats for the given health authority in
+// the background. It is a no-op if either stats or healthAuthorityID is nil.
+func (db *PublishDB) updateStatsAsync(ctx context.Context, stats *model.PublishInfo, healthAuthorityID *int64) {
+	if stats == nil || healthAuthorityID == nil {
+		return
 	}
 
+	logger := logging.FromContext(ctx).Named("updateStatsAsync")
+	go func() {
+		if err := db.UpdateStats(context.Background(), stats.CreatedAt, *healthAuthorityID, stats); err != nil {
+			logger.Errorw("failed to update statistics", "error", err)
+		}
+	}()
+}
+
+// insertAndReviseExposuresInTx contains the revision and insert logic for
+// InsertAndReviseExposures within an existing transaction. It returns the
+// health authority ID for the published keys (if any) so that the caller can
+// update publish stats once the transaction is committed.
+func (db *PublishDB) insertAndReviseExposuresInTx(ctx context.Context, tx pgx.Tx, req *InsertAndReviseExposuresRequest) (*InsertAndReviseExposuresResponse, *int64, error) {
+	logger := logging.FromContext(ctx).Named("InsertAndReviseExposures")
+
 	// Save a handle to the publish info stats, which may be nil.
 	stats := req.PublishInfo
 
@@ -461,8 +575,15 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 	// record of the exposures that were actually inserted/updated after merge
 	// logic.
 	var resp InsertAndReviseExposuresResponse
+	var statsHealthAuthorityID *int64
 
-	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+	if use := req.CertificateUse; use != nil {
+		if err := hadb.RecordCertificateUseInTx(ctx, tx, use.HealthAuthorityID, use.Hash, use.ExpiresAt); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := func() error {
 		// Build the base64-encoded list of keys - this is needed so we can lookup
 		// the keys in the database. Also build a lookup map by key for validation
 		// later.
@@ -669,19 +790,15 @@ func (db *PublishDB) InsertAndReviseExposures(ctx context.Context, req *InsertAn
 			// For all practical purposes - this can be no more than a couple hundred TEKs in a single transaction.
 			stats.NumTEKs = int32(resp.Inserted) + int32(resp.Revised)
 			stats.Revision = resp.Revised > 0
-			go func() {
-				if err := db.UpdateStats(context.Background(), stats.CreatedAt, *healthAuthorityID, stats); err != nil {
-					logger.Errorw("failed to update statistics", "error", err)
-				}
-			}()
+			statsHealthAuthorityID = healthAuthorityID
 		}
 
 		return nil
-	}); err != nil {
-		return nil, err
+	}(); err != nil {
+		return nil, nil, err
 	}
 
-	return &resp, nil
+	return &resp, statsHealthAuthorityID, nil
 }
 
 // DeleteExposuresBefore deletes exposures created before "before" date. Returns the number of records deleted.
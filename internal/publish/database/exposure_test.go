@@ -29,6 +29,8 @@ import (
 	"github.com/google/exposure-notifications-server/internal/pb"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
+	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
+	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/database"
 	"github.com/google/exposure-notifications-server/pkg/errcmp"
@@ -349,6 +351,128 @@ func TestInsertAndReviseExposures_ConfigParadox(t *testing.T) {
 	errcmp.MustMatch(t, err, "configuration paradox")
 }
 
+func TestInsertAndReviseExposuresBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+
+	// Insert an exposure that the batch will try to revise without a token.
+	existing := testExposure(t)
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{existing},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	existing.ReportType = verifyapi.ReportTypeConfirmed
+
+	first := testExposure(t)
+	second := testExposure(t)
+
+	reqs := []*InsertAndReviseExposuresRequest{
+		{Incoming: []*model.Exposure{first}},
+		{Incoming: []*model.Exposure{existing}, RequireToken: true},
+		nil,
+		{Incoming: []*model.Exposure{second}},
+	}
+
+	responses, errs, err := pubDB.InsertAndReviseExposuresBatch(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(responses), len(reqs); got != want {
+		t.Fatalf("expected %d responses, got %d", want, got)
+	}
+
+	for _, i := range []int{0, 3} {
+		if errs[i] != nil {
+			t.Errorf("request %d: unexpected error: %v", i, errs[i])
+			continue
+		}
+		if got, want := int(responses[i].Inserted), 1; got != want {
+			t.Errorf("request %d: expected %d to be %d", i, got, want)
+		}
+	}
+	if !errors.Is(errs[1], ErrNoRevisionToken) {
+		t.Errorf("expected %#v to be %#v", errs[1], ErrNoRevisionToken)
+	}
+	if responses[1] != nil {
+		t.Errorf("expected no response for failed request, got %#v", responses[1])
+	}
+	errcmp.MustMatch(t, errs[2], "missing request")
+
+	// The failed request must not prevent the others from being committed.
+	var got map[string]*model.Exposure
+	if err := testDB.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		var err error
+		got, err = pubDB.ReadExposures(ctx, tx, []string{
+			first.ExposureKeyBase64(), second.ExposureKeyBase64(),
+		})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("expected 2 exposures to be written, got %d", len(got))
+	}
+}
+
+func TestInsertAndReviseExposures_CertificateUse(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	pubDB := New(testDB)
+	haDB := hadb.New(testDB)
+
+	ha := &hamodel.HealthAuthority{Issuer: "iss", Audience: "aud", Name: "iss"}
+	if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Insert an exposure that a request will try to revise without a token.
+	existing := testExposure(t)
+	if _, err := pubDB.InsertAndReviseExposures(ctx, &InsertAndReviseExposuresRequest{
+		Incoming: []*model.Exposure{existing},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	existing.ReportType = verifyapi.ReportTypeConfirmed
+
+	failed := &hamodel.CertificateUse{HealthAuthorityID: ha.ID, Hash: []byte("failed"), ExpiresAt: expiresAt}
+	saved := &hamodel.CertificateUse{HealthAuthorityID: ha.ID, Hash: []byte("saved"), ExpiresAt: expiresAt}
+
+	reqs := []*InsertAndReviseExposuresRequest{
+		{Incoming: []*model.Exposure{existing}, RequireToken: true, CertificateUse: failed},
+		{Incoming: []*model.Exposure{testExposure(t)}, CertificateUse: saved},
+		{Incoming: []*model.Exposure{testExposure(t)}, CertificateUse: saved},
+	}
+
+	_, errs, err := pubDB.InsertAndReviseExposuresBatch(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(errs[0], ErrNoRevisionToken) {
+		t.Errorf("expected %#v to be %#v", errs[0], ErrNoRevisionToken)
+	}
+	if errs[1] != nil {
+		t.Errorf("unexpected error: %v", errs[1])
+	}
+	if !errors.Is(errs[2], hadb.ErrCertificateReused) {
+		t.Errorf("expected %#v to be %#v", errs[2], hadb.ErrCertificateReused)
+	}
+
+	// The certificate of the failed request must not be used up.
+	if used, err := haDB.CertificateUsed(ctx, ha.ID, failed.Hash); err != nil || used {
+		t.Errorf("expected certificate to be unused, got used=%t err=%v", used, err)
+	}
+	if used, err := haDB.CertificateUsed(ctx, ha.ID, saved.Hash); err != nil || !used {
+		t.Errorf("expected certificate to be used, got used=%t err=%v", used, err)
+	}
+}
+
 func TestReviseExposures(t *testing.T) {
 	t.Parallel()
 
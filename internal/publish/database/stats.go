@@ -62,7 +62,8 @@ func (db *PublishDB) ReadStats(ctx context.Context, healthAuthorityID int64) ([]
 	err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 		SELECT
-			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset
+			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+			symptom_onset, test_date_onset, report_types, travelers
 		FROM
 			HealthAuthorityStats
 		WHERE
@@ -93,7 +94,8 @@ func (db *PublishDB) ReadStats(ctx context.Context, healthAuthorityID int64) ([]
 func scanOneHealthAuthorityStats(rows pgx.Row, stats *model.HealthAuthorityStats) error {
 	return rows.Scan(
 		&stats.HealthAuthorityID, &stats.Hour, &stats.PublishCount, &stats.TEKCount,
-		&stats.RevisionCount, &stats.OldestTekDays, &stats.OnsetAgeDays, &stats.MissingOnset)
+		&stats.RevisionCount, &stats.OldestTekDays, &stats.OnsetAgeDays, &stats.MissingOnset,
+		&stats.SymptomOnset, &stats.TestDateOnset, &stats.ReportTypeCount, &stats.TravelerCount)
 }
 
 // UpdateStats performance a read-modify-write to update the requested stats.
@@ -113,7 +115,8 @@ func (db *PublishDB) UpdateStatsInTx(ctx context.Context, tx pgx.Tx, hour time.T
 
 	rows, err := tx.Query(ctx, `
 		SELECT
-			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset
+			health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+			symptom_onset, test_date_onset, report_types, travelers
 		FROM
 			HealthAuthorityStats
 		WHERE
@@ -141,15 +144,18 @@ func (db *PublishDB) UpdateStatsInTx(ctx context.Context, tx pgx.Tx, hour time.T
 	_, err = tx.Exec(ctx, `
 		INSERT INTO
 			HealthAuthorityStats
-			(health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset)
+			(health_authority_id, hour, publish, teks, revisions, oldest_tek_days, onset_age_days, missing_onset,
+			symptom_onset, test_date_onset, report_types, travelers)
 		VALUES
-			($1, $2, $3, $4, $5, $6, $7, $8)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		ON CONFLICT (health_authority_id, hour) DO
 			UPDATE
-			SET publish=$3, teks=$4, revisions=$5, oldest_tek_days=$6, onset_age_days=$7, missing_onset=$8
+			SET publish=$3, teks=$4, revisions=$5, oldest_tek_days=$6, onset_age_days=$7, missing_onset=$8,
+				symptom_onset=$9, test_date_onset=$10, report_types=$11, travelers=$12
 		`,
 		stats.HealthAuthorityID, stats.Hour, stats.PublishCount, stats.TEKCount, stats.RevisionCount,
-		stats.OldestTekDays, stats.OnsetAgeDays, stats.MissingOnset)
+		stats.OldestTekDays, stats.OnsetAgeDays, stats.MissingOnset,
+		stats.SymptomOnset, stats.TestDateOnset, stats.ReportTypeCount, stats.TravelerCount)
 	if err != nil {
 		return fmt.Errorf("update stats: %w", err)
 	}
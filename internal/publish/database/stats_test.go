@@ -22,6 +22,8 @@ import (
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	hadb "github.com/google/exposure-notifications-server/internal/verification/database"
 	hamodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDeleteStatsBefore(t *testing.T) {
@@ -114,6 +116,8 @@ func TestReadStats(t *testing.T) {
 		OldestDays:   14,
 		OnsetDaysAgo: 4,
 		MissingOnset: false,
+		ReportType:   verifyapi.ReportTypeClinical,
+		Traveler:     true,
 	}
 
 	startTime := time.Now().UTC().Add(-10 * time.Hour).Truncate(time.Hour)
@@ -133,4 +137,11 @@ func TestReadStats(t *testing.T) {
 	if len(stats) != 11 {
 		t.Fatalf("added 11 hours of stats, got: %v", len(stats))
 	}
+
+	if diff := cmp.Diff([]int64{0, 0, 1, 0}, stats[0].ReportTypeCount); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if got, want := stats[0].TravelerCount, int64(1); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
 }
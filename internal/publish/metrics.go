@@ -35,11 +35,16 @@ var (
 	mNoPublicKey = stats.Int64(publishMetricsPrefix+"no_public_keys",
 		"uploads where there is no public key", stats.UnitDimensionless)
 
+	mCertificateReused = stats.Int64(publishMetricsPrefix+"certificate_reused",
+		"uploads with an already used verification certificate", stats.UnitDimensionless)
+
 	mExposuresCount = stats.Int64(publishMetricsPrefix+"exposures_count",
 		"exposure count", stats.UnitDimensionless)
 
 	mVerificationBypassed = stats.Int64(publishMetricsPrefix+"verification_bypassed",
 		"Instances of health authority verification being bypassed", stats.UnitDimensionless)
+	mBatchSize = stats.Int64(publishMetricsPrefix+"batch_size",
+		"number of publish requests in a batch publish request", stats.UnitDimensionless)
 	// v1 and v1alpha1
 	mPaddingFailed = stats.Int64(publishMetricsPrefix+"padding_failed",
 		"Instances of response padding failures", stats.UnitDimensionless)
@@ -110,6 +115,19 @@ func init() {
 			Aggregation: view.Sum(),
 			TagKeys:     missingPublicKeyTags,
 		},
+		{
+			Name:        metrics.MetricRoot + "certificate_reused",
+			Description: "Total count of rejected reused verification certificates",
+			Measure:     mCertificateReused,
+			Aggregation: view.Sum(),
+			TagKeys:     missingPublicKeyTags,
+		},
+		{
+			Name:        metrics.MetricRoot + "batch_size",
+			Description: "Distribution of the number of publish requests in a batch",
+			Measure:     mBatchSize,
+			Aggregation: view.Distribution(1, 10, 50, 100, 250, 500, 1000),
+		},
 		// v1 and v1alpha1
 		{
 			Name:        metrics.MetricRoot + "padding_failed",
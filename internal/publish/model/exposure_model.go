@@ -26,6 +26,7 @@ import (
 
 	"github.com/google/exposure-notifications-server/internal/pb/export"
 	"github.com/google/exposure-notifications-server/internal/verification"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
 	"github.com/google/exposure-notifications-server/pkg/base64util"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	"github.com/google/exposure-notifications-server/pkg/timeutils"
@@ -457,6 +458,33 @@ func NewTransformer(config TransformerConfig) (*Transformer, error) {
 	}, nil
 }
 
+// withPolicy returns a copy of the transformer with its limits overridden by
+// the health authority's policy. The transformer itself is returned if there is
+// no policy.
+func (t *Transformer) withPolicy(policy *vermodel.TransformPolicy) *Transformer {
+	if policy.IsEmpty() {
+		return t
+	}
+
+	pt := *t
+	if v := policy.MaxExposureKeys; v != nil {
+		pt.maxExposureKeys = int(*v)
+	}
+	if v := policy.MaxIntervalStartAge; v != nil {
+		pt.maxIntervalStartAge = *v
+	}
+	if v := policy.MaxSymptomOnsetDays; v != nil {
+		pt.maxSymptomOnsetDays = float64(*v)
+	}
+	if v := policy.MaxValidSymptomOnsetReportDays; v != nil {
+		pt.maxValidSymptomOnsetReportDays = *v
+	}
+	if v := policy.DefaultSymptomOnsetDaysAgo; v != nil {
+		pt.defaultSymptomOnsetDaysAgo = *v
+	}
+	return &pt
+}
+
 // KeyTransform represents the settings to apply when transforming an individual key on a publish request.
 type KeyTransform struct {
 	MinStartInterval      int32
@@ -573,6 +601,11 @@ type TransformPublishResult struct {
 func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Publish, regions []string, claims *verification.VerifiedClaims, batchTime time.Time) (*TransformPublishResult, error) {
 	logger := logging.FromContext(ctx).Named("TransformPublish")
 
+	// Health authorities may override the configured limits.
+	if claims != nil {
+		t = t.withPolicy(claims.TransformPolicy)
+	}
+
 	if t.debugReleaseSameDay {
 		logger.Warnw("DEBUG SERVER - CURRENT DAYS KEYS ARE NOT EMBARGOED!")
 	}
@@ -597,6 +630,10 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 	// here and returned for further updating.
 	stats := &PublishInfo{
 		CreatedAt: defaultCreatedAt,
+		Traveler:  inData.Traveler,
+	}
+	if claims != nil {
+		stats.ReportType = claims.ReportType
 	}
 
 	settings := KeyTransform{
@@ -619,6 +656,7 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 
 	// Base level, assume there is no symptom onset interval present.
 	onsetInterval := int32(0)
+	testDateOnset := false
 	if pubInt := inData.SymptomOnsetInterval; pubInt < currentInterval && pubInt >= minSymptomInterval {
 		onsetInterval = pubInt
 	} else if claims != nil {
@@ -626,6 +664,11 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 			// If the symtom onset interval provided on publish is too old to be relevant
 			// and one was provided in the verification certificate, take that one.
 			onsetInterval = int32(claims.SymptomOnsetInterval)
+		} else if tdInt := int32(claims.TestDateInterval); tdInt < currentInterval && tdInt >= minSymptomInterval {
+			// Without any symptom onset, estimate it from the test date using the
+			// health authority's offset.
+			onsetInterval = IntervalNumber(timeutils.SubtractDays(TimeForIntervalNumber(tdInt), claims.TestDateOnsetOffsetDays))
+			testDateOnset = true
 		}
 	}
 	// If we reach this point, and onsetInterval is 0 OR if the onset interval
@@ -644,6 +687,7 @@ func (t *Transformer) TransformPublish(ctx context.Context, inData *verifyapi.Pu
 	// If an onset was provided, that should be put in the stats for this publish.
 	if !stats.MissingOnset {
 		stats.OnsetDaysAgo = int(DaysBetweenIntervals(onsetInterval, currentInterval))
+		stats.TestDateOnset = testDateOnset
 	}
 
 	// Regions are a multi-value property, uppercase them for storage.
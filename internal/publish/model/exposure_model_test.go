@@ -27,6 +27,7 @@ import (
 	"github.com/google/exposure-notifications-server/internal/pb/export"
 	"github.com/google/exposure-notifications-server/internal/project"
 	"github.com/google/exposure-notifications-server/internal/verification"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/base64util"
 	"github.com/google/exposure-notifications-server/pkg/errcmp"
@@ -624,6 +625,7 @@ func TestTransform(t *testing.T) {
 					},
 				},
 				HealthAuthorityID: appPackage,
+				Traveler:          true,
 			},
 			Regions: wantRegions,
 			Claims: &verification.VerifiedClaims{
@@ -641,6 +643,7 @@ func TestTransform(t *testing.T) {
 					LocalProvenance:       true,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(-3),
+					Traveler:              true,
 				},
 				{
 					ExposureKey:           testKeys[1],
@@ -653,12 +656,15 @@ func TestTransform(t *testing.T) {
 					LocalProvenance:       true,
 					ReportType:            verifyapi.ReportTypeConfirmed,
 					DaysSinceSymptomOnset: int32Ptr(-2),
+					Traveler:              true,
 				},
 			},
 			WantStats: &PublishInfo{
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   7,
 				MissingOnset: true,
+				ReportType:   verifyapi.ReportTypeConfirmed,
+				Traveler:     true,
 			},
 		},
 		{
@@ -733,6 +739,7 @@ func TestTransform(t *testing.T) {
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   7,
 				OnsetDaysAgo: 6,
+				ReportType:   verifyapi.ReportTypeConfirmed,
 			},
 		},
 		{
@@ -808,6 +815,7 @@ func TestTransform(t *testing.T) {
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   7,
 				OnsetDaysAgo: 5,
+				ReportType:   verifyapi.ReportTypeClinical,
 			},
 		},
 		{
@@ -884,6 +892,7 @@ func TestTransform(t *testing.T) {
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   7,
 				OnsetDaysAgo: 6,
+				ReportType:   verifyapi.ReportTypeClinical,
 			},
 		},
 		{
@@ -959,6 +968,7 @@ func TestTransform(t *testing.T) {
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   5,
 				MissingOnset: true,
+				ReportType:   verifyapi.ReportTypeClinical,
 			},
 		},
 		{
@@ -1003,6 +1013,7 @@ func TestTransform(t *testing.T) {
 				CreatedAt:    batchTimeRounded,
 				OldestDays:   7,
 				OnsetDaysAgo: 21,
+				ReportType:   verifyapi.ReportTypeClinical,
 			},
 			Warnings: []string{"key 1 symptom onset is too large, 15 > 14 - saving without this key"},
 		},
@@ -1143,6 +1154,228 @@ func TestDefaultSymptomOnset(t *testing.T) {
 	}
 }
 
+func TestTestDateOnset(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	onsetDaysAgo := uint(4)
+	keyInterval := IntervalNumber(timeutils.SubtractDays(now, 3))
+	intervalDaysAgo := func(days uint) uint32 {
+		return uint32(IntervalNumber(timeutils.UTCMidnight(timeutils.SubtractDays(now, days))))
+	}
+
+	cases := []struct {
+		name               string
+		claims             *verification.VerifiedClaims
+		wantDaysSinceOnset *int32
+		wantStats          *PublishInfo
+	}{
+		{
+			name: "test_date",
+			claims: &verification.VerifiedClaims{
+				TestDateInterval: intervalDaysAgo(2),
+			},
+			wantDaysSinceOnset: int32Ptr(-1),
+			wantStats:          &PublishInfo{OnsetDaysAgo: 2, TestDateOnset: true},
+		},
+		{
+			name: "test_date_with_offset",
+			claims: &verification.VerifiedClaims{
+				TestDateInterval:        intervalDaysAgo(2),
+				TestDateOnsetOffsetDays: 2,
+			},
+			wantDaysSinceOnset: int32Ptr(1),
+			wantStats:          &PublishInfo{OnsetDaysAgo: 4, TestDateOnset: true},
+		},
+		{
+			name: "symptom_onset_preferred",
+			claims: &verification.VerifiedClaims{
+				SymptomOnsetInterval:    intervalDaysAgo(5),
+				TestDateInterval:        intervalDaysAgo(2),
+				TestDateOnsetOffsetDays: 2,
+			},
+			wantDaysSinceOnset: int32Ptr(2),
+			wantStats:          &PublishInfo{OnsetDaysAgo: 5},
+		},
+		{
+			name: "offset_too_large",
+			claims: &verification.VerifiedClaims{
+				TestDateInterval:        intervalDaysAgo(2),
+				TestDateOnsetOffsetDays: 30,
+			},
+			wantDaysSinceOnset: int32Ptr(1),
+			wantStats:          &PublishInfo{MissingOnset: true},
+		},
+		{
+			name: "test_date_too_old",
+			claims: &verification.VerifiedClaims{
+				TestDateInterval: intervalDaysAgo(30),
+			},
+			wantDaysSinceOnset: int32Ptr(1),
+			wantStats:          &PublishInfo{MissingOnset: true},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			transformer, err := NewTransformer(&testConfig{
+				maxExposureKeys:                10,
+				maxSameDayKeys:                 1,
+				maxIntervalStartAge:            6 * 24 * time.Hour,
+				truncateWindow:                 time.Minute,
+				maxSymptomOnsetDays:            maxSymptomOnsetDays,
+				maxValidSymptomOnsetReportDays: 14,
+				defaultSymptomOnsetDays:        onsetDaysAgo,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source := &verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{
+					{
+						Key:            encodeKey(generateKey(t)),
+						IntervalNumber: keyInterval,
+						IntervalCount:  verifyapi.MaxIntervalCount,
+					},
+				},
+			}
+
+			ctx := project.TestContext(t)
+			result, err := transformer.TransformPublish(ctx, source, []string{}, tc.claims, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Exposures) != 1 {
+				t.Fatalf("wrong number of keys, want: 1 got :%v", len(result.Exposures))
+			}
+			if diff := cmp.Diff(tc.wantDaysSinceOnset, result.Exposures[0].DaysSinceSymptomOnset); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+
+			opts := cmpopts.IgnoreFields(PublishInfo{}, "CreatedAt", "OldestDays")
+			if diff := cmp.Diff(tc.wantStats, result.PublishInfo, opts); diff != "" {
+				t.Errorf("stats mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTransformPolicy(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	keyInterval := IntervalNumber(timeutils.SubtractDays(now, 3))
+	uintPtr := func(v uint) *uint { return &v }
+	durationPtr := func(d time.Duration) *time.Duration { return &d }
+
+	cases := []struct {
+		name               string
+		policy             *vermodel.TransformPolicy
+		numKeys            int
+		symptomOnsetDays   uint
+		wantErr            string
+		wantKeys           int
+		wantDaysSinceOnset *int32
+	}{
+		{
+			name:               "no_policy",
+			numKeys:            1,
+			wantKeys:           1,
+			wantDaysSinceOnset: int32Ptr(1),
+		},
+		{
+			name:     "max_exposure_keys",
+			policy:   &vermodel.TransformPolicy{MaxExposureKeys: uintPtr(1)},
+			numKeys:  2,
+			wantErr:  "too many exposure keys in publish: 2, max of 1 is allowed",
+			wantKeys: 0,
+		},
+		{
+			name:     "max_interval_start_age",
+			policy:   &vermodel.TransformPolicy{MaxIntervalStartAge: durationPtr(2 * 24 * time.Hour)},
+			numKeys:  1,
+			wantErr:  "key 0 cannot be imported",
+			wantKeys: 0,
+		},
+		{
+			name:     "max_symptom_onset_days",
+			policy:   &vermodel.TransformPolicy{MaxSymptomOnsetDays: uintPtr(0)},
+			numKeys:  1,
+			wantKeys: 0,
+		},
+		{
+			name:               "max_valid_symptom_onset_report_days",
+			policy:             &vermodel.TransformPolicy{MaxValidSymptomOnsetReportDays: uintPtr(4)},
+			numKeys:            1,
+			symptomOnsetDays:   5,
+			wantKeys:           1,
+			wantDaysSinceOnset: int32Ptr(1),
+		},
+		{
+			name:               "default_symptom_onset_days_ago",
+			policy:             &vermodel.TransformPolicy{DefaultSymptomOnsetDaysAgo: uintPtr(1)},
+			numKeys:            1,
+			wantKeys:           1,
+			wantDaysSinceOnset: int32Ptr(-2),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			transformer, err := NewTransformer(&testConfig{
+				maxExposureKeys:                10,
+				maxSameDayKeys:                 1,
+				maxIntervalStartAge:            6 * 24 * time.Hour,
+				truncateWindow:                 time.Minute,
+				maxSymptomOnsetDays:            maxSymptomOnsetDays,
+				maxValidSymptomOnsetReportDays: 14,
+				defaultSymptomOnsetDays:        4,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			source := &verifyapi.Publish{}
+			for i := 0; i < tc.numKeys; i++ {
+				source.Keys = append(source.Keys, verifyapi.ExposureKey{
+					Key:            encodeKey(generateKey(t)),
+					IntervalNumber: keyInterval - int32(i)*verifyapi.MaxIntervalCount,
+					IntervalCount:  verifyapi.MaxIntervalCount,
+				})
+			}
+			claims := &verification.VerifiedClaims{TransformPolicy: tc.policy}
+			if tc.symptomOnsetDays > 0 {
+				claims.SymptomOnsetInterval = uint32(IntervalNumber(
+					timeutils.UTCMidnight(timeutils.SubtractDays(now, tc.symptomOnsetDays))))
+			}
+
+			ctx := project.TestContext(t)
+			result, err := transformer.TransformPublish(ctx, source, []string{}, claims, now)
+			errcmp.MustMatch(t, err, tc.wantErr)
+
+			if got, want := len(result.Exposures), tc.wantKeys; got != want {
+				t.Fatalf("wrong number of keys, want: %v got: %v", want, got)
+			}
+			if tc.wantKeys == 0 {
+				return
+			}
+			if diff := cmp.Diff(tc.wantDaysSinceOnset, result.Exposures[0].DaysSinceSymptomOnset); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestTransformOverlapping(t *testing.T) {
 	t.Parallel()
 
@@ -48,6 +48,20 @@ func platformToInt(platform string) int {
 	}
 }
 
+// Turns a report type into an int for calculation.
+func reportTypeToInt(reportType string) int {
+	switch reportType {
+	case verifyapi.ReportTypeConfirmed:
+		return 1
+	case verifyapi.ReportTypeClinical:
+		return 2
+	case verifyapi.ReportTypeSelfReport:
+		return 3
+	default:
+		return 0
+	}
+}
+
 // HealthAuthorityStats represents the raw metrics for an individual
 // health authority for a given hour.
 type HealthAuthorityStats struct {
@@ -59,40 +73,62 @@ type HealthAuthorityStats struct {
 	OldestTekDays     []int64
 	OnsetAgeDays      []int64
 	MissingOnset      int64
+	SymptomOnset      int64
+	TestDateOnset     int64
+	ReportTypeCount   []int64
+	TravelerCount     int64
 }
 
 // ReduceStats takes hourly breakdowns and rolls them up to daily. The onlyBefore
 // time indicates the cutoff point for inclusion.
 // The dayThreshold indicates how many entries are needed to include a given day.
 // Day boundaries are all in UTC.
-func ReduceStats(hourly []*HealthAuthorityStats, onlyBefore time.Time, dayThreshold int64, embargoPeriod time.Duration) []*verifyapi.StatsDay {
+//
+// The released days are then combined into the requested window. Since only
+// released days are combined, the threshold applies to every day in a week or
+// month and a window can't be used to infer the stats of a withheld day. If
+// afterDaysSinceFirstKey is not nil, only windows that contain a day at or
+// after that many days since the first key are returned.
+//
+// If noise is not nil, it is added to each day before the threshold is
+// applied, so the threshold is compared to the noisy number of publish
+// requests. The noise for a day is the same in every release, so with noise
+// only complete UTC days before onlyBefore are included. Otherwise two releases
+// of the same day would reveal the exact change between them.
+func ReduceStats(hourly []*HealthAuthorityStats, onlyBefore time.Time, dayThreshold int64, embargoPeriod time.Duration, window string, afterDaysSinceFirstKey *int64, noise *StatsNoise) []*verifyapi.StatsDay {
 	embargoRelease := embargoPeriod > 0
 	now := time.Now().UTC()
 
+	if noise != nil {
+		onlyBefore = timeutils.UTCMidnight(onlyBefore)
+	}
+
+	var healthAuthorityID int64
 	days := make(map[time.Time]*verifyapi.StatsDay)
 	// Combine the hours into days based on same UTC midnight time.
 	for _, hour := range hourly {
 		if !hour.Hour.Before(onlyBefore) {
 			continue
 		}
+		healthAuthorityID = hour.HealthAuthorityID
 
 		day := timeutils.UTCMidnight(hour.Hour)
-		if _, ok := days[day]; !ok {
+		metricsDay, ok := days[day]
+		if !ok {
 			// Initialize this day
-			days[day] = &verifyapi.StatsDay{
-				Day:                       day,
-				TEKAgeDistribution:        make([]int64, StatsMaxOldestTEK+1),
-				OnsetToUploadDistribution: make([]int64, StatsMaxOnsetDays+1),
-			}
+			metricsDay = newStatsDay(day)
+			days[day] = metricsDay
 		}
 
-		metricsDay := days[day]
 		metricsDay.PublishRequests.Android += hour.PublishCount[platformToInt(PlatformAndroid)]
 		metricsDay.PublishRequests.IOS += hour.PublishCount[platformToInt(PlatformIOS)]
 		metricsDay.PublishRequests.UnknownPlatform += hour.PublishCount[platformToInt(PlatformUnknown)]
 		metricsDay.TotalTEKsPublished += hour.TEKCount
 		metricsDay.RevisionRequests += hour.RevisionCount
 		metricsDay.RequestsMissingOnsetDate += hour.MissingOnset
+		metricsDay.RequestsWithSymptomOnset += hour.SymptomOnset
+		metricsDay.RequestsWithTestDate += hour.TestDateOnset
+		metricsDay.TravelerRequests += hour.TravelerCount
 
 		for i := 0; i <= StatsMaxOldestTEK && i < len(hour.OldestTekDays); i++ {
 			metricsDay.TEKAgeDistribution[i] += hour.OldestTekDays[i]
@@ -100,27 +136,128 @@ func ReduceStats(hourly []*HealthAuthorityStats, onlyBefore time.Time, dayThresh
 		for i := 0; i <= StatsMaxOnsetDays && i < len(hour.OnsetAgeDays); i++ {
 			metricsDay.OnsetToUploadDistribution[i] += hour.OnsetAgeDays[i]
 		}
+		if len(hour.ReportTypeCount) > reportTypeToInt(verifyapi.ReportTypeSelfReport) {
+			metricsDay.ReportTypes.UnknownReportType += hour.ReportTypeCount[reportTypeToInt("")]
+			metricsDay.ReportTypes.Confirmed += hour.ReportTypeCount[reportTypeToInt(verifyapi.ReportTypeConfirmed)]
+			metricsDay.ReportTypes.Likely += hour.ReportTypeCount[reportTypeToInt(verifyapi.ReportTypeClinical)]
+			metricsDay.ReportTypes.SelfReport += hour.ReportTypeCount[reportTypeToInt(verifyapi.ReportTypeSelfReport)]
+		}
 	}
 
 	// Bring the map back to an array
-	result := make([]*verifyapi.StatsDay, 0, len(days))
+	released := make([]*verifyapi.StatsDay, 0, len(days))
 	for _, day := range days {
+		if noise != nil {
+			noise.addNoise(healthAuthorityID, day)
+		}
+
 		endOfDay := timeutils.UTCMidnight(day.Day.Add(24 * time.Hour))
 		embargoOver := endOfDay.Add(embargoPeriod).Before(now)
 		okToShow := day.PublishRequests.Total() >= dayThreshold || (embargoRelease && embargoOver)
 		if !okToShow {
 			continue
 		}
-		result = append(result, day)
+		released = append(released, day)
 	}
 	// Sort before returning.
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Day.Before(result[j].Day)
+	sort.Slice(released, func(i, j int) bool {
+		return released[i].Day.Before(released[j].Day)
 	})
 
+	// The first key day is based on released days only, so it doesn't reveal
+	// anything about withheld days.
+	var firstKeyDay time.Time
+	for _, day := range released {
+		if day.TotalTEKsPublished > 0 {
+			firstKeyDay = day.Day
+			break
+		}
+	}
+	daysSince := func(t time.Time) int64 {
+		if firstKeyDay.IsZero() {
+			return 0
+		}
+		return int64(t.Sub(firstKeyDay).Hours()) / 24
+	}
+
+	result := make([]*verifyapi.StatsDay, 0, len(released))
+	for _, day := range released {
+		start := windowStart(day.Day, window)
+		if afterDaysSinceFirstKey != nil && daysSince(windowEnd(start, window))-1 < *afterDaysSinceFirstKey {
+			continue
+		}
+
+		if n := len(result); n == 0 || !result[n-1].Day.Equal(start) {
+			bucket := newStatsDay(start)
+			bucket.DaysSinceFirstKey = daysSince(start)
+			result = append(result, bucket)
+		}
+		addStatsDay(result[len(result)-1], day)
+	}
+
 	return result
 }
 
+// newStatsDay creates an empty StatsDay starting on the given day.
+func newStatsDay(day time.Time) *verifyapi.StatsDay {
+	return &verifyapi.StatsDay{
+		Day:                       day,
+		TEKAgeDistribution:        make([]int64, StatsMaxOldestTEK+1),
+		OnsetToUploadDistribution: make([]int64, StatsMaxOnsetDays+1),
+	}
+}
+
+// addStatsDay adds the stats of day to the window.
+func addStatsDay(window, day *verifyapi.StatsDay) {
+	window.PublishRequests.Android += day.PublishRequests.Android
+	window.PublishRequests.IOS += day.PublishRequests.IOS
+	window.PublishRequests.UnknownPlatform += day.PublishRequests.UnknownPlatform
+	window.TotalTEKsPublished += day.TotalTEKsPublished
+	window.RevisionRequests += day.RevisionRequests
+	window.RequestsMissingOnsetDate += day.RequestsMissingOnsetDate
+	window.RequestsWithSymptomOnset += day.RequestsWithSymptomOnset
+	window.RequestsWithTestDate += day.RequestsWithTestDate
+	window.TravelerRequests += day.TravelerRequests
+	window.ReportTypes.UnknownReportType += day.ReportTypes.UnknownReportType
+	window.ReportTypes.Confirmed += day.ReportTypes.Confirmed
+	window.ReportTypes.Likely += day.ReportTypes.Likely
+	window.ReportTypes.SelfReport += day.ReportTypes.SelfReport
+
+	for i := range window.TEKAgeDistribution {
+		window.TEKAgeDistribution[i] += day.TEKAgeDistribution[i]
+	}
+	for i := range window.OnsetToUploadDistribution {
+		window.OnsetToUploadDistribution[i] += day.OnsetToUploadDistribution[i]
+	}
+}
+
+// windowStart returns the UTC midnight that starts the window containing day.
+// Weeks start on Monday.
+func windowStart(day time.Time, window string) time.Time {
+	day = timeutils.UTCMidnight(day)
+	switch window {
+	case verifyapi.StatsWindowWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case verifyapi.StatsWindowMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// windowEnd returns the UTC midnight that ends the window starting at start.
+func windowEnd(start time.Time, window string) time.Time {
+	switch window {
+	case verifyapi.StatsWindowWeek:
+		return start.AddDate(0, 0, 7)
+	case verifyapi.StatsWindowMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
 // InitHour creates a HealthAuthorityStats record for specified hour.
 func InitHour(healthAuthorityID int64, hour time.Time) *HealthAuthorityStats {
 	return &HealthAuthorityStats{
@@ -132,6 +269,10 @@ func InitHour(healthAuthorityID int64, hour time.Time) *HealthAuthorityStats {
 		OldestTekDays:     make([]int64, StatsMaxOldestTEK+1),
 		OnsetAgeDays:      make([]int64, StatsMaxOnsetDays+1),
 		MissingOnset:      0,
+		SymptomOnset:      0,
+		TestDateOnset:     0,
+		ReportTypeCount:   make([]int64, 4),
+		TravelerCount:     0,
 	}
 }
 
@@ -144,6 +285,11 @@ type PublishInfo struct {
 	OldestDays   int
 	OnsetDaysAgo int
 	MissingOnset bool
+	// TestDateOnset indicates the onset was estimated from the test date in the
+	// verification certificate, rather than given as a symptom onset date.
+	TestDateOnset bool
+	ReportType    string
+	Traveler      bool
 }
 
 // AddPublish increments the stats for a given hour. This should be called
@@ -157,6 +303,10 @@ type PublishInfo struct {
 // applyes the in-memory logic.
 func (has *HealthAuthorityStats) AddPublish(info *PublishInfo) {
 	has.PublishCount[platformToInt(info.Platform)]++
+	has.ReportTypeCount[reportTypeToInt(info.ReportType)]++
+	if info.Traveler {
+		has.TravelerCount++
+	}
 
 	has.TEKCount += int64(info.NumTEKs)
 	if info.Revision {
@@ -173,6 +323,11 @@ func (has *HealthAuthorityStats) AddPublish(info *PublishInfo) {
 	if info.MissingOnset {
 		has.MissingOnset++
 	} else {
+		if info.TestDateOnset {
+			has.TestDateOnset++
+		} else {
+			has.SymptomOnset++
+		}
 		if oAge, length := info.OnsetDaysAgo, len(has.OnsetAgeDays); oAge >= 0 && oAge < length {
 			has.OnsetAgeDays[oAge]++
 		} else if oAge >= length {
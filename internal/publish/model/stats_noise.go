@@ -0,0 +1,126 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"strconv"
+
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+)
+
+// StatsNoise adds differential privacy noise to the stats released to a
+// health authority. The noise for a day is derived from a keyed hash of the
+// health authority and the day, so repeated requests return the same values
+// and averaging many responses doesn't remove the noise.
+type StatsNoise struct {
+	// Mechanism is vermodel.StatsNoiseLaplace or vermodel.StatsNoiseGaussian.
+	Mechanism string
+	// Epsilon is the privacy budget spent on each counter. A publish request
+	// changes up to 8 counters of its day, so up to 8 times Epsilon is spent
+	// on each publish request.
+	Epsilon float64
+	// Delta is the probability that the privacy budget is exceeded, it is only
+	// used by the Gaussian mechanism.
+	Delta float64
+	// MaxKeysPerPublish is the most a single publish request can change the
+	// number of published TEKs, see StatsMaxKeysPerPublish.
+	MaxKeysPerPublish int64
+	// Key seeds the noise. Anyone with the key can remove the noise, so it
+	// must be kept secret.
+	Key []byte
+}
+
+// StatsMaxKeysPerPublish returns the most keys a single publish request
+// verified by the health authority can contain, the larger of the server's
+// limit and the health authority's transform policy.
+func StatsMaxKeysPerPublish(serverMax int64, ha *vermodel.HealthAuthority) int64 {
+	max := serverMax
+	if policy := ha.TransformPolicy; policy != nil && policy.MaxExposureKeys != nil {
+		if v := int64(*policy.MaxExposureKeys); v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// addNoise replaces every counter and distribution bucket of day with a noisy
+// value. Counters are never negative after noise is added.
+func (n *StatsNoise) addNoise(healthAuthorityID int64, day *verifyapi.StatsDay) {
+	r := n.rand(healthAuthorityID, day)
+
+	// The order the counters are drawn in must not change, otherwise the noise
+	// for days that were already released would change.
+	for _, v := range []*int64{
+		&day.PublishRequests.UnknownPlatform,
+		&day.PublishRequests.Android,
+		&day.PublishRequests.IOS,
+		&day.RevisionRequests,
+		&day.RequestsMissingOnsetDate,
+		&day.RequestsWithSymptomOnset,
+		&day.RequestsWithTestDate,
+		&day.ReportTypes.UnknownReportType,
+		&day.ReportTypes.Confirmed,
+		&day.ReportTypes.Likely,
+		&day.ReportTypes.SelfReport,
+		&day.TravelerRequests,
+	} {
+		*v = n.noisy(r, *v, 1)
+	}
+	day.TotalTEKsPublished = n.noisy(r, day.TotalTEKsPublished, float64(n.MaxKeysPerPublish))
+	for i, v := range day.TEKAgeDistribution {
+		day.TEKAgeDistribution[i] = n.noisy(r, v, 1)
+	}
+	for i, v := range day.OnsetToUploadDistribution {
+		day.OnsetToUploadDistribution[i] = n.noisy(r, v, 1)
+	}
+}
+
+// rand returns the deterministic random source for a health authority's day.
+func (n *StatsNoise) rand(healthAuthorityID int64, day *verifyapi.StatsDay) *rand.Rand {
+	mac := hmac.New(sha256.New, n.Key)
+	mac.Write([]byte(strconv.FormatInt(healthAuthorityID, 10)))
+	mac.Write([]byte{'/'})
+	mac.Write([]byte(day.Day.UTC().Format("2006-01-02")))
+	seed := binary.BigEndian.Uint64(mac.Sum(nil))
+
+	//nolint:gosec // the seed is secret, the source must be deterministic.
+	return rand.New(rand.NewSource(int64(seed)))
+}
+
+// noisy returns v with noise for the given sensitivity added, rounded and
+// clamped to zero.
+func (n *StatsNoise) noisy(r *rand.Rand, v int64, sensitivity float64) int64 {
+	var noise float64
+	switch n.Mechanism {
+	case vermodel.StatsNoiseGaussian:
+		sigma := sensitivity * math.Sqrt(2*math.Log(1.25/n.Delta)) / n.Epsilon
+		noise = r.NormFloat64() * sigma
+	default:
+		// The difference of two exponential variables is Laplace distributed.
+		b := sensitivity / n.Epsilon
+		noise = b * (r.ExpFloat64() - r.ExpFloat64())
+	}
+
+	if result := math.Round(float64(v) + noise); result > 0 {
+		return int64(result)
+	}
+	return 0
+}
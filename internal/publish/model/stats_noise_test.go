@@ -0,0 +1,222 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatsNoise_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	input := []*HealthAuthorityStats{
+		InitHour(42, day.Add(time.Hour)),
+		InitHour(42, day.Add(25*time.Hour)),
+	}
+	for _, hour := range input {
+		hour.PublishCount[platformToInt(PlatformAndroid)] = 50
+		hour.TEKCount = 500
+	}
+	onlyBefore := day.Add(72 * time.Hour)
+
+	noise := &StatsNoise{
+		Mechanism:         vermodel.StatsNoiseLaplace,
+		Epsilon:           1,
+		MaxKeysPerPublish: 30,
+		Key:               []byte("key"),
+	}
+	first := ReduceStats(input, onlyBefore, 10, 0, verifyapi.StatsWindowDay, nil, noise)
+	second := ReduceStats(input, onlyBefore, 10, 0, verifyapi.StatsWindowDay, nil, noise)
+	if diff := cmp.Diff(first, second); diff != "" {
+		t.Errorf("repeated queries returned different stats (-first, +second):\n%s", diff)
+	}
+
+	exact := ReduceStats(input, onlyBefore, 10, 0, verifyapi.StatsWindowDay, nil, nil)
+	if diff := cmp.Diff(exact, first); diff == "" {
+		t.Errorf("expected noise to be added")
+	}
+
+	otherKey := *noise
+	otherKey.Key = []byte("other")
+	other := ReduceStats(input, onlyBefore, 10, 0, verifyapi.StatsWindowDay, nil, &otherKey)
+	if diff := cmp.Diff(first, other); diff == "" {
+		t.Errorf("expected different keys to produce different noise")
+	}
+}
+
+func TestStatsNoise_CompleteDays(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	morning := InitHour(42, day.Add(2*time.Hour))
+	morning.PublishCount[platformToInt(PlatformAndroid)] = 50
+	evening := InitHour(42, day.Add(18*time.Hour))
+	evening.PublishCount[platformToInt(PlatformAndroid)] = 1
+	hourly := []*HealthAuthorityStats{morning, evening}
+
+	noise := &StatsNoise{
+		Mechanism:         vermodel.StatsNoiseLaplace,
+		Epsilon:           1,
+		MaxKeysPerPublish: 30,
+		Key:               []byte("key"),
+	}
+	reduce := func(onlyBefore time.Time, noise *StatsNoise) []*verifyapi.StatsDay {
+		return ReduceStats(hourly, onlyBefore, 10, 0, verifyapi.StatsWindowDay, nil, noise)
+	}
+
+	// Without noise, the day is released every hour.
+	if got := len(reduce(day.Add(12*time.Hour), nil)); got != 1 {
+		t.Errorf("expected the day to be released before it is complete, got %d days", got)
+	}
+
+	// Snapshots of the same day taken at different hours have the same noise,
+	// so the day is withheld until it is complete. Otherwise the difference
+	// between them would be the exact count of the hours in between.
+	before := reduce(day.Add(12*time.Hour), noise)
+	after := reduce(day.Add(23*time.Hour), noise)
+	if len(before) != 0 || len(after) != 0 {
+		t.Fatalf("expected the incomplete day to be withheld, got %d and %d days", len(before), len(after))
+	}
+
+	complete := reduce(day.Add(25*time.Hour), noise)
+	if got := len(complete); got != 1 {
+		t.Fatalf("expected the complete day to be released, got %d days", got)
+	}
+	if diff := cmp.Diff(complete, reduce(day.Add(47*time.Hour), noise)); diff != "" {
+		t.Errorf("complete day changed between releases (-first, +second):\n%s", diff)
+	}
+}
+
+func TestStatsNoise_Distribution(t *testing.T) {
+	t.Parallel()
+
+	const (
+		days  = 5000
+		value = 1000
+	)
+
+	cases := []struct {
+		name     string
+		noise    *StatsNoise
+		variance float64
+	}{
+		{
+			name: "laplace",
+			noise: &StatsNoise{
+				Mechanism: vermodel.StatsNoiseLaplace,
+				Epsilon:   0.5,
+				Key:       []byte("key"),
+			},
+			// 2b^2 where b = 1 / 0.5
+			variance: 8,
+		},
+		{
+			name: "gaussian",
+			noise: &StatsNoise{
+				Mechanism: vermodel.StatsNoiseGaussian,
+				Epsilon:   0.5,
+				Delta:     1e-5,
+				Key:       []byte("key"),
+			},
+			// sigma = sqrt(2 ln(1.25 / delta)) / epsilon
+			variance: 2 * math.Log(1.25/1e-5) / 0.25,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			var sum, sumSquares float64
+			for i := 0; i < days; i++ {
+				day := newStatsDay(start.AddDate(0, 0, i))
+				day.PublishRequests.Android = value
+				tc.noise.addNoise(1, day)
+
+				diff := float64(day.PublishRequests.Android - value)
+				sum += diff
+				sumSquares += diff * diff
+
+				if got := day.PublishRequests.IOS; got < 0 {
+					t.Fatalf("expected %d to be >= 0", got)
+				}
+			}
+
+			mean := sum / days
+			variance := sumSquares/days - mean*mean
+			if math.Abs(mean) > 0.1*math.Sqrt(tc.variance) {
+				t.Errorf("expected mean %v to be close to 0", mean)
+			}
+			if math.Abs(variance-tc.variance) > 0.15*tc.variance {
+				t.Errorf("expected variance %v to be close to %v", variance, tc.variance)
+			}
+		})
+	}
+}
+
+func TestStatsMaxKeysPerPublish(t *testing.T) {
+	t.Parallel()
+
+	uintPtr := func(v uint) *uint { return &v }
+
+	cases := []struct {
+		name   string
+		policy *vermodel.TransformPolicy
+		want   int64
+	}{
+		{
+			name: "no_policy",
+			want: 30,
+		},
+		{
+			name:   "no_override",
+			policy: &vermodel.TransformPolicy{},
+			want:   30,
+		},
+		{
+			name:   "lower_override",
+			policy: &vermodel.TransformPolicy{MaxExposureKeys: uintPtr(14)},
+			want:   30,
+		},
+		{
+			name:   "higher_override",
+			policy: &vermodel.TransformPolicy{MaxExposureKeys: uintPtr(100)},
+			want:   100,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ha := &vermodel.HealthAuthority{TransformPolicy: tc.policy}
+			if got := StatsMaxKeysPerPublish(30, ha); got != tc.want {
+				t.Errorf("expected %d to be %d", got, tc.want)
+			}
+		})
+	}
+}
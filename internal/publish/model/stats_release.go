@@ -0,0 +1,98 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/hashicorp/go-multierror"
+)
+
+// ErrStatsNoiseNotConfigured is returned when a health authority enables stats
+// noise, but there is no key to seed the noise.
+var ErrStatsNoiseNotConfigured = errors.New("stats noise is not configured")
+
+// StatsReleaseConfig is the configuration for releasing stats to health
+// authorities. It is shared by the stats API and the stats export.
+type StatsReleaseConfig struct {
+	UploadMinimum      int64
+	NoisyUploadMinimum int64
+	EmbargoPeriod      time.Duration
+	NoiseDelta         float64
+	NoiseKey           []byte
+
+	// MaxKeysPerPublish is the server's limit on the number of keys in a
+	// publish request, see StatsMaxKeysPerPublish.
+	MaxKeysPerPublish int64
+}
+
+// Validate returns an error if the configuration is not valid. The errors name
+// the environment variables of the services that release stats.
+func (c *StatsReleaseConfig) Validate() error {
+	var result *multierror.Error
+
+	if c.UploadMinimum < 10 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATS_UPLOAD_MINIMUM` must be >= 10, got: %v", c.UploadMinimum))
+	}
+
+	if m := c.NoisyUploadMinimum; m < 1 || m > c.UploadMinimum {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATS_NOISY_UPLOAD_MINIMUM` must be between 1 and `STATS_UPLOAD_MINIMUM`, got: %v", m))
+	}
+	if d := c.NoiseDelta; !(d > 0 && d < 1) {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATS_NOISE_DELTA` must be > 0 and < 1, got: %v", d))
+	}
+
+	if ep := c.EmbargoPeriod; !(ep >= (48*time.Hour) || ep <= 0) {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `STATS_EMBARGO_PERIOD` must be >= 48h or <= 0 to disable release of stats days below the threshold"))
+	}
+
+	if c.MaxKeysPerPublish <= 0 {
+		result = multierror.Append(result,
+			fmt.Errorf("env var `MAX_KEYS_ON_PUBLISH` must be > 0, got: %v", c.MaxKeysPerPublish))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// ReleaseStats reduces the hourly stats of a health authority to the stats
+// that can be released to it, adding noise if the health authority enabled it.
+// See ReduceStats for the meaning of the other arguments.
+func ReleaseStats(hourly []*HealthAuthorityStats, ha *vermodel.HealthAuthority, cfg *StatsReleaseConfig, onlyBefore time.Time, window string, afterDaysSinceFirstKey *int64) ([]*verifyapi.StatsDay, error) {
+	threshold := cfg.UploadMinimum
+	var noise *StatsNoise
+	if ha.StatsNoise != "" {
+		if len(cfg.NoiseKey) == 0 {
+			return nil, ErrStatsNoiseNotConfigured
+		}
+		noise = &StatsNoise{
+			Mechanism:         ha.StatsNoise,
+			Epsilon:           ha.StatsNoiseEpsilon,
+			Delta:             cfg.NoiseDelta,
+			MaxKeysPerPublish: StatsMaxKeysPerPublish(cfg.MaxKeysPerPublish, ha),
+			Key:               cfg.NoiseKey,
+		}
+		threshold = cfg.NoisyUploadMinimum
+	}
+
+	return ReduceStats(hourly, onlyBefore, threshold, cfg.EmbargoPeriod, window, afterDaysSinceFirstKey, noise), nil
+}
@@ -40,6 +40,8 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestDays:   14,
 			OnsetDaysAgo: 4,
 			MissingOnset: false,
+			ReportType:   verifyapi.ReportTypeConfirmed,
+			Traveler:     true,
 		}
 
 		record.AddPublish(&info)
@@ -53,6 +55,10 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
 			OnsetAgeDays:      []int64{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 			MissingOnset:      0,
+			SymptomOnset:      1,
+			TestDateOnset:     0,
+			ReportTypeCount:   []int64{0, 1, 0, 0},
+			TravelerCount:     1,
 		}
 		compare(want, record, t)
 	}
@@ -65,6 +71,7 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestDays:   10,
 			OnsetDaysAgo: 3,
 			MissingOnset: false,
+			ReportType:   verifyapi.ReportTypeClinical,
 		}
 
 		record.AddPublish(&info)
@@ -78,6 +85,10 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
 			OnsetAgeDays:      []int64{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 			MissingOnset:      0,
+			SymptomOnset:      1,
+			TestDateOnset:     0,
+			ReportTypeCount:   []int64{0, 1, 1, 0},
+			TravelerCount:     1,
 		}
 		compare(want, record, t)
 	}
@@ -103,6 +114,10 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0},
 			OnsetAgeDays:      []int64{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
 			MissingOnset:      1,
+			SymptomOnset:      1,
+			TestDateOnset:     0,
+			ReportTypeCount:   []int64{1, 1, 1, 0},
+			TravelerCount:     1,
 		}
 		compare(want, record, t)
 	}
@@ -115,6 +130,10 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestDays:   StatsMaxOldestTEK + 5,
 			OnsetDaysAgo: StatsMaxOnsetDays + 5,
 			MissingOnset: false,
+
+			TestDateOnset: true,
+			ReportType:    verifyapi.ReportTypeSelfReport,
+			Traveler:      true,
 		}
 
 		record.AddPublish(&info)
@@ -128,6 +147,10 @@ func TestCheckAddPublish(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1},
 			OnsetAgeDays:      []int64{0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
 			MissingOnset:      1,
+			SymptomOnset:      1,
+			TestDateOnset:     1,
+			ReportTypeCount:   []int64{1, 1, 1, 1},
+			TravelerCount:     2,
 		}
 		compare(want, record, t)
 	}
@@ -166,6 +189,8 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 2, 0, 0, 10, 1},
 			OnsetAgeDays:      minPadSlice([]int64{1, 1, 1}, StatsMaxOnsetDays+1),
 			MissingOnset:      1,
+			SymptomOnset:      2,
+			TestDateOnset:     0,
 		},
 		// two entries from 2 days ago
 		{
@@ -177,6 +202,8 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 2, 0, 0, 10, 1},
 			OnsetAgeDays:      minPadSlice([]int64{1, 1, 2, 5, 3, 1}, StatsMaxOnsetDays+1),
 			MissingOnset:      1,
+			SymptomOnset:      3,
+			TestDateOnset:     1,
 		},
 		{
 			HealthAuthorityID: 42,
@@ -187,6 +214,8 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 11, 0},
 			OnsetAgeDays:      minPadSlice([]int64{0, 0, 3, 5, 3}, StatsMaxOnsetDays+1),
 			MissingOnset:      0,
+			SymptomOnset:      8,
+			TestDateOnset:     3,
 		},
 		// one entry from 1 days ago, but not enough uploads to be shown
 		{
@@ -198,6 +227,8 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 0, 0},
 			OnsetAgeDays:      minPadSlice([]int64{0, 0, 1, 1, 1}, StatsMaxOnsetDays+1),
 			MissingOnset:      0,
+			SymptomOnset:      2,
+			TestDateOnset:     1,
 		},
 		// two entries from today, but one shouldn't be shown (time hasn't lapsed yet)
 		{
@@ -209,6 +240,8 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 81, 200, 200, 0},
 			OnsetAgeDays:      minPadSlice([]int64{1, 50, 100, 300, 29}, StatsMaxOnsetDays+1),
 			MissingOnset:      0,
+			SymptomOnset:      400,
+			TestDateOnset:     80,
 		},
 		{ // This record should be held back.
 			HealthAuthorityID: 42,
@@ -219,12 +252,15 @@ func TestReduce(t *testing.T) {
 			OldestTekDays:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 11, 0},
 			OnsetAgeDays:      minPadSlice([]int64{0, 0, 3, 5, 3}, StatsMaxOnsetDays+1),
 			MissingOnset:      0,
+			SymptomOnset:      9,
+			TestDateOnset:     2,
 		},
 	}
 
 	want := []*verifyapi.StatsDay{
 		{
-			Day: startTime,
+			Day:               startTime,
+			DaysSinceFirstKey: 0,
 			PublishRequests: verifyapi.PublishRequests{
 				UnknownPlatform: 1,
 				Android:         1,
@@ -235,9 +271,12 @@ func TestReduce(t *testing.T) {
 			TEKAgeDistribution:        []int64{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 2, 0, 0, 10, 1},
 			OnsetToUploadDistribution: minPadSlice([]int64{1, 1, 1}, StatsMaxOnsetDays+1),
 			RequestsMissingOnsetDate:  1,
+			RequestsWithSymptomOnset:  2,
+			RequestsWithTestDate:      0,
 		},
 		{
-			Day: startTime.Add(24 * time.Hour),
+			Day:               startTime.Add(24 * time.Hour),
+			DaysSinceFirstKey: 1,
 			PublishRequests: verifyapi.PublishRequests{
 				UnknownPlatform: 1,
 				Android:         15,
@@ -248,9 +287,12 @@ func TestReduce(t *testing.T) {
 			TEKAgeDistribution:        []int64{0, 0, 0, 0, 0, 1, 0, 0, 1, 0, 0, 2, 0, 0, 21, 1},
 			OnsetToUploadDistribution: minPadSlice([]int64{1, 1, 5, 10, 6, 1}, StatsMaxOnsetDays+1),
 			RequestsMissingOnsetDate:  1,
+			RequestsWithSymptomOnset:  11,
+			RequestsWithTestDate:      4,
 		},
 		{
-			Day: startTime.Add(72 * time.Hour),
+			Day:               startTime.Add(72 * time.Hour),
+			DaysSinceFirstKey: 3,
 			PublishRequests: verifyapi.PublishRequests{
 				UnknownPlatform: 0,
 				Android:         187,
@@ -261,12 +303,122 @@ func TestReduce(t *testing.T) {
 			TEKAgeDistribution:        []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 81, 200, 200, 0},
 			OnsetToUploadDistribution: minPadSlice([]int64{1, 50, 100, 300, 29}, StatsMaxOnsetDays+1),
 			RequestsMissingOnsetDate:  0,
+			RequestsWithSymptomOnset:  400,
+			RequestsWithTestDate:      80,
 		},
 	}
 
-	got := ReduceStats(input, hour.Add(73*time.Hour), 10, 48*time.Hour)
+	got := ReduceStats(input, hour.Add(73*time.Hour), 10, 48*time.Hour, verifyapi.StatsWindowDay, nil, nil)
 
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Fatalf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestReduce_Window(t *testing.T) {
+	t.Parallel()
+
+	hourStats := func(hour time.Time, publishes int64) *HealthAuthorityStats {
+		stats := InitHour(42, hour)
+		stats.PublishCount[platformToInt(PlatformAndroid)] = publishes
+		stats.TEKCount = publishes
+		stats.ReportTypeCount[reportTypeToInt(verifyapi.ReportTypeConfirmed)] = publishes
+		stats.TravelerCount = 1
+		return stats
+	}
+	statsDay := func(day time.Time, daysSinceFirstKey, publishes, travelers int64) *verifyapi.StatsDay {
+		stats := newStatsDay(day)
+		stats.DaysSinceFirstKey = daysSinceFirstKey
+		stats.PublishRequests.Android = publishes
+		stats.TotalTEKsPublished = publishes
+		stats.ReportTypes.Confirmed = publishes
+		stats.TravelerRequests = travelers
+		return stats
+	}
+
+	// March 1st 2021 is a Monday.
+	march1 := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	march8 := time.Date(2021, 3, 8, 0, 0, 0, 0, time.UTC)
+	march29 := time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC)
+	april1 := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+	input := []*HealthAuthorityStats{
+		hourStats(march1.Add(2*time.Hour), 12),
+		// Below the threshold, must not be included in any window.
+		hourStats(march1.Add(50*time.Hour), 5),
+		hourStats(march8.Add(3*time.Hour), 8),
+		hourStats(march8.Add(4*time.Hour), 12),
+		hourStats(april1.Add(26*time.Hour), 15),
+	}
+	onlyBefore := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		window string
+		after  *int64
+		want   []*verifyapi.StatsDay
+	}{
+		{
+			name:   "day",
+			window: verifyapi.StatsWindowDay,
+			want: []*verifyapi.StatsDay{
+				statsDay(march1, 0, 12, 1),
+				statsDay(march8, 7, 20, 2),
+				statsDay(april1.AddDate(0, 0, 1), 32, 15, 1),
+			},
+		},
+		{
+			name:   "week",
+			window: verifyapi.StatsWindowWeek,
+			want: []*verifyapi.StatsDay{
+				statsDay(march1, 0, 12, 1),
+				statsDay(march8, 7, 20, 2),
+				statsDay(march29, 28, 15, 1),
+			},
+		},
+		{
+			name:   "month",
+			window: verifyapi.StatsWindowMonth,
+			want: []*verifyapi.StatsDay{
+				statsDay(march1, 0, 32, 3),
+				statsDay(april1, 31, 15, 1),
+			},
+		},
+		{
+			name:   "week_after",
+			window: verifyapi.StatsWindowWeek,
+			after:  int64Ptr(7),
+			want: []*verifyapi.StatsDay{
+				statsDay(march8, 7, 20, 2),
+				statsDay(march29, 28, 15, 1),
+			},
+		},
+		{
+			name:   "month_after",
+			window: verifyapi.StatsWindowMonth,
+			after:  int64Ptr(10),
+			want: []*verifyapi.StatsDay{
+				statsDay(march1, 0, 32, 3),
+				statsDay(april1, 31, 15, 1),
+			},
+		},
+		{
+			name:   "after_all",
+			window: verifyapi.StatsWindowDay,
+			after:  int64Ptr(33),
+			want:   []*verifyapi.StatsDay{},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := ReduceStats(input, onlyBefore, 10, 0, tc.window, tc.after, nil)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -62,6 +62,9 @@ type Server struct {
 	tokenAAD              []byte
 	authorizedAppProvider authorizedapp.Provider
 	verifier              *verification.Verifier
+	rateLimiter           middleware.RateLimiter
+	publishRateLimit      *middleware.RateLimitPolicy
+	statsRateLimit        *middleware.RateLimitPolicy
 }
 
 func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Server, error) {
@@ -105,7 +108,9 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 	if err != nil {
 		return nil, fmt.Errorf("revisiondb.New: %w", err)
 	}
-	tm, err := revision.New(ctx, revisionDB, cfg.RevisionKeyCacheDuration, cfg.RevisionToken.MinLength)
+	tm, err := revision.New(ctx, revisionDB, cfg.RevisionKeyCacheDuration, cfg.RevisionToken.MinLength,
+		revision.WithTokenLifetime(cfg.RevisionToken.Lifetime),
+		revision.WithV1AcceptedUntil(cfg.RevisionToken.V1AcceptedUntil.Time))
 	if err != nil {
 		return nil, fmt.Errorf("revision.New: %w", err)
 	}
@@ -114,6 +119,11 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 		return nil, fmt.Errorf("config validation: %w", err)
 	}
 
+	rateLimiter, err := middleware.NewRateLimiter(&cfg.RateLimit, env.Database())
+	if err != nil {
+		return nil, fmt.Errorf("middleware.NewRateLimiter: %w", err)
+	}
+
 	chaffer, err := chaff.NewTracker(chaff.NewJSONResponder(chaffPublishResponse), chaff.DefaultCapacity)
 	if err != nil {
 		return nil, fmt.Errorf("error making chaffer: %w", err)
@@ -129,6 +139,15 @@ func NewServer(ctx context.Context, cfg *Config, env *serverenv.ServerEnv) (*Ser
 		tokenAAD:              aadBytes,
 		authorizedAppProvider: env.AuthorizedAppProvider(),
 		verifier:              verifier,
+		rateLimiter:           rateLimiter,
+		publishRateLimit: &middleware.RateLimitPolicy{
+			Tokens:   cfg.PublishRateLimit,
+			Interval: cfg.RateLimitInterval,
+		},
+		statsRateLimit: &middleware.RateLimitPolicy{
+			Tokens:   cfg.StatsRateLimit,
+			Interval: cfg.RateLimitInterval,
+		},
 	}, nil
 }
 
@@ -148,6 +167,7 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 	// Handle v1 API - this route has to come before the v1alpha route because of
 	// path matching.
 	r.Handle("/v1/publish", s.handlePublishV1())
+	r.Handle("/v1/publish/batch", s.handlePublishBatchV1())
 	r.Handle("/v1/publish/", http.NotFoundHandler())
 
 	// Handle stats retrieval API
@@ -165,6 +185,10 @@ func (s *Server) Routes(ctx context.Context) *mux.Router {
 type response struct {
 	status      int
 	pubResponse *verifyapi.PublishResponse
+
+	// rateLimit is the result of taking a rate limit token for the request, it
+	// is nil if no token was taken.
+	rateLimit *middleware.RateLimitResult
 }
 
 func generatePadding(minPadding, paddingRange int64) (string, error) {
@@ -208,15 +232,60 @@ func newVersionBridge(regions []string) *versionBridge {
 	return &b
 }
 
+// publishRequest carries a single publish request through the phases of
+// processing. Verification and transformation happen in prepare, the database
+// write is performed by the caller, and the response is built in complete.
+// Keeping these separate allows the batch API to group database writes.
+type publishRequest struct {
+	data     *verifyapi.Publish
+	platform string
+
+	token          *pb.RevisionTokenData
+	decryptFail    bool
+	batchTime      time.Time
+	transformError error
+	warnings       []string
+	insertRequest  *database.InsertAndReviseExposuresRequest
+	rateLimit      *middleware.RateLimitResult
+
+	// Observability result for this request.
+	blame     tag.Mutator
+	obsResult tag.Mutator
+}
+
+func newPublishRequest(data *verifyapi.Publish, platform string) *publishRequest {
+	return &publishRequest{
+		data:      data,
+		platform:  platform,
+		blame:     obs.BlameNone,
+		obsResult: obs.ResultOK,
+	}
+}
+
 // process runs the publish business logic over a "v1" version of the publish request
 // and knows how to join in data from previous versions (the provided versionBridge)
 func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform string, bridge *versionBridge) *response {
 	ctx, span := trace.StartSpan(ctx, "(*publish.PublishHandler).process")
 	defer span.End()
 
-	blame := obs.BlameNone
-	obsResult := obs.ResultOK
-	defer obs.RecordLatency(ctx, time.Now(), mLatencyMs, &blame, &obsResult)
+	req := newPublishRequest(data, platform)
+	defer obs.RecordLatency(ctx, time.Now(), mLatencyMs, &req.blame, &req.obsResult)
+
+	resp := s.prepare(ctx, span, req, bridge)
+	if resp == nil {
+		dbResp, err := s.database.InsertAndReviseExposures(ctx, req.insertRequest)
+		resp = s.complete(ctx, span, req, dbResp, err)
+	}
+	resp.rateLimit = req.rateLimit
+	return resp
+}
+
+// prepare loads the health authority configuration, verifies the diagnosis
+// certificate, decodes the revision token and transforms the request into
+// exposures. If the request cannot proceed, the final response is returned.
+// Otherwise the response is nil and req.insertRequest is ready to be written.
+func (s *Server) prepare(ctx context.Context, span *trace.Span, req *publishRequest, bridge *versionBridge) *response {
+	data := req.data
 
 	logger := logging.FromContext(ctx).Named("process").
 		With("health_authority_id", data.HealthAuthorityID)
@@ -231,8 +300,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		if errors.Is(err, authorizedapp.ErrAppNotFound) {
 			message := fmt.Sprintf("unauthorized health authority: %v", data.HealthAuthorityID)
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("ERROR_UNAUTHORIZED_HEALTH_AUTHORITY")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("ERROR_UNAUTHORIZED_HEALTH_AUTHORITY")
 			return &response{
 				status: http.StatusUnauthorized,
 				pubResponse: &verifyapi.PublishResponse{
@@ -249,8 +318,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		// and no other data from the request.
 		message := fmt.Sprintf("error loading health authority config: %v", err)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
-		blame = obs.BlameServer
-		obsResult = obs.ResultError("ERROR_LOADING_HEALTH_AUTHORITY")
+		req.blame = obs.BlameServer
+		req.obsResult = obs.ResultError("ERROR_LOADING_HEALTH_AUTHORITY")
 		return &response{
 			status: http.StatusInternalServerError,
 			pubResponse: &verifyapi.PublishResponse{
@@ -271,8 +340,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 				err := fmt.Errorf("app %v tried to write to unauthorized region %v", appConfig.AppPackageName, r)
 				message := fmt.Sprintf("verifying allowed regions: %v", err)
 				span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
-				blame = obs.BlameClient
-				obsResult = obs.ResultError("ERROR_REGION_NOT_AUTHORIZED")
+				req.blame = obs.BlameClient
+				req.obsResult = obs.ResultError("ERROR_REGION_NOT_AUTHORIZED")
 				return &response{
 					status: http.StatusUnauthorized,
 					pubResponse: &verifyapi.PublishResponse{
@@ -300,8 +369,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 			"health_authority_id", data.HealthAuthorityID)
 		message := fmt.Sprintf("unknown health authority regions for %v", data.HealthAuthorityID)
 		span.SetStatus(trace.Status{Code: trace.StatusCodePermissionDenied, Message: message})
-		blame = obs.BlameClient
-		obsResult = obs.ResultError("ERROR_REGION_NOT_SPECIFIED")
+		req.blame = obs.BlameClient
+		req.obsResult = obs.ResultError("ERROR_REGION_NOT_SPECIFIED")
 		return &response{
 			status: http.StatusInternalServerError,
 			pubResponse: &verifyapi.PublishResponse{
@@ -335,6 +404,12 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 				}
 			}
 
+			code := verifyapi.ErrorVerificationCertificateInvalid
+			if errors.Is(err, verifydb.ErrCertificateReused) {
+				recordCertificateReused(ctx, data.HealthAuthorityID)
+				code = verifyapi.ErrorVerificationCertificateReused
+			}
+
 			message := fmt.Sprintf("unable to validate diagnosis verification: %v", err)
 			if s.config.DebugLogBadCertificates {
 				logger.Errorw(message, "error", err, "jwt", data.VerificationPayload)
@@ -342,13 +417,32 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 				logger.Errorw(message, "error", err)
 			}
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("BAD_VERIFICATION")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("BAD_VERIFICATION")
 			return &response{
 				status: http.StatusUnauthorized,
 				pubResponse: &verifyapi.PublishResponse{
 					ErrorMessage: message,
-					Code:         verifyapi.ErrorVerificationCertificateInvalid,
+					Code:         code,
+				},
+			}
+		}
+	}
+
+	// Requests are only counted once their certificate is verified, see
+	// publishRateLimitKey.
+	if result := s.takeRateLimitToken(ctx, publishRateLimitKey(appConfig, verifiedClaims), s.publishRateLimit); result != nil {
+		req.rateLimit = result
+		if !result.Allowed {
+			message := result.Message()
+			span.SetStatus(trace.Status{Code: trace.StatusCodeResourceExhausted, Message: message})
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("RATE_LIMITED")
+			return &response{
+				status: http.StatusTooManyRequests,
+				pubResponse: &verifyapi.PublishResponse{
+					ErrorMessage: message,
+					Code:         verifyapi.ErrorRateLimited,
 				},
 			}
 		}
@@ -362,7 +456,7 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		if err != nil {
 			logger.Warnw("failed to decode revision token, proceeding without", "error", err)
 		} else {
-			token, err = s.tokenManager.UnmarshalRevisionToken(ctx, encryptedToken, s.tokenAAD)
+			token, err = s.tokenManager.UnmarshalRevisionToken(ctx, encryptedToken, data.HealthAuthorityID, s.tokenAAD)
 			if err != nil {
 				logger.Errorw("failed to unmarshal revision token, treating as if none was provided", "error", err)
 				token = nil // just in case.
@@ -384,8 +478,8 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 		message := fmt.Sprintf("unable to read request data: %v", transformError)
 		logger.Error(message)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
-		blame = obs.BlameClient
-		obsResult = obs.ResultError("TRANSFORM_FAILED")
+		req.blame = obs.BlameClient
+		req.obsResult = obs.ResultError("TRANSFORM_FAILED")
 		return &response{
 			status: http.StatusBadRequest,
 			pubResponse: &verifyapi.PublishResponse{
@@ -398,53 +492,97 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 
 	// Add in the platform
 	if publishInfo != nil {
-		publishInfo.Platform = platform
+		publishInfo.Platform = req.platform
 	}
 
-	resp, err := s.database.InsertAndReviseExposures(ctx, &database.InsertAndReviseExposuresRequest{
+	req.token = token
+	req.decryptFail = decryptFail
+	req.batchTime = batchTime
+	req.transformError = transformError
+	req.warnings = transformWarnings
+	req.insertRequest = &database.InsertAndReviseExposuresRequest{
 		Incoming:    exposures,
 		Token:       token,
 		PublishInfo: publishInfo,
 
 		RequireToken:          !appConfig.BypassRevisionToken,
 		AllowPartialRevisions: s.config.AllowPartialRevisions,
-	})
+	}
+	if verifiedClaims != nil {
+		req.insertRequest.CertificateUse = verifiedClaims.CertificateUse
+	}
+	return nil
+}
+
+// recordCertificateReused logs and counts a publish request that reused a
+// verification certificate.
+func recordCertificateReused(ctx context.Context, healthAuthorityID string) {
+	logger := logging.FromContext(ctx).Named("recordCertificateReused")
+
+	logger.Warnw("received reused verification certificate", "healthAuthorityID", healthAuthorityID)
+	tags := []tag.Mutator{tag.Upsert(healthAuthorityIDTag, healthAuthorityID)}
+	if err := stats.RecordWithTags(ctx, tags, mCertificateReused.M(1)); err != nil {
+		logger.Errorw("failed to record stats for reused certificate", "error", err, "healthAuthorityID", healthAuthorityID)
+	}
+}
+
+// complete builds the final response for a request that was prepared, given
+// the result of writing its exposures to the database.
+func (s *Server) complete(ctx context.Context, span *trace.Span, req *publishRequest, resp *database.InsertAndReviseExposuresResponse, err error) *response {
+	logger := logging.FromContext(ctx).Named("process").
+		With("health_authority_id", req.data.HealthAuthorityID)
+
+	token := req.token
+	batchTime := req.batchTime
+	transformError := req.transformError
+	transformWarnings := req.warnings
+
 	if err != nil {
 		status := http.StatusBadRequest
 		var logMessage, errorMessage, errorCode string
 		var errInvalidReportTypeTransition *model.ErrorKeyInvalidReportTypeTransition
 		switch {
-		case decryptFail || errors.Is(err, database.ErrExistingKeyNotInToken) || errors.Is(err, database.ErrRevisionTokenMetadataMismatch):
+		case req.decryptFail || errors.Is(err, database.ErrExistingKeyNotInToken) || errors.Is(err, database.ErrRevisionTokenMetadataMismatch):
 			logMessage = fmt.Sprintf("revision token present, but invalid: %v", err)
 			errorMessage = "revision token is invalid"
 			errorCode = verifyapi.ErrorInvalidRevisionToken
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("INVALID_REVISION_TOKEN")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("INVALID_REVISION_TOKEN")
 		case errors.Is(err, database.ErrNoRevisionToken):
 			logMessage = "no revision token"
 			errorMessage = "no revision token, but sent existing keys"
 			errorCode = verifyapi.ErrorMissingRevisionToken
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("MISSING_REVISION_TOKEN")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("MISSING_REVISION_TOKEN")
 		case errors.Is(err, model.ErrorKeyAlreadyRevised):
 			logMessage = "key already revised"
 			errorMessage = "key was already revised"
 			errorCode = verifyapi.ErrorKeyAlreadyRevised
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("KEY_ALREADY_REVISED")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("KEY_ALREADY_REVISED")
+		case errors.Is(err, verifydb.ErrCertificateReused):
+			// The certificate was used by a concurrent request since it was
+			// verified.
+			recordCertificateReused(ctx, req.data.HealthAuthorityID)
+			status = http.StatusUnauthorized
+			logMessage = fmt.Sprintf("unable to validate diagnosis verification: %v", err)
+			errorMessage = logMessage
+			errorCode = verifyapi.ErrorVerificationCertificateReused
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("BAD_VERIFICATION")
 		case errors.As(err, &errInvalidReportTypeTransition):
 			logMessage = errInvalidReportTypeTransition.Error()
 			errorMessage = errInvalidReportTypeTransition.Error()
 			errorCode = verifyapi.ErrorInvalidReportTypeTransition
-			blame = obs.BlameClient
-			obsResult = obs.ResultError("INVALID_REPORT_TYPE_TRANSITION")
+			req.blame = obs.BlameClient
+			req.obsResult = obs.ResultError("INVALID_REPORT_TYPE_TRANSITION")
 		default:
 			logMessage = fmt.Sprintf("error writing exposure record: %v", err)
 			errorMessage = http.StatusText(http.StatusInternalServerError)
 			errorCode = verifyapi.ErrorInternalError
 			logger.Errorw("publish error", "error", logMessage)
-			blame = obs.BlameServer
-			obsResult = obs.ResultError("ERROR_DB_WRITE")
+			req.blame = obs.BlameServer
+			req.obsResult = obs.ResultError("ERROR_DB_WRITE")
 		}
 		logger.Debugw("publish error", "error", logMessage)
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: logMessage})
@@ -473,7 +611,7 @@ func (s *Server) process(ctx context.Context, data *verifyapi.Publish, platform
 	newToken := make([]byte, 0)
 	if len(keep.RevisableKeys) != 0 || len(resp.Exposures) != 0 {
 		var err error
-		newToken, err = s.tokenManager.MakeRevisionToken(ctx, &keep, resp.Exposures, s.tokenAAD)
+		newToken, err = s.tokenManager.MakeRevisionToken(ctx, &keep, resp.Exposures, req.data.HealthAuthorityID, s.tokenAAD)
 		if err != nil {
 			// Something failed with the revision token generation or encryption.
 			logger.Errorw("failed to make updated revision token", "error", err)
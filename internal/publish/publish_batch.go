@@ -0,0 +1,168 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
+
+	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/publish/database"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+	obs "github.com/google/exposure-notifications-server/pkg/observability"
+)
+
+// handlePublishBatchV1 returns an http.Handler that can process a batch of V1
+// publish requests in a single call.
+func (s *Server) handlePublishBatchV1() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := trace.StartSpan(r.Context(), "(*publish.PublishHandler).handlePublishBatchV1")
+		defer span.End()
+
+		logger := logging.FromContext(ctx).Named("handlePublishBatchV1")
+
+		w.Header().Set(HeaderAPIVersion, "v1")
+
+		status, response := s.handleBatchRequest(ctx, w, r)
+
+		if padding, err := generatePadding(s.config.ResponsePaddingMinBytes, s.config.ResponsePaddingRange); err != nil {
+			stats.Record(ctx, mPaddingFailed.M(1))
+			logger.Errorw("failed to pad response", "error", err)
+		} else {
+			response.Padding = padding
+		}
+
+		jsonutil.MarshalResponse(w, status, response)
+	})
+}
+
+func (s *Server) handleBatchRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, *verifyapi.PublishBatchResponse) {
+	span := trace.FromContext(ctx)
+
+	var data verifyapi.PublishBatch
+	code, err := jsonutil.UnmarshalWithLimit(w, r, &data, s.config.MaxPublishBatchBytes)
+	if err != nil {
+		if s.config.LogJSONParseErrors {
+			logger := logging.FromContext(ctx).Named("handleBatchRequest")
+			logger.Warnw("v1 batch unmarshal failure", "error", err)
+		}
+
+		message := fmt.Sprintf("error unmarshalling API call, code: %v: %v", code, err)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: message})
+		errorCode := verifyapi.ErrorBadRequest
+		if code == http.StatusInternalServerError {
+			errorCode = verifyapi.ErrorInternalError
+		}
+		return code, &verifyapi.PublishBatchResponse{
+			ErrorMessage: message,
+			Code:         errorCode,
+		}
+	}
+
+	if len(data.Publishes) == 0 {
+		message := "no publish requests in batch"
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
+		return http.StatusBadRequest, &verifyapi.PublishBatchResponse{
+			ErrorMessage: message,
+			Code:         verifyapi.ErrorBadRequest,
+		}
+	}
+	if max := s.config.MaxPublishBatchSize; uint(len(data.Publishes)) > max {
+		message := fmt.Sprintf("too many publish requests in batch: %v, max of %v is allowed", len(data.Publishes), max)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: message})
+		return http.StatusRequestEntityTooLarge, &verifyapi.PublishBatchResponse{
+			ErrorMessage: message,
+			Code:         verifyapi.ErrorBatchTooLarge,
+		}
+	}
+
+	stats.Record(ctx, mBatchSize.M(int64(len(data.Publishes))))
+
+	clientPlatform := platform(r.UserAgent())
+	return http.StatusOK, &verifyapi.PublishBatchResponse{
+		Responses: s.processBatch(ctx, data.Publishes, clientPlatform),
+	}
+}
+
+// processBatch runs the publish business logic over each of the provided
+// requests. Every request is verified and transformed individually, exactly
+// like a single publish request. The resulting database writes are grouped
+// into transactions of at most PublishBatchTransactionSize requests.
+//
+// The returned responses are in the same order as the incoming requests.
+func (s *Server) processBatch(ctx context.Context, publishes []verifyapi.Publish, platform string) []verifyapi.PublishResponse {
+	ctx, span := trace.StartSpan(ctx, "(*publish.PublishHandler).processBatch")
+	defer span.End()
+
+	start := time.Now()
+
+	requests := make([]*publishRequest, len(publishes))
+	spans := make([]*trace.Span, len(publishes))
+	responses := make([]*response, len(publishes))
+
+	// Verify and transform each request. Requests that can't proceed already
+	// have their final response, the rest are queued for writing.
+	pending := make([]int, 0, len(publishes))
+	for i := range publishes {
+		var itemCtx context.Context
+		itemCtx, spans[i] = trace.StartSpan(ctx, "(*publish.PublishHandler).process")
+
+		requests[i] = newPublishRequest(&publishes[i], platform)
+		if resp := s.prepare(itemCtx, spans[i], requests[i], nil); resp != nil {
+			responses[i] = resp
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	groupSize := int(s.config.PublishBatchTransactionSize)
+	for len(pending) > 0 {
+		n := groupSize
+		if n > len(pending) {
+			n = len(pending)
+		}
+		group := pending[:n]
+		pending = pending[n:]
+
+		insertRequests := make([]*database.InsertAndReviseExposuresRequest, len(group))
+		for j, idx := range group {
+			insertRequests[j] = requests[idx].insertRequest
+		}
+
+		dbResponses, dbErrs, err := s.database.InsertAndReviseExposuresBatch(ctx, insertRequests)
+		for j, idx := range group {
+			// If the transaction failed as a whole, nothing in the group was written.
+			if err != nil {
+				responses[idx] = s.complete(ctx, spans[idx], requests[idx], nil, err)
+				continue
+			}
+			responses[idx] = s.complete(ctx, spans[idx], requests[idx], dbResponses[j], dbErrs[j])
+		}
+	}
+
+	result := make([]verifyapi.PublishResponse, len(publishes))
+	for i, req := range requests {
+		obs.RecordLatency(ctx, start, mLatencyMs, &req.blame, &req.obsResult)
+		spans[i].End()
+		result[i] = *responses[i].pubResponse
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	revisiondb "github.com/google/exposure-notifications-server/internal/revision/database"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/util"
+	"github.com/sethvargo/go-envconfig"
+)
+
+func TestPublishBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	kms := keys.TestKeyManager(t)
+	keyID := keys.TestEncryptionKey(t, kms)
+
+	tokenAAD := make([]byte, 16)
+	if _, err := rand.Read(tokenAAD); err != nil {
+		t.Fatalf("not enough entropy: %v", err)
+	}
+	revDB, err := revisiondb.New(testDB, &revisiondb.KMSConfig{WrapperKeyID: keyID, KeyManager: kms})
+	if err != nil {
+		t.Fatalf("unable to create revision DB handle: %v", err)
+	}
+	if _, err := revDB.CreateRevisionKey(ctx); err != nil {
+		t.Fatalf("unable to create revision key: %v", err)
+	}
+
+	var config Config
+	if err := envconfig.ProcessWith(ctx, &config, envconfig.OsLookuper()); err != nil {
+		t.Fatal(err)
+	}
+	config.AuthorizedApp.CacheDuration = time.Nanosecond
+	config.MaxPublishBatchSize = 4
+	config.PublishBatchTransactionSize = 2
+	config.RevisionToken.AAD = tokenAAD
+	config.RevisionToken.KeyID = keyID
+	aaProvider, err := authorizedapp.NewDatabaseProvider(ctx, testDB, config.AuthorizedAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := serverenv.New(ctx,
+		serverenv.WithDatabase(testDB),
+		serverenv.WithAuthorizedAppProvider(aaProvider),
+		serverenv.WithKeyManager(kms))
+
+	haName := "com.example.batch"
+	authApp := aamodel.NewAuthorizedApp()
+	authApp.AppPackageName = haName
+	authApp.BypassHealthAuthorityVerification = true
+	authApp.AllowedRegions["US"] = struct{}{}
+	if err := aadb.New(testDB).InsertAuthorizedApp(ctx, authApp); err != nil {
+		t.Fatal(err)
+	}
+
+	publishServer, err := NewServer(ctx, &config, env)
+	if err != nil {
+		t.Fatalf("unable to create publish handler: %v", err)
+	}
+	handler := publishServer.handlePublishBatchV1()
+
+	send := func(t *testing.T, batch *verifyapi.PublishBatch) (int, *verifyapi.PublishBatchResponse) {
+		t.Helper()
+
+		b, err := json.Marshal(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/publish/batch", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, request)
+
+		var response verifyapi.PublishBatchResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unable to unmarshal response body: %v; data: %v", err, rr.Body.String())
+		}
+		return rr.Code, &response
+	}
+
+	t.Run("mixed_results", func(t *testing.T) {
+		t.Parallel()
+
+		status, response := send(t, &verifyapi.PublishBatch{
+			Publishes: []verifyapi.Publish{
+				{Keys: util.GenerateExposureKeys(2, 0, false), HealthAuthorityID: haName},
+				{Keys: util.GenerateExposureKeys(2, 0, false), HealthAuthorityID: "com.example.unknown"},
+				{Keys: util.GenerateExposureKeys(3, 0, false), HealthAuthorityID: haName},
+			},
+		})
+		if got, want := status, http.StatusOK; got != want {
+			t.Fatalf("expected status %d to be %d: %#v", got, want, response)
+		}
+		if got, want := len(response.Responses), 3; got != want {
+			t.Fatalf("expected %d responses, got %d", want, got)
+		}
+
+		for i, want := range []int{2, 0, 3} {
+			if got := response.Responses[i].InsertedExposures; got != want {
+				t.Errorf("response %d: expected %d inserted exposures, got %d", i, want, got)
+			}
+		}
+		if got, want := response.Responses[1].Code, verifyapi.ErrorUnknownHealthAuthorityID; got != want {
+			t.Errorf("expected code %q to be %q", got, want)
+		}
+		if response.Responses[0].RevisionToken == "" || response.Responses[2].RevisionToken == "" {
+			t.Errorf("expected revision tokens for successful publishes")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		status, response := send(t, &verifyapi.PublishBatch{})
+		if got, want := status, http.StatusBadRequest; got != want {
+			t.Fatalf("expected status %d to be %d", got, want)
+		}
+		if got, want := response.Code, verifyapi.ErrorBadRequest; got != want {
+			t.Errorf("expected code %q to be %q", got, want)
+		}
+	})
+
+	t.Run("too_large", func(t *testing.T) {
+		t.Parallel()
+
+		publishes := make([]verifyapi.Publish, 5)
+		for i := range publishes {
+			publishes[i] = verifyapi.Publish{Keys: util.GenerateExposureKeys(1, 0, false), HealthAuthorityID: haName}
+		}
+
+		status, response := send(t, &verifyapi.PublishBatch{Publishes: publishes})
+		if got, want := status, http.StatusRequestEntityTooLarge; got != want {
+			t.Fatalf("expected status %d to be %d", got, want)
+		}
+		if got, want := response.Code, verifyapi.ErrorBatchTooLarge; got != want {
+			t.Errorf("expected code %q to be %q", got, want)
+		}
+		if len(response.Responses) != 0 {
+			t.Errorf("expected no individual responses, got %d", len(response.Responses))
+		}
+	})
+}
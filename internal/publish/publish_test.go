@@ -502,12 +502,21 @@ func TestPublishWithBypass(t *testing.T) {
 				config.MaxSameStartIntervalKeys = 2
 				config.MaxIntervalAge = 14 * 24 * time.Hour
 				config.StatsUploadMinimum = 10
+				config.StatsNoisyUploadMinimum = 10
+				config.StatsNoiseDelta = 0.00001
+				config.RateLimitInterval = time.Minute
+				config.PublishRateLimit = 6000
+				config.StatsRateLimit = 60
+				config.MaxPublishBatchSize = 10
+				config.MaxPublishBatchBytes = 1_000_000
+				config.PublishBatchTransactionSize = 5
 				aaProvider, err := authorizedapp.NewDatabaseProvider(ctx, testDB, config.AuthorizedAppConfig())
 				if err != nil {
 					t.Fatal(err)
 				}
 				config.RevisionToken.AAD = tokenAAD
 				config.RevisionToken.KeyID = keyID
+				config.RevisionToken.Lifetime = time.Hour
 				config.ResponsePaddingMinBytes = 100
 				config.ResponsePaddingRange = 100
 				config.MaxMagnitudeSymptomOnsetDays = 14
@@ -724,7 +733,7 @@ func TestPublishWithBypass(t *testing.T) {
 						if err != nil {
 							t.Fatalf("revision token encoded incorrectly: %v", err)
 						}
-						revToken, err := tm.UnmarshalRevisionToken(ctx, revTokenBytes, tokenAAD)
+						revToken, err := tm.UnmarshalRevisionToken(ctx, revTokenBytes, tc.Publish.HealthAuthorityID, tokenAAD)
 						if err != nil {
 							t.Fatalf("unable to decrypt revision token: %v", err)
 						}
@@ -1002,7 +1011,7 @@ func TestKeyRevision(t *testing.T) {
 				if err != nil {
 					return "", err
 				}
-				revToken, err := tm.UnmarshalRevisionToken(ctx, tokenBytes, aad)
+				revToken, err := tm.UnmarshalRevisionToken(ctx, tokenBytes, haName, aad)
 				if err != nil {
 					return "", err
 				}
@@ -1015,7 +1024,7 @@ func TestKeyRevision(t *testing.T) {
 					},
 				}
 				revToken.RevisableKeys = revToken.RevisableKeys[0:1]
-				tokenBytes, err = tm.MakeRevisionToken(ctx, revToken, newKeys, aad)
+				tokenBytes, err = tm.MakeRevisionToken(ctx, revToken, newKeys, haName, aad)
 				if err != nil {
 					return "", err
 				}
@@ -24,6 +24,7 @@ import (
 	"go.opencensus.io/trace"
 
 	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/logging"
 	obs "github.com/google/exposure-notifications-server/pkg/observability"
@@ -81,6 +82,9 @@ func (s *Server) handlePublishV1() http.Handler {
 			response.pubResponse.Padding = padding
 		}
 
+		if response.rateLimit != nil {
+			middleware.SetRateLimitHeaders(w, s.publishRateLimit, response.rateLimit)
+		}
 		jsonutil.MarshalResponse(w, response.status, response.pubResponse)
 	})
 }
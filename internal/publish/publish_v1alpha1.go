@@ -24,6 +24,7 @@ import (
 	"go.opencensus.io/trace"
 
 	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/api/v1alpha1"
 	"github.com/google/exposure-notifications-server/pkg/logging"
@@ -107,6 +108,9 @@ func (s *Server) handlePublishV1Alpha1() http.Handler {
 			Warnings:          response.pubResponse.Warnings,
 		}
 
+		if response.rateLimit != nil {
+			middleware.SetRateLimitHeaders(w, s.publishRateLimit, response.rateLimit)
+		}
 		jsonutil.MarshalResponse(w, response.status, alpha1Response)
 	})
 }
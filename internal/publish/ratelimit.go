@@ -0,0 +1,79 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/middleware"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	"github.com/google/exposure-notifications-server/pkg/logging"
+)
+
+// Requests are rate limited after their credentials are verified, so that
+// requests that only claim to be from a health authority can't use up its
+// tokens.
+const (
+	publishRateLimitPrefix    = "publish:"
+	publishAppRateLimitPrefix = "publish-app:"
+	statsRateLimitPrefix      = "stats:"
+	statsIPRateLimitPrefix    = "stats-ip:"
+)
+
+// publishRateLimitKey returns the rate limit key of a publish request, which is
+// the health authority that signed its verification certificate. Requests of
+// apps that bypass verification have no verified health authority, they are
+// limited per app instead.
+func publishRateLimitKey(appConfig *aamodel.AuthorizedApp, claims *verification.VerifiedClaims) string {
+	if claims == nil {
+		return publishAppRateLimitPrefix + appConfig.AppPackageName
+	}
+	return publishRateLimitPrefix + strconv.FormatInt(claims.HealthAuthorityID, 10)
+}
+
+// takeRateLimitToken takes a token from the bucket for key. It returns nil if
+// rate limiting is disabled or the limiter failed, in which case the request is
+// allowed.
+func (s *Server) takeRateLimitToken(ctx context.Context, key string, policy *middleware.RateLimitPolicy) *middleware.RateLimitResult {
+	if s.rateLimiter == nil {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx).Named("takeRateLimitToken")
+
+	result, err := s.rateLimiter.Take(ctx, key, policy)
+	if err != nil {
+		logger.Errorw("failed to take rate limit token", "key", key, "error", err)
+		return nil
+	}
+	if !result.Allowed {
+		logger.Warnw("rate limit exceeded", "key", key)
+	}
+	return result
+}
+
+// clientIP returns the IP address of the client, or the remote address as-is if
+// it has no port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,304 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/authorizedapp"
+	aadb "github.com/google/exposure-notifications-server/internal/authorizedapp/database"
+	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
+	"github.com/google/exposure-notifications-server/internal/project"
+	revisiondb "github.com/google/exposure-notifications-server/internal/revision/database"
+	"github.com/google/exposure-notifications-server/internal/serverenv"
+	testutil "github.com/google/exposure-notifications-server/internal/utils"
+	"github.com/google/exposure-notifications-server/internal/verification"
+	vermodel "github.com/google/exposure-notifications-server/internal/verification/model"
+	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/exposure-notifications-server/pkg/keys"
+	"github.com/google/exposure-notifications-server/pkg/util"
+	"github.com/sethvargo/go-envconfig"
+)
+
+func TestPublishRateLimitKey(t *testing.T) {
+	t.Parallel()
+
+	app := aamodel.NewAuthorizedApp()
+	app.AppPackageName = "com.example.app"
+
+	cases := []struct {
+		name   string
+		claims *verification.VerifiedClaims
+		want   string
+	}{
+		{
+			name:   "verified",
+			claims: &verification.VerifiedClaims{HealthAuthorityID: 7},
+			want:   "publish:7",
+		},
+		{
+			name: "bypass",
+			want: "publish-app:com.example.app",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := publishRateLimitKey(app, tc.claims), tc.want; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+// newRateLimitTestServer creates a publish server with an in-memory rate
+// limiter that allows two publishes and one stats request per hour.
+func newRateLimitTestServer(ctx context.Context, t *testing.T, testDB *database.DB) *Server {
+	t.Helper()
+
+	kms := keys.TestKeyManager(t)
+	keyID := keys.TestEncryptionKey(t, kms)
+
+	tokenAAD := make([]byte, 16)
+	if _, err := rand.Read(tokenAAD); err != nil {
+		t.Fatalf("not enough entropy: %v", err)
+	}
+	revDB, err := revisiondb.New(testDB, &revisiondb.KMSConfig{WrapperKeyID: keyID, KeyManager: kms})
+	if err != nil {
+		t.Fatalf("unable to create revision DB handle: %v", err)
+	}
+	if _, err := revDB.CreateRevisionKey(ctx); err != nil {
+		t.Fatalf("unable to create revision key: %v", err)
+	}
+
+	var config Config
+	if err := envconfig.ProcessWith(ctx, &config, envconfig.OsLookuper()); err != nil {
+		t.Fatal(err)
+	}
+	config.AuthorizedApp.CacheDuration = time.Nanosecond
+	config.RevisionToken.AAD = tokenAAD
+	config.RevisionToken.KeyID = keyID
+	config.RateLimit.Type = "MEMORY"
+	config.RateLimitInterval = time.Hour
+	config.PublishRateLimit = 2
+	config.StatsRateLimit = 1
+	aaProvider, err := authorizedapp.NewDatabaseProvider(ctx, testDB, config.AuthorizedAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := serverenv.New(ctx,
+		serverenv.WithDatabase(testDB),
+		serverenv.WithAuthorizedAppProvider(aaProvider),
+		serverenv.WithKeyManager(kms))
+
+	server, err := NewServer(ctx, &config, env)
+	if err != nil {
+		t.Fatalf("unable to create publish handler: %v", err)
+	}
+	return server
+}
+
+func TestPublishRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	server := newRateLimitTestServer(ctx, t, testDB)
+
+	bypassApp := aamodel.NewAuthorizedApp()
+	bypassApp.AppPackageName = "com.example.bypass"
+	bypassApp.BypassHealthAuthorityVerification = true
+	bypassApp.AllowedRegions["US"] = struct{}{}
+	if err := aadb.New(testDB).InsertAuthorizedApp(ctx, bypassApp); err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedApp := aamodel.NewAuthorizedApp()
+	verifiedApp.AppPackageName = "com.example.verified"
+	verifiedApp.AllowedRegions["US"] = struct{}{}
+	if err := aadb.New(testDB).InsertAuthorizedApp(ctx, verifiedApp); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(t *testing.T, handler http.Handler, path string, body interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, request)
+		return rr
+	}
+
+	// The subtests share buckets, so they run in order.
+
+	t.Run("unverified_not_counted", func(t *testing.T) {
+		// Publishes that fail verification don't take tokens, even if they
+		// name a real health authority.
+		for i := 0; i < 3; i++ {
+			rr := send(t, server.handlePublishV1(), "/v1/publish", &verifyapi.Publish{
+				Keys:                util.GenerateExposureKeys(2, 0, false),
+				HealthAuthorityID:   verifiedApp.AppPackageName,
+				VerificationPayload: "not a certificate",
+				HMACKey:             "aGVsbG8gd29ybGQ=",
+			})
+			if rr.Code == http.StatusTooManyRequests {
+				t.Fatalf("publish %d: expected unverified publish not to be rate limited", i)
+			}
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		rr := send(t, server.handlePublishBatchV1(), "/v1/publish/batch", &verifyapi.PublishBatch{
+			Publishes: []verifyapi.Publish{
+				{Keys: util.GenerateExposureKeys(2, 0, false), HealthAuthorityID: bypassApp.AppPackageName},
+				{Keys: util.GenerateExposureKeys(2, 0, false), HealthAuthorityID: bypassApp.AppPackageName},
+				{Keys: util.GenerateExposureKeys(2, 0, false), HealthAuthorityID: bypassApp.AppPackageName},
+			},
+		})
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, rr.Body.String())
+		}
+
+		var response verifyapi.PublishBatchResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unable to unmarshal response body: %v; data: %v", err, rr.Body.String())
+		}
+		if got, want := len(response.Responses), 3; got != want {
+			t.Fatalf("expected %d responses, got %d", want, got)
+		}
+		for i, want := range []string{"", "", verifyapi.ErrorRateLimited} {
+			if got := response.Responses[i].Code; got != want {
+				t.Errorf("response %d: expected code %q to be %q", i, got, want)
+			}
+		}
+	})
+
+	t.Run("single", func(t *testing.T) {
+		rr := send(t, server.handlePublishV1(), "/v1/publish", &verifyapi.Publish{
+			Keys:              util.GenerateExposureKeys(2, 0, false),
+			HealthAuthorityID: bypassApp.AppPackageName,
+		})
+		if got, want := rr.Code, http.StatusTooManyRequests; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, rr.Body.String())
+		}
+		if rr.Header().Get("Retry-After") == "" {
+			t.Errorf("expected Retry-After header")
+		}
+
+		var response verifyapi.PublishResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unable to unmarshal response body: %v; data: %v", err, rr.Body.String())
+		}
+		if got, want := response.Code, verifyapi.ErrorRateLimited; got != want {
+			t.Errorf("expected code %q to be %q", got, want)
+		}
+	})
+}
+
+func TestStatsRateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	server := newRateLimitTestServer(ctx, t, testDB)
+	signingKey := testutil.GetSigningKey(t)
+
+	healthAuthority := &vermodel.HealthAuthority{
+		Issuer:         "example.gov",
+		Audience:       "n/a",
+		Name:           "example.gov",
+		EnableStatsAPI: true,
+	}
+	healthAuthorityKey := &vermodel.HealthAuthorityKey{
+		Version: "v1",
+		From:    time.Now().Add(-1 * time.Minute),
+	}
+	testutil.InitializeVerificationDB(ctx, t, testDB, healthAuthority, healthAuthorityKey, signingKey)
+
+	token := (&testutil.StatsJWTConfig{
+		HealthAuthority:    healthAuthority,
+		HealthAuthorityKey: healthAuthorityKey,
+		Key:                signingKey.Key,
+		Audience:           server.config.Verification.StatsAudience,
+	}).IssueStatsJWT(t)
+
+	send := func(t *testing.T, authorization, remoteAddr string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/stats", bytes.NewReader([]byte(`{}`)))
+		request = request.WithContext(ctx)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		rr := httptest.NewRecorder()
+		server.handleStats().ServeHTTP(rr, request)
+		return rr
+	}
+
+	// The subtests share buckets, so they run in order.
+
+	t.Run("unverified", func(t *testing.T) {
+		// Unverified requests are limited by client IP, not by the issuer they
+		// claim.
+		if got, want := send(t, "Bearer abc", "192.0.2.1:1234").Code, http.StatusUnauthorized; got != want {
+			t.Errorf("expected status %d to be %d", got, want)
+		}
+		if got, want := send(t, "", "192.0.2.1:1234").Code, http.StatusTooManyRequests; got != want {
+			t.Errorf("expected status %d to be %d", got, want)
+		}
+	})
+
+	t.Run("verified", func(t *testing.T) {
+		if got, want := send(t, "Bearer "+token, "192.0.2.1:1234").Code, http.StatusOK; got != want {
+			t.Errorf("expected status %d to be %d", got, want)
+		}
+
+		rr := send(t, "Bearer "+token, "192.0.2.2:1234")
+		if got, want := rr.Code, http.StatusTooManyRequests; got != want {
+			t.Fatalf("expected status %d to be %d: %s", got, want, rr.Body.String())
+		}
+		var response verifyapi.StatsResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("unable to unmarshal response body: %v; data: %v", err, rr.Body.String())
+		}
+		if got, want := response.ErrorCode, verifyapi.ErrorRateLimited; got != want {
+			t.Errorf("expected code %q to be %q", got, want)
+		}
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/jsonutil"
+	"github.com/google/exposure-notifications-server/internal/middleware"
 	"github.com/google/exposure-notifications-server/internal/publish/model"
 	verifyapi "github.com/google/exposure-notifications-server/pkg/api/v1"
 	"github.com/google/exposure-notifications-server/pkg/logging"
@@ -51,7 +52,7 @@ func (s *Server) handleStats() http.Handler {
 			return
 		}
 
-		response, status := s.handleMetricsRequest(ctx, r.Header.Get("Authorization"), &request)
+		response, status := s.handleMetricsRequest(ctx, w, r, &request)
 		s.addMetricsPadding(ctx, response)
 
 		jsonutil.MarshalResponse(w, status, response)
@@ -68,12 +69,37 @@ func (s *Server) addMetricsPadding(ctx context.Context, response *verifyapi.Stat
 	}
 }
 
-func (s *Server) handleMetricsRequest(ctx context.Context, bearerToken string, request *verifyapi.StatsRequest) (*verifyapi.StatsResponse, int) {
+// rateLimitStats takes a stats rate limit token from the bucket for key and
+// sets the rate limit headers. It returns the response to send if the request
+// is not allowed, and nil otherwise.
+func (s *Server) rateLimitStats(ctx context.Context, w http.ResponseWriter, key string) *verifyapi.StatsResponse {
+	result := s.takeRateLimitToken(ctx, key, s.statsRateLimit)
+	if result == nil {
+		return nil
+	}
+
+	middleware.SetRateLimitHeaders(w, s.statsRateLimit, result)
+	if result.Allowed {
+		return nil
+	}
+	return &verifyapi.StatsResponse{
+		ErrorMessage: result.Message(),
+		ErrorCode:    verifyapi.ErrorRateLimited,
+	}
+}
+
+func (s *Server) handleMetricsRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, request *verifyapi.StatsRequest) (*verifyapi.StatsResponse, int) {
 	logger := logging.FromContext(ctx).Named("handleMetricsRequest")
 
 	response := &verifyapi.StatsResponse{}
 
+	// Requests without a valid token are limited by client IP, so that they
+	// can't use up the tokens of the health authority they claim to be.
+	bearerToken := r.Header.Get("Authorization")
 	if !strings.HasPrefix(bearerToken, "Bearer ") {
+		if resp := s.rateLimitStats(ctx, w, statsIPRateLimitPrefix+clientIP(r)); resp != nil {
+			return resp, http.StatusTooManyRequests
+		}
 		response.ErrorMessage = "Authorization header is not in `Bearer <token>` format"
 		response.ErrorCode = verifyapi.ErrorUnauthorized
 		return response, http.StatusUnauthorized
@@ -81,17 +107,30 @@ func (s *Server) handleMetricsRequest(ctx context.Context, bearerToken string, r
 	// Remove 'Bearer ' from the token.
 	bearerToken = bearerToken[7:]
 
-	// Validate JWT - if valid, the health authority ID (based on issuer) is returned.
-	healthAuthorityID, err := s.verifier.AuthenticateStatsToken(ctx, bearerToken)
+	// Validate JWT - if valid, the health authority (based on issuer) is returned.
+	healthAuthority, err := s.verifier.AuthenticateStatsToken(ctx, bearerToken)
 	if err != nil {
 		logger.Infow("stats authorization failure", "error", err)
+		if resp := s.rateLimitStats(ctx, w, statsIPRateLimitPrefix+clientIP(r)); resp != nil {
+			return resp, http.StatusTooManyRequests
+		}
 		response.ErrorMessage = err.Error()
 		response.ErrorCode = verifyapi.ErrorUnauthorized
 		return response, http.StatusUnauthorized
 	}
 
+	if resp := s.rateLimitStats(ctx, w, statsRateLimitPrefix+healthAuthority.Issuer); resp != nil {
+		return resp, http.StatusTooManyRequests
+	}
+
+	if err := request.Validate(); err != nil {
+		response.ErrorMessage = err.Error()
+		response.ErrorCode = verifyapi.ErrorBadRequest
+		return response, http.StatusBadRequest
+	}
+
 	// retrieve stats
-	stats, err := s.database.ReadStats(ctx, healthAuthorityID)
+	stats, err := s.database.ReadStats(ctx, healthAuthority.ID)
 	if err != nil {
 		logger.Errorw("error reading stats", "error", err)
 		response.ErrorMessage = "error reading stats"
@@ -102,8 +141,22 @@ func (s *Server) handleMetricsRequest(ctx context.Context, bearerToken string, r
 	// Nothing from the current hour can be shown.
 	onlyBefore := time.Now().UTC().Truncate(time.Hour)
 
+	window := request.Window
+	if window == "" {
+		window = verifyapi.StatsWindowDay
+	}
+
 	// Combine days - this also filters things that are "too new" and days that don't meet the threshold.
-	response.Days = model.ReduceStats(stats, onlyBefore, s.config.StatsUploadMinimum, s.config.StatsEmbargoPeriod)
+	days, err := model.ReleaseStats(stats, healthAuthority, s.config.StatsReleaseConfig(), onlyBefore,
+		window, request.AfterDaysSinceFirstKey)
+	if err != nil {
+		logger.Errorw("error releasing stats", "health_authority", healthAuthority.Issuer, "error", err)
+		response.ErrorMessage = "error releasing stats"
+		response.ErrorCode = verifyapi.ErrorInternalError
+		return response, http.StatusInternalServerError
+	}
+	response.Days = days
+	response.Window = window
 
 	// return
 	return response, http.StatusOK
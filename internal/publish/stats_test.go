@@ -93,6 +93,8 @@ func TestRetrieveMetrics(t *testing.T) {
 			NumTEKs:      14,
 			OldestDays:   14,
 			OnsetDaysAgo: 4,
+			ReportType:   verifyapi.ReportTypeConfirmed,
+			Traveler:     true,
 		},
 		{
 			CreatedAt:    startTime,
@@ -100,6 +102,7 @@ func TestRetrieveMetrics(t *testing.T) {
 			NumTEKs:      10,
 			OldestDays:   10,
 			OnsetDaysAgo: 3,
+			ReportType:   verifyapi.ReportTypeClinical,
 		},
 	}
 
@@ -165,7 +168,8 @@ func TestRetrieveMetrics(t *testing.T) {
 	want := verifyapi.StatsResponse{
 		Days: []*verifyapi.StatsDay{
 			{
-				Day: startTime,
+				Day:               startTime,
+				DaysSinceFirstKey: 0,
 				PublishRequests: verifyapi.PublishRequests{
 					Android: 10,
 					IOS:     10,
@@ -173,9 +177,16 @@ func TestRetrieveMetrics(t *testing.T) {
 				TotalTEKsPublished:        240,
 				TEKAgeDistribution:        []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 10, 0, 0, 0, 10, 0},
 				OnsetToUploadDistribution: []int64{0, 0, 0, 10, 10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				RequestsWithSymptomOnset:  20,
+				ReportTypes: verifyapi.ReportTypeRequests{
+					Confirmed: 10,
+					Likely:    10,
+				},
+				TravelerRequests: 10,
 			},
 			{
-				Day: startTime.Add(24 * time.Hour),
+				Day:               startTime.Add(24 * time.Hour),
+				DaysSinceFirstKey: 1,
 				PublishRequests: verifyapi.PublishRequests{
 					Android: 10,
 					IOS:     10,
@@ -183,8 +194,15 @@ func TestRetrieveMetrics(t *testing.T) {
 				TotalTEKsPublished:        240,
 				TEKAgeDistribution:        []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 10, 0, 0, 0, 10, 0},
 				OnsetToUploadDistribution: []int64{0, 0, 0, 10, 10, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+				RequestsWithSymptomOnset:  20,
+				ReportTypes: verifyapi.ReportTypeRequests{
+					Confirmed: 10,
+					Likely:    10,
+				},
+				TravelerRequests: 10,
 			},
 		},
+		Window: verifyapi.StatsWindowDay,
 	}
 
 	ignorePadding := cmpopts.IgnoreFields(verifyapi.StatsResponse{}, "Padding")
@@ -16,7 +16,12 @@
 // and utilities for marshal/unmarshal which also encrypts/decrypts the payload.
 package revision
 
-import "github.com/google/exposure-notifications-server/pkg/base64util"
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/base64util"
+)
 
 // Config represents the configuration and associated environment variables
 // for handling revision tokens.
@@ -25,6 +30,13 @@ type Config struct {
 	KeyID     string      `env:"REVISION_TOKEN_KEY_ID"`
 	AAD       Base64Bytes `env:"REVISION_TOKEN_AAD"` // must be base64 encoded, may come from secret://
 	MinLength uint        `env:"REVISION_TOKEN_MIN_LENGTH, default=28"`
+
+	// How long revision tokens can be used for after they are created.
+	Lifetime time.Duration `env:"REVISION_TOKEN_LIFETIME, default=720h"`
+	// Version 1 tokens, which don't expire and aren't bound to a health
+	// authority, are accepted until this time. If it is not set, they are always
+	// accepted.
+	V1AcceptedUntil RFC3339Time `env:"REVISION_TOKEN_V1_ACCEPTED_UNTIL"`
 }
 
 // Base64Bytes is a type that parses a base64-encoded string into a []byte.
@@ -37,3 +49,19 @@ func (b *Base64Bytes) EnvDecode(val string) error {
 	*b, err = base64util.DecodeString(val)
 	return err
 }
+
+// RFC3339Time is a type that parses an RFC 3339 timestamp into a time.Time.
+type RFC3339Time struct {
+	time.Time
+}
+
+// EnvDecode implements envconfig.Decoder to decode an RFC 3339 timestamp. If an
+// error occurs, it is returned.
+func (t *RFC3339Time) EnvDecode(val string) error {
+	parsed, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return fmt.Errorf("invalid RFC 3339 timestamp: %w", err)
+	}
+	t.Time = parsed
+	return nil
+}
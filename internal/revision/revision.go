@@ -22,9 +22,11 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -35,6 +37,28 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+const (
+	// TokenVersion is the version of the revision tokens that are created.
+	// Version 2 tokens expire and are bound to the health authority that created
+	// them. Version 1 tokens have no version set.
+	TokenVersion = 2
+
+	// DefaultTokenLifetime is how long revision tokens can be used if no
+	// lifetime is configured.
+	DefaultTokenLifetime = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrTokenExpired is returned when a revision token has expired.
+	ErrTokenExpired = errors.New("revision token has expired")
+	// ErrTokenHealthAuthorityMismatch is returned when a revision token was
+	// created for a different health authority.
+	ErrTokenHealthAuthorityMismatch = errors.New("revision token was issued to a different health authority")
+	// ErrTokenVersionNotAccepted is returned when a revision token has a version
+	// that is unknown or no longer accepted.
+	ErrTokenVersionNotAccepted = errors.New("revision token version is not accepted")
+)
+
 // Used for padding only.
 var zeroTEK = pb.RevisableKey{
 	TemporaryExposureKey: make([]byte, 16),
@@ -66,11 +90,39 @@ type TokenManager struct {
 	// the keys are unwrapped.
 	cacheDuration     time.Duration
 	cacheRefreshAfter time.Time
+
+	// How long tokens can be used for after they are created.
+	tokenLifetime time.Duration
+	// Version 1 tokens are rejected after this time, unless it is zero.
+	v1AcceptedUntil time.Time
+
+	now func() time.Time
+}
+
+// Option configures a TokenManager.
+type Option func(*TokenManager)
+
+// WithTokenLifetime sets how long revision tokens can be used for after they
+// are created. Every publish request creates a new token, so this is the
+// longest time between publish requests from the same device.
+func WithTokenLifetime(d time.Duration) Option {
+	return func(tm *TokenManager) {
+		tm.tokenLifetime = d
+	}
+}
+
+// WithV1AcceptedUntil sets the time after which version 1 tokens, which don't
+// expire and aren't bound to a health authority, are rejected. If t is zero,
+// they are always accepted.
+func WithV1AcceptedUntil(t time.Time) Option {
+	return func(tm *TokenManager) {
+		tm.v1AcceptedUntil = t
+	}
 }
 
 // New creates a new TokenManager that uses a database handle to manage a cache
 // of allowed revision keys.
-func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Duration, minTokenSize uint) (*TokenManager, error) {
+func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Duration, minTokenSize uint, opts ...Option) (*TokenManager, error) {
 	if cacheDuration > 60*time.Minute {
 		return nil, fmt.Errorf("cache duration must be <= 60 minutes, got: %v", cacheDuration)
 	}
@@ -81,6 +133,14 @@ func New(ctx context.Context, db *database.RevisionDB, cacheDuration time.Durati
 		minTokenSize:      int(minTokenSize),
 		cacheDuration:     cacheDuration,
 		cacheRefreshAfter: now.Add(-2 * cacheDuration),
+		tokenLifetime:     DefaultTokenLifetime,
+		now:               time.Now,
+	}
+	for _, opt := range opts {
+		opt(tm)
+	}
+	if tm.tokenLifetime <= 0 {
+		return nil, fmt.Errorf("token lifetime must be > 0, got: %v", tm.tokenLifetime)
 	}
 	if err := tm.maybeRefreshCache(ctx); err != nil {
 		return nil, err
@@ -209,9 +269,10 @@ func buildTokenBufer(previous *pb.RevisionTokenData, eKeys []*model.Exposure) *p
 }
 
 // MakeRevisionToken turns the TEK data from a given publish request
-// into an encrypted protocol buffer revision token.
+// into an encrypted protocol buffer revision token, bound to the health
+// authority of the request.
 // This is using envelope encryption, based on the currently active revision key.
-func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.RevisionTokenData, eKeys []*model.Exposure, aad []byte) ([]byte, error) {
+func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.RevisionTokenData, eKeys []*model.Exposure, healthAuthorityID string, aad []byte) ([]byte, error) {
 	if len(eKeys) == 0 && (previous == nil || len(previous.RevisableKeys) == 0) {
 		return nil, fmt.Errorf("no keys or previous keys for which to build revision token")
 	}
@@ -230,6 +291,11 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 	}
 
 	tokenData := buildTokenBufer(previous, eKeys)
+	now := tm.now()
+	tokenData.Version = TokenVersion
+	tokenData.IssuedAt = now.Unix()
+	tokenData.ExpiresAt = now.Add(tm.tokenLifetime).Unix()
+	tokenData.HealthAuthorityID = healthAuthorityID
 	// Padd the revisable keys out w/ the zero key.
 	for len(tokenData.RevisableKeys) < tm.minTokenSize {
 		tokenData.RevisableKeys = append(tokenData.RevisableKeys, &zeroTEK)
@@ -272,7 +338,10 @@ func (tm *TokenManager) MakeRevisionToken(ctx context.Context, previous *pb.Revi
 // and returns the TEK data that was contained in the token if valid.
 //
 // The incoming key ID is used to determine if this token can still be unlocked.
-func (tm *TokenManager) UnmarshalRevisionToken(ctx context.Context, tokenBytes []byte, aad []byte) (*pb.RevisionTokenData, error) {
+// Version 2 tokens must not have expired and must have been issued to the
+// given health authority. Version 1 tokens are only accepted during the
+// configured transition period.
+func (tm *TokenManager) UnmarshalRevisionToken(ctx context.Context, tokenBytes []byte, healthAuthorityID string, aad []byte) (*pb.RevisionTokenData, error) {
 	if err := tm.maybeRefreshCache(ctx); err != nil {
 		return nil, err
 	}
@@ -327,7 +396,16 @@ func (tm *TokenManager) UnmarshalRevisionToken(ctx context.Context, tokenBytes [
 		return nil, fmt.Errorf("failed to unmarshal token data: %w", err)
 	}
 
-	var tokenData pb.RevisionTokenData
+	if err := tm.validateTokenData(&paddedTokenData, healthAuthorityID); err != nil {
+		return nil, err
+	}
+
+	tokenData := pb.RevisionTokenData{
+		Version:           paddedTokenData.Version,
+		IssuedAt:          paddedTokenData.IssuedAt,
+		ExpiresAt:         paddedTokenData.ExpiresAt,
+		HealthAuthorityID: paddedTokenData.HealthAuthorityID,
+	}
 	for _, rk := range paddedTokenData.RevisableKeys {
 		if rk.IntervalNumber == 0 && rk.IntervalCount == 0 {
 			continue
@@ -337,3 +415,29 @@ func (tm *TokenManager) UnmarshalRevisionToken(ctx context.Context, tokenBytes [
 
 	return &tokenData, nil
 }
+
+// validateTokenData checks that decrypted token data can be used by the given
+// health authority.
+func (tm *TokenManager) validateTokenData(tokenData *pb.RevisionTokenData, healthAuthorityID string) error {
+	now := tm.now()
+
+	switch tokenData.Version {
+	case 0, 1:
+		if !tm.v1AcceptedUntil.IsZero() && now.After(tm.v1AcceptedUntil) {
+			return fmt.Errorf("%w: version 1 tokens are not accepted after %v",
+				ErrTokenVersionNotAccepted, tm.v1AcceptedUntil.Format(time.RFC3339))
+		}
+		return nil
+	case TokenVersion:
+		if !now.Before(time.Unix(tokenData.ExpiresAt, 0)) {
+			return ErrTokenExpired
+		}
+		// Health authority IDs are case insensitive.
+		if !strings.EqualFold(tokenData.HealthAuthorityID, healthAuthorityID) {
+			return ErrTokenHealthAuthorityMismatch
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown version %d", ErrTokenVersionNotAccepted, tokenData.Version)
+	}
+}
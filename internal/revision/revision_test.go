@@ -15,6 +15,7 @@
 package revision
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -31,6 +32,8 @@ import (
 
 var testDatabaseInstance *database.TestInstance
 
+const testHealthAuthorityID = "gov.state.health"
+
 func TestMain(m *testing.M) {
 	testDatabaseInstance = database.MustTestInstance()
 	defer testDatabaseInstance.MustClose()
@@ -186,6 +189,8 @@ func TestEncryptDecrypt(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unable to build token manager: %v", err)
 	}
+	now := time.Now().Truncate(time.Second)
+	tm.now = func() time.Time { return now }
 
 	// Build data to serialize and encrypt.
 	source := []*model.Exposure{
@@ -203,6 +208,10 @@ func TestEncryptDecrypt(t *testing.T) {
 	aad := []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
 	// Expected result in the end
 	want := &pb.RevisionTokenData{
+		Version:           TokenVersion,
+		IssuedAt:          now.Unix(),
+		ExpiresAt:         now.Add(DefaultTokenLifetime).Unix(),
+		HealthAuthorityID: testHealthAuthorityID,
 		RevisableKeys: []*pb.RevisableKey{
 			{
 				TemporaryExposureKey: []byte{1, 2, 3, 4},
@@ -217,12 +226,12 @@ func TestEncryptDecrypt(t *testing.T) {
 		},
 	}
 
-	encrypted, err := tm.MakeRevisionToken(ctx, nil, source, aad)
+	encrypted, err := tm.MakeRevisionToken(ctx, nil, source, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
 
-	got, err := tm.UnmarshalRevisionToken(ctx, encrypted, aad)
+	got, err := tm.UnmarshalRevisionToken(ctx, encrypted, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error decrypting token: %v", err)
 	}
@@ -244,7 +253,7 @@ func TestEncryptDecrypt(t *testing.T) {
 
 	// Decryption of the key should fail this time.
 	wantErr := fmt.Sprintf("token has invalid key id: %v", firstKey.KeyID)
-	if _, err := tm.UnmarshalRevisionToken(ctx, encrypted, aad); err == nil || err.Error() != wantErr {
+	if _, err := tm.UnmarshalRevisionToken(ctx, encrypted, testHealthAuthorityID, aad); err == nil || err.Error() != wantErr {
 		t.Fatalf("want error: %v, got: %v", wantErr, err)
 	}
 }
@@ -277,6 +286,8 @@ func TestExpandRevisionToken(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unable to build token manager: %v", err)
 	}
+	now := time.Now().Truncate(time.Second)
+	tm.now = func() time.Time { return now }
 
 	// Create a token where there is a previous but no new keys.
 	previousToken := &pb.RevisionTokenData{
@@ -292,6 +303,10 @@ func TestExpandRevisionToken(t *testing.T) {
 	aad := []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
 	// Expected result in the end
 	want := &pb.RevisionTokenData{
+		Version:           TokenVersion,
+		IssuedAt:          now.Unix(),
+		ExpiresAt:         now.Add(DefaultTokenLifetime).Unix(),
+		HealthAuthorityID: testHealthAuthorityID,
 		RevisableKeys: []*pb.RevisableKey{
 			{
 				TemporaryExposureKey: []byte{1, 2, 3, 4},
@@ -301,12 +316,12 @@ func TestExpandRevisionToken(t *testing.T) {
 		},
 	}
 
-	encrypted, err := tm.MakeRevisionToken(ctx, previousToken, source, aad)
+	encrypted, err := tm.MakeRevisionToken(ctx, previousToken, source, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
 
-	got, err := tm.UnmarshalRevisionToken(ctx, encrypted, aad)
+	got, err := tm.UnmarshalRevisionToken(ctx, encrypted, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error decrypting token: %v", err)
 	}
@@ -324,6 +339,10 @@ func TestExpandRevisionToken(t *testing.T) {
 		},
 	}
 	want = &pb.RevisionTokenData{
+		Version:           TokenVersion,
+		IssuedAt:          now.Unix(),
+		ExpiresAt:         now.Add(DefaultTokenLifetime).Unix(),
+		HealthAuthorityID: testHealthAuthorityID,
 		RevisableKeys: []*pb.RevisableKey{
 			{
 				TemporaryExposureKey: []byte{1, 2, 3, 4},
@@ -338,12 +357,12 @@ func TestExpandRevisionToken(t *testing.T) {
 		},
 	}
 
-	encrypted, err = tm.MakeRevisionToken(ctx, previousToken, source, aad)
+	encrypted, err = tm.MakeRevisionToken(ctx, previousToken, source, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error encrypting and serializing data: %v", err)
 	}
 
-	got, err = tm.UnmarshalRevisionToken(ctx, encrypted, aad)
+	got, err = tm.UnmarshalRevisionToken(ctx, encrypted, testHealthAuthorityID, aad)
 	if err != nil {
 		t.Fatalf("error decrypting token: %v", err)
 	}
@@ -352,3 +371,141 @@ func TestExpandRevisionToken(t *testing.T) {
 		t.Fatalf("mismatch (-want, +got):\n%s", diff)
 	}
 }
+
+func TestUnmarshalRevisionToken_Binding(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+
+	kms := keys.TestKeyManager(t)
+	keyID := keys.TestEncryptionKey(t, kms)
+
+	revDB, err := revisiondb.New(testDB, &revisiondb.KMSConfig{WrapperKeyID: keyID, KeyManager: kms})
+	if err != nil {
+		t.Fatalf("unable to provision revision DB: %v", err)
+	}
+
+	tm, err := New(ctx, revDB, time.Second, 28, WithTokenLifetime(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to build token manager: %v", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	tm.now = func() time.Time { return now }
+
+	source := []*model.Exposure{
+		{
+			ExposureKey:    []byte{1, 2, 3, 4},
+			IntervalNumber: 254321,
+			IntervalCount:  144,
+		},
+	}
+	aad := []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+	encrypted, err := tm.MakeRevisionToken(ctx, nil, source, testHealthAuthorityID, aad)
+	if err != nil {
+		t.Fatalf("error encrypting and serializing data: %v", err)
+	}
+
+	// Health authority IDs are case insensitive.
+	if _, err := tm.UnmarshalRevisionToken(ctx, encrypted, "GOV.State.Health", aad); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := tm.UnmarshalRevisionToken(ctx, encrypted, "gov.other.health", aad); !errors.Is(err, ErrTokenHealthAuthorityMismatch) {
+		t.Errorf("expected %v to be %v", err, ErrTokenHealthAuthorityMismatch)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := tm.UnmarshalRevisionToken(ctx, encrypted, testHealthAuthorityID, aad); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected %v to be %v", err, ErrTokenExpired)
+	}
+}
+
+func TestValidateTokenData(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name            string
+		v1AcceptedUntil time.Time
+		data            *pb.RevisionTokenData
+		healthAuthority string
+		err             error
+	}{
+		{
+			name:            "v1",
+			data:            &pb.RevisionTokenData{},
+			healthAuthority: testHealthAuthorityID,
+		},
+		{
+			name:            "v1_transition",
+			v1AcceptedUntil: now.Add(time.Hour),
+			data:            &pb.RevisionTokenData{Version: 1},
+			healthAuthority: testHealthAuthorityID,
+		},
+		{
+			name:            "v1_after_transition",
+			v1AcceptedUntil: now.Add(-time.Hour),
+			data:            &pb.RevisionTokenData{},
+			healthAuthority: testHealthAuthorityID,
+			err:             ErrTokenVersionNotAccepted,
+		},
+		{
+			name:            "v2",
+			v1AcceptedUntil: now.Add(-time.Hour),
+			data: &pb.RevisionTokenData{
+				Version:           TokenVersion,
+				IssuedAt:          now.Add(-time.Hour).Unix(),
+				ExpiresAt:         now.Add(time.Hour).Unix(),
+				HealthAuthorityID: testHealthAuthorityID,
+			},
+			healthAuthority: testHealthAuthorityID,
+		},
+		{
+			name: "v2_expired",
+			data: &pb.RevisionTokenData{
+				Version:           TokenVersion,
+				IssuedAt:          now.Add(-2 * time.Hour).Unix(),
+				ExpiresAt:         now.Unix(),
+				HealthAuthorityID: testHealthAuthorityID,
+			},
+			healthAuthority: testHealthAuthorityID,
+			err:             ErrTokenExpired,
+		},
+		{
+			name: "v2_other_health_authority",
+			data: &pb.RevisionTokenData{
+				Version:           TokenVersion,
+				IssuedAt:          now.Add(-time.Hour).Unix(),
+				ExpiresAt:         now.Add(time.Hour).Unix(),
+				HealthAuthorityID: testHealthAuthorityID,
+			},
+			healthAuthority: "gov.other.health",
+			err:             ErrTokenHealthAuthorityMismatch,
+		},
+		{
+			name:            "unknown_version",
+			data:            &pb.RevisionTokenData{Version: TokenVersion + 1},
+			healthAuthority: testHealthAuthorityID,
+			err:             ErrTokenVersionNotAccepted,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tm := &TokenManager{
+				v1AcceptedUntil: tc.v1AcceptedUntil,
+				now:             func() time.Time { return now },
+			}
+			if err := tm.validateTokenData(tc.data, tc.healthAuthority); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v to be %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -19,14 +19,17 @@ package storage
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 func init() {
@@ -39,7 +42,8 @@ var _ Blobstore = (*AWSS3)(nil)
 // AWSS3 implements the Blob interface and provides the ability
 // write files to AWS S3.
 type AWSS3 struct {
-	svc *s3.S3
+	svc      *s3.S3
+	uploader *s3manager.Uploader
 }
 
 // NewAWSS3 creates a AWS S3 Service, suitable
@@ -53,7 +57,8 @@ func NewAWSS3(ctx context.Context, _ *Config) (Blobstore, error) {
 	svc := s3.New(sess)
 
 	return &AWSS3{
-		svc: svc,
+		svc:      svc,
+		uploader: s3manager.NewUploaderWithClient(svc),
 	}, nil
 }
 
@@ -79,6 +84,31 @@ func (s *AWSS3) CreateObject(ctx context.Context, bucket, key string, contents [
 	return nil
 }
 
+// NewWriter returns a writer that streams a new S3 object, or overwrites an
+// existing one. Large objects are uploaded in parts as they are written.
+func (s *AWSS3) NewWriter(ctx context.Context, bucket, key string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	cacheControl := "public, max-age=86400"
+	if !cacheable {
+		cacheControl = "no-cache, max-age=0"
+	}
+
+	return newPipeWriter(ctx, func(r io.Reader) error {
+		uploadInput := s3manager.UploadInput{
+			Bucket:       aws.String(bucket),
+			Key:          aws.String(key),
+			CacheControl: aws.String(cacheControl),
+			Body:         r,
+		}
+		if contentType != "" {
+			uploadInput.ContentType = aws.String(contentType)
+		}
+		if _, err := s.uploader.UploadWithContext(ctx, &uploadInput); err != nil {
+			return fmt.Errorf("storage.NewWriter: %w", err)
+		}
+		return nil
+	}), nil
+}
+
 // DeleteObject deletes a S3 object, returns nil if the object was successfully
 // deleted, or of the object doesn't exist.
 func (s *AWSS3) DeleteObject(ctx context.Context, bucket, key string) error {
@@ -99,8 +129,7 @@ func (s *AWSS3) GetObject(ctx context.Context, bucket, key string) ([]byte, erro
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		var aerr awserr.Error
-		if errors.As(err, &aerr) && (aerr.Code() == s3.ErrCodeNoSuchBucket || aerr.Code() == s3.ErrCodeNoSuchKey) {
+		if isS3NotFound(err) {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get object: %w", err)
@@ -114,3 +143,118 @@ func (s *AWSS3) GetObject(ctx context.Context, bucket, key string) ([]byte, erro
 
 	return b, nil
 }
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *AWSS3) NewReader(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, bucket, key, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// If the object does not exist, it returns ErrNotFound.
+func (s *AWSS3) NewRangeReader(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	// S3 can't express an empty range, but the object must still exist.
+	if length == 0 {
+		if _, err := s.GetObjectAttrs(ctx, bucket, key); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	getInput := s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if length > 0 {
+		getInput.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		getInput.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	o, err := s.svc.GetObjectWithContext(ctx, &getInput)
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return o.Body, nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound.
+func (s *AWSS3) GetObjectAttrs(ctx context.Context, bucket, key string) (*ObjectAttrs, error) {
+	o, err := s.svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object attributes: %w", err)
+	}
+
+	return &ObjectAttrs{
+		Name:        key,
+		Size:        aws.Int64Value(o.ContentLength),
+		MD5:         s3ETagMD5(aws.StringValue(o.ETag)),
+		ContentType: aws.StringValue(o.ContentType),
+		Updated:     aws.TimeValue(o.LastModified),
+	}, nil
+}
+
+// ListObjects calls fn for each object in the bucket with the given prefix.
+// S3 does not return the content type when listing.
+func (s *AWSS3) ListObjects(ctx context.Context, bucket, prefix string, fn func(*ObjectAttrs) error) error {
+	var fnErr error
+	if err := s.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			if fnErr = fn(&ObjectAttrs{
+				Name:    aws.StringValue(o.Key),
+				Size:    aws.Int64Value(o.Size),
+				MD5:     s3ETagMD5(aws.StringValue(o.ETag)),
+				Updated: aws.TimeValue(o.LastModified),
+			}); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	return fnErr
+}
+
+// isS3NotFound returns true if the error indicates that the bucket or object
+// does not exist.
+func isS3NotFound(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	switch aerr.Code() {
+	case s3.ErrCodeNoSuchBucket, s3.ErrCodeNoSuchKey, "NotFound":
+		// HEAD requests have no body, so a missing object is only reported as
+		// "NotFound".
+		return true
+	}
+	return false
+}
+
+// s3ETagMD5 returns the MD5 checksum encoded in the ETag. The ETag is only the
+// MD5 of the contents for objects that were not uploaded in multiple parts.
+func s3ETagMD5(etag string) []byte {
+	etag = strings.Trim(etag, `"`)
+	if strings.Contains(etag, "-") {
+		return nil
+	}
+	b, err := hex.DecodeString(etag)
+	if err != nil {
+		return nil
+	}
+	return b
+}
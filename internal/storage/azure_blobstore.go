@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"time"
@@ -165,6 +166,33 @@ func (s *AzureBlobstore) CreateObject(ctx context.Context, container, name strin
 	return nil
 }
 
+// NewWriter returns a writer that streams a new blobstore object, or
+// overwrites an existing one. The blocks are only committed when the writer is
+// closed.
+func (s *AzureBlobstore) NewWriter(ctx context.Context, container, name string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	cacheControl := "public, max-age=86400"
+	if !cacheable {
+		cacheControl = "no-cache, max-age=0"
+	}
+
+	blobURL := s.serviceURL.NewContainerURL(container).NewBlockBlobURL(name)
+	headers := azblob.BlobHTTPHeaders{
+		CacheControl: cacheControl,
+	}
+	if contentType != "" {
+		headers.ContentType = contentType
+	}
+
+	return newPipeWriter(ctx, func(r io.Reader) error {
+		if _, err := azblob.UploadStreamToBlockBlob(ctx, r, blobURL, azblob.UploadStreamToBlockBlobOptions{
+			BlobHTTPHeaders: headers,
+		}); err != nil {
+			return fmt.Errorf("storage.NewWriter: %w", err)
+		}
+		return nil
+	}), nil
+}
+
 // DeleteObject deletes a blobstore object, returns nil if the object was
 // successfully deleted, or if the object doesn't exist.
 func (s *AzureBlobstore) DeleteObject(ctx context.Context, container, name string) error {
@@ -199,3 +227,102 @@ func (s *AzureBlobstore) GetObject(ctx context.Context, container, name string)
 
 	return b.Bytes(), nil
 }
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *AzureBlobstore) NewReader(ctx context.Context, container, name string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, container, name, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// If the object does not exist, it returns ErrNotFound.
+func (s *AzureBlobstore) NewRangeReader(ctx context.Context, container, name string, offset, length int64) (io.ReadCloser, error) {
+	// A count of zero means the rest of the blob to Azure, but the blob must
+	// still exist.
+	if length == 0 {
+		if _, err := s.GetObjectAttrs(ctx, container, name); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	count := int64(azblob.CountToEnd)
+	if length > 0 {
+		count = length
+	}
+
+	blobURL := s.serviceURL.NewContainerURL(container).NewBlockBlobURL(name)
+	dr, err := blobURL.Download(ctx, offset, count, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	return dr.Body(azblob.RetryReaderOptions{MaxRetryRequests: 5}), nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound.
+func (s *AzureBlobstore) GetObjectAttrs(ctx context.Context, container, name string) (*ObjectAttrs, error) {
+	blobURL := s.serviceURL.NewContainerURL(container).NewBlockBlobURL(name)
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object properties: %w", err)
+	}
+
+	return &ObjectAttrs{
+		Name:        name,
+		Size:        props.ContentLength(),
+		MD5:         props.ContentMD5(),
+		ContentType: props.ContentType(),
+		Updated:     props.LastModified(),
+	}, nil
+}
+
+// ListObjects calls fn for each object in the container with the given prefix.
+func (s *AzureBlobstore) ListObjects(ctx context.Context, container, prefix string, fn func(*ObjectAttrs) error) error {
+	containerURL := s.serviceURL.NewContainerURL(container)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{
+			Prefix: prefix,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, blob := range resp.Segment.BlobItems {
+			attrs := &ObjectAttrs{
+				Name:    blob.Name,
+				MD5:     blob.Properties.ContentMD5,
+				Updated: blob.Properties.LastModified,
+			}
+			if v := blob.Properties.ContentLength; v != nil {
+				attrs.Size = *v
+			}
+			if v := blob.Properties.ContentType; v != nil {
+				attrs.ContentType = *v
+			}
+			if err := fn(attrs); err != nil {
+				return err
+			}
+		}
+		marker = resp.NextMarker
+	}
+	return nil
+}
+
+// isAzureNotFound returns true if the error indicates that the blob does not
+// exist.
+func isAzureNotFound(err error) bool {
+	var terr azblob.StorageError
+	if !errors.As(err, &terr) {
+		return false
+	}
+	// HEAD requests have no body, so only the status code is available.
+	return terr.ServiceCode() == azblob.ServiceCodeBlobNotFound ||
+		(terr.Response() != nil && terr.Response().StatusCode == http.StatusNotFound)
+}
@@ -14,8 +14,17 @@
 
 package storage
 
+import (
+	"github.com/google/exposure-notifications-server/pkg/database"
+)
+
 // Config defines the configuration for a blobstore.
 type Config struct {
 	// Type is the type of blobstore.
 	Type string `env:"BLOBSTORE, default=MEMORY"`
+
+	// Database is the connection configuration for the POSTGRES blobstore. It
+	// reads the same environment variables as the service's own database
+	// connection, so objects are stored in the service's database by default.
+	Database database.Config
 }
@@ -18,8 +18,12 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 func init() {
@@ -50,6 +54,57 @@ func (s *FilesystemStorage) CreateObject(ctx context.Context, folder, filename s
 	return nil
 }
 
+// NewWriter returns a writer that streams a new object to the filesystem, or
+// overwrites an existing one. Data is written to a temporary file next to the
+// object, which is renamed into place when the writer is closed.
+// contentType is ignored for this storage implementation.
+func (s *FilesystemStorage) NewWriter(ctx context.Context, folder, filename string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	// Object names can contain slashes, the temporary file goes in the folder
+	// of the object itself.
+	pth := filepath.Join(folder, filename)
+	f, err := os.CreateTemp(filepath.Dir(pth), "."+filepath.Base(pth)+".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object: %w", err)
+	}
+	return &filesystemWriter{
+		ctx:  ctx,
+		f:    f,
+		path: pth,
+	}, nil
+}
+
+// filesystemWriter writes an object to a temporary file and moves it into
+// place on Close.
+type filesystemWriter struct {
+	ctx  context.Context
+	f    *os.File
+	path string
+}
+
+func (w *filesystemWriter) Write(p []byte) (int, error) {
+	return w.f.Write(p)
+}
+
+func (w *filesystemWriter) Close() error {
+	// Always clean up the temporary file. After a successful rename, this is a
+	// no-op.
+	defer os.Remove(w.f.Name())
+
+	if err := w.f.Close(); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.Chmod(w.f.Name(), 0o600); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(w.f.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
+	}
+	return nil
+}
+
 // DeleteObject deletes an object from the filesystem. It returns nil if the
 // object was deleted or if the object no longer exists.
 func (s *FilesystemStorage) DeleteObject(ctx context.Context, folder, filename string) error {
@@ -73,3 +128,105 @@ func (s *FilesystemStorage) GetObject(ctx context.Context, folder, filename stri
 	}
 	return b, nil
 }
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *FilesystemStorage) NewReader(ctx context.Context, folder, filename string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, folder, filename, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// If the object does not exist, it returns ErrNotFound.
+func (s *FilesystemStorage) NewRangeReader(ctx context.Context, folder, filename string, offset, length int64) (io.ReadCloser, error) {
+	pth := filepath.Join(folder, filename)
+	f, err := os.Open(pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return &readCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound. The filesystem does not store checksums or
+// content types.
+func (s *FilesystemStorage) GetObjectAttrs(ctx context.Context, folder, filename string) (*ObjectAttrs, error) {
+	pth := filepath.Join(folder, filename)
+	fi, err := os.Stat(pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return &ObjectAttrs{
+		Name:    filename,
+		Size:    fi.Size(),
+		Updated: fi.ModTime(),
+	}, nil
+}
+
+// ListObjects calls fn for each file under folder whose slash-separated path
+// relative to folder starts with prefix. Hidden files, which include the
+// temporary files of in-progress writes, are skipped.
+func (s *FilesystemStorage) ListObjects(ctx context.Context, folder, prefix string, fn func(*ObjectAttrs) error) error {
+	var objects []*ObjectAttrs
+	if err := filepath.WalkDir(folder, func(pth string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(folder, pth)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &ObjectAttrs{
+			Name:    name,
+			Size:    fi.Size(),
+			Updated: fi.ModTime(),
+		})
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	// WalkDir is lexical per directory, which is not the same as by full path.
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+
+	for _, o := range objects {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCloser combines a reader with the closer of the underlying source.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
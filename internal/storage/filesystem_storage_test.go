@@ -16,11 +16,15 @@ package storage
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestFilesystemStorage_CreateObject(t *testing.T) {
@@ -86,6 +90,88 @@ func TestFilesystemStorage_CreateObject(t *testing.T) {
 	}
 }
 
+func TestFilesystemStorage_NewWriter(t *testing.T) {
+	t.Parallel()
+
+	tmp, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmp) })
+
+	if err := os.Mkdir(filepath.Join(tmp, "nested"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		filepath string
+		contents []byte
+		cancel   bool
+	}{
+		{
+			name:     "default",
+			filepath: "myfile",
+			contents: []byte("contents"),
+		},
+		{
+			name:     "nested",
+			filepath: "nested/1600000000-1600003600-00001.zip",
+			contents: []byte("contents"),
+		},
+		{
+			name:     "cancelled",
+			filepath: "cancelled",
+			contents: []byte("contents"),
+			cancel:   true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(project.TestContext(t))
+			defer cancel()
+
+			storage, err := NewFilesystemStorage(ctx, &Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w, err := storage.NewWriter(ctx, tmp, tc.filepath, false, ContentTypeZip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(tc.contents); err != nil {
+				t.Fatal(err)
+			}
+			if tc.cancel {
+				cancel()
+			}
+			if err := w.Close(); (err != nil) != tc.cancel {
+				t.Fatal(err)
+			}
+
+			contents, err := os.ReadFile(filepath.Join(tmp, tc.filepath))
+			if tc.cancel {
+				if !os.IsNotExist(err) {
+					t.Errorf("expected object to not exist, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(contents, tc.contents) {
+				t.Errorf("expected %q to be %q ", contents, tc.contents)
+			}
+		})
+	}
+}
+
 func TestFilesystemStorage_DeleteObject(t *testing.T) {
 	t.Parallel()
 
@@ -187,3 +273,132 @@ func TestFilesystemStorage_GetObject(t *testing.T) {
 		})
 	}
 }
+
+func TestFilesystemStorage_NewRangeReader(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	cases := []struct {
+		name     string
+		filepath string
+		offset   int64
+		length   int64
+		contents []byte
+		err      error
+	}{
+		{
+			name:     "all",
+			filepath: filepath.Base(f.Name()),
+			offset:   0,
+			length:   -1,
+			contents: []byte("hello world"),
+		},
+		{
+			name:     "offset",
+			filepath: filepath.Base(f.Name()),
+			offset:   6,
+			length:   -1,
+			contents: []byte("world"),
+		},
+		{
+			name:     "range",
+			filepath: filepath.Base(f.Name()),
+			offset:   2,
+			length:   3,
+			contents: []byte("llo"),
+		},
+		{
+			name:     "not_exist",
+			filepath: "not-exist",
+			length:   -1,
+			err:      ErrNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := project.TestContext(t)
+
+			storage, err := NewFilesystemStorage(ctx, &Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := storage.NewRangeReader(ctx, filepath.Dir(f.Name()), tc.filepath, tc.offset, tc.length)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v to be %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			defer r.Close()
+
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := b, tc.contents; !bytes.Equal(got, want) {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFilesystemStorage_ListObjects(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	tmp, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmp) })
+
+	if err := os.Mkdir(filepath.Join(tmp, "a"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := NewFilesystemStorage(ctx, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/2", "a/1", "a.txt", "b", ".hidden"} {
+		if err := storage.CreateObject(ctx, tmp, name, []byte(name), false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(prefix string) []string {
+		var names []string
+		if err := storage.ListObjects(ctx, tmp, prefix, func(attrs *ObjectAttrs) error {
+			if got, want := attrs.Size, int64(len(attrs.Name)); got != want {
+				t.Errorf("%s: expected size %d to be %d", attrs.Name, got, want)
+			}
+			names = append(names, attrs.Name)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	if diff := cmp.Diff([]string{"a.txt", "a/1", "a/2", "b"}, list("")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a/1", "a/2"}, list("a/")); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
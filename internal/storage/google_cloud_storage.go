@@ -24,6 +24,7 @@ import (
 	"io"
 
 	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
 )
 
 func init() {
@@ -71,6 +72,22 @@ func (s *GoogleCloudStorage) CreateObject(ctx context.Context, bucket, objectNam
 	return nil
 }
 
+// NewWriter returns a writer that streams a new cloud storage object, or
+// overwrites an existing one. Cancelling ctx before Close aborts the upload.
+func (s *GoogleCloudStorage) NewWriter(ctx context.Context, bucket, objectName string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	cacheControl := "public, max-age=86400"
+	if !cacheable {
+		cacheControl = "no-cache, max-age=0"
+	}
+
+	wc := s.client.Bucket(bucket).Object(objectName).NewWriter(ctx)
+	wc.CacheControl = cacheControl
+	if contentType != "" {
+		wc.ContentType = contentType
+	}
+	return wc, nil
+}
+
 // DeleteObject deletes a cloud storage object, returns nil if the object was
 // successfully deleted, or of the object doesn't exist.
 func (s *GoogleCloudStorage) DeleteObject(ctx context.Context, bucket, objectName string) error {
@@ -102,3 +119,62 @@ func (s *GoogleCloudStorage) GetObject(ctx context.Context, bucket, object strin
 
 	return b.Bytes(), nil
 }
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *GoogleCloudStorage) NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, bucket, object, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// If the object does not exist, it returns ErrNotFound.
+func (s *GoogleCloudStorage) NewRangeReader(ctx context.Context, bucket, object string, offset, length int64) (io.ReadCloser, error) {
+	r, err := s.client.Bucket(bucket).Object(object).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage.NewRangeReader: %w", err)
+	}
+	return r, nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound.
+func (s *GoogleCloudStorage) GetObjectAttrs(ctx context.Context, bucket, object string) (*ObjectAttrs, error) {
+	attrs, err := s.client.Bucket(bucket).Object(object).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("storage.GetObjectAttrs: %w", err)
+	}
+	return gcsObjectAttrs(attrs), nil
+}
+
+// ListObjects calls fn for each object in the bucket with the given prefix.
+func (s *GoogleCloudStorage) ListObjects(ctx context.Context, bucket, prefix string, fn func(*ObjectAttrs) error) error {
+	it := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("storage.ListObjects: %w", err)
+		}
+		if err := fn(gcsObjectAttrs(attrs)); err != nil {
+			return err
+		}
+	}
+}
+
+func gcsObjectAttrs(attrs *storage.ObjectAttrs) *ObjectAttrs {
+	return &ObjectAttrs{
+		Name:        attrs.Name,
+		Size:        attrs.Size,
+		MD5:         attrs.MD5,
+		ContentType: attrs.ContentType,
+		Updated:     attrs.Updated,
+	}
+}
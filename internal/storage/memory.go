@@ -15,9 +15,15 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5" //nolint:gosec // MD5 is the checksum other storage systems report
+	"io"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 func init() {
@@ -31,13 +37,19 @@ var _ Blobstore = (*Memory)(nil)
 // memory.
 type Memory struct {
 	lock sync.Mutex
-	data map[string][]byte
+	data map[string]*memoryObject
+}
+
+// memoryObject is an object stored in Memory.
+type memoryObject struct {
+	contents []byte
+	updated  time.Time
 }
 
 // NewMemory creates a Blobstore that writes data in memory.
 func NewMemory(_ context.Context, _ *Config) (Blobstore, error) {
 	return &Memory{
-		data: make(map[string][]byte),
+		data: make(map[string]*memoryObject),
 	}, nil
 }
 
@@ -48,10 +60,45 @@ func (s *Memory) CreateObject(_ context.Context, folder, filename string, conten
 	defer s.lock.Unlock()
 
 	pth := path.Join(folder, filename)
-	s.data[pth] = contents
+	s.data[pth] = &memoryObject{
+		contents: contents,
+		updated:  time.Now().UTC(),
+	}
 	return nil
 }
 
+// NewWriter returns a writer that buffers a new object and stores it when the
+// writer is closed.
+// contentType is ignored in this implementation.
+func (s *Memory) NewWriter(ctx context.Context, folder, filename string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	return &memoryWriter{
+		ctx:      ctx,
+		s:        s,
+		folder:   folder,
+		filename: filename,
+	}, nil
+}
+
+// memoryWriter buffers an object for Memory.
+type memoryWriter struct {
+	ctx      context.Context
+	s        *Memory
+	folder   string
+	filename string
+	buf      bytes.Buffer
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *memoryWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	return w.s.CreateObject(w.ctx, w.folder, w.filename, w.buf.Bytes(), false, "")
+}
+
 // DeleteObject deletes an object. It returns nil if the object was deleted or
 // if the object no longer exists.
 func (s *Memory) DeleteObject(_ context.Context, folder, filename string) error {
@@ -74,5 +121,82 @@ func (s *Memory) GetObject(_ context.Context, folder, filename string) ([]byte,
 	if !ok {
 		return nil, ErrNotFound
 	}
-	return v, nil
+	return v.contents, nil
+}
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *Memory) NewReader(ctx context.Context, folder, filename string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, folder, filename, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// If the object does not exist, it returns ErrNotFound.
+func (s *Memory) NewRangeReader(ctx context.Context, folder, filename string, offset, length int64) (io.ReadCloser, error) {
+	b, err := s.GetObject(ctx, folder, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	b = b[offset:]
+	if length >= 0 && length < int64(len(b)) {
+		b = b[:length]
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound.
+// contentType is not stored in this implementation.
+func (s *Memory) GetObjectAttrs(_ context.Context, folder, filename string) (*ObjectAttrs, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pth := path.Join(folder, filename)
+	v, ok := s.data[pth]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v.attrs(filename), nil
+}
+
+// ListObjects calls fn for each object in folder with the given prefix.
+func (s *Memory) ListObjects(_ context.Context, folder, prefix string, fn func(*ObjectAttrs) error) error {
+	s.lock.Lock()
+	dir := ""
+	if f := path.Clean(folder); f != "." {
+		dir = f + "/"
+	}
+	objects := make([]*ObjectAttrs, 0, len(s.data))
+	for pth, v := range s.data {
+		if !strings.HasPrefix(pth, dir+prefix) {
+			continue
+		}
+		objects = append(objects, v.attrs(strings.TrimPrefix(pth, dir)))
+	}
+	s.lock.Unlock()
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+
+	for _, o := range objects {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *memoryObject) attrs(name string) *ObjectAttrs {
+	sum := md5.Sum(o.contents) //nolint:gosec
+	return &ObjectAttrs{
+		Name:    name,
+		Size:    int64(len(o.contents)),
+		MD5:     sum[:],
+		Updated: o.updated,
+	}
 }
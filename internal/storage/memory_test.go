@@ -0,0 +1,83 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemory_ObjectAttrs(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+
+	storage, err := NewMemory(ctx, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"root/b", "root/a", "other/c"} {
+		if err := storage.CreateObject(ctx, "bucket", name, []byte(name), false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	if err := storage.ListObjects(ctx, "bucket", "root/", func(attrs *ObjectAttrs) error {
+		names = append(names, attrs.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"root/a", "root/b"}, names); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	attrs, err := storage.GetObjectAttrs(ctx, "bucket", "root/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum([]byte("root/a")) //nolint:gosec
+	if got, want := attrs.MD5, sum[:]; !bytes.Equal(got, want) {
+		t.Errorf("expected %x to be %x", got, want)
+	}
+	if got, want := attrs.Size, int64(6); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+
+	if _, err := storage.GetObjectAttrs(ctx, "bucket", "root/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+
+	r, err := storage.NewRangeReader(ctx, "bucket", "root/a", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "ot"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+}
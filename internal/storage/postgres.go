@@ -0,0 +1,268 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // MD5 is the checksum other storage systems report
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/database"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+func init() {
+	RegisterBlobstore("POSTGRES", NewPostgres)
+}
+
+// Compile-time check to verify implements interface.
+var _ Blobstore = (*Postgres)(nil)
+
+// Postgres implements Blobstore and stores objects in a table in a Postgres
+// database. It is intended for small deployments that do not have access to
+// cloud object storage, and can be shared by multiple replicas. Objects are
+// held in memory while they are read or written, so it is not suitable for
+// very large objects.
+type Postgres struct {
+	db *database.DB
+}
+
+// NewPostgres creates a Blobstore that stores objects in the database
+// described by the config's database settings.
+func NewPostgres(ctx context.Context, cfg *Config) (Blobstore, error) {
+	db, err := database.NewFromEnv(ctx, &cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &Postgres{db: db}, nil
+}
+
+// CreateObject creates a new object or overwrites an existing one.
+func (s *Postgres) CreateObject(ctx context.Context, bucket, name string, contents []byte, cacheable bool, contentType string) error {
+	if contents == nil {
+		contents = []byte{}
+	}
+	sum := md5.Sum(contents) //nolint:gosec
+
+	return s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				Blob
+				(bucket, name, contents, content_type, cacheable, md5, updated_at)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (bucket, name) DO UPDATE SET
+				contents = EXCLUDED.contents,
+				content_type = EXCLUDED.content_type,
+				cacheable = EXCLUDED.cacheable,
+				md5 = EXCLUDED.md5,
+				updated_at = EXCLUDED.updated_at
+		`, bucket, name, contents, contentType, cacheable, sum[:], time.Now().UTC()); err != nil {
+			return fmt.Errorf("inserting blob: %w", err)
+		}
+		return nil
+	})
+}
+
+// NewWriter returns a writer that buffers a new object and stores it when the
+// writer is closed.
+func (s *Postgres) NewWriter(ctx context.Context, bucket, name string, cacheable bool, contentType string) (io.WriteCloser, error) {
+	return &postgresWriter{
+		ctx:         ctx,
+		s:           s,
+		bucket:      bucket,
+		name:        name,
+		cacheable:   cacheable,
+		contentType: contentType,
+	}, nil
+}
+
+// postgresWriter buffers an object for Postgres.
+type postgresWriter struct {
+	ctx         context.Context
+	s           *Postgres
+	bucket      string
+	name        string
+	cacheable   bool
+	contentType string
+	buf         bytes.Buffer
+}
+
+func (w *postgresWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *postgresWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	return w.s.CreateObject(w.ctx, w.bucket, w.name, w.buf.Bytes(), w.cacheable, w.contentType)
+}
+
+// DeleteObject deletes an object. It returns nil if the object was deleted or
+// if the object no longer exists.
+func (s *Postgres) DeleteObject(ctx context.Context, bucket, name string) error {
+	return s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM
+				Blob
+			WHERE
+				bucket = $1 AND name = $2
+		`, bucket, name); err != nil {
+			return fmt.Errorf("deleting blob: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetObject returns the contents for the given object. If the object does not
+// exist, it returns ErrNotFound.
+func (s *Postgres) GetObject(ctx context.Context, bucket, name string) ([]byte, error) {
+	return s.readRange(ctx, bucket, name, 0, -1)
+}
+
+// NewReader returns a reader for the contents of the given object. If the
+// object does not exist, it returns ErrNotFound.
+func (s *Postgres) NewReader(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	return s.NewRangeReader(ctx, bucket, name, 0, -1)
+}
+
+// NewRangeReader returns a reader for part of the contents of the given object.
+// Only the requested range is read from the database. If the object does not
+// exist, it returns ErrNotFound.
+func (s *Postgres) NewRangeReader(ctx context.Context, bucket, name string, offset, length int64) (io.ReadCloser, error) {
+	b, err := s.readRange(ctx, bucket, name, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// readRange reads up to length bytes of the object's contents, starting at
+// offset. If length is negative, the object is read until the end.
+func (s *Postgres) readRange(ctx context.Context, bucket, name string, offset, length int64) ([]byte, error) {
+	if offset < 0 {
+		offset = 0
+	}
+
+	// SUBSTRING is 1-indexed, and a NULL length reads until the end.
+	var count *int64
+	if length >= 0 {
+		count = &length
+	}
+
+	var b []byte
+	if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				SUBSTRING(contents FROM $3::INT FOR COALESCE($4::INT, LENGTH(contents)))
+			FROM
+				Blob
+			WHERE
+				bucket = $1 AND name = $2
+		`, bucket, name, offset+1, count)
+		if err := row.Scan(&b); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("reading blob: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetObjectAttrs returns the metadata for the given object. If the object does
+// not exist, it returns ErrNotFound.
+func (s *Postgres) GetObjectAttrs(ctx context.Context, bucket, name string) (*ObjectAttrs, error) {
+	var attrs *ObjectAttrs
+	if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				name, LENGTH(contents), md5, content_type, updated_at
+			FROM
+				Blob
+			WHERE
+				bucket = $1 AND name = $2
+		`, bucket, name)
+
+		var err error
+		attrs, err = scanObjectAttrs(row)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("reading blob attributes: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// ListObjects calls fn for each object in the bucket with the given prefix.
+// The metadata is read before fn is called, so fn may use the database.
+func (s *Postgres) ListObjects(ctx context.Context, bucket, prefix string, fn func(*ObjectAttrs) error) error {
+	var objects []*ObjectAttrs
+	if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				name, LENGTH(contents), md5, content_type, updated_at
+			FROM
+				Blob
+			WHERE
+				bucket = $1 AND LEFT(name, LENGTH($2)) = $2
+			ORDER BY name COLLATE "C"
+		`, bucket, prefix)
+		if err != nil {
+			return fmt.Errorf("listing blobs: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			attrs, err := scanObjectAttrs(rows)
+			if err != nil {
+				return fmt.Errorf("reading blob attributes: %w", err)
+			}
+			objects = append(objects, attrs)
+		}
+		return rows.Err()
+	}); err != nil {
+		return err
+	}
+
+	for _, o := range objects {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanObjectAttrs(row pgx.Row) (*ObjectAttrs, error) {
+	var attrs ObjectAttrs
+	if err := row.Scan(&attrs.Name, &attrs.Size, &attrs.MD5, &attrs.ContentType, &attrs.Updated); err != nil {
+		return nil, err
+	}
+	return &attrs, nil
+}
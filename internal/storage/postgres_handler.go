@@ -0,0 +1,90 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/logging"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// Compile-time check to verify implements interface.
+var _ http.Handler = (*Postgres)(nil)
+
+// ServeHTTP serves objects at /<bucket>/<name>, setting the same cache headers
+// the cloud storage backends set on upload. Range and conditional requests are
+// supported. Mount it with http.StripPrefix to serve it under a sub path.
+func (s *Postgres) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	bucket, name := parts[0], parts[1]
+
+	var (
+		contents    []byte
+		contentType string
+		cacheable   bool
+		sum         []byte
+		updated     time.Time
+	)
+	if err := s.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				contents, content_type, cacheable, md5, updated_at
+			FROM
+				Blob
+			WHERE
+				bucket = $1 AND name = $2
+		`, bucket, name)
+		return row.Scan(&contents, &contentType, &cacheable, &sum, &updated)
+	}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		logging.FromContext(ctx).Errorw("failed to read blob", "bucket", bucket, "name", name, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	cacheControl := "public, max-age=86400"
+	if !cacheable {
+		cacheControl = "no-cache, max-age=0"
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum)+`"`)
+	// If no content type was stored, ServeContent detects it.
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	http.ServeContent(w, r, name, updated, bytes.NewReader(contents))
+}
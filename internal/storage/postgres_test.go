@@ -0,0 +1,243 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/pkg/database"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testDatabaseInstance *database.TestInstance
+
+func TestMain(m *testing.M) {
+	testDatabaseInstance = database.MustTestInstance()
+	defer testDatabaseInstance.MustClose()
+	m.Run()
+}
+
+func TestPostgres(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	storage := &Postgres{db: testDB}
+
+	for _, name := range []string{"root/b", "root/a", "root_c", "other/d"} {
+		if err := storage.CreateObject(ctx, "bucket", name, []byte(name), false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Overwrites the existing object.
+	if err := storage.CreateObject(ctx, "bucket", "root/a", []byte("contents"), true, ContentTypeTextPlain); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := storage.GetObject(ctx, "bucket", "root/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "contents"; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	if _, err := storage.GetObject(ctx, "bucket", "root/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+
+	attrs, err := storage.GetObjectAttrs(ctx, "bucket", "root/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attrs.Size, int64(8); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if got, want := attrs.ContentType, ContentTypeTextPlain; got != want {
+		t.Errorf("expected %q to be %q", got, want)
+	}
+
+	var names []string
+	if err := storage.ListObjects(ctx, "bucket", "root/", func(attrs *ObjectAttrs) error {
+		names = append(names, attrs.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"root/a", "root/b"}, names); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	ranges := []struct {
+		offset, length int64
+		want           string
+	}{
+		{offset: 2, length: 3, want: "nte"},
+		{offset: 5, length: -1, want: "nts"},
+		{offset: 0, length: 0, want: ""},
+		{offset: 20, length: 4, want: ""},
+	}
+	for _, tc := range ranges {
+		r, err := storage.NewRangeReader(ctx, "bucket", "root/a", tc.offset, tc.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != tc.want {
+			t.Errorf("range %d+%d: expected %q to be %q", tc.offset, tc.length, got, tc.want)
+		}
+	}
+
+	// The object is not created if the context is cancelled before Close.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	w, err := storage.NewWriter(cancelCtx, "bucket", "root/cancelled", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := w.Close(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v to be %v", err, context.Canceled)
+	}
+	if _, err := storage.GetObject(ctx, "bucket", "root/cancelled"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+
+	if err := storage.DeleteObject(ctx, "bucket", "root/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.DeleteObject(ctx, "bucket", "root/b"); err != nil {
+		t.Errorf("expected deleting a missing object to succeed: %v", err)
+	}
+	if _, err := storage.GetObject(ctx, "bucket", "root/b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v to be %v", err, ErrNotFound)
+	}
+}
+
+func TestPostgres_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	storage := &Postgres{db: testDB}
+
+	if err := storage.CreateObject(ctx, "bucket", "root/file.zip", []byte("contents"), true, ContentTypeZip); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.CreateObject(ctx, "bucket", "root/index.txt", []byte("root/file.zip"), false, ContentTypeTextPlain); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		path         string
+		rangeHeader  string
+		code         int
+		body         string
+		contentType  string
+		cacheControl string
+	}{
+		{
+			name:         "cacheable",
+			method:       http.MethodGet,
+			path:         "/bucket/root/file.zip",
+			code:         http.StatusOK,
+			body:         "contents",
+			contentType:  ContentTypeZip,
+			cacheControl: "public, max-age=86400",
+		},
+		{
+			name:         "not_cacheable",
+			method:       http.MethodGet,
+			path:         "/bucket/root/index.txt",
+			code:         http.StatusOK,
+			body:         "root/file.zip",
+			contentType:  ContentTypeTextPlain,
+			cacheControl: "no-cache, max-age=0",
+		},
+		{
+			name:         "range",
+			method:       http.MethodGet,
+			path:         "/bucket/root/file.zip",
+			rangeHeader:  "bytes=2-4",
+			code:         http.StatusPartialContent,
+			body:         "nte",
+			contentType:  ContentTypeZip,
+			cacheControl: "public, max-age=86400",
+		},
+		{
+			name:   "missing",
+			method: http.MethodGet,
+			path:   "/bucket/root/missing.zip",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:   "no_name",
+			method: http.MethodGet,
+			path:   "/bucket",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:   "post",
+			method: http.MethodPost,
+			path:   "/bucket/root/file.zip",
+			code:   http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.rangeHeader != "" {
+				r.Header.Set("Range", tc.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+			storage.ServeHTTP(w, r)
+
+			if got, want := w.Code, tc.code; got != want {
+				t.Fatalf("expected %d to be %d: %s", got, want, w.Body.String())
+			}
+			if tc.code >= 300 {
+				return
+			}
+			if got, want := w.Body.String(), tc.body; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+			if got, want := w.Header().Get("Content-Type"), tc.contentType; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+			if got, want := w.Header().Get("Cache-Control"), tc.cacheControl; got != want {
+				t.Errorf("expected %q to be %q", got, want)
+			}
+		})
+	}
+}
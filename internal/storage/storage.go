@@ -18,8 +18,10 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"sync"
+	"time"
 )
 
 var ErrNotFound = fmt.Errorf("storage object not found")
@@ -27,6 +29,9 @@ var ErrNotFound = fmt.Errorf("storage object not found")
 const (
 	ContentTypeTextPlain = "text/plain"
 	ContentTypeZip       = "application/zip"
+	ContentTypeJSON      = "application/json"
+	ContentTypeJSONLines = "application/jsonl"
+	ContentTypeCSV       = "text/csv"
 )
 
 // Blobstore defines the minimum interface for a blob storage system.
@@ -35,11 +40,57 @@ type Blobstore interface {
 	// If contentType is blank, the default for the chosen storage implementation is used.
 	CreateObject(ctx context.Context, parent, name string, contents []byte, cacheable bool, contentType string) error
 
+	// NewWriter returns a writer that streams a new object into the storage
+	// system, overwriting any existing object. The object is only created once
+	// the writer is closed without error. If ctx is cancelled before Close is
+	// called, the object is not created.
+	// If contentType is blank, the default for the chosen storage implementation is used.
+	NewWriter(ctx context.Context, parent, name string, cacheable bool, contentType string) (io.WriteCloser, error)
+
 	// DeleteObject deletes an object or does nothing if the object doesn't exist.
 	DeleteObject(ctx context.Context, parent, bame string) error
 
 	// GetObject fetches the object's contents.
 	GetObject(ctx context.Context, parent, name string) ([]byte, error)
+
+	// NewReader returns a reader for the object's contents, which the caller
+	// must close. If the object does not exist, it returns ErrNotFound.
+	NewReader(ctx context.Context, parent, name string) (io.ReadCloser, error)
+
+	// NewRangeReader returns a reader for up to length bytes of the object's
+	// contents, starting at offset. If length is negative, the object is read
+	// until the end. If the object does not exist, it returns ErrNotFound.
+	NewRangeReader(ctx context.Context, parent, name string, offset, length int64) (io.ReadCloser, error)
+
+	// GetObjectAttrs returns the object's metadata without reading its
+	// contents. If the object does not exist, it returns ErrNotFound.
+	GetObjectAttrs(ctx context.Context, parent, name string) (*ObjectAttrs, error)
+
+	// ListObjects calls fn with the metadata of each object in parent whose name
+	// starts with prefix, in lexicographical order. If fn returns an error,
+	// listing stops and that error is returned.
+	ListObjects(ctx context.Context, parent, prefix string, fn func(*ObjectAttrs) error) error
+}
+
+// ObjectAttrs is the metadata for an object in a Blobstore.
+type ObjectAttrs struct {
+	// Name is the name of the object within its parent.
+	Name string
+
+	// Size is the size of the object's contents in bytes.
+	Size int64
+
+	// MD5 is the MD5 checksum of the object's contents. It is empty if the
+	// storage system does not provide one, for example for multipart uploads to
+	// S3 or on the filesystem.
+	MD5 []byte
+
+	// ContentType is the object's content type. It may be empty if it is not
+	// stored or, for some storage systems, when listing objects.
+	ContentType string
+
+	// Updated is the time at which the object was last written.
+	Updated time.Time
 }
 
 // BlobstoreFunc is a func that returns a blobstore or error.
@@ -91,3 +142,49 @@ func BlobstoreFor(ctx context.Context, cfg *Config) (Blobstore, error) {
 	}
 	return fn(ctx, cfg)
 }
+
+// pipeWriter is an io.WriteCloser that feeds an upload running in the
+// background. It is used by implementations whose client libraries consume an
+// io.Reader rather than exposing a writer.
+type pipeWriter struct {
+	ctx  context.Context
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// newPipeWriter starts upload in a goroutine, reading from the returned
+// writer. Closing the writer waits for the upload to finish.
+func newPipeWriter(ctx context.Context, upload func(r io.Reader) error) *pipeWriter {
+	pr, pw := io.Pipe()
+	w := &pipeWriter{
+		ctx:  ctx,
+		pw:   pw,
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := upload(pr)
+		// Unblock any pending writes if the upload stopped early.
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+
+	return w
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload. If the context was cancelled, the upload is
+// aborted instead.
+func (w *pipeWriter) Close() error {
+	if err := w.ctx.Err(); err != nil {
+		w.pw.CloseWithError(err)
+		<-w.done
+		return err
+	}
+
+	w.pw.Close()
+	return <-w.done
+}
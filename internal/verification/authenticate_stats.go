@@ -25,14 +25,14 @@ import (
 )
 
 // AuthenticateStatsToken parse the provided JWT and determines if it is an authorized stats request
-// and returns the authorized health authority ID.
-func (v *Verifier) AuthenticateStatsToken(ctx context.Context, rawToken string) (int64, error) {
-	var healthAuthorityID int64
+// and returns the authorized health authority.
+func (v *Verifier) AuthenticateStatsToken(ctx context.Context, rawToken string) (*model.HealthAuthority, error) {
+	var authorized *model.HealthAuthority
 	var claims *jwt.StandardClaims
 
 	token, err := jwt.ParseWithClaims(rawToken, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if method, ok := token.Method.(*jwt.SigningMethodECDSA); !ok || method.Name != jwt.SigningMethodES256.Name {
-			return nil, fmt.Errorf("unsupported signing method, must be %v", jwt.SigningMethodES256.Name)
+		if err := checkSigningMethod(token.Method); err != nil {
+			return nil, err
 		}
 
 		kidHeader, ok := token.Header["kid"]
@@ -85,23 +85,23 @@ func (v *Verifier) AuthenticateStatsToken(ctx context.Context, rawToken string)
 		// Look for the matching 'kid'
 		for _, key := range healthAuthority.Keys {
 			if key.Version == kid && key.IsValid() {
-				healthAuthorityID = healthAuthority.ID
-				return key.PublicKey()
+				authorized = healthAuthority
+				return verificationKey(token.Method, key)
 			}
 		}
 		return nil, fmt.Errorf("key not found: kid: %v iss: %v ", kid, claims.Issuer)
 	})
 	if err != nil {
-		return 0, fmt.Errorf("unauthorized: %w", err)
+		return nil, fmt.Errorf("unauthorized: %w", err)
 	}
 
 	if !token.Valid {
-		return 0, fmt.Errorf("authentication token invalid")
+		return nil, fmt.Errorf("authentication token invalid")
 	}
 
 	if !claims.VerifyAudience(v.config.StatsAudience, true) {
-		return 0, fmt.Errorf("unauthorized, audience mismatch")
+		return nil, fmt.Errorf("unauthorized, audience mismatch")
 	}
 
-	return healthAuthorityID, nil
+	return authorized, nil
 }
@@ -225,12 +225,12 @@ func TestAuthenticateStatsToken(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			gotID, err := verifier.AuthenticateStatsToken(ctx, jwtString)
+			got, err := verifier.AuthenticateStatsToken(ctx, jwtString)
 			errcmp.MustMatch(t, err, tc.Error)
 
 			if tc.Error == "" {
-				if gotID != healthAuthority.ID {
-					t.Fatalf("incorrect health authority id want: %v, got: %v", healthAuthority.ID, gotID)
+				if got.ID != healthAuthority.ID {
+					t.Fatalf("incorrect health authority id want: %v, got: %v", healthAuthority.ID, got.ID)
 				}
 			}
 		})
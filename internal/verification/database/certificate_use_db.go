@@ -0,0 +1,104 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// ErrCertificateReused indicates the certificate was already used in a
+// previous publish request, and the health authority enforces replay
+// protection.
+var ErrCertificateReused = errors.New("verification certificate has already been used")
+
+// RecordCertificateUse records that the verification certificate identified by
+// hash was used for the health authority. It returns ErrCertificateReused if the
+// certificate was already recorded and has not yet expired.
+func (db *HealthAuthorityDB) RecordCertificateUse(ctx context.Context, healthAuthorityID int64, hash []byte, expiresAt time.Time) error {
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		return RecordCertificateUseInTx(ctx, tx, healthAuthorityID, hash, expiresAt)
+	})
+}
+
+// RecordCertificateUseInTx is RecordCertificateUse within an existing
+// transaction, so the use is only recorded if the transaction commits.
+func RecordCertificateUseInTx(ctx context.Context, tx pgx.Tx, healthAuthorityID int64, hash []byte, expiresAt time.Time) error {
+	// An expired record that has not been cleaned up yet does not count as a
+	// use, it is replaced instead.
+	result, err := tx.Exec(ctx, `
+		INSERT INTO
+			VerificationCertificateUse
+			(health_authority_id, certificate_hash, expires_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (health_authority_id, certificate_hash) DO UPDATE
+			SET expires_at = EXCLUDED.expires_at
+			WHERE VerificationCertificateUse.expires_at < NOW()
+		`, healthAuthorityID, hash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("inserting verification certificate use: %w", err)
+	}
+	if result.RowsAffected() != 1 {
+		return ErrCertificateReused
+	}
+	return nil
+}
+
+// CertificateUsed returns true if the verification certificate identified by
+// hash was already used for the health authority, and has not yet expired.
+func (db *HealthAuthorityDB) CertificateUsed(ctx context.Context, healthAuthorityID int64, hash []byte) (bool, error) {
+	var used bool
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM
+					VerificationCertificateUse
+				WHERE
+					health_authority_id = $1 AND certificate_hash = $2 AND expires_at >= NOW()
+			)
+			`, healthAuthorityID, hash)
+		return row.Scan(&used)
+	}); err != nil {
+		return false, fmt.Errorf("checking verification certificate use: %w", err)
+	}
+	return used, nil
+}
+
+// DeleteExpiredCertificateUses deletes the records of verification
+// certificates that expired before the given time.
+func (db *HealthAuthorityDB) DeleteExpiredCertificateUses(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM
+				VerificationCertificateUse
+			WHERE
+				expires_at < $1
+			`, before)
+		if err != nil {
+			return fmt.Errorf("deleting verification certificate uses: %w", err)
+		}
+		count = result.RowsAffected()
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+)
+
+func TestCertificateUses(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := New(testDB)
+
+	var has []*model.HealthAuthority
+	for _, iss := range []string{"iss1", "iss2"} {
+		ha := &model.HealthAuthority{Issuer: iss, Audience: "aud", Name: iss}
+		if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+			t.Fatal(err)
+		}
+		has = append(has, ha)
+	}
+
+	now := time.Now()
+	hash := []byte("certificate")
+	if err := haDB.RecordCertificateUse(ctx, has[0].ID, hash, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := haDB.RecordCertificateUse(ctx, has[0].ID, hash, now.Add(time.Hour)); !errors.Is(err, ErrCertificateReused) {
+		t.Errorf("expected %v to be %v", err, ErrCertificateReused)
+	}
+	// Certificates are tracked per health authority.
+	if used, err := haDB.CertificateUsed(ctx, has[1].ID, hash); err != nil || used {
+		t.Errorf("expected certificate to be unused, got used=%t err=%v", used, err)
+	}
+	if err := haDB.RecordCertificateUse(ctx, has[1].ID, hash, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := haDB.CertificateUsed(ctx, has[1].ID, hash); err != nil || !used {
+		t.Errorf("expected certificate to be used, got used=%t err=%v", used, err)
+	}
+
+	// An expired use is replaced.
+	expired := []byte("expired")
+	if err := haDB.RecordCertificateUse(ctx, has[0].ID, expired, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := haDB.RecordCertificateUse(ctx, has[0].ID, expired, now.Add(-time.Minute)); err != nil {
+		t.Errorf("expected expired use to be replaced: %v", err)
+	}
+
+	count, err := haDB.DeleteExpiredCertificateUses(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := count, int64(1); got != want {
+		t.Errorf("expected %d to be %d", got, want)
+	}
+	if err := haDB.RecordCertificateUse(ctx, has[0].ID, hash, now.Add(time.Hour)); !errors.Is(err, ErrCertificateReused) {
+		t.Errorf("expected %v to be %v", err, ErrCertificateReused)
+	}
+}
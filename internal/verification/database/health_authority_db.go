@@ -56,15 +56,19 @@ func (db *HealthAuthorityDB) AddHealthAuthority(ctx context.Context, ha *model.H
 		row := tx.QueryRow(ctx, `
 			INSERT INTO
 				HealthAuthority
-				(iss, aud, name, jwks_uri, enable_stats)
+				(iss, aud, name, jwks_uri, enable_stats, enable_replay_protection,
+				test_date_onset_offset_days, stats_noise, stats_noise_epsilon,
+				stats_export_bucket, stats_export_filename_root)
 			VALUES
-				($1, $2, $3, $4, $5)
+				($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 			RETURNING id
-			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI)
+			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.EnableReplayProtection,
+			ha.TestDateOnsetOffsetDays, ha.StatsNoise, ha.StatsNoiseEpsilon,
+			ha.StatsExportBucket, ha.StatsExportFilenameRoot)
 		if err := row.Scan(&ha.ID); err != nil {
 			return fmt.Errorf("inserting healthauthority: %w", err)
 		}
-		return nil
+		return saveTransformPolicy(ctx, tx, ha)
 	})
 }
 
@@ -81,17 +85,22 @@ func (db *HealthAuthorityDB) UpdateHealthAuthority(ctx context.Context, ha *mode
 		result, err := tx.Exec(ctx, `
 			UPDATE HealthAuthority
 			SET
-				iss = $1, aud = $2, name = $3, jwks_uri = $4, enable_stats = $5
+				iss = $1, aud = $2, name = $3, jwks_uri = $4, enable_stats = $5,
+				enable_replay_protection = $6, test_date_onset_offset_days = $7,
+				stats_noise = $8, stats_noise_epsilon = $9,
+				stats_export_bucket = $10, stats_export_filename_root = $11
 			WHERE
-				id = $6
-			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.ID)
+				id = $12
+			`, ha.Issuer, ha.Audience, ha.Name, ha.JwksURI, ha.EnableStatsAPI, ha.EnableReplayProtection,
+			ha.TestDateOnsetOffsetDays, ha.StatsNoise, ha.StatsNoiseEpsilon,
+			ha.StatsExportBucket, ha.StatsExportFilenameRoot, ha.ID)
 		if err != nil {
 			return fmt.Errorf("updating health authority: %w", err)
 		}
 		if result.RowsAffected() != 1 {
 			return fmt.Errorf("no rows updates")
 		}
-		return nil
+		return saveTransformPolicy(ctx, tx, ha)
 	})
 }
 
@@ -101,7 +110,9 @@ func (db *HealthAuthorityDB) GetHealthAuthorityByID(ctx context.Context, id int6
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, enable_replay_protection,
+				test_date_onset_offset_days, stats_noise, stats_noise_epsilon,
+				stats_export_bucket, stats_export_filename_root
 			FROM
 				HealthAuthority
 			WHERE
@@ -113,7 +124,8 @@ func (db *HealthAuthorityDB) GetHealthAuthorityByID(ctx context.Context, id int6
 		if err != nil {
 			return fmt.Errorf("failed to parse: %w", err)
 		}
-		return nil
+		ha.TransformPolicy, err = getTransformPolicy(ctx, tx, ha.ID)
+		return err
 	}); err != nil {
 		return nil, fmt.Errorf("get health authority by id: %w", err)
 	}
@@ -134,7 +146,9 @@ func (db *HealthAuthorityDB) GetHealthAuthority(ctx context.Context, issuer stri
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		row := tx.QueryRow(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, enable_replay_protection,
+				test_date_onset_offset_days, stats_noise, stats_noise_epsilon,
+				stats_export_bucket, stats_export_filename_root
 			FROM
 				HealthAuthority
 			WHERE
@@ -146,7 +160,8 @@ func (db *HealthAuthorityDB) GetHealthAuthority(ctx context.Context, issuer stri
 		if err != nil {
 			return err
 		}
-		return nil
+		ha.TransformPolicy, err = getTransformPolicy(ctx, tx, ha.ID)
+		return err
 	}); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrHealthAuthorityNotFound
@@ -171,7 +186,9 @@ func (db *HealthAuthorityDB) ListAllHealthAuthoritiesWithoutKeys(ctx context.Con
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				id, iss, aud, name, jwks_uri, enable_stats
+				id, iss, aud, name, jwks_uri, enable_stats, enable_replay_protection,
+				test_date_onset_offset_days, stats_noise, stats_noise_epsilon,
+				stats_export_bucket, stats_export_filename_root
 			FROM
 				HealthAuthority
 			ORDER BY iss ASC
@@ -202,7 +219,9 @@ func (db *HealthAuthorityDB) ListAllHealthAuthoritiesWithoutKeys(ctx context.Con
 
 func scanOneHealthAuthority(row pgx.Row) (*model.HealthAuthority, error) {
 	var ha model.HealthAuthority
-	if err := row.Scan(&ha.ID, &ha.Issuer, &ha.Audience, &ha.Name, &ha.JwksURI, &ha.EnableStatsAPI); err != nil {
+	if err := row.Scan(&ha.ID, &ha.Issuer, &ha.Audience, &ha.Name, &ha.JwksURI, &ha.EnableStatsAPI, &ha.EnableReplayProtection,
+		&ha.TestDateOnsetOffsetDays, &ha.StatsNoise, &ha.StatsNoiseEpsilon,
+		&ha.StatsExportBucket, &ha.StatsExportFilenameRoot); err != nil {
 		return nil, err
 	}
 	return &ha, nil
@@ -229,10 +248,10 @@ func (db *HealthAuthorityDB) AddHealthAuthorityKey(ctx context.Context, ha *mode
 		result, err := tx.Exec(ctx, `
 			INSERT INTO
 				HealthAuthorityKey
-				(health_authority_id, version, from_timestamp, thru_timestamp, public_key)
+				(health_authority_id, version, from_timestamp, thru_timestamp, public_key, algorithm)
 			VALUES
-				($1, $2, $3, $4, $5)
-			`, hak.AuthorityID, hak.Version, hak.From, thru, hak.PublicKeyPEM)
+				($1, $2, $3, $4, $5, $6)
+			`, hak.AuthorityID, hak.Version, hak.From, thru, hak.PublicKeyPEM, hak.EffectiveAlgorithm())
 		if err != nil {
 			return fmt.Errorf("inserting healthauthoritykey: %w", err)
 		}
@@ -273,10 +292,10 @@ func (db *HealthAuthorityDB) UpdateHealthAuthorityKey(ctx context.Context, hak *
 		result, err := tx.Exec(ctx, `
 			UPDATE HealthAuthorityKey
 			SET
-				from_timestamp = $1, thru_timestamp = $2, public_key = $3
+				from_timestamp = $1, thru_timestamp = $2, public_key = $3, algorithm = $4
 			WHERE
-				health_authority_id = $4 AND version = $5
-			`, hak.From, thru, hak.PublicKeyPEM, hak.AuthorityID, hak.Version)
+				health_authority_id = $5 AND version = $6
+			`, hak.From, thru, hak.PublicKeyPEM, hak.EffectiveAlgorithm(), hak.AuthorityID, hak.Version)
 		if err != nil {
 			return fmt.Errorf("updating health authority key: %w", err)
 		}
@@ -293,7 +312,7 @@ func (db *HealthAuthorityDB) GetHealthAuthorityKeys(ctx context.Context, ha *mod
 	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
 		rows, err := tx.Query(ctx, `
 			SELECT
-				health_authority_id, version, from_timestamp, thru_timestamp, public_key
+				health_authority_id, version, from_timestamp, thru_timestamp, public_key, algorithm
 			FROM
 				HealthAuthorityKey
 			WHERE
@@ -313,7 +332,7 @@ func (db *HealthAuthorityDB) GetHealthAuthorityKeys(ctx context.Context, ha *mod
 
 			var key model.HealthAuthorityKey
 			var thru *time.Time
-			if err := rows.Scan(&key.AuthorityID, &key.Version, &key.From, &thru, &key.PublicKeyPEM); err != nil {
+			if err := rows.Scan(&key.AuthorityID, &key.Version, &key.From, &thru, &key.PublicKeyPEM, &key.Algorithm); err != nil {
 				return fmt.Errorf("failed to parse: %w", err)
 			}
 			if thru != nil {
@@ -32,6 +32,10 @@ const (
 	validPEM = `-----BEGIN PUBLIC KEY-----
 MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEA+k9YktDK3UpOhBIy+O17biuwd/g
 IBSEEHOdgpAynz0yrHpkWL6vxjNHxRdWcImZxPgL0NVHMdY4TlsL7qaxBQ==
+-----END PUBLIC KEY-----`
+
+	validEd25519PEM = `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAd0d7jEV1WxzZbmZXy+bS3vrtQCTMw2lMicuR/oKtS/E=
 -----END PUBLIC KEY-----`
 )
 
@@ -135,9 +139,82 @@ func TestAddRetrieveHealthAuthority(t *testing.T) {
 	}
 
 	want.EnableStatsAPI = true
+	want.StatsNoise = model.StatsNoiseLaplace
+	want.StatsNoiseEpsilon = 0.5
+	want.StatsExportBucket = "stats-bucket"
+	want.StatsExportFilenameRoot = "stats/mystate"
 	if err := haDB.UpdateHealthAuthority(ctx, want); err != nil {
 		t.Fatal(err)
 	}
+
+	got, err = haDB.GetHealthAuthorityByID(ctx, want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestHealthAuthorityTransformPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := New(testDB)
+
+	maxKeys := uint(20)
+	maxAge := 10 * 24 * time.Hour
+	defaultOnset := uint(2)
+	want := &model.HealthAuthority{
+		Issuer:   "doh.mystate.gov",
+		Audience: "ens.usacovid.org",
+		Name:     "My State Department of Healthiness",
+		TransformPolicy: &model.TransformPolicy{
+			MaxExposureKeys:            &maxKeys,
+			MaxIntervalStartAge:        &maxAge,
+			DefaultSymptomOnsetDaysAgo: &defaultOnset,
+		},
+	}
+	if err := haDB.AddHealthAuthority(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := haDB.GetHealthAuthorityByID(ctx, want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Updating overwrites the whole policy.
+	maxOnset := uint(10)
+	want.TransformPolicy = &model.TransformPolicy{MaxSymptomOnsetDays: &maxOnset}
+	if err := haDB.UpdateHealthAuthority(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err = haDB.GetHealthAuthority(ctx, want.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	// An empty policy is removed.
+	want.TransformPolicy = &model.TransformPolicy{}
+	if err := haDB.UpdateHealthAuthority(ctx, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err = haDB.GetHealthAuthorityByID(ctx, want.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TransformPolicy != nil {
+		t.Errorf("expected policy to be removed, got %#v", got.TransformPolicy)
+	}
 }
 
 func TestAddRetrieveHealthAuthorityKeys(t *testing.T) {
@@ -163,11 +240,20 @@ func TestAddRetrieveHealthAuthorityKeys(t *testing.T) {
 			Version:      "v1",
 			From:         time.Now().Add(-1 * time.Minute).Truncate(time.Second),
 			PublicKeyPEM: validPEM,
+			Algorithm:    model.AlgorithmES256,
+		},
+		{
+			Version:      "v2",
+			From:         time.Now().Add(-1 * time.Minute).Truncate(time.Second),
+			PublicKeyPEM: validEd25519PEM,
+			Algorithm:    model.AlgorithmEdDSA,
 		},
 	}
 
-	if err := haDB.AddHealthAuthorityKey(ctx, want, wantKeys[0]); err != nil {
-		t.Fatal(err)
+	for _, hak := range wantKeys {
+		if err := haDB.AddHealthAuthorityKey(ctx, want, hak); err != nil {
+			t.Fatal(err)
+		}
 	}
 	want.Keys = wantKeys
 
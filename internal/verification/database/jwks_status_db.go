@@ -0,0 +1,175 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/exposure-notifications-server/pkg/database"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// GetJWKSStatus returns the JWKS fetch status of a health authority. If the
+// keys have never been fetched, an empty status is returned.
+func (db *HealthAuthorityDB) GetJWKSStatus(ctx context.Context, healthAuthorityID int64) (*model.JWKSStatus, error) {
+	status := &model.JWKSStatus{HealthAuthorityID: healthAuthorityID}
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, `
+			SELECT
+				etag, last_modified, next_fetch_at, last_success_at, last_error, last_error_at,
+				consecutive_failures, key_fingerprint
+			FROM
+				HealthAuthorityJWKSStatus
+			WHERE
+				health_authority_id = $1
+		`, healthAuthorityID)
+
+		var etag, lastModified, lastError, fingerprint *string
+		var nextFetchAt, lastSuccessAt, lastErrorAt *time.Time
+		if err := row.Scan(&etag, &lastModified, &nextFetchAt, &lastSuccessAt, &lastError, &lastErrorAt,
+			&status.ConsecutiveFailures, &fingerprint); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		status.ETag = stringValue(etag)
+		status.LastModified = stringValue(lastModified)
+		status.NextFetchAt = timeValue(nextFetchAt)
+		status.LastSuccessAt = timeValue(lastSuccessAt)
+		status.LastError = stringValue(lastError)
+		status.LastErrorAt = timeValue(lastErrorAt)
+		status.KeyFingerprint = stringValue(fingerprint)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("get jwks status: %w", err)
+	}
+	return status, nil
+}
+
+// SaveJWKSStatus stores the JWKS fetch status of a health authority. If keySet
+// is not nil, it is added to the health authority's key set history.
+func (db *HealthAuthorityDB) SaveJWKSStatus(ctx context.Context, status *model.JWKSStatus, keySet *model.JWKSKeySet) error {
+	if status == nil {
+		return errors.New("provided JWKSStatus cannot be nil")
+	}
+
+	return db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				HealthAuthorityJWKSStatus
+				(health_authority_id, etag, last_modified, next_fetch_at, last_success_at, last_error, last_error_at,
+				consecutive_failures, key_fingerprint)
+			VALUES
+				($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (health_authority_id) DO UPDATE
+			SET
+				etag = EXCLUDED.etag,
+				last_modified = EXCLUDED.last_modified,
+				next_fetch_at = EXCLUDED.next_fetch_at,
+				last_success_at = EXCLUDED.last_success_at,
+				last_error = EXCLUDED.last_error,
+				last_error_at = EXCLUDED.last_error_at,
+				consecutive_failures = EXCLUDED.consecutive_failures,
+				key_fingerprint = EXCLUDED.key_fingerprint
+			`, status.HealthAuthorityID, nullableString(status.ETag), nullableString(status.LastModified),
+			database.NullableTime(status.NextFetchAt), database.NullableTime(status.LastSuccessAt),
+			nullableString(status.LastError), database.NullableTime(status.LastErrorAt),
+			status.ConsecutiveFailures, nullableString(status.KeyFingerprint)); err != nil {
+			return fmt.Errorf("upserting jwks status: %w", err)
+		}
+
+		if keySet == nil {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO
+				HealthAuthorityJWKSKeySet
+				(health_authority_id, fingerprint, key_count, observed_at)
+			VALUES
+				($1, $2, $3, $4)
+			`, status.HealthAuthorityID, keySet.Fingerprint, keySet.KeyCount, keySet.ObservedAt); err != nil {
+			return fmt.Errorf("inserting jwks key set: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListJWKSKeySets returns up to limit of the most recently seen key sets of a
+// health authority, newest first.
+func (db *HealthAuthorityDB) ListJWKSKeySets(ctx context.Context, healthAuthorityID int64, limit int) ([]*model.JWKSKeySet, error) {
+	var keySets []*model.JWKSKeySet
+
+	if err := db.db.InTx(ctx, pgx.ReadCommitted, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT
+				fingerprint, key_count, observed_at
+			FROM
+				HealthAuthorityJWKSKeySet
+			WHERE
+				health_authority_id = $1
+			ORDER BY
+				observed_at DESC, id DESC
+			LIMIT $2
+		`, healthAuthorityID, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("failed to iterate: %w", err)
+			}
+
+			var keySet model.JWKSKeySet
+			if err := rows.Scan(&keySet.Fingerprint, &keySet.KeyCount, &keySet.ObservedAt); err != nil {
+				return fmt.Errorf("failed to parse: %w", err)
+			}
+			keySets = append(keySets, &keySet)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("list jwks key sets: %w", err)
+	}
+	return keySets, nil
+}
+
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func timeValue(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
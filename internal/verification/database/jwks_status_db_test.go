@@ -0,0 +1,89 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/project"
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestJWKSStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := New(testDB)
+
+	ha := &model.HealthAuthority{Issuer: "iss", Audience: "aud", Name: "name"}
+	if err := haDB.AddHealthAuthority(ctx, ha); err != nil {
+		t.Fatal(err)
+	}
+
+	// Never fetched.
+	got, err := haDB.GetJWKSStatus(ctx, ha.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&model.JWKSStatus{HealthAuthorityID: ha.ID}, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	want := &model.JWKSStatus{
+		HealthAuthorityID: ha.ID,
+		ETag:              `"v1"`,
+		NextFetchAt:       now.Add(time.Minute),
+		LastSuccessAt:     now,
+		KeyFingerprint:    "abc",
+	}
+	first := &model.JWKSKeySet{Fingerprint: "abc", KeyCount: 1, ObservedAt: now}
+	if err := haDB.SaveJWKSStatus(ctx, want, first); err != nil {
+		t.Fatal(err)
+	}
+
+	want.LastError = "connection refused"
+	want.LastErrorAt = now.Add(time.Minute)
+	want.ConsecutiveFailures = 1
+	if err := haDB.SaveJWKSStatus(ctx, want, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = haDB.GetJWKSStatus(ctx, ha.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := cmpopts.EquateApproxTime(time.Millisecond)
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	second := &model.JWKSKeySet{Fingerprint: "def", KeyCount: 2, ObservedAt: now.Add(time.Hour)}
+	if err := haDB.SaveJWKSStatus(ctx, want, second); err != nil {
+		t.Fatal(err)
+	}
+
+	keySets, err := haDB.ListJWKSKeySets(ctx, ha.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*model.JWKSKeySet{second, first}, keySets, opts); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/verification/model"
+
+	pgx "github.com/jackc/pgx/v4"
+)
+
+// saveTransformPolicy stores the transform policy of the health authority,
+// removing any stored policy if it is empty.
+func saveTransformPolicy(ctx context.Context, tx pgx.Tx, ha *model.HealthAuthority) error {
+	if ha.TransformPolicy.IsEmpty() {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM HealthAuthorityTransformPolicy
+			WHERE
+				health_authority_id = $1
+			`, ha.ID); err != nil {
+			return fmt.Errorf("deleting transform policy: %w", err)
+		}
+		return nil
+	}
+
+	p := ha.TransformPolicy
+	var maxIntervalStartAge *int64
+	if p.MaxIntervalStartAge != nil {
+		seconds := int64(*p.MaxIntervalStartAge / time.Second)
+		maxIntervalStartAge = &seconds
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO
+			HealthAuthorityTransformPolicy
+			(health_authority_id, max_exposure_keys, max_interval_start_age_seconds, max_symptom_onset_days,
+			max_valid_symptom_onset_report_days, default_symptom_onset_days_ago)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (health_authority_id) DO UPDATE
+		SET
+			max_exposure_keys = EXCLUDED.max_exposure_keys,
+			max_interval_start_age_seconds = EXCLUDED.max_interval_start_age_seconds,
+			max_symptom_onset_days = EXCLUDED.max_symptom_onset_days,
+			max_valid_symptom_onset_report_days = EXCLUDED.max_valid_symptom_onset_report_days,
+			default_symptom_onset_days_ago = EXCLUDED.default_symptom_onset_days_ago
+		`, ha.ID, nullableInt(p.MaxExposureKeys), maxIntervalStartAge, nullableInt(p.MaxSymptomOnsetDays),
+		nullableInt(p.MaxValidSymptomOnsetReportDays), nullableInt(p.DefaultSymptomOnsetDaysAgo)); err != nil {
+		return fmt.Errorf("upserting transform policy: %w", err)
+	}
+	return nil
+}
+
+// getTransformPolicy reads the transform policy for the health authority, it
+// returns nil if there is none.
+func getTransformPolicy(ctx context.Context, tx pgx.Tx, healthAuthorityID int64) (*model.TransformPolicy, error) {
+	row := tx.QueryRow(ctx, `
+		SELECT
+			max_exposure_keys, max_interval_start_age_seconds, max_symptom_onset_days,
+			max_valid_symptom_onset_report_days, default_symptom_onset_days_ago
+		FROM
+			HealthAuthorityTransformPolicy
+		WHERE
+			health_authority_id = $1
+		`, healthAuthorityID)
+
+	var maxKeys, maxIntervalStartAge, maxSymptomOnsetDays, maxValidSymptomOnsetReportDays, defaultSymptomOnsetDaysAgo *int64
+	if err := row.Scan(&maxKeys, &maxIntervalStartAge, &maxSymptomOnsetDays,
+		&maxValidSymptomOnsetReportDays, &defaultSymptomOnsetDaysAgo); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading transform policy: %w", err)
+	}
+
+	policy := &model.TransformPolicy{
+		MaxExposureKeys:                uintOrNil(maxKeys),
+		MaxSymptomOnsetDays:            uintOrNil(maxSymptomOnsetDays),
+		MaxValidSymptomOnsetReportDays: uintOrNil(maxValidSymptomOnsetReportDays),
+		DefaultSymptomOnsetDaysAgo:     uintOrNil(defaultSymptomOnsetDaysAgo),
+	}
+	if maxIntervalStartAge != nil {
+		age := time.Duration(*maxIntervalStartAge) * time.Second
+		policy.MaxIntervalStartAge = &age
+	}
+	return policy, nil
+}
+
+func nullableInt(v *uint) *int64 {
+	if v == nil {
+		return nil
+	}
+	i := int64(*v)
+	return &i
+}
+
+func uintOrNil(v *int64) *uint {
+	if v == nil {
+		return nil
+	}
+	u := uint(*v)
+	return &u
+}
@@ -16,7 +16,10 @@
 package model
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"time"
@@ -35,13 +38,54 @@ type HealthAuthority struct {
 	Keys           []*HealthAuthorityKey
 	JwksURI        *string
 	EnableStatsAPI bool
+
+	// EnableReplayProtection rejects verification certificates that have already
+	// been used in a publish request.
+	EnableReplayProtection bool
+
+	// TestDateOnsetOffsetDays is how many days before the test date symptom
+	// onset is assumed to be, for certificates with a test date but no symptom
+	// onset.
+	TestDateOnsetOffsetDays uint
+
+	// TransformPolicy optionally overrides the limits applied to publish
+	// requests verified against this health authority.
+	TransformPolicy *TransformPolicy
+
+	// StatsNoise is the differential privacy mechanism used to add noise to
+	// the stats released to this health authority, one of StatsNoiseMechanisms.
+	// If empty, no noise is added. StatsNoiseEpsilon is the privacy budget
+	// spent on each released counter, not on each publish request. A publish
+	// request changes up to 8 counters of its day.
+	StatsNoise        string
+	StatsNoiseEpsilon float64
+
+	// StatsExportBucket and StatsExportFilenameRoot, if set, are where the
+	// export service writes this health authority's released stats every day.
+	StatsExportBucket       string
+	StatsExportFilenameRoot string
 }
 
+// Differential privacy mechanisms that can be used to add noise to stats.
+const (
+	StatsNoiseLaplace  = "laplace"
+	StatsNoiseGaussian = "gaussian"
+)
+
+// StatsNoiseMechanisms lists the supported stats noise mechanisms.
+var StatsNoiseMechanisms = []string{StatsNoiseLaplace, StatsNoiseGaussian}
+
 // JWKSEnabled returns true if JWKS discovery is enabled for this health authority.
 func (ha *HealthAuthority) JWKSEnabled() bool {
 	return !(ha.JwksURI == nil || len(*ha.JwksURI) == 0)
 }
 
+// StatsExportEnabled returns true if the stats for this health authority are
+// written to the blobstore.
+func (ha *HealthAuthority) StatsExportEnabled() bool {
+	return ha.StatsExportBucket != ""
+}
+
 // SetJWKS sets the optional JwksURI property of the HealthAuthority.
 func (ha *HealthAuthority) SetJWKS(uri string) {
 	uri = project.TrimSpaceAndNonPrintable(uri)
@@ -63,9 +107,46 @@ func (ha *HealthAuthority) Validate() error {
 	if ha.Name == "" {
 		return errors.New("name cannot be empty")
 	}
+	if err := ha.TransformPolicy.Validate(); err != nil {
+		return fmt.Errorf("invalid transform policy: %w", err)
+	}
+	switch ha.StatsNoise {
+	case "":
+	case StatsNoiseLaplace, StatsNoiseGaussian:
+		if !(ha.StatsNoiseEpsilon > 0) {
+			return fmt.Errorf("stats noise epsilon must be > 0, got: %v", ha.StatsNoiseEpsilon)
+		}
+	default:
+		return fmt.Errorf("invalid stats noise mechanism: %q", ha.StatsNoise)
+	}
 	return nil
 }
 
+// CertificateUse identifies a verification certificate used in a publish
+// request, for health authorities with replay protection.
+type CertificateUse struct {
+	HealthAuthorityID int64
+	// Hash is the SHA-256 hash that identifies the certificate.
+	Hash []byte
+	// ExpiresAt is when the certificate expires, the use only needs to be
+	// remembered until then.
+	ExpiresAt time.Time
+}
+
+// Signing algorithms, as used in the JWT "alg" header, that health authority
+// keys can use to sign verification certificates.
+const (
+	AlgorithmES256 = "ES256"
+	AlgorithmPS256 = "PS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for PS256 keys.
+const minRSAKeyBits = 2048
+
+// Algorithms lists the supported signing algorithms.
+var Algorithms = []string{AlgorithmES256, AlgorithmPS256, AlgorithmEdDSA}
+
 // HealthAuthorityKey represents a public key version for a given health authority.
 type HealthAuthorityKey struct {
 	AuthorityID  int64
@@ -73,6 +154,18 @@ type HealthAuthorityKey struct {
 	From         time.Time
 	Thru         time.Time
 	PublicKeyPEM string
+
+	// Algorithm is the JWT signing algorithm of the key, one of Algorithms. If
+	// empty, AlgorithmES256 is used.
+	Algorithm string
+}
+
+// EffectiveAlgorithm returns the signing algorithm of the key.
+func (k *HealthAuthorityKey) EffectiveAlgorithm() string {
+	if k.Algorithm == "" {
+		return AlgorithmES256
+	}
+	return k.Algorithm
 }
 
 // Validate returns an error if the HealthAuthorityKey is not valid.
@@ -106,8 +199,45 @@ func (k *HealthAuthorityKey) Revoke() {
 	}
 }
 
-// PublicKey decodes the PublicKeyPEM text and returns the `*ecdsa.PublicKey`
-// This system only supports verifying ECDSA JWTs, `alg: ES256`.
-func (k *HealthAuthorityKey) PublicKey() (*ecdsa.PublicKey, error) {
-	return keys.ParseECDSAPublicKey(k.PublicKeyPEM)
+// PublicKey decodes the PublicKeyPEM text and returns the public key, checking
+// that it can be used with the key's algorithm. The key is a `*ecdsa.PublicKey`
+// for ES256, `*rsa.PublicKey` for PS256 and `ed25519.PublicKey` for EdDSA.
+func (k *HealthAuthorityKey) PublicKey() (crypto.PublicKey, error) {
+	alg := k.EffectiveAlgorithm()
+	if !validAlgorithm(alg) {
+		return nil, fmt.Errorf("unsupported algorithm: %q", alg)
+	}
+
+	pub, err := keys.ParsePublicKey(k.PublicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ := pub.(type) {
+	case *ecdsa.PublicKey:
+		if alg == AlgorithmES256 {
+			return typ, nil
+		}
+	case *rsa.PublicKey:
+		if alg == AlgorithmPS256 {
+			if bits := typ.N.BitLen(); bits < minRSAKeyBits {
+				return nil, fmt.Errorf("RSA key must be at least %d bits, got %d", minRSAKeyBits, bits)
+			}
+			return typ, nil
+		}
+	case ed25519.PublicKey:
+		if alg == AlgorithmEdDSA {
+			return typ, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported public key type: %T for algorithm %s", pub, alg)
+}
+
+func validAlgorithm(alg string) bool {
+	for _, a := range Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
 }
@@ -151,10 +151,28 @@ func TestIsValid(t *testing.T) {
 func TestPublicKeyParse(t *testing.T) {
 	t.Parallel()
 
+	ecdsaPEM := `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEA+k9YktDK3UpOhBIy+O17biuwd/g
+IBSEEHOdgpAynz0yrHpkWL6vxjNHxRdWcImZxPgL0NVHMdY4TlsL7qaxBQ==
+-----END PUBLIC KEY-----`
+	rsaPEM := `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAvs3MAjWBFJecFLwT4lhd
+HxXbn7EaVbx3/JgiXG3Q3PCCxEYQq6SRYp/4qJpZJ2nAW+BoMCxZjTBq8bmby3WT
+js5A/G62dLgq5qKRsny6kw2ix3tFXb0I9TsPSUieVmxPgioFF1ytvIU7wKQ07vAZ
+HW05DlJJM3E9WhB/ZVKl9NmVp01CcojfhmENPNu65XaAWEMp4txyyX7rU8iPPSsK
+QCmoWZQ6r1E1r5+/RumIobbwdYxax3esvC4B3W2jyLFqMJGVBrhWf7tDki/3mCub
+NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
+6wIDAQAB
+-----END PUBLIC KEY-----`
+	ed25519PEM := `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAd0d7jEV1WxzZbmZXy+bS3vrtQCTMw2lMicuR/oKtS/E=
+-----END PUBLIC KEY-----`
+
 	cases := []struct {
-		name     string
-		pemBlock string
-		msg      string
+		name      string
+		algorithm string
+		pemBlock  string
+		msg       string
 	}{
 		{
 			name: "valid PEM",
@@ -183,6 +201,38 @@ NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
 -----END PUBLIC KEY-----`,
 			msg: "unsupported public key type: *rsa.PublicKey",
 		},
+		{
+			name:      "es256",
+			algorithm: AlgorithmES256,
+			pemBlock:  ecdsaPEM,
+		},
+		{
+			name:      "ps256",
+			algorithm: AlgorithmPS256,
+			pemBlock:  rsaPEM,
+		},
+		{
+			name:      "ps256_wrong_key_type",
+			algorithm: AlgorithmPS256,
+			pemBlock:  ecdsaPEM,
+			msg:       "unsupported public key type: *ecdsa.PublicKey for algorithm PS256",
+		},
+		{
+			name:      "eddsa",
+			algorithm: AlgorithmEdDSA,
+			pemBlock:  ed25519PEM,
+		},
+		{
+			name:     "ed25519_without_algorithm",
+			pemBlock: ed25519PEM,
+			msg:      "unsupported public key type: ed25519.PublicKey for algorithm ES256",
+		},
+		{
+			name:      "unknown_algorithm",
+			algorithm: "HS256",
+			pemBlock:  ecdsaPEM,
+			msg:       `unsupported algorithm: "HS256"`,
+		},
 	}
 
 	for _, tc := range cases {
@@ -193,13 +243,49 @@ NTG3+oqI0Q6a3kPOuAAAupr373j7O1YXrM2KAix966EPwTNlK7YCcJa0m6PKz9DT
 
 			hak := HealthAuthorityKey{
 				PublicKeyPEM: tc.pemBlock,
+				Algorithm:    tc.algorithm,
 			}
 
 			k, err := hak.PublicKey()
 			errcmp.MustMatch(t, err, tc.msg)
 			if err == nil && k == nil {
-				t.Errorf("ECDSA public key is unexpectedly nil")
+				t.Errorf("public key is unexpectedly nil")
+			}
+		})
+	}
+}
+
+func TestHealthAuthority_ValidateStatsNoise(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		noise   string
+		epsilon float64
+		msg     string
+	}{
+		{name: "none"},
+		{name: "laplace", noise: StatsNoiseLaplace, epsilon: 1},
+		{name: "gaussian", noise: StatsNoiseGaussian, epsilon: 0.5},
+		{name: "missing_epsilon", noise: StatsNoiseLaplace, msg: "stats noise epsilon must be > 0"},
+		{name: "negative_epsilon", noise: StatsNoiseGaussian, epsilon: -1, msg: "stats noise epsilon must be > 0"},
+		{name: "invalid", noise: "uniform", epsilon: 1, msg: `invalid stats noise mechanism: "uniform"`},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ha := &HealthAuthority{
+				Issuer:            "iss",
+				Audience:          "aud",
+				Name:              "name",
+				StatsNoise:        tc.noise,
+				StatsNoiseEpsilon: tc.epsilon,
 			}
+			errcmp.MustMatch(t, ha.Validate(), tc.msg)
 		})
 	}
 }
@@ -0,0 +1,48 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// JWKSStatus is the state of fetching the JWKS document of a health authority.
+type JWKSStatus struct {
+	HealthAuthorityID int64
+
+	// ETag and LastModified are the validators of the last successfully
+	// processed document, they are sent on the next fetch so the server can
+	// reply that nothing changed.
+	ETag         string
+	LastModified string
+
+	// NextFetchAt is when the cached document expires, based on its
+	// Cache-Control or Expires headers. Fetches before then are skipped.
+	NextFetchAt time.Time
+
+	LastSuccessAt       time.Time
+	LastError           string
+	LastErrorAt         time.Time
+	ConsecutiveFailures int
+
+	// KeyFingerprint identifies the current set of keys.
+	KeyFingerprint string
+}
+
+// JWKSKeySet is a set of keys that was seen in a health authority's JWKS
+// document.
+type JWKSKeySet struct {
+	Fingerprint string
+	KeyCount    int
+	ObservedAt  time.Time
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// TransformPolicy overrides the server wide limits that are applied when
+// transforming publish requests verified against a health authority. Fields
+// that are nil use the server's configured value.
+type TransformPolicy struct {
+	MaxExposureKeys                *uint
+	MaxIntervalStartAge            *time.Duration
+	MaxSymptomOnsetDays            *uint
+	MaxValidSymptomOnsetReportDays *uint
+	DefaultSymptomOnsetDaysAgo     *uint
+}
+
+// IsEmpty returns true if the policy doesn't override anything.
+func (p *TransformPolicy) IsEmpty() bool {
+	return p == nil ||
+		(p.MaxExposureKeys == nil &&
+			p.MaxIntervalStartAge == nil &&
+			p.MaxSymptomOnsetDays == nil &&
+			p.MaxValidSymptomOnsetReportDays == nil &&
+			p.DefaultSymptomOnsetDaysAgo == nil)
+}
+
+// Validate returns an error if the TransformPolicy is not valid.
+func (p *TransformPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxExposureKeys != nil && *p.MaxExposureKeys == 0 {
+		return errors.New("max exposure keys must be > 0")
+	}
+	if p.MaxIntervalStartAge != nil {
+		if age := *p.MaxIntervalStartAge; age <= 0 || age%time.Second != 0 {
+			return errors.New("max interval start age must be a positive number of seconds")
+		}
+	}
+	return nil
+}
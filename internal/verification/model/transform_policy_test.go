@@ -0,0 +1,84 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestTransformPolicy_Validate(t *testing.T) {
+	t.Parallel()
+
+	uintPtr := func(v uint) *uint { return &v }
+	durationPtr := func(d time.Duration) *time.Duration { return &d }
+
+	cases := []struct {
+		name   string
+		policy *TransformPolicy
+		empty  bool
+		msg    string
+	}{
+		{
+			name:  "nil",
+			empty: true,
+		},
+		{
+			name:   "empty",
+			policy: &TransformPolicy{},
+			empty:  true,
+		},
+		{
+			name: "valid",
+			policy: &TransformPolicy{
+				MaxExposureKeys:                uintPtr(30),
+				MaxIntervalStartAge:            durationPtr(14 * 24 * time.Hour),
+				MaxSymptomOnsetDays:            uintPtr(14),
+				MaxValidSymptomOnsetReportDays: uintPtr(21),
+				DefaultSymptomOnsetDaysAgo:     uintPtr(0),
+			},
+		},
+		{
+			name:   "zero_max_keys",
+			policy: &TransformPolicy{MaxExposureKeys: uintPtr(0)},
+			msg:    "max exposure keys must be > 0",
+		},
+		{
+			name:   "negative_start_age",
+			policy: &TransformPolicy{MaxIntervalStartAge: durationPtr(-time.Hour)},
+			msg:    "max interval start age must be a positive number of seconds",
+		},
+		{
+			name:   "fractional_start_age",
+			policy: &TransformPolicy{MaxIntervalStartAge: durationPtr(1500 * time.Millisecond)},
+			msg:    "max interval start age must be a positive number of seconds",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, want := tc.policy.IsEmpty(), tc.empty; got != want {
+				t.Errorf("expected IsEmpty %t to be %t", got, want)
+			}
+			errcmp.MustMatch(t, tc.policy.Validate(), tc.msg)
+		})
+	}
+}
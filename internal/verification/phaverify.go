@@ -20,8 +20,10 @@ package verification
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"time"
 
 	aamodel "github.com/google/exposure-notifications-server/internal/authorizedapp/model"
 	"github.com/google/exposure-notifications-server/internal/verification/database"
@@ -63,6 +65,19 @@ type VerifiedClaims struct {
 	HealthAuthorityID    int64
 	ReportType           string // blank indicates no report type was present.
 	SymptomOnsetInterval uint32 // 0 indicates no symptom onset interval present. This should be checked for "reasonable" value before application.
+	TestDateInterval     uint32 // 0 indicates no test date interval present. This should be checked for "reasonable" value before application.
+
+	// TestDateOnsetOffsetDays is the number of days before the test date that
+	// symptom onset is estimated to be, when only the test date is known.
+	TestDateOnsetOffsetDays uint
+
+	// TransformPolicy overrides the server's transform limits for the health
+	// authority, it is nil if there are no overrides.
+	TransformPolicy *model.TransformPolicy
+
+	// CertificateUse must be recorded when the publish request is saved, if the
+	// health authority enables replay protection. It is nil otherwise.
+	CertificateUse *model.CertificateUse
 }
 
 // VerifyDiagnosisCertificate accepts a publish request (from which is extracts the JWT),
@@ -71,14 +86,14 @@ type VerifiedClaims struct {
 func (v *Verifier) VerifyDiagnosisCertificate(ctx context.Context, authApp *aamodel.AuthorizedApp, publish *verifyapi.Publish) (*VerifiedClaims, error) {
 	logger := logging.FromContext(ctx)
 	// These get assigned during the ParseWithClaims closure.
-	var healthAuthorityID int64
+	var healthAuthority *model.HealthAuthority
 	var claims *verifyapi.VerificationClaims
 
 	// Unpack JWT so we can determine issuer and key version.
 	// ParseWithClaims also calls .Valid() on the parsed token.
 	token, err := jwt.ParseWithClaims(publish.VerificationPayload, &verifyapi.VerificationClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if method, ok := token.Method.(*jwt.SigningMethodECDSA); !ok || method.Name != jwt.SigningMethodES256.Name {
-			return nil, fmt.Errorf("unsupported signing method, must be %v", jwt.SigningMethodES256.Name)
+		if err := checkSigningMethod(token.Method); err != nil {
+			return nil, err
 		}
 
 		var ok bool
@@ -125,9 +140,9 @@ func (v *Verifier) VerifyDiagnosisCertificate(ctx context.Context, authApp *aamo
 		for _, hak := range ha.Keys {
 			// Key version matches and the key is valid based on the current time.
 			if hak.Version == kid && hak.IsValid() {
-				healthAuthorityID = ha.ID
+				healthAuthority = ha
 				// Extract the public key from the PEM block.
-				return hak.PublicKey()
+				return verificationKey(token.Method, hak)
 			}
 		}
 		return nil, ErrNoPublicKeys
@@ -148,6 +163,8 @@ func (v *Verifier) VerifyDiagnosisCertificate(ctx context.Context, authApp *aamo
 		return nil, fmt.Errorf("invalid verificationPayload")
 	}
 
+	healthAuthorityID := healthAuthority.ID
+
 	// JWT is valid and signature is valid.
 	// This is chacked after the signature verification to prevent timing attacks.
 	if _, ok := authApp.AllowedHealthAuthorityIDs[healthAuthorityID]; !ok {
@@ -182,10 +199,75 @@ func (v *Verifier) VerifyDiagnosisCertificate(ctx context.Context, authApp *aamo
 		return nil, fmt.Errorf("HMAC mismatch, publish request does not match disgnosis verification certificate")
 	}
 
+	// The certificate use is only recorded when the keys are saved, so that
+	// requests that fail later don't use it up. Certificates that were already
+	// used are rejected here, before doing any more work.
+	var certificateUse *model.CertificateUse
+	if healthAuthority.EnableReplayProtection {
+		certificateUse, err = v.certificateUse(ctx, healthAuthorityID, publish.VerificationPayload, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Everything looks good. Return the relevant verified claims.
 	return &VerifiedClaims{
 		HealthAuthorityID:    healthAuthorityID,
 		ReportType:           claims.ReportType,
 		SymptomOnsetInterval: claims.SymptomOnsetInterval,
+		TestDateInterval:     claims.TestDateInterval,
+
+		TestDateOnsetOffsetDays: healthAuthority.TestDateOnsetOffsetDays,
+		TransformPolicy:         healthAuthority.TransformPolicy,
+		CertificateUse:          certificateUse,
+	}, nil
+}
+
+// checkSigningMethod returns an error if tokens signed with method can never
+// be verified, before any keys are looked up.
+func checkSigningMethod(method jwt.SigningMethod) error {
+	for _, alg := range model.Algorithms {
+		if method.Alg() == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported signing method %v, must be one of %v", method.Alg(), model.Algorithms)
+}
+
+// verificationKey returns the public key of hak, if it is for the algorithm
+// the token was signed with. The algorithm comes from the key, never the
+// token, so a token can't select how its own signature is checked.
+func verificationKey(method jwt.SigningMethod, hak *model.HealthAuthorityKey) (interface{}, error) {
+	if got, want := method.Alg(), hak.EffectiveAlgorithm(); got != want {
+		return nil, fmt.Errorf("signing method %v does not match algorithm %v of key %v", got, want, hak.Version)
+	}
+	return hak.PublicKey()
+}
+
+// certificateUse returns the use of a certificate to record, or
+// database.ErrCertificateReused if it was already used. Certificates are
+// identified by their `jti` claim, or the whole token if there is none.
+func (v *Verifier) certificateUse(ctx context.Context, healthAuthorityID int64, token string, claims *verifyapi.VerificationClaims) (*model.CertificateUse, error) {
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("replay protection requires certificates to have an expiry time")
+	}
+
+	id := token
+	if claims.Id != "" {
+		id = "jti:" + claims.Id
+	}
+	hash := sha256.Sum256([]byte(id))
+
+	used, err := v.db.CertificateUsed(ctx, healthAuthorityID, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, database.ErrCertificateReused
+	}
+	return &model.CertificateUse{
+		HealthAuthorityID: healthAuthorityID,
+		Hash:              hash[:],
+		ExpiresAt:         time.Unix(claims.ExpiresAt, 0),
 	}, nil
 }
@@ -18,12 +18,16 @@
 package verification
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -134,6 +138,8 @@ func TestVerifyCertificate(t *testing.T) {
 						Issuer:   issuer,
 						Audience: audience,
 						Name:     "Very Real Health Authority",
+
+						TestDateOnsetOffsetDays: 2,
 					}
 					if err := haDB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
 						t.Fatal(err)
@@ -230,6 +236,7 @@ func TestVerifyCertificate(t *testing.T) {
 						v1claims.SignedMAC = tc.MacAdjustment + base64.StdEncoding.EncodeToString(hmac)
 						v1claims.ReportType = "confirmed"
 						v1claims.SymptomOnsetInterval = 250250
+						v1claims.TestDateInterval = 250394
 						claims = v1claims
 					}
 
@@ -261,6 +268,11 @@ func TestVerifyCertificate(t *testing.T) {
 							HealthAuthorityID:    healthAuthority.ID,
 							ReportType:           "confirmed",
 							SymptomOnsetInterval: 250250,
+
+							TestDateOnsetOffsetDays: 2,
+						}
+						if version == 1 {
+							want.TestDateInterval = 250394
 						}
 						if diff := cmp.Diff(want, verifiedClaims); diff != "" {
 							t.Errorf("claims mismatch (-want, +got):\n%s", diff)
@@ -271,3 +283,312 @@ func TestVerifyCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyCertificate_Algorithms(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := database.New(testDB)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		algorithm  string
+		publicKey  crypto.PublicKey
+		method     jwt.SigningMethod
+		signingKey interface{}
+		err        string
+	}{
+		{
+			name:       "es256",
+			algorithm:  model.AlgorithmES256,
+			publicKey:  ecKey.Public(),
+			method:     jwt.SigningMethodES256,
+			signingKey: ecKey,
+		},
+		{
+			name:       "default",
+			publicKey:  ecKey.Public(),
+			method:     jwt.SigningMethodES256,
+			signingKey: ecKey,
+		},
+		{
+			name:       "ps256",
+			algorithm:  model.AlgorithmPS256,
+			publicKey:  rsaKey.Public(),
+			method:     jwt.SigningMethodPS256,
+			signingKey: rsaKey,
+		},
+		{
+			name:       "eddsa",
+			algorithm:  model.AlgorithmEdDSA,
+			publicKey:  edKey.Public(),
+			method:     SigningMethodEdDSA,
+			signingKey: edKey,
+		},
+		{
+			name:       "rs256_with_rsa_key",
+			algorithm:  model.AlgorithmPS256,
+			publicKey:  rsaKey.Public(),
+			method:     jwt.SigningMethodRS256,
+			signingKey: rsaKey,
+			err:        "unsupported signing method RS256",
+		},
+		{
+			name:       "algorithm_mismatch",
+			algorithm:  model.AlgorithmEdDSA,
+			publicKey:  edKey.Public(),
+			method:     jwt.SigningMethodES256,
+			signingKey: ecKey,
+			err:        "signing method ES256 does not match algorithm EdDSA",
+		},
+		{
+			name:       "hmac",
+			algorithm:  model.AlgorithmES256,
+			publicKey:  ecKey.Public(),
+			method:     jwt.SigningMethodHS256,
+			signingKey: []byte("secret"),
+			err:        "unsupported signing method HS256",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			x509EncodedPub, err := x509.MarshalPKIXPublicKey(tc.publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pemPublicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub}))
+
+			healthAuthority := model.HealthAuthority{
+				Issuer:   "issuer-" + tc.name,
+				Audience: "aud-" + tc.name,
+				Name:     "Very Real Health Authority",
+			}
+			if err := haDB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
+				t.Fatal(err)
+			}
+			hak := model.HealthAuthorityKey{
+				Version:      "v1",
+				From:         time.Now(),
+				PublicKeyPEM: pemPublicKey,
+				Algorithm:    tc.algorithm,
+			}
+			if err := haDB.AddHealthAuthorityKey(ctx, &healthAuthority, &hak); err != nil {
+				t.Fatal(err)
+			}
+
+			authApp := aamodel.NewAuthorizedApp()
+			authApp.AllowedHealthAuthorityIDs[healthAuthority.ID] = struct{}{}
+
+			hmacKeyBytes := make([]byte, 32)
+			if _, err := rand.Read(hmacKeyBytes); err != nil {
+				t.Fatal(err)
+			}
+			publish := verifyapi.Publish{
+				Keys: []verifyapi.ExposureKey{
+					{
+						Key:            "IRgYIhYiy4WMl9z68bMk6w==",
+						IntervalNumber: 2650032,
+						IntervalCount:  144,
+					},
+				},
+				HMACKey: base64.StdEncoding.EncodeToString(hmacKeyBytes),
+			}
+			allHMACs, err := utils.CalculateAllAllowedExposureKeyHMAC(publish.Keys, hmacKeyBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims := verifyapi.NewVerificationClaims()
+			claims.Audience = healthAuthority.Audience
+			claims.Issuer = healthAuthority.Issuer
+			claims.IssuedAt = time.Now().Unix()
+			claims.ExpiresAt = time.Now().Add(5 * time.Minute).Unix()
+			claims.SignedMAC = base64.StdEncoding.EncodeToString(allHMACs[0])
+
+			token := jwt.NewWithClaims(tc.method, claims)
+			token.Header["kid"] = "v1"
+			jwtText, err := token.SignedString(tc.signingKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			publish.VerificationPayload = jwtText
+
+			verifier, err := New(haDB, &Config{time.Nanosecond, "audience"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			verifiedClaims, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, &publish)
+			errcmp.MustMatch(t, err, tc.err)
+			if tc.err == "" && verifiedClaims.HealthAuthorityID != healthAuthority.ID {
+				t.Errorf("expected %d to be %d", verifiedClaims.HealthAuthorityID, healthAuthority.ID)
+			}
+		})
+	}
+}
+
+func TestVerifyCertificate_ReplayProtection(t *testing.T) {
+	t.Parallel()
+
+	ctx := project.TestContext(t)
+	testDB, _ := testDatabaseInstance.NewDatabase(t)
+	haDB := database.New(testDB)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x509EncodedPub, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemPublicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509EncodedPub}))
+
+	healthAuthority := model.HealthAuthority{
+		Issuer:                 "issuer",
+		Audience:               "aud",
+		Name:                   "Very Real Health Authority",
+		EnableReplayProtection: true,
+	}
+	if err := haDB.AddHealthAuthority(ctx, &healthAuthority); err != nil {
+		t.Fatal(err)
+	}
+	hak := model.HealthAuthorityKey{
+		Version:      "v1",
+		From:         time.Now().Add(-time.Minute),
+		PublicKeyPEM: pemPublicKey,
+	}
+	if err := haDB.AddHealthAuthorityKey(ctx, &healthAuthority, &hak); err != nil {
+		t.Fatal(err)
+	}
+
+	authApp := aamodel.NewAuthorizedApp()
+	authApp.AllowedHealthAuthorityIDs[healthAuthority.ID] = struct{}{}
+
+	verifier, err := New(haDB, &Config{time.Nanosecond, "audience"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// newPublish creates a publish request with a newly signed certificate.
+	newPublish := func(t *testing.T, jti string, expiresAt int64) *verifyapi.Publish {
+		t.Helper()
+
+		hmacKeyBytes := make([]byte, 32)
+		if _, err := rand.Read(hmacKeyBytes); err != nil {
+			t.Fatal(err)
+		}
+		publish := &verifyapi.Publish{
+			Keys: []verifyapi.ExposureKey{
+				{
+					Key:            "IRgYIhYiy4WMl9z68bMk6w==",
+					IntervalNumber: 2650032,
+					IntervalCount:  144,
+				},
+			},
+			HMACKey: base64.StdEncoding.EncodeToString(hmacKeyBytes),
+		}
+		allHMACs, err := utils.CalculateAllAllowedExposureKeyHMAC(publish.Keys, hmacKeyBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims := verifyapi.NewVerificationClaims()
+		claims.Audience = healthAuthority.Audience
+		claims.Issuer = healthAuthority.Issuer
+		claims.Id = jti
+		claims.IssuedAt = time.Now().Unix()
+		claims.ExpiresAt = expiresAt
+		claims.SignedMAC = base64.StdEncoding.EncodeToString(allHMACs[0])
+
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = "v1"
+		jwtText, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		publish.VerificationPayload = jwtText
+		return publish
+	}
+
+	expiresAt := time.Now().Add(5 * time.Minute).Unix()
+
+	// verifyAndRecord verifies the publish request and records the certificate
+	// use, like a publish request that saves its keys.
+	verifyAndRecord := func(t *testing.T, publish *verifyapi.Publish) error {
+		t.Helper()
+
+		claims, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, publish)
+		if err != nil {
+			return err
+		}
+		use := claims.CertificateUse
+		if use == nil {
+			t.Fatal("expected a certificate use to record")
+		}
+		if err := haDB.RecordCertificateUse(ctx, use.HealthAuthorityID, use.Hash, use.ExpiresAt); err != nil {
+			t.Fatal(err)
+		}
+		return nil
+	}
+
+	// Without a jti, the token itself can't be reused.
+	publish := newPublish(t, "", expiresAt)
+	if err := verifyAndRecord(t, publish); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, publish); !errors.Is(err, database.ErrCertificateReused) {
+		t.Errorf("expected %v to be %v", err, database.ErrCertificateReused)
+	}
+
+	// With a jti, a different token with the same jti is also rejected.
+	if err := verifyAndRecord(t, newPublish(t, "abc", expiresAt)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, newPublish(t, "abc", expiresAt)); !errors.Is(err, database.ErrCertificateReused) {
+		t.Errorf("expected %v to be %v", err, database.ErrCertificateReused)
+	}
+	if err := verifyAndRecord(t, newPublish(t, "def", expiresAt)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rejected request doesn't use up the certificate.
+	publish = newPublish(t, "", expiresAt)
+	hmacKey := publish.HMACKey
+	publish.HMACKey = "4" + hmacKey
+	if _, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, publish); err == nil {
+		t.Fatal("expected error")
+	}
+	publish.HMACKey = hmacKey
+
+	// Verifying doesn't use up the certificate, only recording the use does.
+	if _, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, publish); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyAndRecord(t, publish); err != nil {
+		t.Fatal(err)
+	}
+
+	// Certificates must expire to be tracked.
+	if _, err := verifier.VerifyDiagnosisCertificate(ctx, authApp, newPublish(t, "", 0)); err == nil {
+		t.Error("expected error for certificate without expiry")
+	}
+}
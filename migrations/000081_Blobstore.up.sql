@@ -0,0 +1,28 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE TABLE Blob (
+    bucket TEXT NOT NULL,
+    name TEXT NOT NULL,
+    contents BYTEA NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    cacheable BOOL NOT NULL DEFAULT false,
+    md5 BYTEA NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (bucket, name)
+);
+
+END;
@@ -0,0 +1,29 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+ALTER TABLE HealthAuthority
+  ADD COLUMN enable_replay_protection BOOL NOT NULL DEFAULT false;
+
+CREATE TABLE VerificationCertificateUse (
+  health_authority_id INT NOT NULL REFERENCES HealthAuthority(id) ON DELETE CASCADE,
+  certificate_hash BYTEA NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (health_authority_id, certificate_hash)
+);
+
+CREATE INDEX verification_certificate_use_expires_at ON VerificationCertificateUse(expires_at);
+
+END;
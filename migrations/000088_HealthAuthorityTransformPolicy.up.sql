@@ -0,0 +1,26 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE TABLE HealthAuthorityTransformPolicy (
+  health_authority_id INT PRIMARY KEY REFERENCES HealthAuthority(id) ON DELETE CASCADE,
+  max_exposure_keys INT,
+  max_interval_start_age_seconds INT,
+  max_symptom_onset_days INT,
+  max_valid_symptom_onset_report_days INT,
+  default_symptom_onset_days_ago INT
+);
+
+END;
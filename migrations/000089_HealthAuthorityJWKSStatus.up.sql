@@ -0,0 +1,39 @@
+-- Copyright 2021 the Exposure Notification Server authors
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+--      http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License.
+
+BEGIN;
+
+CREATE TABLE HealthAuthorityJWKSStatus (
+  health_authority_id INT PRIMARY KEY REFERENCES HealthAuthority(id) ON DELETE CASCADE,
+  etag TEXT,
+  last_modified TEXT,
+  next_fetch_at TIMESTAMPTZ,
+  last_success_at TIMESTAMPTZ,
+  last_error TEXT,
+  last_error_at TIMESTAMPTZ,
+  consecutive_failures INT NOT NULL DEFAULT 0,
+  key_fingerprint TEXT
+);
+
+CREATE TABLE HealthAuthorityJWKSKeySet (
+  id SERIAL PRIMARY KEY,
+  health_authority_id INT NOT NULL REFERENCES HealthAuthority(id) ON DELETE CASCADE,
+  fingerprint TEXT NOT NULL,
+  key_count INT NOT NULL,
+  observed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX health_authority_jwks_key_set_observed_at ON HealthAuthorityJWKSKeySet(health_authority_id, observed_at);
+
+END;
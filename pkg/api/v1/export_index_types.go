@@ -0,0 +1,46 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// ExportIndex is the contents of the index.json file that is written next to
+// index.txt for exports that enable the JSON index format. It lists the same
+// files, in the same order, along with enough metadata for a client to skip
+// files it has already processed without downloading them.
+type ExportIndex struct {
+	Files []*ExportIndexFile `json:"files"`
+}
+
+// ExportIndexFile describes a single export file in the index.
+type ExportIndexFile struct {
+	// Filename is the object name of the export file, as listed in index.txt.
+	Filename string `json:"filename"`
+
+	// StartTimestamp and EndTimestamp are the UTC seconds since the epoch stored
+	// in the export file's header.
+	StartTimestamp int64 `json:"start_timestamp"`
+	EndTimestamp   int64 `json:"end_timestamp"`
+
+	// BatchNum and BatchSize position this file among the files of the export
+	// batch that created it.
+	BatchNum  int `json:"batch_num"`
+	BatchSize int `json:"batch_size"`
+
+	// KeyCount, SHA256 and Size describe the file's contents. SHA256 is hex
+	// encoded. They are omitted for files exported before this metadata was
+	// recorded.
+	KeyCount int    `json:"key_count,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+}
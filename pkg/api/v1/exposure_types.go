@@ -44,6 +44,10 @@ const (
 	ErrorHealthAuthorityMissingRegionConfiguration = "health_authority_missing_region_config"
 	// ErrorVerificationCertificateInvalid indicates a problem with the verification certificate.
 	ErrorVerificationCertificateInvalid = "health_authority_verification_certificate_invalid"
+	// ErrorVerificationCertificateReused indicates the verification certificate
+	// was already used in a previous publish request and the health authority
+	// does not allow certificates to be reused.
+	ErrorVerificationCertificateReused = "health_authority_verification_certificate_reused"
 	// ErrorBadRequest indicates that the client sent a request that couldn't be parsed correctly
 	// or otherwise contains invalid data, see the extended ErrorMessage for details.
 	ErrorBadRequest = "bad_request"
@@ -64,6 +68,13 @@ const (
 	// request had invalid data (size, timing metadata) and were dropped. Other
 	// keys were saved.
 	ErrorPartialFailure = "partial_failure"
+	// ErrorBatchTooLarge indicates that a batch publish request contained more
+	// publish requests than the server allows in a single call.
+	ErrorBatchTooLarge = "batch_too_large"
+	// ErrorRateLimited indicates that too many requests were sent for the
+	// health authority. The request should be retried after the number of
+	// seconds in the Retry-After response header.
+	ErrorRateLimited = "rate_limited"
 )
 
 // Publish represents the body of the PublishInfectedIds API call.
@@ -135,6 +146,36 @@ type PublishResponse struct {
 	Warnings          []string `json:"warnings,omitempty"`
 }
 
+// PublishBatch represents the body of a batch publish API call. It allows a
+// trusted partner (e.g. a test lab) to submit many publish requests in a single
+// HTTP call.
+//
+// publishes: Required, each entry is processed independently with the exact
+// same semantics as a single call to the publish API.
+//
+// Padding: random base64 encoded data to obscure the request size. The server
+// will not process this data in any way.
+type PublishBatch struct {
+	Publishes []Publish `json:"publishes"`
+
+	Padding string `json:"padding"`
+}
+
+// PublishBatchResponse is sent back to the client on a batch publish request.
+//
+// If the batch could be read, Responses contains one PublishResponse for each
+// entry in the request, in the same order. Individual entries may succeed or
+// fail independently, see the Code and ErrorMessage on each response.
+//
+// If the batch as a whole could not be processed, Responses is empty and the
+// ErrorMessage and Code fields are set.
+type PublishBatchResponse struct {
+	Responses    []PublishResponse `json:"responses,omitempty"`
+	ErrorMessage string            `json:"error,omitempty"`
+	Code         string            `json:"code,omitempty"`
+	Padding      string            `json:"padding,omitempty"`
+}
+
 // ExposureKey is the 16 byte key, the start time of the key and the
 // duration of the key. A duration of 0 means 24 hours.
 // - ALL fields are REQUIRED and must meet the constraints below.
@@ -17,6 +17,7 @@ package v1
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -26,8 +27,29 @@ import (
 const (
 	// ErrorUnauthorized is returned if the provided bearer token is invalid.
 	ErrorUnauthorized = "unauthorized"
+
+	// StatsWindowDay aggregates stats per UTC day. This is the default.
+	StatsWindowDay = "day"
+	// StatsWindowWeek aggregates stats per week, starting on Monday (UTC).
+	StatsWindowWeek = "week"
+	// StatsWindowMonth aggregates stats per calendar month (UTC).
+	StatsWindowMonth = "month"
+)
+
+// Distributions of StatsDay that can be written with MarshalDistributionCSV.
+const (
+	StatsDistributionTEKAge        = "tek_age_distribution"
+	StatsDistributionOnsetToUpload = "onset_to_upload_distribution"
 )
 
+// ValidStatsWindows is the set of aggregation windows that can be requested.
+var ValidStatsWindows = map[string]bool{
+	"":               true,
+	StatsWindowDay:   true,
+	StatsWindowWeek:  true,
+	StatsWindowMonth: true,
+}
+
 // StatsRequest represents the request to retrieve publish metrics for a specific
 // health authority.
 //
@@ -37,14 +59,32 @@ const (
 //
 // New stats are released every hour. And stats for a day (UTC) only start to be released
 // once there have been a sufficient numbers of publish requests for that day.
+// Weekly and monthly windows are built only from days that have been released,
+// so they never contain data that would not be shown when requesting daily stats.
 //
 // This API is invoked via POST request to /v1/stats
 type StatsRequest struct {
-	// currently no data fields in the request.
+	// Window is the aggregation window, one of "day", "week" or "month". If
+	// empty, stats are aggregated per day.
+	Window string `json:"window,omitempty"`
+
+	// AfterDaysSinceFirstKey, if set, only returns the windows that contain a
+	// day whose DaysSinceFirstKey is greater than or equal to this value. Clients
+	// can pass the DaysSinceFirstKey of the last window they received to only
+	// fetch new and still changing windows.
+	AfterDaysSinceFirstKey *int64 `json:"after_days_since_first_key,omitempty"`
 
 	Padding string `json:"padding"`
 }
 
+// Validate returns an error if the request is not valid.
+func (s *StatsRequest) Validate() error {
+	if !ValidStatsWindows[s.Window] {
+		return fmt.Errorf("invalid window: %q", s.Window)
+	}
+	return nil
+}
+
 // StatsDays represents a logical collection of stats.
 type StatsDays []*StatsDay
 
@@ -52,9 +92,13 @@ type StatsDays []*StatsDay
 //
 // There may be gaps in the Days if a day has insufficient data.
 type StatsResponse struct {
-	// Individual days. There may be gaps if a day does not have enough data.
+	// Individual days, or weeks or months if a larger window was requested.
+	// There may be gaps if a day does not have enough data.
 	Days StatsDays `json:"days,omitempty"`
 
+	// Window is the aggregation window of Days.
+	Window string `json:"window,omitempty"`
+
 	ErrorMessage string `json:"error,omitempty"`
 	ErrorCode    string `json:"code,omitempty"`
 
@@ -65,7 +109,13 @@ type StatsResponse struct {
 type StatsDay struct {
 	// Day will be set to midnight UTC of the day represented. An individual day
 	// isn't released until there is a minimum threshold for updates has been met.
-	Day                time.Time       `json:"day"`
+	// For weekly and monthly windows, this is the first day of the window.
+	Day time.Time `json:"day"`
+	// DaysSinceFirstKey is the number of days between the first released day
+	// with published keys and Day. It is negative for a window that starts
+	// before that day. Since old stats are deleted after the retention period,
+	// the first day can move forward over time.
+	DaysSinceFirstKey  int64           `json:"days_since_first_key"`
 	PublishRequests    PublishRequests `json:"publish_requests"`
 	TotalTEKsPublished int64           `json:"total_teks_published"`
 	// RevisionRequests is the number of publish requests that contained at least one TEK revision.
@@ -82,6 +132,21 @@ type StatsDay struct {
 	// RequestsMissingOnsetDate is the number of publish requests where no onset date
 	// was provided. These request are not included in the onset to upload distribution.
 	RequestsMissingOnsetDate int64 `json:"requests_missing_onset_date"`
+	// RequestsWithSymptomOnset is the number of publish requests where a symptom
+	// onset date was provided.
+	RequestsWithSymptomOnset int64 `json:"requests_with_symptom_onset"`
+	// RequestsWithTestDate is the number of publish requests where no symptom
+	// onset date was provided, and onset was estimated from the test date in
+	// the verification certificate. These requests are included in the onset to
+	// upload distribution.
+	RequestsWithTestDate int64 `json:"requests_with_test_date"`
+
+	// ReportTypes is the number of publish requests by the report type in the
+	// verification certificate.
+	ReportTypes ReportTypeRequests `json:"report_types"`
+	// TravelerRequests is the number of publish requests that were flagged as
+	// traveler. The rest of the requests were not.
+	TravelerRequests int64 `json:"requests_from_travelers"`
 }
 
 func (s *StatsDay) IsEmpty() bool {
@@ -108,6 +173,14 @@ func (p *PublishRequests) Total() int64 {
 	return p.UnknownPlatform + p.Android + p.IOS
 }
 
+// ReportTypeRequests is a summary of publish requests by report type.
+type ReportTypeRequests struct {
+	UnknownReportType int64 `json:"unknown"`
+	Confirmed         int64 `json:"confirmed"`
+	Likely            int64 `json:"likely"`
+	SelfReport        int64 `json:"self_report"`
+}
+
 // TEKAgeDistributionAsString returns an array of TEKAgeDistribution
 // as strings instead of int64
 func (s *StatsDay) TEKAgeDistributionAsString() []string {
@@ -142,6 +215,10 @@ func (s StatsDays) MarshalCSV() ([]byte, error) {
 		"day",
 		"publish_requests_unknown", "publish_requests_android", "publish_requests_ios",
 		"total_teks_published", "requests_with_revisions", "requests_missing_onset_date", "tek_age_distribution", "onset_to_upload_distribution",
+		"requests_with_symptom_onset", "requests_with_test_date",
+		"days_since_first_key",
+		"report_type_unknown", "report_type_confirmed", "report_type_likely", "report_type_self_report",
+		"requests_from_travelers",
 	}); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
@@ -157,6 +234,14 @@ func (s StatsDays) MarshalCSV() ([]byte, error) {
 			strconv.FormatInt(stat.RequestsMissingOnsetDate, 10),
 			strings.Join(stat.TEKAgeDistributionAsString(), "|"),
 			strings.Join(stat.OnsetToUploadDistributionAsString(), "|"),
+			strconv.FormatInt(stat.RequestsWithSymptomOnset, 10),
+			strconv.FormatInt(stat.RequestsWithTestDate, 10),
+			strconv.FormatInt(stat.DaysSinceFirstKey, 10),
+			strconv.FormatInt(stat.ReportTypes.UnknownReportType, 10),
+			strconv.FormatInt(stat.ReportTypes.Confirmed, 10),
+			strconv.FormatInt(stat.ReportTypes.Likely, 10),
+			strconv.FormatInt(stat.ReportTypes.SelfReport, 10),
+			strconv.FormatInt(stat.TravelerRequests, 10),
 		}); err != nil {
 			return nil, fmt.Errorf("failed to write CSV entry %d: %w", i, err)
 		}
@@ -169,3 +254,84 @@ func (s StatsDays) MarshalCSV() ([]byte, error) {
 
 	return b.Bytes(), nil
 }
+
+// MarshalJSONLines returns bytes in JSON Lines format, one StatsDay per line.
+func (s StatsDays) MarshalJSONLines() ([]byte, error) {
+	// Do nothing if there's no records
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	var b bytes.Buffer
+	for i, stat := range s {
+		line, err := json.Marshal(stat)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal entry %d: %w", i, err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// MarshalDistributionCSV returns one of the distributions in CSV format, with
+// a column for each bucket instead of joining the buckets into a single
+// column. The last column is the bucket for all larger values.
+func (s StatsDays) MarshalDistributionCSV(distribution string) ([]byte, error) {
+	var get func(*StatsDay) []int64
+	switch distribution {
+	case StatsDistributionTEKAge:
+		get = func(d *StatsDay) []int64 { return d.TEKAgeDistribution }
+	case StatsDistributionOnsetToUpload:
+		get = func(d *StatsDay) []int64 { return d.OnsetToUploadDistribution }
+	default:
+		return nil, fmt.Errorf("unknown distribution: %q", distribution)
+	}
+
+	// Do nothing if there's no records
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	buckets := 0
+	for _, stat := range s {
+		if l := len(get(stat)); l > buckets {
+			buckets = l
+		}
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+
+	header := make([]string, 0, buckets+1)
+	header = append(header, "day")
+	for i := 0; i < buckets; i++ {
+		header = append(header, strconv.Itoa(i))
+	}
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for i, stat := range s {
+		values := get(stat)
+		row := make([]string, 0, buckets+1)
+		row = append(row, stat.Day.Format("2006-01-02"))
+		for j := 0; j < buckets; j++ {
+			var v int64
+			if j < len(values) {
+				v = values[j]
+			}
+			row = append(row, strconv.FormatInt(v, 10))
+		}
+		if err := w.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV entry %d: %w", i, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to create CSV: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
@@ -15,6 +15,9 @@
 package v1
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -109,10 +112,19 @@ func TestStatsDays_MarshalCSV(t *testing.T) {
 					TEKAgeDistribution:        []int64{2, 4, 5},
 					OnsetToUploadDistribution: []int64{1, 3, 4},
 					RequestsMissingOnsetDate:  7,
+					RequestsWithSymptomOnset:  5,
+					RequestsWithTestDate:      3,
+					DaysSinceFirstKey:         12,
+					ReportTypes: ReportTypeRequests{
+						UnknownReportType: 1,
+						Confirmed:         3,
+						Likely:            2,
+					},
+					TravelerRequests: 4,
 				},
 			},
-			exp: `day,publish_requests_unknown,publish_requests_android,publish_requests_ios,total_teks_published,requests_with_revisions,requests_missing_onset_date,tek_age_distribution,onset_to_upload_distribution
-2020-02-03,1,2,3,10,9,7,2|4|5,1|3|4
+			exp: `day,publish_requests_unknown,publish_requests_android,publish_requests_ios,total_teks_published,requests_with_revisions,requests_missing_onset_date,tek_age_distribution,onset_to_upload_distribution,requests_with_symptom_onset,requests_with_test_date,days_since_first_key,report_type_unknown,report_type_confirmed,report_type_likely,report_type_self_report,requests_from_travelers
+2020-02-03,1,2,3,10,9,7,2|4|5,1|3|4,5,3,12,1,3,2,0,4
 `,
 		},
 	}
@@ -133,3 +145,140 @@ func TestStatsDays_MarshalCSV(t *testing.T) {
 		})
 	}
 }
+
+func TestStatsRequestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		window string
+		err    bool
+	}{
+		{name: "default", window: ""},
+		{name: "day", window: StatsWindowDay},
+		{name: "week", window: StatsWindowWeek},
+		{name: "month", window: StatsWindowMonth},
+		{name: "invalid", window: "fortnight", err: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := &StatsRequest{Window: tc.window}
+			if err := request.Validate(); (err != nil) != tc.err {
+				t.Errorf("expected error: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestMarshalJSONLines(t *testing.T) {
+	t.Parallel()
+
+	stats := StatsDays{
+		{
+			Day:                time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC),
+			PublishRequests:    PublishRequests{Android: 2, IOS: 3},
+			TotalTEKsPublished: 10,
+			TEKAgeDistribution: []int64{2, 4, 5},
+		},
+		{
+			Day:                       time.Date(2020, 2, 4, 0, 0, 0, 0, time.UTC),
+			DaysSinceFirstKey:         1,
+			PublishRequests:           PublishRequests{UnknownPlatform: 1},
+			OnsetToUploadDistribution: []int64{1, 3, 4},
+			ReportTypes:               ReportTypeRequests{Confirmed: 1},
+		},
+	}
+
+	b, err := stats.MarshalJSONLines()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got StatsDays
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var day StatsDay
+		if err := json.Unmarshal(scanner.Bytes(), &day); err != nil {
+			t.Fatalf("line %d: %v", len(got), err)
+		}
+		got = append(got, &day)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(stats, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if b, err := StatsDays(nil).MarshalJSONLines(); err != nil || b != nil {
+		t.Errorf("expected no output for no stats, got %q, %v", b, err)
+	}
+}
+
+func TestMarshalDistributionCSV(t *testing.T) {
+	t.Parallel()
+
+	stats := StatsDays{
+		{
+			Day:                       time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC),
+			TEKAgeDistribution:        []int64{2, 4, 5},
+			OnsetToUploadDistribution: []int64{1, 3},
+		},
+		{
+			Day:                       time.Date(2020, 2, 4, 0, 0, 0, 0, time.UTC),
+			TEKAgeDistribution:        []int64{0, 1},
+			OnsetToUploadDistribution: []int64{7, 8},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		distribution string
+		exp          string
+		err          bool
+	}{
+		{
+			name:         "tek_age",
+			distribution: StatsDistributionTEKAge,
+			exp: `day,0,1,2
+2020-02-03,2,4,5
+2020-02-04,0,1,0
+`,
+		},
+		{
+			name:         "onset_to_upload",
+			distribution: StatsDistributionOnsetToUpload,
+			exp: `day,0,1
+2020-02-03,1,3
+2020-02-04,7,8
+`,
+		},
+		{
+			name:         "unknown",
+			distribution: "platform_distribution",
+			err:          true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := stats.MarshalDistributionCSV(tc.distribution)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.exp, string(b)); diff != "" {
+				t.Errorf("bad csv (-want, +got): %s", diff)
+			}
+		})
+	}
+}
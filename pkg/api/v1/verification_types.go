@@ -76,6 +76,10 @@ type VerificationClaims struct {
 	// onset will be calculated.
 	// Optional. If present, TEKs will be adjusted accordingly on publish.
 	SymptomOnsetInterval uint32 `json:"symptomOnsetInterval,omitempty"`
+	// TestDateInterval uses the same 10 minute interval timing as TEKs use, and is the date the test was taken.
+	// Optional. If present and there is no symptom onset interval, it is used to estimate symptom onset, using the
+	// offset configured for the health authority.
+	TestDateInterval uint32 `json:"testDateInterval,omitempty"`
 
 	// SignedMac is the HMAC of the TEKs that may be uploaded with the certificate containing these claims.
 	// Required, indicates what can be uploaded with this certificate.
@@ -0,0 +1,106 @@
+// Copyright 2021 the Exposure Notifications Server authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/pkg/errcmp"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(t *testing.T, pub crypto.PublicKey) string {
+		t.Helper()
+
+		b, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}))
+	}
+
+	cases := []struct {
+		name string
+		pem  string
+		want crypto.PublicKey
+		err  string
+	}{
+		{
+			name: "ecdsa",
+			pem:  encode(t, &ecKey.PublicKey),
+			want: &ecKey.PublicKey,
+		},
+		{
+			name: "rsa",
+			pem:  encode(t, &rsaKey.PublicKey),
+			want: &rsaKey.PublicKey,
+		},
+		{
+			name: "ed25519",
+			pem:  encode(t, edPublic),
+			want: edPublic,
+		},
+		{
+			name: "pkcs1",
+			pem:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})),
+			err:  "x509.ParsePKIXPublicKey",
+		},
+		{
+			name: "not_pem",
+			pem:  "foo",
+			err:  "unable to decode PEM block",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePublicKey(tc.pem)
+			errcmp.MustMatch(t, err, tc.err)
+			if tc.want == nil {
+				return
+			}
+			if eq, ok := got.(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(tc.want) {
+				t.Errorf("expected %v to be %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -34,3 +34,22 @@ resource "google_secret_manager_secret_version" "revision_token_aad_secret_versi
   secret      = google_secret_manager_secret.revision_token_aad.id
   secret_data = random_id.revision_token_aad.b64_std
 }
+
+resource "random_id" "stats_noise_key" {
+  byte_length = 32
+}
+
+resource "google_secret_manager_secret" "stats_noise_key" {
+  secret_id = "stats-noise-key"
+  replication {
+    automatic = true
+  }
+  depends_on = [
+    google_project_service.services["secretmanager.googleapis.com"],
+  ]
+}
+
+resource "google_secret_manager_secret_version" "stats_noise_key_secret_version" {
+  secret      = google_secret_manager_secret.stats_noise_key.id
+  secret_data = random_id.stats_noise_key.b64_std
+}
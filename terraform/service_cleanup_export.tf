@@ -188,3 +188,30 @@ resource "google_cloud_scheduler_job" "cleanup-export-worker" {
     google_project_service.services["cloudscheduler.googleapis.com"],
   ]
 }
+
+resource "google_cloud_scheduler_job" "cleanup-export-reconcile" {
+  name             = "cleanup-export-reconcile"
+  region           = var.cloudscheduler_location
+  schedule         = var.cleanup_export_reconcile_cron_schedule
+  time_zone        = "America/Los_Angeles"
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "POST"
+    uri         = "${google_cloud_run_service.cleanup-export.status.0.url}/reconcile"
+    oidc_token {
+      audience              = google_cloud_run_service.cleanup-export.status.0.url
+      service_account_email = google_service_account.cleanup-export-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.cleanup-export-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
@@ -45,6 +45,12 @@ resource "google_secret_manager_secret_iam_member" "export-db" {
   member    = "serviceAccount:${google_service_account.export.email}"
 }
 
+resource "google_secret_manager_secret_iam_member" "export-stats-noise-key" {
+  secret_id = google_secret_manager_secret.stats_noise_key.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.export.email}"
+}
+
 resource "google_storage_bucket_iam_member" "export-objectadmin" {
   bucket = google_storage_bucket.export.name
   role   = "roles/storage.objectAdmin" // overwrite is not included in objectCreator
@@ -100,6 +106,11 @@ resource "google_cloud_run_service" "export" {
         dynamic "env" {
           for_each = merge(
             local.common_cloudrun_env_vars,
+            {
+              // Must match the exposure service, so that exported stats have
+              // the same noise as the stats API.
+              "STATS_NOISE_KEY" = "secret://${google_secret_manager_secret_version.stats_noise_key_secret_version.id}"
+            },
 
             // This MUST come last to allow overrides!
             lookup(var.service_environment, "_all", {}),
@@ -225,3 +236,30 @@ resource "google_cloud_scheduler_job" "export-create-batches" {
     google_project_service.services["cloudscheduler.googleapis.com"],
   ]
 }
+
+resource "google_cloud_scheduler_job" "export-stats" {
+  name             = "export-stats"
+  region           = var.cloudscheduler_location
+  schedule         = var.export_stats_cron_schedule
+  time_zone        = "America/Los_Angeles"
+  attempt_deadline = "600s"
+
+  retry_config {
+    retry_count = 3
+  }
+
+  http_target {
+    http_method = "GET"
+    uri         = "${google_cloud_run_service.export.status.0.url}/export-stats"
+    oidc_token {
+      audience              = google_cloud_run_service.export.status.0.url
+      service_account_email = google_service_account.export-invoker.email
+    }
+  }
+
+  depends_on = [
+    google_app_engine_application.app,
+    google_cloud_run_service_iam_member.export-invoker,
+    google_project_service.services["cloudscheduler.googleapis.com"],
+  ]
+}
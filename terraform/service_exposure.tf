@@ -51,6 +51,12 @@ resource "google_secret_manager_secret_iam_member" "revision-token-aad" {
   member    = "serviceAccount:${google_service_account.exposure.email}"
 }
 
+resource "google_secret_manager_secret_iam_member" "exposure-stats-noise-key" {
+  secret_id = google_secret_manager_secret.stats_noise_key.id
+  role      = "roles/secretmanager.secretAccessor"
+  member    = "serviceAccount:${google_service_account.exposure.email}"
+}
+
 resource "google_kms_key_ring_iam_member" "revision-tokens-encrypt-decrypt" {
   key_ring_id = google_kms_key_ring.revision-tokens.self_link
   role        = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
@@ -104,6 +110,7 @@ resource "google_cloud_run_service" "exposure" {
             {
               "REVISION_TOKEN_KEY_ID" = google_kms_crypto_key.token-key.self_link
               "REVISION_TOKEN_AAD"    = "secret://${google_secret_manager_secret_version.revision_token_aad_secret_version.id}"
+              "STATS_NOISE_KEY"       = "secret://${google_secret_manager_secret_version.stats_noise_key_secret_version.id}"
             },
 
             // This MUST come last to allow overrides!
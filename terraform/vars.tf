@@ -186,6 +186,13 @@ variable "export_create_batches_cron_schedule" {
   description = "Schedule to execute the export create batches service."
 }
 
+variable "export_stats_cron_schedule" {
+  type    = string
+  default = "0 4 * * *"
+
+  description = "Schedule to export the released stats of health authorities to their stats export buckets."
+}
+
 variable "cleanup_exposure_worker_cron_schedule" {
   type    = string
   default = "0 */4 * * *"
@@ -200,6 +207,13 @@ variable "cleanup_export_worker_cron_schedule" {
   description = "Schedule to execute the cleanup export worker service."
 }
 
+variable "cleanup_export_reconcile_cron_schedule" {
+  type    = string
+  default = "30 3 * * *"
+
+  description = "Schedule to reconcile the export buckets with the database."
+}
+
 variable "generate_cron_schedule" {
   type    = string
   default = "0 0 1 1 0"
@@ -19,7 +19,6 @@ import (
 	"context"
 	"flag"
 	"log"
-	"regexp"
 	"time"
 
 	"github.com/google/exposure-notifications-server/internal/federationin"
@@ -31,16 +30,12 @@ import (
 )
 
 var (
-	validQueryIDStr    = `\A[a-z][a-z0-9-_]*[a-z0-9]\z`
-	validQueryIDRegexp = regexp.MustCompile(validQueryIDStr)
-
-	validServerAddrStr    = `\A[a-z0-9.-]+(:\d+)?\z`
-	validServerAddrRegexp = regexp.MustCompile(validServerAddrStr)
-
-	queryID       = flag.String("query-id", "", "(Required) The ID of the federation query to set.")
-	serverAddr    = flag.String("server-addr", "", "(Required) The address of the remote server, in the form some-server:some-port")
-	audience      = flag.String("audience", federationin.DefaultAudience, "(Required) The OIDC audience to use when creating client tokens.")
-	lastTimestamp = flag.String("last-timestamp", "", "The last timestamp (RFC3339) to set; queries start from this point and go forward.")
+	queryID        = flag.String("query-id", "", "(Required) The ID of the federation query to set.")
+	serverAddr     = flag.String("server-addr", "", "(Required) The address of the remote server, in the form some-server:some-port")
+	audience       = flag.String("audience", federationin.DefaultAudience, "(Required) The OIDC audience to use when creating client tokens.")
+	credentialType = flag.String("credential-type", model.CredentialTypeGoogle, "The credentials sent to the remote server, one of GOOGLE, OIDC, STATIC or NONE.")
+	transport      = flag.String("transport", model.TransportGRPC, "How the remote server is called, GRPC or HTTP for its HTTP+JSON gateway.")
+	lastTimestamp  = flag.String("last-timestamp", "", "The last timestamp (RFC3339) to set; queries start from this point and go forward.")
 )
 
 func main() {
@@ -52,14 +47,14 @@ func main() {
 	if *queryID == "" {
 		log.Fatalf("--query-id is required")
 	}
-	if !validQueryIDRegexp.MatchString(*queryID) {
-		log.Fatalf("--query-id %q must match %s", *queryID, validQueryIDStr)
+	if !model.ValidQueryIDRegexp.MatchString(*queryID) {
+		log.Fatalf("--query-id %q must match %s", *queryID, model.ValidQueryIDStr)
 	}
 	if *serverAddr == "" {
 		log.Fatalf("--server-addr is required")
 	}
-	if !validServerAddrRegexp.MatchString(*serverAddr) {
-		log.Fatalf("--server-addr %q must match %s", *serverAddr, validServerAddrStr)
+	if !model.ValidServerAddrRegexp.MatchString(*serverAddr) {
+		log.Fatalf("--server-addr %q must match %s", *serverAddr, model.ValidServerAddrStr)
 	}
 	if *audience == "" {
 		log.Fatalf("--audience is required")
@@ -67,6 +62,12 @@ func main() {
 	if !federationin.ValidAudienceRegexp.MatchString(*audience) {
 		log.Fatalf("--audience %q must match %s", *audience, federationin.ValidAudienceStr)
 	}
+	if !oneOf(*credentialType, model.CredentialTypes) {
+		log.Fatalf("--credential-type %q must be one of %v", *credentialType, model.CredentialTypes)
+	}
+	if !oneOf(*transport, model.Transports) {
+		log.Fatalf("--transport %q must be one of %v", *transport, model.Transports)
+	}
 	var lastTime time.Time
 	if *lastTimestamp != "" {
 		var err error
@@ -90,6 +91,8 @@ func main() {
 		QueryID:        *queryID,
 		ServerAddr:     *serverAddr,
 		Audience:       *audience,
+		CredentialType: *credentialType,
+		Transport:      *transport,
 		IncludeRegions: includeRegions,
 		ExcludeRegions: excludeRegions,
 		LastTimestamp:  lastTime,
@@ -101,3 +104,12 @@ func main() {
 
 	log.Printf("Successfully added query %s %#v", *queryID, query)
 }
+
+func oneOf(value string, valid []string) bool {
+	for _, v := range valid {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -36,7 +36,8 @@ const (
 var (
 	testRegions = []string{"TEST", "PROBE"}
 
-	subject  = flag.String("subject", "", "(Required) The OIDC subject (for issuer https://accounts.google.com, this is the obfuscated Gaia ID.)")
+	issuer   = flag.String("issuer", defaultIssuer, "The OIDC issuer, or the issuer distinguished name of the client certificate for mutual TLS.")
+	subject  = flag.String("subject", "", "(Required) The OIDC subject (for issuer https://accounts.google.com, this is the obfuscated Gaia ID), or the subject distinguished name of the client certificate for mutual TLS.")
 	audience = flag.String("audience", federationin.DefaultAudience, "The OIDC audience; leaving this blank will cause server to not enforce the audience claim.")
 	note     = flag.String("note", "", "An open text note to include on the record.")
 )
@@ -47,6 +48,9 @@ func main() {
 	flag.Var(&excludeRegions, "exclude-regions", "A comma-separated list fo regions to exclude from the query.")
 	flag.Parse()
 
+	if *issuer == "" {
+		log.Fatalf("--issuer is required")
+	}
 	if *subject == "" {
 		log.Fatalf("--subject is required")
 	}
@@ -80,7 +84,7 @@ func main() {
 	db := database.New(env.Database())
 
 	auth := &model.FederationOutAuthorization{
-		Issuer:         defaultIssuer, // Authorization interceptor currently only supports defaultIssuer.
+		Issuer:         *issuer,
 		Subject:        *subject,
 		Audience:       *audience,
 		Note:           *note,